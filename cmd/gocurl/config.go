@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/erfi/gocurl/internal/app"
+	"github.com/erfi/gocurl/internal/output"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	profileName   string
+	showAllConfig bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect configuration files and profiles",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [flags]",
+	Short: "Print the effective configuration and where each value comes from",
+	Long: `show prints the settings a run would use after merging, in order of
+precedence, command-line flags, GOCURL_* environment variables, the selected
+profile and the built-in defaults.
+
+Profiles are read from ~/.config/gocurl/config.yaml (or $XDG_CONFIG_HOME)
+and from .gocurl.yaml in the working directory or a parent, which overrides
+the user file.`,
+	Example: `  gocurl config show
+  gocurl config show -P staging-edge
+  gocurl config show -P staging-edge --timeout 2s --all`,
+	Args: cobra.NoArgs,
+	RunE: runConfigShow,
+}
+
+func init() {
+	configShowCmd.Flags().BoolVar(&showAllConfig, "all", false, "Include settings left at their defaults")
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// configSources is the outcome of merging flags, environment and profile
+type configSources struct {
+	profile string
+	files   []string
+	baseURL string
+	sources map[string]string // Flag name -> where its value came from
+}
+
+// applyConfigSources fills every flag not given on the command line from its
+// GOCURL_* environment variable or, failing that, the selected profile. List
+// flags such as -H and --resolve are merged instead, with the higher source
+// winning for the same header or host.
+func applyConfigSources(flags *pflag.FlagSet) (*configSources, error) {
+	dir, _ := os.Getwd()
+	files, err := app.LoadConfigFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	name := profileName
+	if name == "" {
+		name = os.Getenv(app.EnvName("profile"))
+	}
+	name, profile, err := files.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+	for key := range profile {
+		if key != app.BaseURLSetting && flags.Lookup(key) == nil {
+			return nil, fmt.Errorf("profile '%s': unknown setting '%s' (settings are long flag names, e.g. header, timeout)", name, key)
+		}
+	}
+
+	cs := &configSources{profile: name, files: files.Paths, sources: make(map[string]string)}
+	profileSource := "profile " + name
+
+	var applyErr error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if applyErr != nil || f.Name == "profile" || f.Name == "help" {
+			return
+		}
+		envName := app.EnvName(f.Name)
+		env, hasEnv := os.LookupEnv(envName)
+		values, hasProfile := profile[f.Name]
+
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			var merged, sources []string
+			if hasProfile {
+				merged = values
+				sources = append(sources, profileSource)
+			}
+			if hasEnv {
+				// Values such as headers may contain commas, so lists are newline-separated
+				merged = mergeList(f.Name, merged, strings.Split(strings.TrimSpace(env), "\n"))
+				sources = append(sources, "env "+envName)
+			}
+			if f.Changed {
+				merged = mergeList(f.Name, merged, slice.GetSlice())
+				sources = append(sources, "flag")
+			}
+			if len(sources) > 0 {
+				applyErr = slice.Replace(merged)
+				cs.sources[f.Name] = strings.Join(sources, " + ")
+			}
+			return
+		}
+
+		switch {
+		case f.Changed:
+			cs.sources[f.Name] = "flag"
+		case hasEnv:
+			if err := f.Value.Set(env); err != nil {
+				applyErr = fmt.Errorf("invalid %s: %w", envName, err)
+			}
+			cs.sources[f.Name] = "env " + envName
+		case hasProfile:
+			if len(values) != 1 {
+				applyErr = fmt.Errorf("profile '%s': %s takes a single value", name, f.Name)
+			} else if err := f.Value.Set(values[0]); err != nil {
+				applyErr = fmt.Errorf("profile '%s': invalid %s: %w", name, f.Name, err)
+			}
+			cs.sources[f.Name] = profileSource
+		}
+	})
+	if applyErr != nil {
+		return nil, applyErr
+	}
+
+	if env, ok := os.LookupEnv(app.EnvName(app.BaseURLSetting)); ok {
+		cs.baseURL = env
+		cs.sources[app.BaseURLSetting] = "env " + app.EnvName(app.BaseURLSetting)
+	} else if values, ok := profile[app.BaseURLSetting]; ok && len(values) > 0 {
+		cs.baseURL = values[0]
+		cs.sources[app.BaseURLSetting] = profileSource
+	}
+	return cs, nil
+}
+
+// mergeList appends a higher-precedence list to a lower one; headers are
+// merged by name
+func mergeList(name string, lower, higher []string) []string {
+	if name == "header" {
+		return app.MergeHeaders(lower, higher)
+	}
+	return append(append([]string{}, lower...), higher...)
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	cs, err := applyConfigSources(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if noColor {
+		color.NoColor = true
+	}
+
+	report := output.ConfigReport{Profile: cs.profile, Files: cs.files}
+	if cs.baseURL != "" {
+		report.Settings = append(report.Settings, output.Setting{
+			Name:   app.BaseURLSetting,
+			Value:  app.MaskSecret(app.BaseURLSetting, cs.baseURL),
+			Source: cs.sources[app.BaseURLSetting],
+		})
+	}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" || f.Name == "all" {
+			return
+		}
+		source, ok := cs.sources[f.Name]
+		if !ok {
+			if f.Name == "profile" || !showAllConfig {
+				return
+			}
+			source = "default"
+		}
+		// Output of config show gets pasted into tickets, so credentials
+		// are masked; the source still shows where they came from
+		value := app.MaskSecret(f.Name, f.Value.String())
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			var values []string
+			for _, v := range slice.GetSlice() {
+				values = append(values, app.MaskSecret(f.Name, v))
+			}
+			value = strings.Join(values, "\n")
+		}
+		report.Settings = append(report.Settings, output.Setting{Name: f.Name, Value: value, Source: source})
+	})
+
+	if outputFormat == "json" {
+		return output.WriteConfigJSON(os.Stdout, report)
+	}
+	output.WriteConfigTable(os.Stdout, report)
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/erfi/gocurl/internal/app"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchDNSServers string
+	benchDoHURL     string
+	benchDNSOverTLS bool
+	benchQueries    int
+	benchTimeout    string
+	benchNoSystem   bool
+)
+
+var dnsBenchCmd = &cobra.Command{
+	Use:   "dns-bench [flags] HOST",
+	Short: "Compare lookup latency of DNS resolvers",
+	Long: `dns-bench resolves HOST repeatedly through the system resolver and each
+configured DNS server, DoT server or DoH endpoint, and reports lookup
+latency percentiles per resolver.`,
+	Example: `  gocurl dns-bench --dns-servers 1.1.1.1,8.8.8.8 example.com
+  gocurl dns-bench --dns-servers 1.1.1.1 --dns-over-tls example.com
+  gocurl dns-bench --doh-url https://cloudflare-dns.com/dns-query -n 50 example.com`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDNSBench,
+}
+
+func init() {
+	dnsBenchCmd.Flags().StringVar(&benchDNSServers, "dns-servers", "", "Comma-separated DNS servers to benchmark")
+	dnsBenchCmd.Flags().StringVar(&benchDoHURL, "doh-url", "", "DNS-over-HTTPS endpoint to benchmark")
+	dnsBenchCmd.Flags().BoolVar(&benchDNSOverTLS, "dns-over-tls", false, "Query --dns-servers over DNS-over-TLS (port 853)")
+	dnsBenchCmd.Flags().IntVarP(&benchQueries, "queries", "n", 20, "Number of lookups per resolver")
+	dnsBenchCmd.Flags().StringVar(&benchTimeout, "timeout", "5s", "Timeout per lookup")
+	dnsBenchCmd.Flags().BoolVar(&benchNoSystem, "no-system", false, "Skip the system resolver")
+
+	rootCmd.AddCommand(dnsBenchCmd)
+}
+
+func runDNSBench(cmd *cobra.Command, args []string) error {
+	if noColor {
+		color.NoColor = true
+	}
+
+	return app.RunDNSBench(&app.DNSBenchConfig{
+		Host:          args[0],
+		DNSServers:    splitList(benchDNSServers),
+		DoHURL:        benchDoHURL,
+		DNSOverTLS:    benchDNSOverTLS,
+		Queries:       benchQueries,
+		Timeout:       benchTimeout,
+		IncludeSystem: !benchNoSystem,
+		OutputFormat:  outputFormat,
+		Quiet:         quiet,
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var fromCurlCmd = &cobra.Command{
+	Use:   "from-curl [flags] 'curl ...'",
+	Short: "Measure a request given as a curl command line",
+	Long: `from-curl parses a curl command, such as one copied with "Copy as cURL"
+from browser devtools, and measures the request it describes. Headers,
+data, credentials, TLS and connection options are imported; options that
+gocurl cannot reproduce are reported as warnings. gocurl flags such as
+-n, -c and -o still control the run.
+
+As with curl, redirects are only followed when the command includes -L.`,
+	Example: `  gocurl from-curl 'curl -X POST -H "Content-Type: application/json" --data-raw "{}" https://api.example.com/items'
+  gocurl from-curl -n 100 -c 10 "$(cat request.sh)"
+  pbpaste | gocurl from-curl -`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFromCurl,
+}
+
+func init() {
+	rootCmd.AddCommand(fromCurlCmd)
+}
+
+func runFromCurl(cmd *cobra.Command, args []string) error {
+	sources, err := applyConfigSources(cmd.Flags())
+	if err != nil {
+		return err
+	}
+
+	command := args[0]
+	if command == "-" {
+		contents, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read curl command: %w", err)
+		}
+		command = string(contents)
+	}
+	return runMeasurement(nil, command, sources.baseURL)
+}
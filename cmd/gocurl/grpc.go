@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/erfi/gocurl/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	grpcProtos      []string
+	grpcImportPaths []string
+	grpcProtosets   []string
+	grpcPlaintext   bool
+)
+
+var grpcCmd = &cobra.Command{
+	Use:   "grpc [flags] address [service/method]",
+	Short: "Call a unary or server-streaming gRPC method and measure it",
+	Long: `grpc invokes a method over HTTP/2 with the request message given as JSON
+in --data, and reports the DNS, TCP and TLS phases like any other request
+with the grpc-status in place of the HTTP status. The address is host:port
+(TLS unless --plaintext) or an http:// or https:// URL.
+
+The schema comes from server reflection unless --proto or --protoset is
+given. Without a method, grpc lists the services the server exposes through
+reflection.
+
+Server-streaming responses are analysed like the chunks of a stream, so
+--expect-streaming catches a proxy that buffers the messages. -n, -c and -d
+turn the call into a load test, reported with a gRPC status distribution.`,
+	Example: `  gocurl grpc api.example.com:443
+  gocurl grpc api.example.com:443 helloworld.Greeter/SayHello --data '{"name": "gocurl"}'
+  gocurl grpc --plaintext localhost:50051 --proto route_guide.proto routeguide.RouteGuide/ListFeatures
+  gocurl grpc --protoset api.protoset -n 1000 -c 20 api.example.com:443 api.v1.Users/Get --data '{"id": 7}'`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runGRPC,
+	// A failing call is a test result, not a usage mistake
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(grpcCmd)
+	grpcCmd.Flags().StringArrayVar(&grpcProtos, "proto", nil, "Compile the schema from a .proto file instead of using reflection (repeatable)")
+	grpcCmd.Flags().StringArrayVar(&grpcImportPaths, "import-path", nil, "Directory to search for --proto files and their imports (repeatable)")
+	grpcCmd.Flags().StringArrayVar(&grpcProtosets, "protoset", nil, "Read the schema from a compiled descriptor set (repeatable)")
+	grpcCmd.Flags().BoolVar(&grpcPlaintext, "plaintext", false, "Use HTTP/2 without TLS (h2c) for a host:port address")
+}
+
+func runGRPC(cmd *cobra.Command, args []string) error {
+	if _, err := applyConfigSources(cmd.Flags()); err != nil {
+		return err
+	}
+	if err := applyFlagImplications(); err != nil {
+		return err
+	}
+
+	opts := app.GRPCOptions{
+		Target:      args[0],
+		Protos:      grpcProtos,
+		ImportPaths: grpcImportPaths,
+		Protosets:   grpcProtosets,
+		Plaintext:   grpcPlaintext,
+	}
+	if len(args) > 1 {
+		opts.Method = args[1]
+	}
+	return app.RunGRPC(opts, buildConfig(nil))
+}
@@ -2,36 +2,86 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/erfi/gocurl/internal/app"
+	"github.com/erfi/gocurl/internal/client"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
 var (
-	outputFormat   string
-	noColor        bool
-	verbose        bool
-	quiet          bool
-	requests       int
-	concurrency    int
-	duration       string
-	headers        []string
-	method         string
-	data           string
-	timeout        string
-	insecure       bool
-	urlListFile    string
-	useStdin       bool
-	includeHeaders bool
-	showBody       bool
-	showErrorBody  bool
-	headRequest    bool
-	enableStreaming bool
-	resolveHosts   []string
-	connectToHosts []string
-	expectStreaming bool
-	stallThreshold  string
+	outputFormat     string
+	noColor          bool
+	verbose          bool
+	quiet            bool
+	requests         int
+	concurrency      int
+	duration         string
+	headers          []string
+	method           string
+	data             string
+	timeout          string
+	insecure         bool
+	urlListFile      string
+	useStdin         bool
+	includeHeaders   bool
+	showBody         bool
+	showErrorBody    bool
+	headRequest      bool
+	enableStreaming  bool
+	resolveHosts     []string
+	connectToHosts   []string
+	expectStreaming  bool
+	stallThreshold   string
+	compareProtocols bool
+	certFile         string
+	keyFile          string
+	caCert           string
+	caPath           string
+	tlsMin           string
+	tlsMax           string
+	ciphers          string
+	curves           string
+	sni              string
+	certExpiryWarn   string
+	http1            bool
+	http2            bool
+	http3            bool
+	tlsResume        int
+	tlsKeyLog        string
+	dnsServers       string
+	dohURL           string
+	dnsOverTLS       bool
+	ipv4Only         bool
+	ipv6Only         bool
+	proxyURL         string
+	proxyUser        string
+	noProxy          string
+	maxRedirs        int
+	noFollow         bool
+	cookie           string
+	cookieJar        string
+	cookiePerWorker  bool
+	user             string
+	digest           bool
+	oauth2Bearer     string
+	awsSigV4         string
+	oauth2TokenURL   string
+	oauth2ClientID   string
+	oauth2Secret     string
+	oauth2Scope      string
+	compressed       string
+	compareEncodings bool
+	fromCurl         string
+	emit             string
+	streamFormat     string
+	h2Trace          bool
+	wsMessages       int
+	wsListen         string
+	wsScript         string
 )
 
 var rootCmd = &cobra.Command{
@@ -47,8 +97,11 @@ TCP connection time, TLS handshake time, server processing time, and more.`,
   gocurl -o json https://api.example.com
   gocurl -o graph -n 100 -c 10 https://api.example.com
   gocurl -H "Authorization: Bearer token" https://api.example.com
+  gocurl --compare-protocols -n 50 -c 5 https://api.example.com
   gocurl -L urls.txt -n 10 -c 5
-  cat urls.txt | gocurl -L - -n 10`,
+  cat urls.txt | gocurl -L - -n 10
+  gocurl --from-curl request.sh -n 20
+  gocurl dns-bench --dns-servers 1.1.1.1,8.8.8.8 example.com`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runHTTPTest,
 }
@@ -60,6 +113,9 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output with additional details")
 	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Minimal output (errors only)")
 
+	// Configuration flags
+	rootCmd.Flags().StringVarP(&profileName, "profile", "P", "", "Use a named profile from ~/.config/gocurl/config.yaml or .gocurl.yaml (also GOCURL_PROFILE)")
+
 	// HTTP flags
 	rootCmd.Flags().IntVarP(&requests, "requests", "n", 1, "Number of requests per URL")
 	rootCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 1, "Concurrent workers")
@@ -71,37 +127,112 @@ func init() {
 	rootCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Skip TLS verification")
 	rootCmd.Flags().StringVarP(&urlListFile, "url-list", "L", "", "File containing URLs (one per line), use '-' for stdin")
 	rootCmd.Flags().BoolVar(&useStdin, "stdin", false, "Read URLs from stdin")
+	rootCmd.Flags().StringVar(&fromCurl, "from-curl", "", "Import the request from a curl command line in FILE ('-' for stdin)")
+	rootCmd.Flags().StringVar(&emit, "emit", "", "Print an equivalent command or program: curl|httpie|go (load tests: one per URL with failed requests, printed whenever --emit is set)")
+	rootCmd.Flags().StringVarP(&cookie, "cookie", "b", "", "Send cookies: \"name=value; name2=value2\" or a Netscape cookie file to load")
+	rootCmd.Flags().StringVar(&cookieJar, "cookie-jar", "", "Write all cookies to FILE (Netscape format) when the run ends")
+	rootCmd.Flags().BoolVar(&cookiePerWorker, "cookie-per-worker", false, "Give each load-test worker its own cookie jar (one session per worker)")
+
+	// Authentication flags
+	rootCmd.Flags().StringVarP(&user, "user", "u", "", "Credentials as user:password (basic auth unless --digest; access key:secret with --aws-sigv4)")
+	rootCmd.Flags().BoolVar(&digest, "digest", false, "Use HTTP digest authentication for --user")
+	rootCmd.Flags().StringVar(&oauth2Bearer, "oauth2-bearer", "", "Send a static OAuth2 bearer token")
+	rootCmd.Flags().StringVar(&awsSigV4, "aws-sigv4", "", "Sign requests with AWS SigV4: provider:region:service (e.g., aws:us-east-1:s3)")
+	rootCmd.Flags().StringVar(&oauth2TokenURL, "oauth2-token-url", "", "Fetch a bearer token from this endpoint (client credentials grant)")
+	rootCmd.Flags().StringVar(&oauth2ClientID, "oauth2-client-id", "", "OAuth2 client ID for --oauth2-token-url")
+	rootCmd.Flags().StringVar(&oauth2Secret, "oauth2-client-secret", "", "OAuth2 client secret for --oauth2-token-url")
+	rootCmd.Flags().StringVar(&oauth2Scope, "oauth2-scope", "", "Scope to request with --oauth2-token-url")
 
 	// Response display flags
 	rootCmd.Flags().BoolVarP(&includeHeaders, "include", "i", false, "Include response headers in output")
 	rootCmd.Flags().BoolVarP(&headRequest, "head", "I", false, "Make HEAD request (show headers only)")
+	rootCmd.Flags().IntVar(&maxRedirs, "max-redirs", client.DefaultMaxRedirects, "Maximum number of redirects to follow (0 disables following)")
+	rootCmd.Flags().BoolVar(&noFollow, "no-follow", false, "Do not follow redirects; report the redirect response itself")
 	rootCmd.Flags().BoolVar(&showBody, "show-body", false, "Show response body in output")
 	rootCmd.Flags().BoolVar(&showErrorBody, "show-error", false, "Show response body for error responses (4xx, 5xx)")
+	rootCmd.Flags().StringVar(&compressed, "compressed", "", "Request compression and report wire vs decoded size; optionally pick encodings (--compressed=br,zstd)")
+	rootCmd.Flags().Lookup("compressed").NoOptDefVal = "all"
+	rootCmd.Flags().BoolVar(&compareEncodings, "compare-encodings", false, "Run the request once per encoding (identity, zstd, br, gzip, deflate) and compare sizes")
 
 	// Performance analysis flags
 	rootCmd.Flags().BoolVar(&enableStreaming, "streaming", false, "Enable detailed streaming metrics (chunk-level timing)")
 	rootCmd.Flags().BoolVar(&expectStreaming, "expect-streaming", false, "Exit with error if streaming is not detected (implies --streaming)")
 	rootCmd.Flags().StringVar(&stallThreshold, "stall-threshold", "500ms", "Duration threshold for detecting stalls in streaming")
+	rootCmd.Flags().StringVar(&streamFormat, "stream-format", "", "Decode model token deltas: openai|anthropic|ndjson (implies --streaming)")
+	rootCmd.Flags().BoolVar(&h2Trace, "h2-trace", false, "Trace HTTP/2 frames: SETTINGS, flow control, DATA timing, RST_STREAM, GOAWAY and PING RTT")
+	rootCmd.Flags().IntVar(&wsMessages, "ws-messages", 0, "Echo round trips to time on a ws:// or wss:// URL, sending --data (default 5 without --ws-script or --ws-listen)")
+	rootCmd.Flags().StringVar(&wsListen, "ws-listen", "", "Collect server-pushed WebSocket messages for this long before closing (e.g., 10s)")
+	rootCmd.Flags().StringVar(&wsScript, "ws-script", "", "YAML file of WebSocket send/expect/receive/sleep steps ('-' for stdin)")
+	rootCmd.Flags().BoolVar(&compareProtocols, "compare-protocols", false, "Run the request or load test over HTTP/1.1, HTTP/2 and HTTP/3 and compare")
 
 	// Connection control flags
 	rootCmd.Flags().StringArrayVar(&resolveHosts, "resolve", []string{}, "Resolve host:port to address (format: host:port:addr)")
 	rootCmd.Flags().StringArrayVar(&connectToHosts, "connect-to", []string{}, "Connect to host:port instead (format: host1:port1:host2:port2)")
+	rootCmd.Flags().StringVar(&dnsServers, "dns-servers", "", "Comma-separated DNS servers to use instead of the system resolver (e.g., 1.1.1.1,8.8.8.8:53)")
+	rootCmd.Flags().StringVar(&dohURL, "doh-url", "", "Resolve names over DNS-over-HTTPS using this endpoint")
+	rootCmd.Flags().BoolVar(&dnsOverTLS, "dns-over-tls", false, "Query --dns-servers over DNS-over-TLS (port 853)")
+	rootCmd.Flags().BoolVarP(&ipv4Only, "ipv4", "4", false, "Connect over IPv4 only")
+	rootCmd.Flags().BoolVarP(&ipv6Only, "ipv6", "6", false, "Connect over IPv6 only")
+	rootCmd.MarkFlagsMutuallyExclusive("ipv4", "ipv6")
+	rootCmd.Flags().StringVarP(&proxyURL, "proxy", "x", "", "Proxy URL: http://, https://, socks5:// or socks5h:// (default from HTTP_PROXY/HTTPS_PROXY)")
+	rootCmd.Flags().StringVarP(&proxyUser, "proxy-user", "U", "", "Proxy credentials (user:password)")
+	rootCmd.Flags().StringVar(&noProxy, "noproxy", "", "Comma-separated hosts that bypass the proxy (overrides NO_PROXY)")
+
+	// Protocol flags
+	rootCmd.Flags().BoolVar(&http1, "http1.1", false, "Use HTTP/1.1 only")
+	rootCmd.Flags().BoolVar(&http2, "http2", false, "Require HTTP/2 (prior knowledge for http:// URLs)")
+	rootCmd.Flags().BoolVar(&http3, "http3", false, "Use HTTP/3 over QUIC")
+	rootCmd.MarkFlagsMutuallyExclusive("http1.1", "http2", "http3", "compare-protocols")
+
+	// TLS flags
+	rootCmd.Flags().StringVar(&certFile, "cert", "", "Client certificate file (PEM) for mTLS")
+	rootCmd.Flags().StringVar(&keyFile, "key", "", "Client private key file (PEM), defaults to --cert")
+	rootCmd.Flags().StringVar(&caCert, "cacert", "", "CA bundle (PEM) to verify the server against")
+	rootCmd.Flags().StringVar(&caPath, "capath", "", "Directory of CA certificates (PEM) to verify the server against")
+	rootCmd.Flags().StringVar(&tlsMin, "tls-min", "", "Minimum TLS version (1.0, 1.1, 1.2, 1.3)")
+	rootCmd.Flags().StringVar(&tlsMax, "tls-max", "", "Maximum TLS version (1.0, 1.1, 1.2, 1.3)")
+	rootCmd.Flags().StringVar(&ciphers, "ciphers", "", "Comma-separated TLS 1.0-1.2 cipher suites (IANA names)")
+	rootCmd.Flags().StringVar(&curves, "curves", "", "Comma-separated key exchange groups (e.g., X25519,P-256)")
+	rootCmd.Flags().StringVar(&sni, "sni", "", "Override the TLS server name (SNI)")
+	rootCmd.Flags().StringVar(&tlsKeyLog, "tls-keylog", "", "Append TLS session keys to FILE for Wireshark (also honours SSLKEYLOGFILE)")
+	rootCmd.Flags().IntVar(&tlsResume, "tls-resume", 0, "Measure a full TLS handshake followed by N resumed handshakes on fresh connections")
+	rootCmd.Flags().StringVar(&certExpiryWarn, "cert-expiry-warn", "", "Exit with error if a certificate in the chain expires within this duration (e.g., 21d)")
+
+	// from-curl runs the same measurement, so it takes the same flags, and
+	// config show takes them to preview their effect; grpc sets up its
+	// connections and load tests with them
+	fromCurlCmd.Flags().AddFlagSet(rootCmd.Flags())
+	configShowCmd.Flags().AddFlagSet(rootCmd.Flags())
+	grpcCmd.Flags().AddFlagSet(rootCmd.Flags())
 }
 
 func runHTTPTest(cmd *cobra.Command, args []string) error {
-	if noColor {
-		color.NoColor = true
+	sources, err := applyConfigSources(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if fromCurl == "" {
+		return runMeasurement(args, "", sources.baseURL)
 	}
 
-	// Handle HEAD request flag
-	if headRequest {
-		method = "HEAD"
-		includeHeaders = true // Always show headers for HEAD requests
+	var contents []byte
+	if fromCurl == "-" {
+		contents, err = io.ReadAll(os.Stdin)
+	} else {
+		contents, err = os.ReadFile(fromCurl)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read curl command: %w", err)
 	}
+	return runMeasurement(args, string(contents), sources.baseURL)
+}
 
-	// --expect-streaming implies --streaming
-	if expectStreaming {
-		enableStreaming = true
+// runMeasurement builds the application config from the flags, applies an
+// imported curl command if there is one, and runs it. A relative URL argument
+// is resolved against the profile's base URL.
+func runMeasurement(args []string, curlCommand, baseURL string) error {
+	if err := applyFlagImplications(); err != nil {
+		return err
 	}
 
 	var urls []string
@@ -128,38 +259,165 @@ func runHTTPTest(cmd *cobra.Command, args []string) error {
 		}
 	} else if len(args) > 0 {
 		// Single URL from argument
-		urls = []string{args[0]}
-	} else {
+		urls = []string{app.ResolveURL(baseURL, args[0])}
+	} else if baseURL != "" && curlCommand == "" {
+		urls = []string{baseURL}
+	} else if curlCommand == "" {
 		return fmt.Errorf("no URL provided (use a URL argument or -L flag)")
 	}
 
-	config := &app.Config{
-		URLs:            urls,
-		Method:          method,
-		Headers:         headers,
-		Data:            data,
-		Requests:        requests,
-		Concurrency:     concurrency,
-		Duration:        duration,
-		Timeout:         timeout,
-		Insecure:        insecure,
-		OutputFormat:    outputFormat,
-		Verbose:         verbose,
-		Quiet:           quiet,
-		IncludeHeaders:  includeHeaders,
-		ShowBody:        showBody,
-		ShowErrorBody:   showErrorBody,
-		EnableStreaming: enableStreaming,
-		ResolveHosts:    resolveHosts,
-		ConnectToHosts:  connectToHosts,
-		ExpectStreaming: expectStreaming,
-		StallThreshold:  stallThreshold,
-	}
-
-	application := app.New(config)
+	config := buildConfig(urls)
+	// Load tests of the root command are bounded by -n; --duration applies
+	// to the run, scenario and grpc commands
+	config.Duration = ""
+
+	// The curl command describes the request; the flags still control the run
+	if curlCommand != "" {
+		warnings, err := app.ParseCurlCommand(curlCommand, config)
+		if err != nil {
+			return fmt.Errorf("failed to import curl command: %w", err)
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "%s %s\n", color.YellowString("⚠ curl:"), warning)
+		}
+	}
+
+	application, err := app.New(config)
+	if err != nil {
+		return err
+	}
 	return application.Run()
 }
 
+// applyFlagImplications applies the flags that imply others and rejects
+// invalid combinations
+func applyFlagImplications() error {
+	if noColor {
+		color.NoColor = true
+	}
+
+	// Handle HEAD request flag
+	if headRequest {
+		method = "HEAD"
+		includeHeaders = true // Always show headers for HEAD requests
+	}
+
+	// --expect-streaming and --stream-format imply --streaming
+	if expectStreaming || streamFormat != "" {
+		enableStreaming = true
+	}
+
+	if maxRedirs < 0 {
+		return fmt.Errorf("--max-redirs must be 0 or greater")
+	}
+	return nil
+}
+
+// buildConfig translates the flags into an application config
+func buildConfig(urls []string) *app.Config {
+	return &app.Config{
+		URLs:             urls,
+		Method:           method,
+		Headers:          headers,
+		Data:             data,
+		Requests:         requests,
+		Concurrency:      concurrency,
+		Duration:         duration,
+		Timeout:          timeout,
+		Insecure:         insecure,
+		OutputFormat:     outputFormat,
+		Verbose:          verbose,
+		Quiet:            quiet,
+		IncludeHeaders:   includeHeaders,
+		ShowBody:         showBody,
+		ShowErrorBody:    showErrorBody,
+		EnableStreaming:  enableStreaming,
+		ResolveHosts:     resolveHosts,
+		ConnectToHosts:   connectToHosts,
+		ExpectStreaming:  expectStreaming,
+		StallThreshold:   stallThreshold,
+		CompareProtocols: compareProtocols,
+		CertFile:         certFile,
+		KeyFile:          keyFile,
+		CACert:           caCert,
+		CAPath:           caPath,
+		TLSMin:           tlsMin,
+		TLSMax:           tlsMax,
+		Ciphers:          splitList(ciphers),
+		Curves:           splitList(curves),
+		SNI:              sni,
+		CertExpiryWarn:   certExpiryWarn,
+		Protocol:         selectedProtocol(),
+		TLSResume:        tlsResume,
+		TLSKeyLog:        tlsKeyLog,
+		DNSServers:       splitList(dnsServers),
+		DoHURL:           dohURL,
+		DNSOverTLS:       dnsOverTLS,
+		IPVersion:        selectedIPVersion(),
+		Proxy:            proxyURL,
+		ProxyUser:        proxyUser,
+		NoProxy:          noProxy,
+		MaxRedirects:     maxRedirs,
+		NoFollow:         noFollow || maxRedirs == 0,
+		Cookie:           cookie,
+		CookieJar:        cookieJar,
+		CookiePerWorker:  cookiePerWorker,
+		User:             user,
+		Digest:           digest,
+		OAuth2Bearer:     oauth2Bearer,
+		AWSSigV4:         awsSigV4,
+		OAuth2TokenURL:   oauth2TokenURL,
+		OAuth2ClientID:   oauth2ClientID,
+		OAuth2Secret:     oauth2Secret,
+		OAuth2Scope:      oauth2Scope,
+		Compressed:       compressed,
+		CompareEncodings: compareEncodings,
+		Emit:             emit,
+		StreamFormat:     streamFormat,
+		H2Trace:          h2Trace,
+		WSMessages:       wsMessages,
+		WSListen:         wsListen,
+		WSScript:         wsScript,
+	}
+}
+
+// selectedProtocol maps the --http1.1/--http2/--http3 flags to a client protocol
+func selectedProtocol() string {
+	switch {
+	case http1:
+		return client.ProtocolHTTP1
+	case http2:
+		return client.ProtocolHTTP2
+	case http3:
+		return client.ProtocolHTTP3
+	default:
+		return ""
+	}
+}
+
+// selectedIPVersion maps the -4/-6 flags to a client IP version
+func selectedIPVersion() int {
+	switch {
+	case ipv4Only:
+		return 4
+	case ipv6Only:
+		return 6
+	default:
+		return 0
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
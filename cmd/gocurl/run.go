@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/erfi/gocurl/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [flags] plan.yaml",
+	Short: "Run the test cases of a plan file and report them together",
+	Long: `run executes every case of a YAML test plan, one after another or all at
+once with "parallel: true", checks each case against its thresholds and
+prints one consolidated report. The exit code is non-zero if any case fails.
+
+Each case sets its own url, method, headers, body, requests, concurrency,
+duration, timeout and thresholds; the plan's defaults block fills in what a
+case leaves out. The other gocurl flags (and -P profiles) apply to every
+case, so -k, --resolve or --http2 can be set once for the whole plan.
+Use '-' to read the plan from stdin.`,
+	Example: `  gocurl run nightly.yaml
+  gocurl run -o json nightly.yaml > report.json
+  gocurl run -P staging-edge --resolve api.example.com:443:203.0.113.10 nightly.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPlan,
+	// A failing case is a test result, not a usage mistake
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	// root.go's init has already run, so its flags can be shared here
+	runCmd.Flags().AddFlagSet(rootCmd.Flags())
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	sources, err := applyConfigSources(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := applyFlagImplications(); err != nil {
+		return err
+	}
+
+	plan, err := app.LoadPlan(args[0])
+	if err != nil {
+		return err
+	}
+	if plan.BaseURL == "" {
+		plan.BaseURL = sources.baseURL
+	}
+	return app.RunPlan(plan, buildConfig(nil))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/erfi/gocurl/internal/app"
+	"github.com/spf13/cobra"
+)
+
+var scenarioCmd = &cobra.Command{
+	Use:   "scenario [flags] flow.yaml",
+	Short: "Run a multi-step user flow with values carried between steps",
+	Long: `scenario runs the ordered steps of a YAML flow, such as login, then fetch
+the profile with the token from the login response, then place an order.
+Steps extract values from responses with a JSONPath, a regex or a header
+into variables that later steps reference as {{name}}, and may pause for a
+think time before the next step.
+
+-c sets the number of virtual users, each running flows back to back, and
+-n the total number of flows (or -d to run flows for a duration). Every
+flow starts a new cookie session. The report shows statistics per step and
+the end-to-end flow duration; the exit code is non-zero if any flow failed.
+Use '-' to read the scenario from stdin.`,
+	Example: `  gocurl scenario checkout.yaml
+  gocurl scenario -c 20 -d 5m checkout.yaml
+  gocurl scenario -P staging-edge -n 100 -c 10 -o json checkout.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runScenario,
+	// A failing flow is a test result, not a usage mistake
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(scenarioCmd)
+	// root.go's init has already run, so its flags can be shared here
+	scenarioCmd.Flags().AddFlagSet(rootCmd.Flags())
+}
+
+func runScenario(cmd *cobra.Command, args []string) error {
+	sources, err := applyConfigSources(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	if err := applyFlagImplications(); err != nil {
+		return err
+	}
+
+	scenario, err := app.LoadScenario(args[0])
+	if err != nil {
+		return err
+	}
+	if scenario.BaseURL == "" {
+		scenario.BaseURL = sources.baseURL
+	}
+	return app.RunScenario(scenario, buildConfig(nil))
+}
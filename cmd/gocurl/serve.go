@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/erfi/gocurl/internal/app"
+	"github.com/erfi/gocurl/internal/server"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen    string
+	serveTLSListen string
+	serveCert      string
+	serveKey       string
+	serveTLSDelay  time.Duration
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [flags]",
+	Short: "Start a local test server with controllable response behaviour",
+	Long: `serve starts a calibration server for checking gocurl's measurements
+against behaviour you set per request with query parameters: fixed or
+jittered latency, response size, chunked streaming at a given cadence,
+buffering, mid-stream stalls, status codes and connection resets.
+
+The plain listener speaks HTTP/1.1 and h2c (HTTP/2 without TLS); the TLS
+listener speaks HTTP/1.1 and h2 with a self-signed certificate unless
+--cert and --key are given. --tls-delay slows every TLS handshake down.
+Request / without parameters for the list of parameters, and use -v to
+log each request.`,
+	Example: `  gocurl serve
+  gocurl serve --tls-listen 127.0.0.1:8443 --tls-delay 200ms -v
+  gocurl --expect-streaming 'http://127.0.0.1:8080/?chunks=20&interval=100ms&stall=2s'
+  gocurl --http2 -k 'https://127.0.0.1:8443/?latency=50ms&jitter=20ms' -n 200 -c 10`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "Address for HTTP/1.1 and h2c (empty to disable)")
+	serveCmd.Flags().StringVar(&serveTLSListen, "tls-listen", "", "Address for HTTPS with HTTP/1.1 and h2")
+	serveCmd.Flags().StringVar(&serveCert, "cert", "", "TLS certificate file (default: self-signed)")
+	serveCmd.Flags().StringVar(&serveKey, "key", "", "TLS private key file")
+	serveCmd.Flags().DurationVar(&serveTLSDelay, "tls-delay", 0, "Delay every TLS handshake by this long")
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	var log io.Writer
+	if verbose {
+		log = os.Stderr
+	}
+	s, err := server.New(server.Options{
+		Addr:     serveListen,
+		TLSAddr:  serveTLSListen,
+		CertFile: serveCert,
+		KeyFile:  serveKey,
+		TLSDelay: serveTLSDelay,
+		Log:      log,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.Start(); err != nil {
+		return err
+	}
+
+	for _, addr := range s.Addrs() {
+		fmt.Fprintf(os.Stderr, "%s Listening on %s\n", color.GreenString("✓"), addr)
+	}
+
+	ctx, cancel := app.SetupSignalHandler()
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case err := <-s.Err():
+		return err
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelShutdown()
+	return s.Shutdown(shutdownCtx)
+}
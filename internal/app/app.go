@@ -2,8 +2,10 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"strings"
 	"sync"
@@ -16,38 +18,215 @@ import (
 
 // Config contains application configuration
 type Config struct {
-	URLs            []string
-	Method          string
-	Headers         []string
-	Data            string
-	Requests        int
-	Concurrency     int
-	Duration        string
-	Timeout         string
-	Insecure        bool
-	OutputFormat    string
-	Verbose         bool
-	Quiet           bool
-	IncludeHeaders  bool
-	ShowBody        bool
-	ShowErrorBody   bool
-	EnableStreaming bool
-	ResolveHosts    []string
-	ConnectToHosts  []string
-	ExpectStreaming bool
-	StallThreshold  string
+	URLs             []string
+	Method           string
+	Headers          []string
+	Data             string
+	Requests         int
+	Concurrency      int
+	Duration         string
+	Timeout          string
+	Insecure         bool
+	OutputFormat     string
+	Verbose          bool
+	Quiet            bool
+	IncludeHeaders   bool
+	ShowBody         bool
+	ShowErrorBody    bool
+	EnableStreaming  bool
+	ResolveHosts     []string
+	ConnectToHosts   []string
+	ExpectStreaming  bool
+	StallThreshold   string
+	CompareProtocols bool
+	CertFile         string
+	KeyFile          string
+	CACert           string
+	CAPath           string
+	TLSMin           string
+	TLSMax           string
+	Ciphers          []string
+	Curves           []string
+	SNI              string
+	CertExpiryWarn   string
+	Protocol         string
+	TLSResume        int
+	TLSKeyLog        string
+	DNSServers       []string
+	DoHURL           string
+	DNSOverTLS       bool
+	IPVersion        int
+	Proxy            string
+	ProxyUser        string
+	NoProxy          string
+	MaxRedirects     int
+	NoFollow         bool
+	Cookie           string // -b: "name=value; ..." or a Netscape cookie file
+	CookieJar        string // File to write cookies to when the run ends
+	CookiePerWorker  bool   // Give every load-test worker its own cookie jar
+	User             string
+	Digest           bool
+	OAuth2Bearer     string
+	AWSSigV4         string
+	OAuth2TokenURL   string
+	OAuth2ClientID   string
+	OAuth2Secret     string
+	OAuth2Scope      string
+	Compressed       string // Encodings to request and decode; "" leaves gzip to the transport
+	CompareEncodings bool
+	Emit             string // Print the request as a curl, httpie or go snippet
+	StreamFormat     string // Decode model output deltas: openai, anthropic or ndjson
+	H2Trace          bool   // Record the HTTP/2 frames of a single request
+	WSMessages       int    // Echo round trips for ws:// URLs; 0 picks a default
+	WSListen         string // Collect server-pushed WebSocket messages for this long
+	WSScript         string // YAML file of WebSocket send/expect/receive/sleep steps
+}
+
+// isLoadTest reports whether the config asks for more than one request
+func (c *Config) isLoadTest() bool {
+	return c.Requests > 1 || c.Duration != ""
+}
+
+// loadDuration parses --duration; zero means the run is bounded by -n only
+func (c *Config) loadDuration() (time.Duration, error) {
+	if c.Duration == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.Duration)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration '%s': expected a positive duration such as 30s or 5m", c.Duration)
+	}
+	return d, nil
 }
 
 // App represents the main application
 type App struct {
-	config    *Config
-	client    *client.Client
-	collector *metrics.Collector
-	formatter output.Formatter
+	config       *Config
+	clientConfig *client.Config
+	client       *client.Client
+	collector    *metrics.Collector
+	formatter    output.Formatter
+	cookies      *client.CookieJar
+	auth         client.Authenticator
+	failures     *failedRequests // Load-test failures, kept for --emit
+	grpc         *grpcMethod     // Method called by gocurl grpc
 }
 
 // New creates a new application instance
-func New(config *Config) *App {
+func New(config *Config) (*App, error) {
+	if err := validateEmitFormat(config.Emit); err != nil {
+		return nil, err
+	}
+	if _, err := config.loadDuration(); err != nil {
+		return nil, err
+	}
+	if config.Concurrency < 1 {
+		return nil, fmt.Errorf("--concurrency must be 1 or greater")
+	}
+	if err := validateWebSocket(config); err != nil {
+		return nil, err
+	}
+	if err := validateH2Trace(config); err != nil {
+		return nil, err
+	}
+	if err := validateCompare(config); err != nil {
+		return nil, err
+	}
+	if err := validateCompareEncodings(config); err != nil {
+		return nil, err
+	}
+
+	clientConfig, err := buildClientConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyLog, ok := clientConfig.TLSConfig.KeyLogWriter.(*client.KeyLogWriter); ok && !config.Quiet {
+		fmt.Fprintf(os.Stderr, "Note: writing TLS session keys to %s\n", keyLog.Path())
+	}
+
+	cookies, err := loadCookieJar(config)
+	if err != nil {
+		return nil, err
+	}
+	clientConfig.CookieJar = cookies
+
+	// Built once so that an OAuth2 token is shared by every client of the run
+	auth, err := client.NewAuthenticator(client.AuthOptions{
+		User:               config.User,
+		Digest:             config.Digest,
+		Bearer:             config.OAuth2Bearer,
+		AWSSigV4:           config.AWSSigV4,
+		OAuth2TokenURL:     config.OAuth2TokenURL,
+		OAuth2ClientID:     config.OAuth2ClientID,
+		OAuth2ClientSecret: config.OAuth2Secret,
+		OAuth2Scope:        config.OAuth2Scope,
+	})
+	if err != nil {
+		return nil, err
+	}
+	clientConfig.Auth = auth
+
+	httpClient := client.NewClient(clientConfig)
+	collector := metrics.NewCollector()
+	formatter, _ := output.GetFormatter(config.OutputFormat, config.Verbose)
+
+	return &App{
+		config:       config,
+		clientConfig: clientConfig,
+		client:       httpClient,
+		collector:    collector,
+		formatter:    formatter,
+		cookies:      cookies,
+		auth:         auth,
+		failures:     newFailedRequests(),
+	}, nil
+}
+
+// loadCookieJar creates the run's cookie jar, seeded from -b when it names a
+// file. A missing file is not an error so that the same file can be passed to
+// -b and --cookie-jar on the first run.
+func loadCookieJar(config *Config) (*client.CookieJar, error) {
+	jar := client.NewCookieJar()
+	if config.Cookie == "" || client.IsCookieString(config.Cookie) {
+		return jar, nil
+	}
+	if err := jar.LoadFile(config.Cookie); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return jar, nil
+}
+
+// requestHeaders returns the -H headers plus literal -b cookies
+func (a *App) requestHeaders() client.Headers {
+	return a.withCookie(client.ParseHeaders(a.config.Headers))
+}
+
+// withCookie adds literal -b cookies to headers, merged into the first Cookie
+// header so that only one is sent
+func (a *App) withCookie(headers client.Headers) client.Headers {
+	if a.config.Cookie != "" && client.IsCookieString(a.config.Cookie) {
+		for i, h := range headers {
+			if !h.Remove && strings.EqualFold(h.Name, "Cookie") {
+				headers[i].Value += "; " + a.config.Cookie
+				return headers
+			}
+		}
+		headers = append(headers, client.Header{Name: "Cookie", Value: a.config.Cookie})
+	}
+	return headers
+}
+
+// keyLogPath returns the TLS key log destination: --tls-keylog, else SSLKEYLOGFILE
+func keyLogPath(config *Config) string {
+	if config.TLSKeyLog != "" {
+		return config.TLSKeyLog
+	}
+	return os.Getenv("SSLKEYLOGFILE")
+}
+
+// buildClientConfig translates application flags into an HTTP client configuration
+func buildClientConfig(config *Config) (*client.Config, error) {
 	// Parse timeout
 	timeout, err := time.ParseDuration(config.Timeout)
 	if err != nil {
@@ -78,6 +257,59 @@ func New(config *Config) *App {
 		}
 	}
 
+	// TLS settings are errors rather than warnings: silently falling back
+	// would measure a different handshake than the one asked for
+	tlsConfig, err := client.BuildTLSConfig(config.Insecure, client.TLSOptions{
+		CertFile:   config.CertFile,
+		KeyFile:    config.KeyFile,
+		CAFile:     config.CACert,
+		CAPath:     config.CAPath,
+		MinVersion: config.TLSMin,
+		MaxVersion: config.TLSMax,
+		Ciphers:    config.Ciphers,
+		Curves:     config.Curves,
+		ServerName: config.SNI,
+		KeyLogFile: keyLogPath(config),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resolver, err := client.NewResolver(client.ResolverOptions{
+		Servers:    config.DNSServers,
+		DoHURL:     config.DoHURL,
+		DNSOverTLS: config.DNSOverTLS,
+		Timeout:    timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Proxy != "" {
+		if _, err := client.ParseProxyURL(config.Proxy); err != nil {
+			return nil, err
+		}
+		if config.Protocol == client.ProtocolHTTP3 {
+			return nil, fmt.Errorf("--http3 cannot be used with --proxy: QUIC is not tunnelled through proxies")
+		}
+	}
+
+	var acceptEncoding []string
+	if config.Compressed != "" {
+		acceptEncoding, err = client.ParseEncodings(config.Compressed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var streamFormat string
+	if config.StreamFormat != "" {
+		streamFormat, err = client.ParseStreamFormat(config.StreamFormat)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Configure HTTP client based on number of requests
 	clientConfig := &client.Config{
 		Timeout:        timeout,
@@ -88,9 +320,23 @@ func New(config *Config) *App {
 		ResolveMap:     resolveMap,
 		ConnectToMap:   connectToMap,
 		StallThreshold: stallThreshold,
+		TLSConfig:      tlsConfig,
+		Protocol:       config.Protocol,
+		Resolver:       resolver,
+		IPVersion:      config.IPVersion,
+		Proxy:          config.Proxy,
+		ProxyUser:      config.ProxyUser,
+		NoProxy:        config.NoProxy,
+		MaxRedirects:   config.MaxRedirects,
+		NoFollow:       config.NoFollow,
+		AcceptEncoding: acceptEncoding,
+		StreamFormat:   streamFormat,
+		H2Trace:        config.H2Trace,
+		// net/http asks for gzip whenever Accept-Encoding is unset
+		NoCompression: client.ParseHeaders(config.Headers).Removes("Accept-Encoding"),
 	}
 
-	if config.Requests == 1 {
+	if !config.isLoadTest() {
 		// Single request: disable keep-alives to measure connection establishment
 		clientConfig.DisableKeepAlive = true
 		clientConfig.MaxIdleConns = 1
@@ -102,21 +348,44 @@ func New(config *Config) *App {
 		clientConfig.MaxIdlePerHost = config.Concurrency
 	}
 
-	httpClient := client.NewClient(clientConfig)
-	collector := metrics.NewCollector()
-	formatter, _ := output.GetFormatter(config.OutputFormat, config.Verbose)
-
-	return &App{
-		config:    config,
-		client:    httpClient,
-		collector: collector,
-		formatter: formatter,
-	}
+	return clientConfig, nil
 }
 
 // Run executes the application
 func (a *App) Run() error {
-	if a.config.Requests == 1 {
+	err := a.run()
+
+	// Like curl, the cookie jar is written even when the run failed
+	if a.config.CookieJar != "" {
+		if saveErr := a.cookies.SaveFile(a.config.CookieJar); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+
+	if oauth, ok := a.auth.(*client.OAuth2ClientCredentials); ok && !a.config.Quiet {
+		output.WriteTokenFetches(os.Stderr, oauth.Fetches())
+	}
+	return err
+}
+
+// run dispatches to the selected mode
+func (a *App) run() error {
+	if a.config.CompareProtocols {
+		return a.runCompare()
+	}
+	if a.config.CompareEncodings {
+		return a.runCompareEncodings()
+	}
+	if a.config.TLSResume > 0 {
+		return a.runResumption()
+	}
+	if len(a.config.URLs) > 0 && client.IsWebSocketURL(a.config.URLs[0]) {
+		return a.runWebSocket()
+	}
+	if a.grpc != nil && !a.config.isLoadTest() {
+		return a.runGRPCCall(context.Background())
+	}
+	if !a.config.isLoadTest() {
 		return a.runSingle()
 	}
 	return a.runLoad()
@@ -129,7 +398,7 @@ func (a *App) runSingle() error {
 	}
 
 	url := a.config.URLs[0]
-	headers := client.ParseHeaders(a.config.Headers)
+	headers := a.requestHeaders()
 
 	var body io.Reader
 	if a.config.Data != "" {
@@ -174,6 +443,16 @@ func (a *App) runSingle() error {
 	// For table output, also write streaming metrics separately
 	if streamMetrics != nil && a.config.OutputFormat == "table" {
 		output.WriteStreamingMetrics(os.Stdout, streamMetrics, a.config.Verbose)
+		if timing.Tokens != nil {
+			output.WriteTokenMetrics(os.Stdout, timing.Tokens)
+		}
+	}
+	a.writeH2Trace(timing)
+
+	if a.config.Emit != "" {
+		if err := a.writeSnippet(url); err != nil {
+			return err
+		}
 	}
 
 	// Validate streaming expectation
@@ -188,11 +467,27 @@ func (a *App) runSingle() error {
 		return fmt.Errorf("request error: %s", timing.Error)
 	}
 
+	// Certificate monitoring: fail when the chain is close to expiry
+	if a.config.CertExpiryWarn != "" {
+		threshold, err := parseDayDuration(a.config.CertExpiryWarn)
+		if err != nil {
+			return fmt.Errorf("invalid --cert-expiry-warn: %w", err)
+		}
+		if err := checkCertExpiry(timing, threshold, time.Now()); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // validateStreaming checks if streaming requirements are met
 func (a *App) validateStreaming(metrics *client.StreamMetrics) error {
+	// Event streams are judged by event cadence rather than byte chunks
+	if metrics.SSE != nil {
+		return a.validateEventStream(metrics.SSE)
+	}
+
 	// Check if streaming was detected
 	if metrics.BufferingAnalysis == nil {
 		return fmt.Errorf("streaming validation failed: no buffering analysis available")
@@ -219,75 +514,149 @@ func (a *App) validateStreaming(metrics *client.StreamMetrics) error {
 	return nil
 }
 
+// validateEventStream checks that Server-Sent Events arrived one by one
+func (a *App) validateEventStream(sse *client.SSEMetrics) error {
+	if sse.Events < 2 {
+		return fmt.Errorf("streaming validation failed: %d event(s) received, need at least 2 to judge cadence", sse.Events)
+	}
+	if sse.BufferingDetected {
+		return fmt.Errorf("streaming validation failed: events coalesced (%d of %d events arrived together in %d read(s))",
+			sse.CoalescedEvents, sse.Events, sse.CoalescedReads)
+	}
+
+	if !a.config.Quiet {
+		fmt.Fprintf(os.Stdout, "\n✓ Streaming validation passed (%d events, first after %v, p50 gap %v)\n",
+			sse.Events,
+			time.Duration(sse.TimeToFirstEvent).Round(time.Millisecond),
+			time.Duration(sse.P50Gap).Round(time.Millisecond))
+	}
+	return nil
+}
+
 // runLoad executes multiple concurrent requests
 func (a *App) runLoad() error {
 	if len(a.config.URLs) == 0 {
 		return fmt.Errorf("no URLs provided")
 	}
 
-	totalRequests := a.config.Requests * len(a.config.URLs)
-
 	if !a.config.Quiet {
-		fmt.Printf("Running load test: %d URLs x %d requests = %d total requests with concurrency %d\n",
-			len(a.config.URLs), a.config.Requests, totalRequests, a.config.Concurrency)
+		if a.config.Duration != "" {
+			fmt.Printf("Running load test: %d URLs for %s with concurrency %d\n",
+				len(a.config.URLs), a.config.Duration, a.config.Concurrency)
+		} else {
+			totalRequests := a.config.Requests * len(a.config.URLs)
+			fmt.Printf("Running load test: %d URLs x %d requests = %d total requests with concurrency %d\n",
+				len(a.config.URLs), a.config.Requests, totalRequests, a.config.Concurrency)
+		}
 	}
 
-	headers := client.ParseHeaders(a.config.Headers)
+	a.executeLoad(a.client, a.collector)
+
+	// Calculate and display statistics
+	stats := a.collector.Calculate()
 
-	// Create worker pool
-	type job struct {
-		url string
-		id  int
+	if err := a.formatter.WriteMultiple(os.Stdout, stats); err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
 	}
 
-	jobs := make(chan job, totalRequests)
+	if a.config.Emit != "" {
+		return a.writeFailedSnippets()
+	}
+	return nil
+}
+
+// executeLoad runs the configured requests through a worker pool, recording
+// every measurement in the given collector. With --duration the workers cycle
+// through the URLs until the time is up; otherwise each URL gets -n requests.
+func (a *App) executeLoad(httpClient *client.Client, collector *metrics.Collector) {
+	headers := a.requestHeaders()
+	duration, _ := a.config.loadDuration()
+
+	// With --cookie-per-worker every worker is a separate session that starts
+	// from the cookies loaded with -b
+	var workerJars []*client.CookieJar
+
+	jobs := make(chan string)
 	var wg sync.WaitGroup
 
 	// Start workers
 	for i := 0; i < a.config.Concurrency; i++ {
+		workerClient := httpClient
+		if a.config.CookiePerWorker {
+			jar := a.cookies.Clone()
+			workerJars = append(workerJars, jar)
+			workerClient = httpClient.WithCookieJar(jar)
+		}
+
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for j := range jobs {
-				var body io.Reader
-				if a.config.Data != "" {
-					body = strings.NewReader(a.config.Data)
-				}
-
-				timing, _ := a.client.MeasureRequest(
-					j.url,
-					a.config.Method,
-					headers,
-					body,
-				)
-
+			for url := range jobs {
+				timing := a.measure(workerClient, url, headers)
 				if timing != nil {
-					a.collector.Record(timing)
+					collector.Record(timing)
+					if timing.Error != "" {
+						a.failures.record(url, timing.Error)
+					}
 				}
 			}
 		}()
 	}
 
-	// Send jobs for each URL
-	jobID := 0
-	for _, url := range a.config.URLs {
-		for i := 0; i < a.config.Requests; i++ {
-			jobs <- job{url: url, id: jobID}
-			jobID++
+	// Hand out jobs: a fixed count per URL, or round-robin until the deadline
+	if duration > 0 {
+		deadline := time.Now().Add(duration)
+		for i := 0; time.Now().Before(deadline); i++ {
+			jobs <- a.config.URLs[i%len(a.config.URLs)]
+		}
+	} else {
+		for _, url := range a.config.URLs {
+			for i := 0; i < a.config.Requests; i++ {
+				jobs <- url
+			}
 		}
 	}
 	close(jobs)
 
 	// Wait for all workers to complete
 	wg.Wait()
-	a.collector.Finalize()
+	collector.Finalize()
 
-	// Calculate and display statistics
-	stats := a.collector.Calculate()
+	// Keep every session's cookies for --cookie-jar
+	for _, jar := range workerJars {
+		a.cookies.Merge(jar)
+	}
+}
 
-	if err := a.formatter.WriteMultiple(os.Stdout, stats); err != nil {
-		return fmt.Errorf("failed to format output: %w", err)
+// measure makes one load-test request
+func (a *App) measure(httpClient *client.Client, url string, headers client.Headers) *client.TimingBreakdown {
+	if a.grpc != nil {
+		timing, _ := httpClient.MeasureGRPC(context.Background(), url, headers, a.grpc.call)
+		return timing
 	}
 
-	return nil
+	var body io.Reader
+	if a.config.Data != "" {
+		body = strings.NewReader(a.config.Data)
+	}
+
+	if a.config.StreamFormat != "" {
+		// Token streams are decoded as they arrive; the per-read detail is
+		// not kept across a load test
+		timing, _, _ := httpClient.MeasureRequestWithStreaming(
+			context.Background(),
+			url,
+			a.config.Method,
+			headers,
+			body,
+		)
+		return timing
+	}
+	timing, _ := httpClient.MeasureRequest(
+		url,
+		a.config.Method,
+		headers,
+		body,
+	)
+	return timing
 }
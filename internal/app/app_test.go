@@ -0,0 +1,25 @@
+package app
+
+import "testing"
+
+func TestNewRejectsZeroConcurrency(t *testing.T) {
+	for _, requests := range []int{1, 3} {
+		config := &Config{URLs: []string{"http://localhost/"}, Method: "GET", Requests: requests, Concurrency: 0, Timeout: "1s"}
+		if _, err := New(config); err == nil {
+			t.Errorf("-n %d -c 0: expected an error", requests)
+		}
+	}
+}
+
+func TestBuildClientConfigRemovedAcceptEncoding(t *testing.T) {
+	for headers, expected := range map[string]bool{"Accept-Encoding:": true, "Accept-Encoding: br": false} {
+		config := &Config{Headers: []string{headers}, Requests: 1, Concurrency: 1, Timeout: "1s"}
+		clientConfig, err := buildClientConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if clientConfig.NoCompression != expected {
+			t.Errorf("%s: expected NoCompression %v", headers, expected)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+)
+
+// parseDayDuration parses durations that may use a day suffix ("21d") in
+// addition to the units understood by time.ParseDuration
+func parseDayDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid duration '%s'", s)
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+	return d, nil
+}
+
+// checkCertExpiry fails if any certificate in the peer chain expires within threshold
+func checkCertExpiry(timing *client.TimingBreakdown, threshold time.Duration, now time.Time) error {
+	soonest, ok := client.SoonestExpiry(timing.TLSCertificates)
+	if !ok {
+		return fmt.Errorf("certificate expiry check failed: no TLS certificate was presented")
+	}
+
+	remaining := soonest.NotAfter.Sub(now)
+	if remaining < 0 {
+		return fmt.Errorf("certificate expiry check failed: %s expired on %s",
+			soonest.Subject, soonest.NotAfter.Format(time.RFC3339))
+	}
+
+	if remaining < threshold {
+		return fmt.Errorf("certificate expiry check failed: %s expires in %d days (threshold %.0f days)",
+			soonest.Subject, soonest.DaysUntilExpiry, math.Ceil(threshold.Hours()/24))
+	}
+
+	return nil
+}
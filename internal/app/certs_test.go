@@ -0,0 +1,67 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+)
+
+func TestParseDayDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"21d", 21 * 24 * time.Hour, false},
+		{"0.5d", 12 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{"-1d", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := parseDayDuration(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if d != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, d)
+			}
+		})
+	}
+}
+
+func TestCheckCertExpiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	threshold := 21 * 24 * time.Hour
+
+	timing := &client.TimingBreakdown{
+		TLSCertificates: []client.CertificateInfo{
+			{Subject: "CN=leaf", NotAfter: now.Add(90 * 24 * time.Hour), DaysUntilExpiry: 90},
+			{Subject: "CN=intermediate", NotAfter: now.Add(10 * 24 * time.Hour), DaysUntilExpiry: 10},
+		},
+	}
+
+	err := checkCertExpiry(timing, threshold, now)
+	if err == nil || !strings.Contains(err.Error(), "CN=intermediate") {
+		t.Errorf("Expected intermediate expiry failure, got %v", err)
+	}
+
+	timing.TLSCertificates[1].NotAfter = now.Add(60 * 24 * time.Hour)
+	if err := checkCertExpiry(timing, threshold, now); err != nil {
+		t.Errorf("Expected chain to pass, got %v", err)
+	}
+
+	if err := checkCertExpiry(&client.TimingBreakdown{}, threshold, now); err == nil {
+		t.Error("Expected failure when no certificate was presented")
+	}
+}
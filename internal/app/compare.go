@@ -0,0 +1,128 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/metrics"
+	"github.com/erfi/gocurl/internal/output"
+)
+
+// compareProtocols lists the protocols exercised by --compare-protocols, in display order
+var compareProtocols = []struct {
+	id    string
+	label string
+}{
+	{client.ProtocolHTTP1, "HTTP/1.1"},
+	{client.ProtocolHTTP2, "HTTP/2"},
+	{client.ProtocolHTTP3, "HTTP/3"},
+}
+
+// validateCompare rejects a --compare-protocols run that would leave URLs
+// unmeasured: a single request compares one URL, while a load test
+// spreads its requests over all of them
+func validateCompare(config *Config) error {
+	if config.CompareProtocols && !config.isLoadTest() && len(config.URLs) > 1 {
+		return fmt.Errorf("--compare-protocols measures a single URL, got %d; pass one URL or add -n to compare a load test over all of them", len(config.URLs))
+	}
+	return nil
+}
+
+// runCompare executes the same request or load test once per protocol, each
+// with its own client, and prints a single comparison
+func (a *App) runCompare() error {
+	if len(a.config.URLs) == 0 {
+		return fmt.Errorf("no URLs provided")
+	}
+
+	results := make([]output.ProtocolResult, 0, len(compareProtocols))
+	for _, p := range compareProtocols {
+		if !a.config.Quiet && a.config.OutputFormat != "json" {
+			fmt.Fprintf(os.Stderr, "Measuring %s...\n", p.label)
+		}
+		results = append(results, a.measureProtocol(p.id, p.label))
+	}
+
+	if a.config.OutputFormat == "json" {
+		if err := output.WriteComparisonJSON(os.Stdout, results); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	} else {
+		output.WriteComparisonTable(os.Stdout, results, a.config.isLoadTest())
+	}
+
+	// Fail only if no protocol produced a usable result
+	for _, r := range results {
+		if r.Error == "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("all protocols failed")
+}
+
+// measureProtocol runs the configured workload pinned to a single protocol
+func (a *App) measureProtocol(protocol, label string) output.ProtocolResult {
+	result := output.ProtocolResult{Protocol: label}
+
+	// QUIC only carries TLS traffic and is not tunnelled through proxies
+	if protocol == client.ProtocolHTTP3 {
+		if a.config.Proxy != "" {
+			result.Error = "HTTP/3 cannot be used through a proxy"
+			return result
+		}
+		for _, url := range a.config.URLs {
+			if !strings.HasPrefix(strings.ToLower(url), "https://") {
+				result.Error = "HTTP/3 requires an https:// URL"
+				return result
+			}
+		}
+	}
+
+	clientConfig, err := buildClientConfig(a.config)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	clientConfig.Protocol = protocol
+
+	// Each protocol starts from the same cookies so the runs stay comparable
+	cookies := a.cookies.Clone()
+	defer a.cookies.Merge(cookies)
+	clientConfig.CookieJar = cookies
+	clientConfig.Auth = a.auth
+
+	httpClient := client.NewClient(clientConfig)
+	defer httpClient.Close()
+
+	if !a.config.isLoadTest() {
+		var body io.Reader
+		if a.config.Data != "" {
+			body = strings.NewReader(a.config.Data)
+		}
+
+		timing, err := httpClient.MeasureRequest(
+			a.config.URLs[0],
+			a.config.Method,
+			a.requestHeaders(),
+			body,
+		)
+		result.Timing = timing
+		if err != nil {
+			result.Error = err.Error()
+		} else if timing != nil && timing.Error != "" {
+			result.Error = timing.Error
+		}
+		return result
+	}
+
+	collector := metrics.NewCollector()
+	a.executeLoad(httpClient, collector)
+	result.Stats = collector.Calculate()
+	if result.Stats.SuccessfulRequests == 0 {
+		result.Error = "no successful requests"
+	}
+	return result
+}
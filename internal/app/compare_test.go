@@ -0,0 +1,22 @@
+package app
+
+import "testing"
+
+func TestValidateCompare(t *testing.T) {
+	urls := []string{"https://a.example.com", "https://b.example.com"}
+	valid := []*Config{
+		{URLs: urls},
+		{URLs: urls[:1], CompareProtocols: true},
+		{URLs: urls, CompareProtocols: true, Requests: 10},
+		{URLs: urls, CompareProtocols: true, Duration: "5s"},
+	}
+	for _, config := range valid {
+		if err := validateCompare(config); err != nil {
+			t.Errorf("%+v: unexpected error %v", config, err)
+		}
+	}
+
+	if err := validateCompare(&Config{URLs: urls, CompareProtocols: true, Requests: 1}); err == nil {
+		t.Error("Expected an error comparing a single request over several URLs")
+	}
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/metrics"
+	"github.com/erfi/gocurl/internal/output"
+)
+
+// DNSBenchConfig contains configuration for the dns-bench command
+type DNSBenchConfig struct {
+	Host          string
+	DNSServers    []string
+	DoHURL        string
+	DNSOverTLS    bool
+	Queries       int
+	Timeout       string
+	IncludeSystem bool
+	OutputFormat  string
+	Quiet         bool
+}
+
+// benchResolver is one resolver under test
+type benchResolver struct {
+	name     string
+	resolver *net.Resolver
+}
+
+// RunDNSBench queries every configured resolver repeatedly and reports
+// lookup-latency percentiles per resolver
+func RunDNSBench(config *DNSBenchConfig) error {
+	timeout, err := time.ParseDuration(config.Timeout)
+	if err != nil {
+		timeout = 5 * time.Second
+	}
+
+	resolvers, err := buildBenchResolvers(config, timeout)
+	if err != nil {
+		return err
+	}
+	if len(resolvers) == 0 {
+		return fmt.Errorf("no resolvers to benchmark (use --dns-servers, --doh-url or --system)")
+	}
+
+	results := make([]output.ResolverResult, 0, len(resolvers))
+	for _, r := range resolvers {
+		if !config.Quiet && config.OutputFormat != "json" {
+			fmt.Fprintf(os.Stderr, "Querying %s...\n", r.name)
+		}
+		results = append(results, benchmarkResolver(r, config.Host, config.Queries, timeout))
+	}
+
+	if config.OutputFormat == "json" {
+		return output.WriteDNSBenchJSON(os.Stdout, config.Host, results)
+	}
+	output.WriteDNSBenchTable(os.Stdout, config.Host, results)
+	return nil
+}
+
+// buildBenchResolvers creates one resolver per server so each is measured on its own
+func buildBenchResolvers(config *DNSBenchConfig, timeout time.Duration) ([]benchResolver, error) {
+	var resolvers []benchResolver
+
+	if config.IncludeSystem {
+		resolvers = append(resolvers, benchResolver{name: "system", resolver: &net.Resolver{}})
+	}
+
+	protocol := "udp"
+	if config.DNSOverTLS {
+		protocol = "tls"
+	}
+	for _, server := range config.DNSServers {
+		resolver, err := client.NewResolver(client.ResolverOptions{
+			Servers:    []string{server},
+			DNSOverTLS: config.DNSOverTLS,
+			Timeout:    timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resolvers = append(resolvers, benchResolver{name: protocol + "://" + server, resolver: resolver})
+	}
+
+	if config.DoHURL != "" {
+		resolver, err := client.NewResolver(client.ResolverOptions{DoHURL: config.DoHURL, Timeout: timeout})
+		if err != nil {
+			return nil, err
+		}
+		resolvers = append(resolvers, benchResolver{name: config.DoHURL, resolver: resolver})
+	}
+
+	return resolvers, nil
+}
+
+// benchmarkResolver runs sequential lookups so queries don't compete with each other
+func benchmarkResolver(r benchResolver, host string, queries int, timeout time.Duration) output.ResolverResult {
+	collector := metrics.NewCollector()
+	addresses := make(map[string]bool)
+	result := output.ResolverResult{Resolver: r.name}
+
+	for i := 0; i < queries; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		ips, err := r.resolver.LookupIPAddr(ctx, host)
+		elapsed := client.Duration(time.Since(start))
+		cancel()
+
+		timing := &client.TimingBreakdown{DNSLookup: elapsed, Total: elapsed}
+		if err != nil {
+			timing.Error = err.Error()
+			result.LastError = err.Error()
+		}
+		for _, ip := range ips {
+			addresses[ip.String()] = true
+		}
+		collector.Record(timing)
+	}
+
+	collector.Finalize()
+	result.Stats = collector.Calculate()
+
+	for addr := range addresses {
+		result.Addresses = append(result.Addresses, addr)
+	}
+	sort.Strings(result.Addresses)
+
+	return result
+}
@@ -0,0 +1,819 @@
+package app
+
+import (
+	"crypto/tls"
+	"fmt"
+	"go/format"
+	"net"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/fatih/color"
+)
+
+// Snippet formats accepted by --emit
+const (
+	EmitCurl   = "curl"
+	EmitHTTPie = "httpie"
+	EmitGo     = "go"
+)
+
+// snippetRequest is the effective request a snippet reproduces: the app
+// flags for options that map one to one, and the client config for values
+// gocurl derived from them
+type snippetRequest struct {
+	url     string
+	config  *Config
+	client  *client.Config
+	headers client.Headers
+}
+
+// validateEmitFormat checks an --emit value
+func validateEmitFormat(format string) error {
+	switch format {
+	case "", EmitCurl, EmitHTTPie, EmitGo:
+		return nil
+	default:
+		return fmt.Errorf("invalid --emit format '%s': expected curl, httpie or go", format)
+	}
+}
+
+// buildSnippet renders the request in the given format
+func buildSnippet(format string, r snippetRequest) (string, error) {
+	switch format {
+	case EmitCurl:
+		return curlSnippet(r), nil
+	case EmitHTTPie:
+		return httpieSnippet(r), nil
+	case EmitGo:
+		return goSnippet(r)
+	default:
+		return "", validateEmitFormat(format)
+	}
+}
+
+// snippetComment prefixes a line for the given format
+func snippetComment(format, line string) string {
+	if format == EmitGo {
+		return "// " + line
+	}
+	return "# " + line
+}
+
+// effectiveHeaders returns the -H headers plus the defaults gocurl adds, so
+// that the snippet sends what gocurl sent
+func (r snippetRequest) effectiveHeaders() client.Headers {
+	headers := append(client.Headers{}, r.headers...)
+	if !r.hasHeader("User-Agent") {
+		headers = append(headers, client.Header{Name: "User-Agent", Value: client.DefaultUserAgent})
+	}
+	return headers
+}
+
+// hasHeader reports whether the request sets or removes the named header
+func (r snippetRequest) hasHeader(name string) bool {
+	for _, h := range r.headers {
+		if strings.EqualFold(h.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptEncoding returns the Accept-Encoding gocurl sends for --compressed
+func (r snippetRequest) acceptEncoding() string {
+	if len(r.client.AcceptEncoding) == 0 || r.hasHeader("Accept-Encoding") {
+		return ""
+	}
+	return strings.Join(r.client.AcceptEncoding, ", ")
+}
+
+// followsRedirects returns whether redirects are followed and how many
+func (r snippetRequest) followsRedirects() (bool, int) {
+	if r.client.NoFollow {
+		return false, 0
+	}
+	max := r.client.MaxRedirects
+	if max == 0 {
+		max = client.DefaultMaxRedirects
+	}
+	return true, max
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,+%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// headerArg renders a header the way curl and HTTPie expect: "Name:" removes
+// it and "Name;" sends it empty
+func headerArg(h client.Header, separator string) string {
+	switch {
+	case h.Remove:
+		return h.Name + ":"
+	case h.Value == "":
+		return h.Name + ";"
+	default:
+		return h.Name + separator + h.Value
+	}
+}
+
+// seconds formats a timeout for curl and HTTPie
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// commandBuilder joins shell arguments into a multi-line command, one option
+// per line, with comments for what the tool cannot reproduce
+type commandBuilder struct {
+	notes []string
+	lines []string
+}
+
+// line adds arguments, quoted for the shell, on a line of their own
+func (b *commandBuilder) line(args ...string) {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	b.lines = append(b.lines, strings.Join(quoted, " "))
+}
+
+// lineRaw adds arguments that are already quoted, e.g. ones expanding variables
+func (b *commandBuilder) lineRaw(args ...string) {
+	b.lines = append(b.lines, strings.Join(args, " "))
+}
+
+func (b *commandBuilder) note(format string, args ...interface{}) {
+	b.notes = append(b.notes, "# "+fmt.Sprintf(format, args...))
+}
+
+func (b *commandBuilder) String() string {
+	var sb strings.Builder
+	for _, note := range b.notes {
+		sb.WriteString(note + "\n")
+	}
+	sb.WriteString(strings.Join(b.lines, " \\\n  "))
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// curlSnippet renders an equivalent curl command
+func curlSnippet(r snippetRequest) string {
+	config := r.config
+	b := &commandBuilder{}
+	b.line("curl")
+
+	switch {
+	case config.Method == "HEAD":
+		b.line("-I")
+	case config.Method != "" && config.Method != "GET":
+		if config.Data == "" || config.Method != "POST" {
+			b.line("-X", config.Method)
+		}
+	}
+
+	for _, h := range r.effectiveHeaders() {
+		b.line("-H", headerArg(h, ": "))
+	}
+	if config.Data != "" {
+		b.line("--data-raw", config.Data)
+	}
+
+	if encoding := r.acceptEncoding(); encoding != "" {
+		if len(r.client.AcceptEncoding) != len(client.SupportedEncodings) {
+			b.line("-H", "Accept-Encoding: "+encoding)
+		}
+		b.line("--compressed")
+	}
+
+	if config.Cookie != "" && !client.IsCookieString(config.Cookie) {
+		b.line("-b", config.Cookie)
+	}
+	if config.CookieJar != "" {
+		b.line("-c", config.CookieJar)
+	}
+
+	addCurlAuth(b, config)
+
+	if follow, max := r.followsRedirects(); follow {
+		b.line("-L")
+		if max != 50 {
+			// curl's own default is 50
+			b.line("--max-redirs", strconv.Itoa(max))
+		}
+	}
+
+	for _, resolve := range config.ResolveHosts {
+		b.line("--resolve", resolve)
+	}
+	for _, connectTo := range config.ConnectToHosts {
+		b.line("--connect-to", connectTo)
+	}
+	switch config.IPVersion {
+	case 4:
+		b.line("-4")
+	case 6:
+		b.line("-6")
+	}
+	if len(config.DNSServers) > 0 {
+		if config.DNSOverTLS {
+			b.note("curl cannot resolve over DNS-over-TLS; using plain DNS")
+		}
+		b.line("--dns-servers", strings.Join(config.DNSServers, ","))
+	}
+	if config.DoHURL != "" {
+		b.line("--doh-url", config.DoHURL)
+	}
+
+	if config.Proxy != "" {
+		b.line("-x", config.Proxy)
+	}
+	if config.ProxyUser != "" {
+		b.line("-U", config.ProxyUser)
+	}
+	if config.NoProxy != "" {
+		b.line("--noproxy", config.NoProxy)
+	}
+
+	switch config.Protocol {
+	case client.ProtocolHTTP1:
+		b.line("--http1.1")
+	case client.ProtocolHTTP2:
+		if strings.HasPrefix(r.url, "http://") {
+			b.line("--http2-prior-knowledge")
+		} else {
+			b.line("--http2")
+		}
+	case client.ProtocolHTTP3:
+		b.line("--http3-only")
+	}
+
+	if config.Insecure {
+		b.line("-k")
+	}
+	if config.CACert != "" {
+		b.line("--cacert", config.CACert)
+	}
+	if config.CAPath != "" {
+		b.line("--capath", config.CAPath)
+	}
+	if config.CertFile != "" {
+		b.line("--cert", config.CertFile)
+	}
+	if config.KeyFile != "" {
+		b.line("--key", config.KeyFile)
+	}
+	if config.TLSMin != "" {
+		b.line("--tlsv" + strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(config.TLSMin), "tlsv"), "tls"))
+	}
+	if config.TLSMax != "" {
+		b.line("--tls-max", strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(config.TLSMax), "tlsv"), "tls"))
+	}
+	if len(config.Ciphers) > 0 {
+		b.line("--ciphers", strings.Join(config.Ciphers, ":"))
+	}
+	if len(config.Curves) > 0 {
+		b.line("--curves", strings.Join(config.Curves, ":"))
+	}
+	if config.SNI != "" {
+		b.note("curl cannot set the TLS server name separately; gocurl sent SNI %s", config.SNI)
+	}
+
+	b.line("--max-time", seconds(r.client.Timeout))
+	b.line(r.url)
+	return b.String()
+}
+
+// addCurlAuth adds the authentication options; OAuth2 client credentials
+// become a bearer token fetched beforehand, with the client secret left in
+// an environment variable so that it does not end up in pasted snippets
+func addCurlAuth(b *commandBuilder, config *Config) {
+	switch {
+	case config.AWSSigV4 != "":
+		b.line("--aws-sigv4", config.AWSSigV4)
+		if config.User != "" {
+			b.line("-u", config.User)
+		} else {
+			b.lineRaw("-u", `"$AWS_ACCESS_KEY_ID:$AWS_SECRET_ACCESS_KEY"`)
+			if os.Getenv("AWS_SESSION_TOKEN") != "" {
+				b.lineRaw("-H", `"x-amz-security-token: $AWS_SESSION_TOKEN"`)
+			}
+		}
+	case config.User != "":
+		if config.Digest {
+			b.line("--digest")
+		}
+		b.line("-u", config.User)
+	case config.OAuth2Bearer != "":
+		b.line("--oauth2-bearer", config.OAuth2Bearer)
+	case config.OAuth2TokenURL != "":
+		b.note("TOKEN comes from the client credentials grant:")
+		b.note(`  curl -u "$OAUTH2_CLIENT_ID:$OAUTH2_CLIENT_SECRET" -d grant_type=client_credentials %s`,
+			shellQuote(config.OAuth2TokenURL))
+		b.lineRaw("--oauth2-bearer", `"$TOKEN"`)
+	}
+}
+
+// httpieSnippet renders an equivalent HTTPie command. HTTPie has no
+// equivalent for connection-level options, so those become comments.
+func httpieSnippet(r snippetRequest) string {
+	config := r.config
+	b := &commandBuilder{}
+	b.line("http")
+
+	if follow, max := r.followsRedirects(); follow {
+		b.line("--follow", "--max-redirects="+strconv.Itoa(max))
+	}
+	b.line("--timeout=" + seconds(r.client.Timeout))
+
+	switch {
+	case config.Insecure:
+		b.line("--verify=no")
+	case config.CACert != "":
+		b.line("--verify=" + config.CACert)
+	case config.CAPath != "":
+		b.line("--verify=" + config.CAPath)
+	}
+	if config.CertFile != "" {
+		b.line("--cert=" + config.CertFile)
+	}
+	if config.KeyFile != "" {
+		b.line("--cert-key=" + config.KeyFile)
+	}
+	if len(config.Ciphers) > 0 {
+		b.line("--ciphers=" + strings.Join(config.Ciphers, ":"))
+	}
+
+	if config.Proxy != "" {
+		proxy := config.Proxy
+		if config.ProxyUser != "" {
+			if scheme, rest, ok := strings.Cut(proxy, "://"); ok {
+				proxy = scheme + "://" + config.ProxyUser + "@" + rest
+			}
+		}
+		b.line("--proxy=http:"+proxy, "--proxy=https:"+proxy)
+	}
+
+	switch {
+	case config.User != "" && config.AWSSigV4 == "":
+		if config.Digest {
+			b.line("--auth-type=digest")
+		}
+		b.line("--auth=" + config.User)
+	case config.OAuth2Bearer != "":
+		b.line("--auth-type=bearer", "--auth="+config.OAuth2Bearer)
+	case config.OAuth2TokenURL != "":
+		b.note("TOKEN comes from the client credentials grant:")
+		b.note(`  http -a "$OAUTH2_CLIENT_ID:$OAUTH2_CLIENT_SECRET" --form POST %s grant_type=client_credentials`,
+			shellQuote(config.OAuth2TokenURL))
+		b.lineRaw("--auth-type=bearer", `--auth="$TOKEN"`)
+	}
+
+	if config.Data != "" {
+		b.line("--raw", config.Data)
+	}
+
+	method := config.Method
+	if method == "" {
+		method = "GET"
+	}
+	b.line(method, r.url)
+
+	for _, h := range r.effectiveHeaders() {
+		b.line(headerArg(h, ":"))
+	}
+	if encoding := r.acceptEncoding(); encoding != "" {
+		b.line("Accept-Encoding:" + encoding)
+	}
+
+	// Everything HTTPie cannot express is listed so the difference is visible
+	var unsupported []string
+	for _, resolve := range config.ResolveHosts {
+		unsupported = append(unsupported, "--resolve "+resolve)
+	}
+	for _, connectTo := range config.ConnectToHosts {
+		unsupported = append(unsupported, "--connect-to "+connectTo)
+	}
+	if config.Protocol == client.ProtocolHTTP2 || config.Protocol == client.ProtocolHTTP3 {
+		unsupported = append(unsupported, "--"+config.Protocol)
+	}
+	if config.IPVersion != 0 {
+		unsupported = append(unsupported, fmt.Sprintf("-%d", config.IPVersion))
+	}
+	if config.TLSMin != "" {
+		unsupported = append(unsupported, "--tls-min "+config.TLSMin)
+	}
+	if config.TLSMax != "" {
+		unsupported = append(unsupported, "--tls-max "+config.TLSMax)
+	}
+	if len(config.Curves) > 0 {
+		unsupported = append(unsupported, "--curves "+strings.Join(config.Curves, ","))
+	}
+	if config.SNI != "" {
+		unsupported = append(unsupported, "--sni "+config.SNI)
+	}
+	if config.AWSSigV4 != "" {
+		unsupported = append(unsupported, "--aws-sigv4 "+config.AWSSigV4)
+	}
+	if len(config.DNSServers) > 0 || config.DoHURL != "" {
+		unsupported = append(unsupported, "custom DNS resolvers")
+	}
+	if config.NoProxy != "" {
+		unsupported = append(unsupported, "--noproxy "+config.NoProxy)
+	}
+	if config.Cookie != "" && !client.IsCookieString(config.Cookie) {
+		unsupported = append(unsupported, "-b "+config.Cookie)
+	}
+	if len(unsupported) > 0 {
+		b.note("HTTPie cannot reproduce: %s", strings.Join(unsupported, ", "))
+	}
+	return b.String()
+}
+
+// goSnippet renders a standalone Go program that makes the same request with net/http
+func goSnippet(r snippetRequest) (string, error) {
+	config := r.config
+	imports := map[string]bool{"fmt": true, "io": true, "log": true, "net/http": true, "time": true}
+	var transport, setup, request []string
+	var unsupported []string
+
+	// Dialing: --resolve, --connect-to and -4/-6
+	overrides := make(map[string]string)
+	for addr, ip := range r.client.ResolveMap {
+		if _, port, err := net.SplitHostPort(addr); err == nil {
+			overrides[addr] = net.JoinHostPort(ip, port)
+		}
+	}
+	for addr, target := range r.client.ConnectToMap {
+		overrides[addr] = target
+	}
+	network := map[int]string{4: "tcp4", 6: "tcp6"}[config.IPVersion]
+	if len(overrides) > 0 || network != "" {
+		imports["context"] = true
+		imports["net"] = true
+		if len(overrides) > 0 {
+			addrs := make([]string, 0, len(overrides))
+			for addr := range overrides {
+				addrs = append(addrs, addr)
+			}
+			sort.Strings(addrs)
+			setup = append(setup, "// --resolve and --connect-to", "overrides := map[string]string{")
+			for _, addr := range addrs {
+				setup = append(setup, fmt.Sprintf("%q: %q,", addr, overrides[addr]))
+			}
+			setup = append(setup, "}")
+		}
+		setup = append(setup, "dialer := &net.Dialer{}")
+		transport = append(transport, "DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {")
+		if len(overrides) > 0 {
+			transport = append(transport, "if target, ok := overrides[addr]; ok {", "addr = target", "}")
+		}
+		if network != "" {
+			transport = append(transport, fmt.Sprintf("network = %q", network))
+		}
+		transport = append(transport, "return dialer.DialContext(ctx, network, addr)", "},")
+	}
+
+	// Proxy
+	if config.Proxy != "" {
+		imports["net/url"] = true
+		proxy := config.Proxy
+		if config.ProxyUser != "" {
+			if scheme, rest, ok := strings.Cut(proxy, "://"); ok {
+				proxy = scheme + "://" + config.ProxyUser + "@" + rest
+			}
+		}
+		setup = append(setup, fmt.Sprintf("proxyURL, err := url.Parse(%q)", proxy), "if err != nil {", "log.Fatal(err)", "}")
+		transport = append(transport, "Proxy: http.ProxyURL(proxyURL),")
+		if config.NoProxy != "" {
+			unsupported = append(unsupported, "--noproxy "+config.NoProxy)
+		}
+	} else {
+		transport = append(transport, "Proxy: http.ProxyFromEnvironment,")
+	}
+
+	// TLS
+	var tlsFields []string
+	if config.Insecure {
+		tlsFields = append(tlsFields, "InsecureSkipVerify: true,")
+	}
+	if config.SNI != "" {
+		tlsFields = append(tlsFields, fmt.Sprintf("ServerName: %q,", config.SNI))
+	}
+	if config.TLSMin != "" {
+		version, err := client.ParseTLSVersion(config.TLSMin)
+		if err != nil {
+			return "", err
+		}
+		tlsFields = append(tlsFields, "MinVersion: "+goTLSVersion(version)+",")
+	}
+	if config.TLSMax != "" {
+		version, err := client.ParseTLSVersion(config.TLSMax)
+		if err != nil {
+			return "", err
+		}
+		tlsFields = append(tlsFields, "MaxVersion: "+goTLSVersion(version)+",")
+	}
+	if len(config.Ciphers) > 0 {
+		if _, err := client.ParseCipherSuites(config.Ciphers); err != nil {
+			return "", err
+		}
+		names := make([]string, len(config.Ciphers))
+		for i, name := range config.Ciphers {
+			names[i] = "tls." + strings.ToUpper(strings.TrimSpace(name))
+		}
+		tlsFields = append(tlsFields, "CipherSuites: []uint16{"+strings.Join(names, ", ")+"},")
+	}
+	if len(config.Curves) > 0 {
+		curves, err := client.ParseCurves(config.Curves)
+		if err != nil {
+			return "", err
+		}
+		names := make([]string, len(curves))
+		for i, curve := range curves {
+			names[i] = "tls." + curve.String()
+		}
+		tlsFields = append(tlsFields, "CurvePreferences: []tls.CurveID{"+strings.Join(names, ", ")+"},")
+	}
+	if config.CertFile != "" {
+		keyFile := config.KeyFile
+		if keyFile == "" {
+			keyFile = config.CertFile
+		}
+		setup = append(setup,
+			fmt.Sprintf("cert, err := tls.LoadX509KeyPair(%q, %q)", config.CertFile, keyFile),
+			"if err != nil {", "log.Fatal(err)", "}")
+		tlsFields = append(tlsFields, "Certificates: []tls.Certificate{cert},")
+	}
+	if config.CACert != "" {
+		imports["crypto/x509"] = true
+		imports["os"] = true
+		setup = append(setup,
+			fmt.Sprintf("caPEM, err := os.ReadFile(%q)", config.CACert),
+			"if err != nil {", "log.Fatal(err)", "}",
+			"roots := x509.NewCertPool()",
+			"roots.AppendCertsFromPEM(caPEM)")
+		tlsFields = append(tlsFields, "RootCAs: roots,")
+	}
+	if config.CAPath != "" {
+		unsupported = append(unsupported, "--capath "+config.CAPath)
+	}
+	if len(tlsFields) > 0 {
+		imports["crypto/tls"] = true
+		transport = append(transport, "TLSClientConfig: &tls.Config{")
+		transport = append(transport, tlsFields...)
+		transport = append(transport, "},")
+	}
+
+	// Protocol
+	switch config.Protocol {
+	case client.ProtocolHTTP1:
+		setup = append(setup, "protocols := new(http.Protocols)", "protocols.SetHTTP1(true)")
+		transport = append(transport, "Protocols: protocols,")
+	case client.ProtocolHTTP2:
+		setup = append(setup, "protocols := new(http.Protocols)", "protocols.SetHTTP2(true)")
+		if strings.HasPrefix(r.url, "http://") {
+			setup = append(setup, "protocols.SetUnencryptedHTTP2(true)")
+		}
+		transport = append(transport, "Protocols: protocols,")
+	case client.ProtocolHTTP3:
+		unsupported = append(unsupported, "--http3 (net/http has no HTTP/3; see github.com/quic-go/quic-go/http3)")
+		transport = append(transport, "ForceAttemptHTTP2: true,")
+	default:
+		transport = append(transport, "ForceAttemptHTTP2: true,")
+	}
+	if !config.isLoadTest() {
+		transport = append(transport, "DisableKeepAlives: true,")
+	}
+
+	// Client and redirects
+	clientFields := []string{"Transport: transport,", fmt.Sprintf("Timeout: %s,", goDuration(r.client.Timeout))}
+	if follow, max := r.followsRedirects(); !follow {
+		clientFields = append(clientFields,
+			"CheckRedirect: func(req *http.Request, via []*http.Request) error {",
+			"return http.ErrUseLastResponse",
+			"},")
+	} else if max != client.DefaultMaxRedirects {
+		clientFields = append(clientFields,
+			"CheckRedirect: func(req *http.Request, via []*http.Request) error {",
+			fmt.Sprintf("if len(via) >= %d {", max),
+			fmt.Sprintf("return fmt.Errorf(\"stopped after %d redirects\")", max),
+			"}",
+			"return nil",
+			"},")
+	}
+
+	// Request
+	method := config.Method
+	if method == "" {
+		method = "GET"
+	}
+	body := "nil"
+	if config.Data != "" {
+		imports["strings"] = true
+		body = fmt.Sprintf("strings.NewReader(%s)", goString(config.Data))
+	}
+	request = append(request,
+		fmt.Sprintf("req, err := http.NewRequest(%q, %q, %s)", method, r.url, body),
+		"if err != nil {", "log.Fatal(err)", "}")
+	for _, h := range r.effectiveHeaders() {
+		switch {
+		case strings.EqualFold(h.Name, "Host"):
+			request = append(request, fmt.Sprintf("req.Host = %q", h.Value))
+		case h.Remove:
+			request = append(request, fmt.Sprintf("req.Header[%q] = nil // not sent", textproto.CanonicalMIMEHeaderKey(h.Name)))
+		default:
+			request = append(request, fmt.Sprintf("req.Header.Add(%q, %q)", h.Name, h.Value))
+		}
+	}
+	if encoding := r.acceptEncoding(); encoding != "" {
+		request = append(request, fmt.Sprintf("req.Header.Set(\"Accept-Encoding\", %q) // the body is not decoded", encoding))
+	}
+	switch {
+	case config.AWSSigV4 != "":
+		unsupported = append(unsupported, "--aws-sigv4 (sign with github.com/aws/aws-sdk-go-v2/aws/signer/v4)")
+	case config.User != "" && config.Digest:
+		unsupported = append(unsupported, "--digest")
+	case config.User != "":
+		user, password, _ := strings.Cut(config.User, ":")
+		request = append(request, fmt.Sprintf("req.SetBasicAuth(%q, %q)", user, password))
+	case config.OAuth2Bearer != "":
+		request = append(request, fmt.Sprintf("req.Header.Set(\"Authorization\", %q)", "Bearer "+config.OAuth2Bearer))
+	case config.OAuth2TokenURL != "":
+		imports["os"] = true
+		unsupported = append(unsupported, "--oauth2-token-url (set TOKEN from "+config.OAuth2TokenURL+"; see golang.org/x/oauth2/clientcredentials)")
+		request = append(request, `req.Header.Set("Authorization", "Bearer "+os.Getenv("TOKEN"))`)
+	}
+	if config.Cookie != "" && !client.IsCookieString(config.Cookie) {
+		unsupported = append(unsupported, "-b "+config.Cookie+" (cookie file)")
+	}
+	if len(config.DNSServers) > 0 || config.DoHURL != "" {
+		unsupported = append(unsupported, "custom DNS resolvers")
+	}
+
+	var src strings.Builder
+	src.WriteString("// Reproduces a gocurl request with net/http.\n")
+	if len(unsupported) > 0 {
+		src.WriteString("//\n// Not reproduced:\n")
+		for _, u := range unsupported {
+			src.WriteString("//   - " + u + "\n")
+		}
+	}
+	src.WriteString("package main\n\nimport (\n")
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&src, "%q\n", name)
+	}
+	src.WriteString(")\n\nfunc main() {\n")
+	for _, line := range setup {
+		src.WriteString(line + "\n")
+	}
+	src.WriteString("transport := &http.Transport{\n")
+	for _, line := range transport {
+		src.WriteString(line + "\n")
+	}
+	src.WriteString("}\nclient := &http.Client{\n")
+	for _, line := range clientFields {
+		src.WriteString(line + "\n")
+	}
+	src.WriteString("}\n\n")
+	for _, line := range request {
+		src.WriteString(line + "\n")
+	}
+	src.WriteString(`
+start := time.Now()
+resp, err := client.Do(req)
+if err != nil {
+log.Fatal(err)
+}
+defer resp.Body.Close()
+n, err := io.Copy(io.Discard, resp.Body)
+if err != nil {
+log.Fatal(err)
+}
+fmt.Printf("%s %s, %d bytes in %s\n", resp.Proto, resp.Status, n, time.Since(start))
+}
+`)
+
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return "", fmt.Errorf("failed to format Go snippet: %w", err)
+	}
+	return string(formatted), nil
+}
+
+// goTLSVersion returns the crypto/tls constant for a version
+func goTLSVersion(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "tls.VersionTLS10"
+	case tls.VersionTLS11:
+		return "tls.VersionTLS11"
+	case tls.VersionTLS12:
+		return "tls.VersionTLS12"
+	default:
+		return "tls.VersionTLS13"
+	}
+}
+
+// goDuration renders a duration as a Go expression
+func goDuration(d time.Duration) string {
+	switch {
+	case d%time.Second == 0:
+		return fmt.Sprintf("%d * time.Second", d/time.Second)
+	case d%time.Millisecond == 0:
+		return fmt.Sprintf("%d * time.Millisecond", d/time.Millisecond)
+	default:
+		return fmt.Sprintf("time.Duration(%d)", d)
+	}
+}
+
+// goString renders s as a Go string literal, preferring a raw string for bodies such as JSON
+func goString(s string) string {
+	if !strings.ContainsAny(s, "`\r") && strconv.CanBackquote(strings.ReplaceAll(s, "\n", "")) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// snippet renders the run's request to url in the --emit format
+func (a *App) snippet(url string) (string, error) {
+	return buildSnippet(a.config.Emit, snippetRequest{
+		url:     url,
+		config:  a.config,
+		client:  a.clientConfig,
+		headers: a.requestHeaders(),
+	})
+}
+
+// writeSnippet prints the --emit snippet for url. Table output gets it on
+// stdout after the results; machine-readable output keeps stdout clean, so
+// the snippet goes to stderr.
+func (a *App) writeSnippet(url string, notes ...string) error {
+	snippet, err := a.snippet(url)
+	if err != nil {
+		return err
+	}
+
+	w := os.Stderr
+	if a.config.OutputFormat == "table" {
+		w = os.Stdout
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s\n", color.CyanString("=== Reproduce with %s ===", a.config.Emit))
+	for _, note := range notes {
+		fmt.Fprintln(w, snippetComment(a.config.Emit, note))
+	}
+	fmt.Fprint(w, snippet)
+	return nil
+}
+
+// failedRequests tracks load-test failures by URL for --emit
+type failedRequests struct {
+	mu     sync.Mutex
+	order  []string
+	counts map[string]int
+	errors map[string]string
+}
+
+func newFailedRequests() *failedRequests {
+	return &failedRequests{counts: make(map[string]int), errors: make(map[string]string)}
+}
+
+// record notes a failed request; the first error seen for a URL is kept
+func (f *failedRequests) record(url, err string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.counts[url] == 0 {
+		f.order = append(f.order, url)
+		f.errors[url] = err
+	}
+	f.counts[url]++
+}
+
+// writeFailedSnippets prints one --emit snippet per URL that had failures
+func (a *App) writeFailedSnippets() error {
+	for _, url := range a.failures.order {
+		note := fmt.Sprintf("%d request(s) to %s failed: %s", a.failures.counts[url], url, a.failures.errors[url])
+		if err := a.writeSnippet(url, note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
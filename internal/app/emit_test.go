@@ -0,0 +1,187 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/erfi/gocurl/internal/client"
+)
+
+// newSnippetRequest builds the request a snippet is generated from, as App does
+func newSnippetRequest(t *testing.T, config *Config) snippetRequest {
+	t.Helper()
+	clientConfig, err := buildClientConfig(config)
+	if err != nil {
+		t.Fatalf("buildClientConfig failed: %v", err)
+	}
+	return snippetRequest{
+		url:     config.URLs[0],
+		config:  config,
+		client:  clientConfig,
+		headers: client.ParseHeaders(config.Headers),
+	}
+}
+
+func snippetConfig() *Config {
+	return &Config{
+		URLs:           []string{"https://api.example.com/items"},
+		Method:         "PUT",
+		Headers:        []string{"Content-Type: application/json", "Accept: a", "Accept: b", "X-Gone:"},
+		Data:           `{"name":"it's"}`,
+		Requests:       1,
+		Timeout:        "2500ms",
+		Insecure:       true,
+		ResolveHosts:   []string{"api.example.com:443:127.0.0.1"},
+		ConnectToHosts: []string{"api.example.com:8443:backend:443"},
+		TLSMin:         "1.2",
+		Protocol:       client.ProtocolHTTP2,
+		User:           "alice:secret",
+		Compressed:     "all",
+		MaxRedirects:   3,
+	}
+}
+
+func TestCurlSnippetRoundTrip(t *testing.T) {
+	config := snippetConfig()
+	snippet := curlSnippet(newSnippetRequest(t, config))
+
+	imported := &Config{Method: "GET"}
+	warnings, err := ParseCurlCommand(snippet, imported)
+	if err != nil {
+		t.Fatalf("Emitted command does not parse: %v\n%s", err, snippet)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+
+	if imported.Method != config.Method || imported.Data != config.Data || imported.User != config.User {
+		t.Errorf("Request mismatch: %s %q %q", imported.Method, imported.Data, imported.User)
+	}
+	wantHeaders := append(append([]string{}, config.Headers...), "User-Agent: "+client.DefaultUserAgent)
+	if !reflect.DeepEqual(imported.Headers, wantHeaders) {
+		t.Errorf("Expected headers %v, got %v", wantHeaders, imported.Headers)
+	}
+	if !reflect.DeepEqual(imported.ResolveHosts, config.ResolveHosts) || !reflect.DeepEqual(imported.ConnectToHosts, config.ConnectToHosts) {
+		t.Errorf("Connection mappings mismatch: %v %v", imported.ResolveHosts, imported.ConnectToHosts)
+	}
+	if !imported.Insecure || imported.TLSMin != "1.2" || imported.Protocol != client.ProtocolHTTP2 {
+		t.Errorf("TLS or protocol mismatch: %v %s %s", imported.Insecure, imported.TLSMin, imported.Protocol)
+	}
+	if imported.Timeout != "2.5s" || imported.Compressed != "all" || imported.NoFollow || imported.MaxRedirects != 3 {
+		t.Errorf("Timeout, compression or redirects mismatch: %s %s %v %d",
+			imported.Timeout, imported.Compressed, imported.NoFollow, imported.MaxRedirects)
+	}
+}
+
+func TestCurlSnippetNoFollow(t *testing.T) {
+	config := &Config{URLs: []string{"http://example.com/"}, Method: "GET", Requests: 1, NoFollow: true}
+	snippet := curlSnippet(newSnippetRequest(t, config))
+	if strings.Contains(snippet, "-L") {
+		t.Errorf("Expected no -L without redirects:\n%s", snippet)
+	}
+}
+
+func TestHTTPieSnippet(t *testing.T) {
+	snippet := httpieSnippet(newSnippetRequest(t, snippetConfig()))
+
+	for _, want := range []string{
+		"# HTTPie cannot reproduce: --resolve api.example.com:443:127.0.0.1, --connect-to api.example.com:8443:backend:443, --http2, --tls-min 1.2",
+		"--follow --max-redirects=3",
+		"--timeout=2.5",
+		"--verify=no",
+		"--auth=alice:secret",
+		`--raw '{"name":"it'\''s"}'`,
+		"PUT https://api.example.com/items",
+		"Content-Type:application/json",
+		"Accept:a \\\n  Accept:b",
+		"X-Gone:",
+		"'Accept-Encoding:zstd, br, gzip, deflate'",
+	} {
+		if !strings.Contains(snippet, want) {
+			t.Errorf("Expected %q in snippet:\n%s", want, snippet)
+		}
+	}
+}
+
+func TestSnippetOAuth2ClientSecret(t *testing.T) {
+	config := &Config{
+		URLs:           []string{"https://api.example.com/"},
+		Method:         "GET",
+		Requests:       1,
+		OAuth2TokenURL: "https://auth.example.com/token",
+		OAuth2ClientID: "gocurl-client",
+		OAuth2Secret:   "s3cret",
+	}
+	r := newSnippetRequest(t, config)
+	for name, snippet := range map[string]string{"curl": curlSnippet(r), "httpie": httpieSnippet(r)} {
+		if strings.Contains(snippet, "s3cret") {
+			t.Errorf("%s: client secret in snippet:\n%s", name, snippet)
+		}
+		if !strings.Contains(snippet, `"$OAUTH2_CLIENT_ID:$OAUTH2_CLIENT_SECRET"`) {
+			t.Errorf("%s: expected the client credentials as variables:\n%s", name, snippet)
+		}
+	}
+}
+
+func TestGoSnippet(t *testing.T) {
+	config := snippetConfig()
+	config.Curves = []string{"X25519", "P-256"}
+	config.SNI = "internal.example"
+
+	snippet, err := goSnippet(newSnippetRequest(t, config))
+	if err != nil {
+		t.Fatalf("goSnippet failed: %v", err)
+	}
+
+	for _, want := range []string{
+		`"api.example.com:443":  "127.0.0.1:443"`,
+		`"api.example.com:8443": "backend:443"`,
+		"InsecureSkipVerify: true",
+		`ServerName:         "internal.example"`,
+		"MinVersion:         tls.VersionTLS12",
+		"CurvePreferences:   []tls.CurveID{tls.X25519, tls.CurveP256}",
+		"protocols.SetHTTP2(true)",
+		"Timeout:   2500 * time.Millisecond",
+		"if len(via) >= 3 {",
+		"req, err := http.NewRequest(\"PUT\", \"https://api.example.com/items\", strings.NewReader(`{\"name\":\"it's\"}`))",
+		`req.Header.Add("Accept", "a")`,
+		`req.Header.Add("Accept", "b")`,
+		`req.Header["X-Gone"] = nil`,
+		`req.Header.Add("User-Agent", "gocurl/1.0")`,
+		`req.SetBasicAuth("alice", "secret")`,
+	} {
+		if !strings.Contains(snippet, want) {
+			t.Errorf("Expected %q in snippet:\n%s", want, snippet)
+		}
+	}
+}
+
+func TestGoSnippetUnsupported(t *testing.T) {
+	config := &Config{
+		URLs:     []string{"https://example.com/"},
+		Method:   "GET",
+		Requests: 1,
+		Protocol: client.ProtocolHTTP3,
+		User:     "bob:pw",
+		Digest:   true,
+	}
+	snippet, err := goSnippet(newSnippetRequest(t, config))
+	if err != nil {
+		t.Fatalf("goSnippet failed: %v", err)
+	}
+	if !strings.Contains(snippet, "//   - --http3") || !strings.Contains(snippet, "//   - --digest") {
+		t.Errorf("Expected HTTP/3 and digest auth to be listed as not reproduced:\n%s", snippet)
+	}
+}
+
+func TestValidateEmitFormat(t *testing.T) {
+	for _, format := range []string{"", "curl", "httpie", "go"} {
+		if err := validateEmitFormat(format); err != nil {
+			t.Errorf("Expected %q to be valid: %v", format, err)
+		}
+	}
+	if err := validateEmitFormat("wget"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
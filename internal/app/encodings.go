@@ -0,0 +1,113 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/output"
+)
+
+// runCompareEncodings requests the URL once per content encoding, each
+// on a fresh client, starting with an uncompressed baseline
+func (a *App) runCompareEncodings() error {
+	if len(a.config.URLs) == 0 {
+		return fmt.Errorf("no URLs provided")
+	}
+
+	encodings, err := compareEncodingList(a.config.Compressed)
+	if err != nil {
+		return err
+	}
+
+	results := make([]output.EncodingResult, 0, len(encodings))
+	for _, encoding := range encodings {
+		if !a.config.Quiet && a.config.OutputFormat != "json" {
+			fmt.Fprintf(os.Stderr, "Measuring %s...\n", encoding)
+		}
+		results = append(results, a.measureEncoding(encoding))
+	}
+
+	if a.config.OutputFormat == "json" {
+		if err := output.WriteEncodingComparisonJSON(os.Stdout, results); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	} else {
+		output.WriteEncodingComparisonTable(os.Stdout, results)
+	}
+
+	for _, r := range results {
+		if r.Error == "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("all encodings failed")
+}
+
+// validateCompareEncodings rejects the options --compare-encodings would
+// ignore: it requests one URL once per encoding
+func validateCompareEncodings(config *Config) error {
+	if !config.CompareEncodings {
+		return nil
+	}
+	switch {
+	case len(config.URLs) > 1:
+		return fmt.Errorf("--compare-encodings measures a single URL, got %d", len(config.URLs))
+	case config.isLoadTest():
+		return fmt.Errorf("--compare-encodings measures a single request per encoding; -n and --duration cannot be used")
+	}
+	return nil
+}
+
+// compareEncodingList returns the encodings to compare: those selected with
+// --compressed (all supported ones by default), preceded by identity
+func compareEncodingList(selected string) ([]string, error) {
+	encodings, err := client.ParseEncodings(selected)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []string{client.EncodingIdentity}
+	for _, encoding := range encodings {
+		if encoding != client.EncodingIdentity {
+			list = append(list, encoding)
+		}
+	}
+	return list, nil
+}
+
+// measureEncoding runs a single request that offers only the given encoding
+func (a *App) measureEncoding(encoding string) output.EncodingResult {
+	result := output.EncodingResult{Encoding: encoding}
+
+	clientConfig, err := buildClientConfig(a.config)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	clientConfig.AcceptEncoding = []string{encoding}
+	clientConfig.CookieJar = a.cookies
+	clientConfig.Auth = a.auth
+
+	httpClient := client.NewClient(clientConfig)
+	defer httpClient.Close()
+
+	var body io.Reader
+	if a.config.Data != "" {
+		body = strings.NewReader(a.config.Data)
+	}
+
+	// An explicit -H Accept-Encoding would defeat the comparison
+	headers := a.requestHeaders().Del("Accept-Encoding")
+
+	timing, err := httpClient.MeasureRequest(a.config.URLs[0], a.config.Method, headers, body)
+	result.Timing = timing
+	if err != nil {
+		result.Error = err.Error()
+	} else if timing != nil && timing.Error != "" {
+		result.Error = timing.Error
+	}
+	return result
+}
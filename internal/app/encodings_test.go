@@ -0,0 +1,28 @@
+package app
+
+import "testing"
+
+func TestValidateCompareEncodings(t *testing.T) {
+	urls := []string{"https://a.example.com", "https://b.example.com"}
+	valid := []*Config{
+		{URLs: urls, Requests: 5},
+		{URLs: urls[:1], Requests: 1, CompareEncodings: true},
+	}
+	for _, config := range valid {
+		if err := validateCompareEncodings(config); err != nil {
+			t.Errorf("%+v: unexpected error %v", config, err)
+		}
+	}
+
+	invalid := map[string]*Config{
+		"several URLs": {URLs: urls, Requests: 1},
+		"requests":     {URLs: urls[:1], Requests: 5},
+		"duration":     {URLs: urls[:1], Requests: 1, Duration: "10s"},
+	}
+	for name, config := range invalid {
+		config.CompareEncodings = true
+		if err := validateCompareEncodings(config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
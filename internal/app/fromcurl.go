@@ -0,0 +1,664 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/erfi/gocurl/internal/client"
+)
+
+// curlOption describes how an imported curl option maps onto Config
+type curlOption struct {
+	hasArg bool
+	apply  func(c *curlImport, arg string) error
+}
+
+// curlImport accumulates the request described by a curl command line
+type curlImport struct {
+	config   *Config
+	warnings []string
+	data     []string
+	method   string
+	urls     []string
+	get      bool
+	follow   bool
+}
+
+// ParseCurlCommand parses a curl command line, such as one produced by a
+// browser's "Copy as cURL", and applies the request it describes to config.
+// Run options already in config (requests, concurrency, output) are kept.
+// Options gocurl cannot reproduce are skipped with a warning.
+func ParseCurlCommand(command string, config *Config) ([]string, error) {
+	words, err := splitCommandLine(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) > 0 && (words[0] == "curl" || strings.HasSuffix(words[0], "/curl")) {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("empty curl command")
+	}
+
+	c := &curlImport{config: config}
+
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+
+		switch {
+		case word == "--":
+			c.urls = append(c.urls, words[i+1:]...)
+			i = len(words)
+		case strings.HasPrefix(word, "--"):
+			name := word[2:]
+			opt, ok := curlLongOptions[name]
+			if !ok {
+				// curl accepts --no-X to turn boolean options off
+				if negated, found := curlLongOptions[strings.TrimPrefix(name, "no-")]; found && !negated.hasArg {
+					c.warn("%s ignored", word)
+					continue
+				}
+				if curlArgOptions[name] && i+1 < len(words) {
+					c.warn("unsupported option %s %q ignored", word, words[i+1])
+					i++
+				} else {
+					c.warn("unsupported option %s ignored", word)
+				}
+				continue
+			}
+			var arg string
+			if opt.hasArg {
+				if i+1 >= len(words) {
+					return nil, fmt.Errorf("curl option %s requires a value", word)
+				}
+				i++
+				arg = words[i]
+			}
+			if err := opt.apply(c, arg); err != nil {
+				return nil, fmt.Errorf("curl option %s: %w", word, err)
+			}
+		case strings.HasPrefix(word, "-") && len(word) > 1:
+			// Short options can be combined (-sSL) and take their value
+			// attached (-XPOST) or as the next word (-X POST)
+			for j := 1; j < len(word); j++ {
+				flag := word[j : j+1]
+				name, ok := curlShortOptions[flag]
+				if !ok {
+					if curlArgOptions[flag] {
+						value := word[j+1:]
+						if value == "" && i+1 < len(words) {
+							i++
+							value = words[i]
+						}
+						c.warn("unsupported option -%s %q ignored", flag, value)
+						break
+					}
+					c.warn("unsupported option -%s ignored", flag)
+					continue
+				}
+				opt := curlLongOptions[name]
+				if !opt.hasArg {
+					if err := opt.apply(c, ""); err != nil {
+						return nil, fmt.Errorf("curl option -%s: %w", flag, err)
+					}
+					continue
+				}
+				arg := word[j+1:]
+				if arg == "" {
+					if i+1 >= len(words) {
+						return nil, fmt.Errorf("curl option -%s requires a value", flag)
+					}
+					i++
+					arg = words[i]
+				}
+				if err := opt.apply(c, arg); err != nil {
+					return nil, fmt.Errorf("curl option -%s: %w", flag, err)
+				}
+				break
+			}
+		default:
+			c.urls = append(c.urls, word)
+		}
+	}
+
+	if len(c.urls) == 0 {
+		return nil, fmt.Errorf("no URL in curl command")
+	}
+	c.finish()
+	return c.warnings, nil
+}
+
+// finish applies the options that depend on each other: data, -G and the method
+func (c *curlImport) finish() {
+	config := c.config
+	urls := c.urls
+
+	if len(c.data) > 0 {
+		data := strings.Join(c.data, "&")
+		if c.get {
+			// -G moves the data into the query string
+			for i, u := range urls {
+				separator := "?"
+				if strings.Contains(u, "?") {
+					separator = "&"
+				}
+				urls[i] = u + separator + data
+			}
+		} else {
+			config.Data = data
+			if c.method == "" {
+				c.method = "POST"
+			}
+			if client.ParseHeaders(config.Headers).Get("Content-Type") == "" {
+				config.Headers = append(config.Headers, "Content-Type: application/x-www-form-urlencoded")
+			}
+		}
+	}
+
+	if c.method != "" {
+		config.Method = c.method
+	}
+	config.URLs = urls
+
+	// curl only follows redirects with -L
+	if !c.follow {
+		config.NoFollow = true
+	}
+}
+
+func (c *curlImport) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// addData records a -d/--data-* value; raw values are taken literally, others
+// may name a file with @
+func (c *curlImport) addData(arg string, raw, binary bool) error {
+	if !raw && strings.HasPrefix(arg, "@") {
+		contents, err := readCurlFile(arg[1:])
+		if err != nil {
+			return err
+		}
+		if !binary {
+			// Like curl, -d @file drops carriage returns and newlines
+			contents = strings.NewReplacer("\r", "", "\n", "").Replace(contents)
+		}
+		arg = contents
+	}
+	c.data = append(c.data, arg)
+	return nil
+}
+
+// addURLEncodedData implements --data-urlencode: "content", "=content",
+// "name=content", "@file" and "name@file"
+func (c *curlImport) addURLEncodedData(arg string) error {
+	name, content := "", arg
+	if i := strings.IndexAny(arg, "=@"); i >= 0 {
+		name, content = arg[:i], arg[i+1:]
+		if arg[i] == '@' {
+			contents, err := readCurlFile(content)
+			if err != nil {
+				return err
+			}
+			content = contents
+		}
+	}
+
+	encoded := url.QueryEscape(content)
+	if name != "" {
+		encoded = name + "=" + encoded
+	}
+	c.data = append(c.data, encoded)
+	return nil
+}
+
+// readCurlFile reads a file named in a curl option; "-" is stdin
+func readCurlFile(name string) (string, error) {
+	if name == "-" {
+		name = os.Stdin.Name()
+	}
+	contents, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return string(contents), nil
+}
+
+// setProtocol records --http1.1/--http2/--http3
+func setProtocol(protocol string) func(c *curlImport, arg string) error {
+	return func(c *curlImport, arg string) error {
+		c.config.Protocol = protocol
+		return nil
+	}
+}
+
+// setTLSMin records --tlsv1.x, which sets the minimum TLS version
+func setTLSMin(version string) func(c *curlImport, arg string) error {
+	return func(c *curlImport, arg string) error {
+		c.config.TLSMin = version
+		return nil
+	}
+}
+
+// ignored accepts options that only affect curl's own output
+func ignored(c *curlImport, arg string) error {
+	return nil
+}
+
+// curlLongOptions are the curl options gocurl can reproduce, by long name
+var curlLongOptions = map[string]curlOption{
+	"request": {true, func(c *curlImport, arg string) error {
+		c.method = arg
+		return nil
+	}},
+	"header": {true, func(c *curlImport, arg string) error {
+		if strings.HasPrefix(arg, "@") {
+			return fmt.Errorf("reading headers from a file is not supported")
+		}
+		c.config.Headers = append(c.config.Headers, arg)
+		return nil
+	}},
+	"user-agent": {true, func(c *curlImport, arg string) error {
+		c.config.Headers = append(c.config.Headers, "User-Agent: "+arg)
+		return nil
+	}},
+	"referer": {true, func(c *curlImport, arg string) error {
+		c.config.Headers = append(c.config.Headers, "Referer: "+arg)
+		return nil
+	}},
+	"data": {true, func(c *curlImport, arg string) error {
+		return c.addData(arg, false, false)
+	}},
+	"data-ascii": {true, func(c *curlImport, arg string) error {
+		return c.addData(arg, false, false)
+	}},
+	"data-binary": {true, func(c *curlImport, arg string) error {
+		return c.addData(arg, false, true)
+	}},
+	"data-raw": {true, func(c *curlImport, arg string) error {
+		return c.addData(arg, true, true)
+	}},
+	"data-urlencode": {true, func(c *curlImport, arg string) error {
+		return c.addURLEncodedData(arg)
+	}},
+	"json": {true, func(c *curlImport, arg string) error {
+		if err := c.addData(arg, false, true); err != nil {
+			return err
+		}
+		c.config.Headers = append(c.config.Headers, "Content-Type: application/json", "Accept: application/json")
+		return nil
+	}},
+	"get": {false, func(c *curlImport, arg string) error {
+		c.get = true
+		return nil
+	}},
+	"head": {false, func(c *curlImport, arg string) error {
+		c.method = "HEAD"
+		c.config.IncludeHeaders = true
+		return nil
+	}},
+	"include": {false, func(c *curlImport, arg string) error {
+		c.config.IncludeHeaders = true
+		return nil
+	}},
+	"url": {true, func(c *curlImport, arg string) error {
+		c.urls = append(c.urls, arg)
+		return nil
+	}},
+	"user": {true, func(c *curlImport, arg string) error {
+		c.config.User = arg
+		return nil
+	}},
+	"digest": {false, func(c *curlImport, arg string) error {
+		c.config.Digest = true
+		return nil
+	}},
+	"basic": {false, ignored},
+	"oauth2-bearer": {true, func(c *curlImport, arg string) error {
+		c.config.OAuth2Bearer = arg
+		return nil
+	}},
+	"aws-sigv4": {true, func(c *curlImport, arg string) error {
+		c.config.AWSSigV4 = arg
+		return nil
+	}},
+	"cookie": {true, func(c *curlImport, arg string) error {
+		c.config.Cookie = arg
+		return nil
+	}},
+	"cookie-jar": {true, func(c *curlImport, arg string) error {
+		c.config.CookieJar = arg
+		return nil
+	}},
+	"insecure": {false, func(c *curlImport, arg string) error {
+		c.config.Insecure = true
+		return nil
+	}},
+	"compressed": {false, func(c *curlImport, arg string) error {
+		if c.config.Compressed == "" {
+			c.config.Compressed = "all"
+		}
+		return nil
+	}},
+	"resolve": {true, func(c *curlImport, arg string) error {
+		c.config.ResolveHosts = append(c.config.ResolveHosts, arg)
+		return nil
+	}},
+	"connect-to": {true, func(c *curlImport, arg string) error {
+		c.config.ConnectToHosts = append(c.config.ConnectToHosts, arg)
+		return nil
+	}},
+	"location": {false, func(c *curlImport, arg string) error {
+		c.follow = true
+		return nil
+	}},
+	"max-redirs": {true, func(c *curlImport, arg string) error {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", arg)
+		}
+		if n >= 0 {
+			c.config.MaxRedirects = n
+		}
+		// MaxRedirects of 0 means the default limit; curl follows none
+		if n == 0 {
+			c.config.NoFollow = true
+		}
+		return nil
+	}},
+	"max-time": {true, func(c *curlImport, arg string) error {
+		seconds, err := strconv.ParseFloat(arg, 64)
+		if err != nil || seconds <= 0 {
+			return fmt.Errorf("invalid number of seconds %q", arg)
+		}
+		c.config.Timeout = strconv.FormatFloat(seconds, 'f', -1, 64) + "s"
+		return nil
+	}},
+	"proxy": {true, func(c *curlImport, arg string) error {
+		c.config.Proxy = arg
+		return nil
+	}},
+	"proxy-user": {true, func(c *curlImport, arg string) error {
+		c.config.ProxyUser = arg
+		return nil
+	}},
+	"noproxy": {true, func(c *curlImport, arg string) error {
+		c.config.NoProxy = arg
+		return nil
+	}},
+	"cacert": {true, func(c *curlImport, arg string) error {
+		c.config.CACert = arg
+		return nil
+	}},
+	"capath": {true, func(c *curlImport, arg string) error {
+		c.config.CAPath = arg
+		return nil
+	}},
+	"cert": {true, func(c *curlImport, arg string) error {
+		if file, _, ok := strings.Cut(arg, ":"); ok && !isWindowsPath(arg) {
+			c.warn("client certificate passphrases are not supported; using %s", file)
+			arg = file
+		}
+		c.config.CertFile = arg
+		return nil
+	}},
+	"key": {true, func(c *curlImport, arg string) error {
+		c.config.KeyFile = arg
+		return nil
+	}},
+	"ciphers": {true, func(c *curlImport, arg string) error {
+		c.config.Ciphers = append(c.config.Ciphers, strings.Split(arg, ":")...)
+		return nil
+	}},
+	"curves": {true, func(c *curlImport, arg string) error {
+		c.config.Curves = append(c.config.Curves, strings.Split(arg, ":")...)
+		return nil
+	}},
+	"tls-max": {true, func(c *curlImport, arg string) error {
+		c.config.TLSMax = arg
+		return nil
+	}},
+	"tlsv1":                 {false, setTLSMin("1.0")},
+	"tlsv1.0":               {false, setTLSMin("1.0")},
+	"tlsv1.1":               {false, setTLSMin("1.1")},
+	"tlsv1.2":               {false, setTLSMin("1.2")},
+	"tlsv1.3":               {false, setTLSMin("1.3")},
+	"http1.1":               {false, setProtocol(client.ProtocolHTTP1)},
+	"http2":                 {false, setProtocol(client.ProtocolHTTP2)},
+	"http2-prior-knowledge": {false, setProtocol(client.ProtocolHTTP2)},
+	"http3":                 {false, setProtocol(client.ProtocolHTTP3)},
+	"http3-only":            {false, setProtocol(client.ProtocolHTTP3)},
+	"ipv4": {false, func(c *curlImport, arg string) error {
+		c.config.IPVersion = 4
+		return nil
+	}},
+	"ipv6": {false, func(c *curlImport, arg string) error {
+		c.config.IPVersion = 6
+		return nil
+	}},
+	"doh-url": {true, func(c *curlImport, arg string) error {
+		c.config.DoHURL = arg
+		return nil
+	}},
+	"dns-servers": {true, func(c *curlImport, arg string) error {
+		c.config.DNSServers = append(c.config.DNSServers, strings.Split(arg, ",")...)
+		return nil
+	}},
+	"verbose": {false, func(c *curlImport, arg string) error {
+		c.config.Verbose = true
+		return nil
+	}},
+	"silent":            {false, ignored},
+	"show-error":        {false, ignored},
+	"no-progress-meter": {false, ignored},
+	"progress-bar":      {false, ignored},
+	"globoff":           {false, ignored},
+	"fail":              {false, ignored},
+	"no-buffer":         {false, ignored},
+}
+
+// curlShortOptions maps curl's short options to their long names
+var curlShortOptions = map[string]string{
+	"X": "request",
+	"H": "header",
+	"A": "user-agent",
+	"e": "referer",
+	"d": "data",
+	"G": "get",
+	"I": "head",
+	"i": "include",
+	"u": "user",
+	"b": "cookie",
+	"c": "cookie-jar",
+	"k": "insecure",
+	"L": "location",
+	"m": "max-time",
+	"x": "proxy",
+	"U": "proxy-user",
+	"E": "cert",
+	"4": "ipv4",
+	"6": "ipv6",
+	"v": "verbose",
+	"s": "silent",
+	"S": "show-error",
+	"#": "progress-bar",
+	"g": "globoff",
+	"f": "fail",
+	"N": "no-buffer",
+}
+
+// curlArgOptions are unsupported curl options that take a value, so that the
+// value is skipped rather than mistaken for a URL
+var curlArgOptions = map[string]bool{
+	"o": true, "output": true,
+	"w": true, "write-out": true,
+	"F": true, "form": true, "form-string": true,
+	"T": true, "upload-file": true,
+	"K": true, "config": true,
+	"r": true, "range": true,
+	"z": true, "time-cond": true,
+	"D": true, "dump-header": true,
+	"Y": true, "speed-limit": true,
+	"y": true, "speed-time": true,
+	"retry": true, "retry-delay": true, "retry-max-time": true,
+	"connect-timeout": true, "limit-rate": true, "interface": true,
+	"local-port": true, "trace": true, "trace-ascii": true, "stderr": true,
+	"pass": true, "key-type": true, "cert-type": true, "proxy-cacert": true,
+	"proxy-cert": true, "proxy-key": true, "request-target": true, "unix-socket": true,
+	"abstract-unix-socket": true, "variable": true, "expand-url": true,
+}
+
+// isWindowsPath reports whether a --cert value starts with a drive letter, so
+// its colon is not a passphrase separator
+func isWindowsPath(s string) bool {
+	return len(s) > 2 && s[1] == ':' && (s[2] == '\\' || s[2] == '/')
+}
+
+// splitCommandLine splits a POSIX shell command line into words. It handles
+// single and double quotes, backslash escapes, line continuations and bash's
+// $'...' strings, which browsers use for bodies with special characters.
+func splitCommandLine(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case ch == '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+				continue
+			}
+			if i+2 < len(s) && s[i+1] == '\r' && s[i+2] == '\n' {
+				i += 2
+				continue
+			}
+			if i+1 < len(s) {
+				i++
+				word.WriteByte(s[i])
+			}
+			inWord = true
+		case ch == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ' quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case ch == '$' && i+1 < len(s) && s[i+1] == '\'':
+			n, err := readANSIQuoted(s[i+2:], &word)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 2
+			inWord = true
+		case ch == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					switch s[i+1] {
+					case '"', '\\', '$', '`':
+						i++
+					case '\n':
+						i++
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated \" quote")
+			}
+			inWord = true
+		default:
+			word.WriteByte(ch)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// readANSIQuoted decodes the body of a $'...' string into word and returns the
+// number of bytes consumed, including the closing quote
+func readANSIQuoted(s string, word *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch == '\'' {
+			return i + 1, nil
+		}
+		if ch != '\\' || i+1 >= len(s) {
+			word.WriteByte(ch)
+			continue
+		}
+
+		i++
+		switch esc := s[i]; esc {
+		case 'n':
+			word.WriteByte('\n')
+		case 't':
+			word.WriteByte('\t')
+		case 'r':
+			word.WriteByte('\r')
+		case 'a':
+			word.WriteByte('\a')
+		case 'b':
+			word.WriteByte('\b')
+		case 'f':
+			word.WriteByte('\f')
+		case 'v':
+			word.WriteByte('\v')
+		case 'e', 'E':
+			word.WriteByte(0x1b)
+		case 'x', 'u', 'U':
+			digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[esc]
+			end := i + 1
+			for end < len(s) && end-i-1 < digits && isHexDigit(s[end]) {
+				end++
+			}
+			if end == i+1 {
+				word.WriteByte('\\')
+				word.WriteByte(esc)
+				continue
+			}
+			value, _ := strconv.ParseUint(s[i+1:end], 16, 32)
+			if esc == 'x' {
+				word.WriteByte(byte(value))
+			} else {
+				word.WriteRune(rune(value))
+			}
+			i = end - 1
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := i
+			for end < len(s) && end-i < 3 && s[end] >= '0' && s[end] <= '7' {
+				end++
+			}
+			value, _ := strconv.ParseUint(s[i:end], 8, 8)
+			word.WriteByte(byte(value))
+			i = end - 1
+		default:
+			// \\, \', \" and \? stand for themselves; unknown escapes are kept
+			if esc != '\\' && esc != '\'' && esc != '"' && esc != '?' {
+				word.WriteByte('\\')
+			}
+			word.WriteByte(esc)
+		}
+	}
+	return 0, fmt.Errorf("unterminated $' quote")
+}
+
+func isHexDigit(b byte) bool {
+	return ('0' <= b && b <= '9') || ('a' <= b && b <= 'f') || ('A' <= b && b <= 'F')
+}
@@ -0,0 +1,194 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"plain words", "curl -s https://example.com", []string{"curl", "-s", "https://example.com"}},
+		{"single quotes", `curl -H 'Accept: */*' 'https://example.com/a b'`, []string{"curl", "-H", "Accept: */*", "https://example.com/a b"}},
+		{"double quotes with escapes", `curl -d "{\"a\":\"\$1\"}"`, []string{"curl", "-d", `{"a":"$1"}`}},
+		{"line continuation", "curl 'https://example.com' \\\n  -H 'X-A: 1' \\\r\n  --compressed", []string{"curl", "https://example.com", "-H", "X-A: 1", "--compressed"}},
+		{"adjacent quoting", `a'b'"c"\ d`, []string{"abc d"}},
+		{"ansi-c quoting", `curl --data-raw $'{"msg":"it\'s\\né\x41"}'`, []string{"curl", "--data-raw", "{\"msg\":\"it's\\néA\"}"}},
+		{"ansi-c escapes", `$'a\tb\nc'`, []string{"a\tb\nc"}},
+		{"empty quotes", `curl -H ''`, []string{"curl", "-H", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitCommandLine(tt.input)
+			if err != nil {
+				t.Fatalf("splitCommandLine failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	for _, input := range []string{`curl 'unterminated`, `curl "unterminated`, `curl $'unterminated`} {
+		if _, err := splitCommandLine(input); err == nil {
+			t.Errorf("Expected an error for %s", input)
+		}
+	}
+}
+
+func TestParseCurlCommand(t *testing.T) {
+	// A "Copy as cURL" command from browser devtools
+	command := `curl 'https://api.example.com/v1/items?x=1' \
+  -H 'accept: application/json' \
+  -H 'content-type: application/json' \
+  -b 'session=abc; theme=dark' \
+  --data-raw $'{"name":"it\'s"}' \
+  --compressed`
+
+	config := &Config{Method: "GET", Requests: 10, Timeout: "30s"}
+	warnings, err := ParseCurlCommand(command, config)
+	if err != nil {
+		t.Fatalf("ParseCurlCommand failed: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+
+	if !reflect.DeepEqual(config.URLs, []string{"https://api.example.com/v1/items?x=1"}) {
+		t.Errorf("Unexpected URLs %v", config.URLs)
+	}
+	if config.Method != "POST" {
+		t.Errorf("Expected POST for a request with data, got %s", config.Method)
+	}
+	if config.Data != `{"name":"it's"}` {
+		t.Errorf("Unexpected data %q", config.Data)
+	}
+	if !reflect.DeepEqual(config.Headers, []string{"accept: application/json", "content-type: application/json"}) {
+		t.Errorf("Unexpected headers %v", config.Headers)
+	}
+	if config.Cookie != "session=abc; theme=dark" {
+		t.Errorf("Unexpected cookie %q", config.Cookie)
+	}
+	if config.Compressed != "all" {
+		t.Errorf("Expected --compressed to select all encodings, got %q", config.Compressed)
+	}
+	if !config.NoFollow {
+		t.Error("Expected redirects not to be followed without -L")
+	}
+	if config.Requests != 10 || config.Timeout != "30s" {
+		t.Error("Run options should be kept")
+	}
+}
+
+func TestParseCurlCommandMaxRedirsZero(t *testing.T) {
+	config := &Config{Method: "GET", MaxRedirects: 10}
+	if _, err := ParseCurlCommand(`curl -L --max-redirs 0 https://example.com/`, config); err != nil {
+		t.Fatalf("ParseCurlCommand failed: %v", err)
+	}
+	if !config.NoFollow {
+		t.Error("Expected --max-redirs 0 not to follow redirects")
+	}
+}
+
+func TestParseCurlCommandOptions(t *testing.T) {
+	command := `curl -sSLk -XPUT -u alice:secret --resolve example.com:443:127.0.0.1 ` +
+		`--connect-to example.com:443:backend:8443 -d a=1 -d b=2 -m 2.5 --http2 -A agent/1 https://example.com/`
+
+	config := &Config{Method: "GET"}
+	warnings, err := ParseCurlCommand(command, config)
+	if err != nil {
+		t.Fatalf("ParseCurlCommand failed: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+
+	if config.Method != "PUT" || !config.Insecure || config.NoFollow {
+		t.Errorf("Expected PUT, insecure and following redirects; got %s, %v, %v", config.Method, config.Insecure, config.NoFollow)
+	}
+	if config.User != "alice:secret" {
+		t.Errorf("Unexpected user %q", config.User)
+	}
+	if config.Data != "a=1&b=2" {
+		t.Errorf("Expected data joined with &, got %q", config.Data)
+	}
+	if !reflect.DeepEqual(config.ResolveHosts, []string{"example.com:443:127.0.0.1"}) {
+		t.Errorf("Unexpected resolve %v", config.ResolveHosts)
+	}
+	if !reflect.DeepEqual(config.ConnectToHosts, []string{"example.com:443:backend:8443"}) {
+		t.Errorf("Unexpected connect-to %v", config.ConnectToHosts)
+	}
+	if config.Timeout != "2.5s" || config.Protocol != "http2" {
+		t.Errorf("Unexpected timeout %q or protocol %q", config.Timeout, config.Protocol)
+	}
+	if !reflect.DeepEqual(config.Headers, []string{"User-Agent: agent/1", "Content-Type: application/x-www-form-urlencoded"}) {
+		t.Errorf("Unexpected headers %v", config.Headers)
+	}
+}
+
+func TestParseCurlCommandDataVariants(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "body.txt")
+	if err := os.WriteFile(file, []byte("line1\nline2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		args   string
+		data   string
+		method string
+		url    string
+	}{
+		{"file strips newlines", "-d @" + file, "line1line2", "POST", "https://example.com/"},
+		{"binary file kept as is", "--data-binary @" + file, "line1\nline2\n", "POST", "https://example.com/"},
+		{"raw keeps @", "--data-raw @" + file, "@" + file, "POST", "https://example.com/"},
+		{"urlencode", "--data-urlencode 'q=a b&c' --data-urlencode =x/y", "q=a+b%26c&x%2Fy", "POST", "https://example.com/"},
+		{"get moves data to the query", "-G -d a=1 -d b=2", "", "GET", "https://example.com/?a=1&b=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Method: "GET"}
+			if _, err := ParseCurlCommand("curl "+tt.args+" https://example.com/", config); err != nil {
+				t.Fatalf("ParseCurlCommand failed: %v", err)
+			}
+			if config.Data != tt.data || config.Method != tt.method || config.URLs[0] != tt.url {
+				t.Errorf("Got data %q, method %s, URL %s", config.Data, config.Method, config.URLs[0])
+			}
+		})
+	}
+}
+
+func TestParseCurlCommandWarnings(t *testing.T) {
+	config := &Config{Method: "GET"}
+	warnings, err := ParseCurlCommand(`curl -o out.html --retry 3 -F file=@x.png --no-compressed --tcp-nodelay https://example.com`, config)
+	if err != nil {
+		t.Fatalf("ParseCurlCommand failed: %v", err)
+	}
+	if len(warnings) != 5 {
+		t.Fatalf("Expected 5 warnings, got %d: %v", len(warnings), warnings)
+	}
+	if !strings.Contains(warnings[1], "--retry") || !strings.Contains(warnings[4], "--tcp-nodelay") {
+		t.Errorf("Warnings should name the options: %v", warnings)
+	}
+	// Option values must not be taken as URLs
+	if !reflect.DeepEqual(config.URLs, []string{"https://example.com"}) {
+		t.Errorf("Unexpected URLs %v", config.URLs)
+	}
+}
+
+func TestParseCurlCommandErrors(t *testing.T) {
+	for _, command := range []string{"", "curl", "curl -s", "curl https://example.com -H", "curl -H @headers.txt https://example.com"} {
+		if _, err := ParseCurlCommand(command, &Config{}); err == nil {
+			t.Errorf("Expected an error for %q", command)
+		}
+	}
+}
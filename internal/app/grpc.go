@@ -0,0 +1,309 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/output"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GRPCOptions select the method to call and where its schema comes from.
+// Without Protos or Protosets the schema is fetched with server reflection.
+type GRPCOptions struct {
+	Target      string   // host:port, or an http:// or https:// URL
+	Method      string   // package.Service/Method; empty lists the services
+	Protos      []string // .proto files to compile, relative to an import path
+	ImportPaths []string // Directories searched for Protos and their imports
+	Protosets   []string // Compiled descriptor sets (protoc -o, buf build -o)
+	Plaintext   bool     // Use h2c instead of TLS for a host:port target
+}
+
+// grpcMethod is the call made by every request of a gRPC run
+type grpcMethod struct {
+	target string
+	call   client.GRPCCall
+	output protoreflect.MessageDescriptor
+	types  *dynamicpb.Types // Resolves Any fields in JSON
+}
+
+// RunGRPC calls a unary or server-streaming method once, or as a load test
+// with -n/-d, and reports grpc-status in place of the HTTP status. Without a
+// method it lists the services of the server.
+func RunGRPC(opts GRPCOptions, config *Config) error {
+	target, err := grpcTarget(opts.Target, opts.Plaintext)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case config.Protocol == client.ProtocolHTTP1 || config.Protocol == client.ProtocolHTTP3:
+		return fmt.Errorf("gRPC runs over HTTP/2; --http1.1 and --http3 cannot be used")
+	case config.CompareProtocols || config.CompareEncodings || config.TLSResume > 0:
+		return fmt.Errorf("--compare-protocols, --compare-encodings and --tls-resume cannot be used with gocurl grpc")
+	case config.Emit != "":
+		return fmt.Errorf("--emit does not support gRPC")
+	case len(opts.Protos) > 0 && len(opts.Protosets) > 0:
+		return fmt.Errorf("use either --proto or --protoset, not both")
+	}
+
+	runConfig := *config
+	runConfig.URLs = []string{target}
+	runConfig.Protocol = client.ProtocolHTTP2
+	runConfig.StreamFormat = ""
+	// A single call shows its responses, as grpcurl does
+	runConfig.ShowBody = !runConfig.isLoadTest()
+	a, err := New(&runConfig)
+	if err != nil {
+		return err
+	}
+	defer a.client.Close()
+
+	ctx := context.Background()
+	if opts.Method == "" {
+		return a.listGRPCServices(ctx, target)
+	}
+
+	if a.grpc, err = a.resolveGRPCMethod(ctx, opts, target); err != nil {
+		return err
+	}
+	return a.Run()
+}
+
+// resolveGRPCMethod looks the method up in the schema and encodes the request
+// message from --data
+func (a *App) resolveGRPCMethod(ctx context.Context, opts GRPCOptions, target string) (*grpcMethod, error) {
+	service, method, err := splitGRPCMethod(opts.Method)
+	if err != nil {
+		return nil, err
+	}
+	files, err := a.loadGRPCFiles(ctx, opts, target, service)
+	if err != nil {
+		return nil, err
+	}
+	md, err := findGRPCMethod(files, service, method)
+	if err != nil {
+		return nil, err
+	}
+	if md.IsStreamingClient() {
+		return nil, fmt.Errorf("%s is a client-streaming method; only unary and server-streaming methods are supported", md.FullName())
+	}
+
+	types := dynamicpb.NewTypes(files)
+	message, err := encodeGRPCRequest(md.Input(), a.config.Data, types)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcMethod{
+		target: target,
+		call: client.GRPCCall{
+			Method:          "/" + string(md.Parent().FullName()) + "/" + string(md.Name()),
+			Message:         message,
+			ServerStreaming: md.IsStreamingServer(),
+		},
+		output: md.Output(),
+		types:  types,
+	}, nil
+}
+
+// grpcTarget turns host:port into a base URL; URLs are kept as they are
+func grpcTarget(target string, plaintext bool) (string, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return strings.TrimSuffix(target, "/"), nil
+	}
+	if strings.Contains(target, "/") {
+		return "", fmt.Errorf("invalid gRPC address '%s': expected host:port or an http(s):// URL", target)
+	}
+	if plaintext {
+		return "http://" + target, nil
+	}
+	return "https://" + target, nil
+}
+
+// splitGRPCMethod accepts package.Service/Method and package.Service.Method
+func splitGRPCMethod(name string) (string, string, error) {
+	name = strings.TrimPrefix(name, "/")
+	i := strings.LastIndex(name, "/")
+	if i < 0 {
+		i = strings.LastIndex(name, ".")
+	}
+	if i <= 0 || i == len(name)-1 {
+		return "", "", fmt.Errorf("invalid method '%s': expected package.Service/Method", name)
+	}
+	return name[:i], name[i+1:], nil
+}
+
+// loadGRPCFiles builds the schema from --proto, --protoset or server reflection
+func (a *App) loadGRPCFiles(ctx context.Context, opts GRPCOptions, target, service string) (*protoregistry.Files, error) {
+	switch {
+	case len(opts.Protos) > 0:
+		compiler := protocompile.Compiler{
+			Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: opts.ImportPaths}),
+		}
+		compiled, err := compiler.Compile(ctx, opts.Protos...)
+		if err != nil {
+			return nil, err
+		}
+		files := new(protoregistry.Files)
+		for _, fd := range compiled {
+			if err := registerFile(files, fd); err != nil {
+				return nil, err
+			}
+		}
+		return files, nil
+
+	case len(opts.Protosets) > 0:
+		set := &descriptorpb.FileDescriptorSet{}
+		seen := make(map[string]bool)
+		for _, path := range opts.Protosets {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read protoset: %w", err)
+			}
+			var fds descriptorpb.FileDescriptorSet
+			if err := proto.Unmarshal(data, &fds); err != nil {
+				return nil, fmt.Errorf("%s: not a descriptor set: %w", path, err)
+			}
+			for _, fd := range fds.File {
+				if !seen[fd.GetName()] {
+					seen[fd.GetName()] = true
+					set.File = append(set.File, fd)
+				}
+			}
+		}
+		files, err := protodesc.NewFiles(set)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protoset (build it with --include_imports): %w", err)
+		}
+		return files, nil
+
+	default:
+		set, err := a.client.GRPCFileDescriptors(ctx, target, a.requestHeaders(), service)
+		if err != nil {
+			return nil, err
+		}
+		return protodesc.NewFiles(set)
+	}
+}
+
+// registerFile adds a compiled file after the files it imports
+func registerFile(files *protoregistry.Files, fd protoreflect.FileDescriptor) error {
+	if _, err := files.FindFileByPath(fd.Path()); err == nil {
+		return nil
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := registerFile(files, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	return files.RegisterFile(fd)
+}
+
+// findGRPCMethod looks a method up in the schema
+func findGRPCMethod(files *protoregistry.Files, service, method string) (protoreflect.MethodDescriptor, error) {
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found", service)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("service %s has no method %s", service, method)
+	}
+	return md, nil
+}
+
+// encodeGRPCRequest converts the --data JSON into the method's input message;
+// no data sends an empty message
+func encodeGRPCRequest(input protoreflect.MessageDescriptor, data string, types *dynamicpb.Types) ([]byte, error) {
+	msg := dynamicpb.NewMessage(input)
+	if strings.TrimSpace(data) != "" {
+		if err := (protojson.UnmarshalOptions{Resolver: types}).Unmarshal([]byte(data), msg); err != nil {
+			return nil, fmt.Errorf("invalid %s in --data: %w", input.FullName(), err)
+		}
+	}
+	return proto.Marshal(msg)
+}
+
+// decodeResponses fills in the JSON form of the kept response messages
+func (g *grpcMethod) decodeResponses(m *client.GRPCMetrics) {
+	for _, raw := range m.RawResponses {
+		msg := dynamicpb.NewMessage(g.output)
+		var text []byte
+		err := (proto.UnmarshalOptions{Resolver: g.types}).Unmarshal(raw, msg)
+		if err == nil {
+			text, err = (protojson.MarshalOptions{Resolver: g.types}).Marshal(msg)
+		}
+		if err != nil {
+			text, _ = json.Marshal(map[string]string{"error": err.Error()})
+		}
+		m.Responses = append(m.Responses, json.RawMessage(text))
+	}
+}
+
+// listGRPCServices prints the services found with server reflection
+func (a *App) listGRPCServices(ctx context.Context, target string) error {
+	services, err := a.client.GRPCListServices(ctx, target, a.requestHeaders())
+	if err != nil {
+		return err
+	}
+	if a.config.OutputFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(services)
+	}
+	for _, service := range services {
+		fmt.Fprintln(os.Stdout, service)
+	}
+	return nil
+}
+
+// runGRPCCall makes a single measured call
+func (a *App) runGRPCCall(ctx context.Context) error {
+	timing, err := a.client.MeasureGRPC(ctx, a.grpc.target, a.requestHeaders(), a.grpc.call)
+	if timing == nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	if timing.GRPC != nil {
+		a.grpc.decodeResponses(timing.GRPC)
+	}
+
+	if err := a.formatter.Write(os.Stdout, timing); err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	if a.config.OutputFormat == "table" {
+		if timing.GRPC != nil {
+			output.WriteGRPCMetrics(os.Stdout, timing.GRPC)
+		}
+		output.WriteStreamingMetrics(os.Stdout, timing.Streaming, a.config.Verbose)
+	}
+	a.writeH2Trace(timing)
+
+	if timing.Error != "" {
+		return fmt.Errorf("request error: %s", timing.Error)
+	}
+	if a.config.ExpectStreaming {
+		if timing.Streaming == nil {
+			return fmt.Errorf("streaming validation failed: %s is not a server-streaming method or sent no messages", a.grpc.call.Method)
+		}
+		if err := a.validateStreaming(timing.Streaming); err != nil {
+			return err
+		}
+	}
+	return nil
+}
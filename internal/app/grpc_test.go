@@ -0,0 +1,300 @@
+package app
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const echoProto = `syntax = "proto3";
+package test;
+
+message Ping {
+  string text = 1;
+  int32 count = 2;
+}
+
+message Pong {
+  string text = 1;
+  int32 seq = 2;
+}
+
+service Echo {
+  rpc Say(Ping) returns (Pong);
+  rpc Repeat(Ping) returns (stream Pong);
+  rpc Fail(Ping) returns (Pong);
+  rpc Collect(stream Ping) returns (Pong);
+}
+`
+
+// newEchoServer starts a grpc-go server for echo.proto with reflection and
+// returns its address
+func newEchoServer(t *testing.T) string {
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{Accessor: protocompile.SourceAccessorFromMap(map[string]string{"echo.proto": echoProto})},
+	}
+	compiled, err := compiler.Compile(context.Background(), "echo.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := compiled[0]
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(fd); err != nil {
+		t.Fatal(err)
+	}
+	ping, pong := fd.Messages().ByName("Ping"), fd.Messages().ByName("Pong")
+
+	reply := func(in *dynamicpb.Message, seq int32) *dynamicpb.Message {
+		out := dynamicpb.NewMessage(pong)
+		out.Set(pong.Fields().ByName("text"), in.Get(ping.Fields().ByName("text")))
+		out.Set(pong.Fields().ByName("seq"), protoreflect.ValueOfInt32(seq))
+		return out
+	}
+	unary := func(fail bool) grpc.MethodHandler {
+		return func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+			in := dynamicpb.NewMessage(ping)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if fail {
+				return nil, status.Error(codes.NotFound, "nothing to fail")
+			}
+			return reply(in, 1), nil
+		}
+	}
+
+	s := grpc.NewServer()
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Echo",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			{MethodName: "Say", Handler: unary(false)},
+			{MethodName: "Fail", Handler: unary(true)},
+		},
+		Streams: []grpc.StreamDesc{
+			{StreamName: "Repeat", ServerStreams: true, Handler: func(_ any, stream grpc.ServerStream) error {
+				in := dynamicpb.NewMessage(ping)
+				if err := stream.RecvMsg(in); err != nil {
+					return err
+				}
+				count := in.Get(ping.Fields().ByName("count")).Int()
+				for i := int64(1); i <= count; i++ {
+					if err := stream.SendMsg(reply(in, int32(i))); err != nil {
+						return err
+					}
+				}
+				return nil
+			}},
+			{StreamName: "Collect", ClientStreams: true, Handler: func(_ any, stream grpc.ServerStream) error {
+				return status.Error(codes.Unimplemented, "not used")
+			}},
+		},
+	}, struct{}{})
+	reflectionv1.RegisterServerReflectionServer(s, reflection.NewServerV1(reflection.ServerOptions{Services: s, DescriptorResolver: files}))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// newGRPCTestApp builds the App that RunGRPC would for a plaintext target
+func newGRPCTestApp(t *testing.T, addr string, config *Config) (*App, string) {
+	target, err := grpcTarget(addr, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.URLs = []string{target}
+	config.Protocol = client.ProtocolHTTP2
+	config.Timeout = "5s"
+	config.Concurrency = max(config.Concurrency, 1)
+	config.Requests = max(config.Requests, 1)
+	config.OutputFormat = "json"
+	a, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.client.Close)
+	return a, target
+}
+
+func TestGRPCReflection(t *testing.T) {
+	addr := newEchoServer(t)
+	a, target := newGRPCTestApp(t, addr, &Config{ShowBody: true, Data: `{"text": "hi"}`})
+	ctx := context.Background()
+
+	services, err := a.client.GRPCListServices(ctx, target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(services, ",") != "grpc.reflection.v1.ServerReflection,test.Echo" {
+		t.Errorf("Unexpected services %v", services)
+	}
+
+	method, err := a.resolveGRPCMethod(ctx, GRPCOptions{Method: "test.Echo/Say"}, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method.call.Method != "/test.Echo/Say" || method.call.ServerStreaming {
+		t.Errorf("Unexpected call %+v", method.call)
+	}
+	timing, err := a.client.MeasureGRPC(ctx, target, nil, method.call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	method.decodeResponses(timing.GRPC)
+	if timing.Error != "" || len(timing.GRPC.Responses) != 1 {
+		t.Fatalf("Unexpected result: error %q, %+v", timing.Error, timing.GRPC)
+	}
+	if got := string(timing.GRPC.Responses[0]); strings.ReplaceAll(got, " ", "") != `{"text":"hi","seq":1}` {
+		t.Errorf("Unexpected response %s", got)
+	}
+
+	// A non-OK status arrives as a trailers-only response
+	method, err = a.resolveGRPCMethod(ctx, GRPCOptions{Method: "test.Echo.Fail"}, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timing, _ = a.client.MeasureGRPC(ctx, target, nil, method.call)
+	if timing.GRPC.Code != 5 || timing.Error != "grpc-status 5 NOT_FOUND: nothing to fail" {
+		t.Errorf("Unexpected status %+v (%q)", timing.GRPC, timing.Error)
+	}
+
+	for _, name := range []string{"test.Echo/Collect", "test.Echo/Missing", "test.Nope/Say", "Say"} {
+		if _, err := a.resolveGRPCMethod(ctx, GRPCOptions{Method: name}, target); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestGRPCServerStreamingFromProto(t *testing.T) {
+	addr := newEchoServer(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "echo.proto"), []byte(echoProto), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, target := newGRPCTestApp(t, addr, &Config{Data: `{"text": "tick", "count": 5}`})
+	method, err := a.resolveGRPCMethod(context.Background(), GRPCOptions{Method: "test.Echo/Repeat", Protos: []string{"echo.proto"}, ImportPaths: []string{dir}}, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !method.call.ServerStreaming {
+		t.Fatal("Expected Repeat to be server-streaming")
+	}
+	timing, err := a.client.MeasureGRPC(context.Background(), target, nil, method.call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing.Error != "" || timing.GRPC.Messages != 5 {
+		t.Fatalf("Expected 5 messages, got %+v (%q)", timing.GRPC, timing.Error)
+	}
+	if timing.Streaming == nil || timing.Streaming.TotalChunks != 5 {
+		t.Errorf("Expected stream metrics for 5 messages, got %+v", timing.Streaming)
+	}
+}
+
+func TestGRPCProtoset(t *testing.T) {
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{Accessor: protocompile.SourceAccessorFromMap(map[string]string{"echo.proto": echoProto})},
+	}
+	compiled, err := compiler.Compile(context.Background(), "echo.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(compiled[0])}}
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "echo.protoset")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, target := newGRPCTestApp(t, "127.0.0.1:1", &Config{Data: `{"text": "x"}`})
+	method, err := a.resolveGRPCMethod(context.Background(), GRPCOptions{Method: "test.Echo/Say", Protosets: []string{path}}, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(method.call.Message) != 3 {
+		t.Errorf("Expected a 3-byte request message, got %x", method.call.Message)
+	}
+
+	a.config.Data = `{"txt": "x"}`
+	if _, err := a.resolveGRPCMethod(context.Background(), GRPCOptions{Method: "test.Echo/Say", Protosets: []string{path}}, target); err == nil {
+		t.Error("Expected an error for an unknown field in --data")
+	}
+}
+
+func TestGRPCLoad(t *testing.T) {
+	addr := newEchoServer(t)
+	a, target := newGRPCTestApp(t, addr, &Config{Requests: 20, Concurrency: 4, Data: `{"text": "load"}`})
+	ctx := context.Background()
+
+	var err error
+	if a.grpc, err = a.resolveGRPCMethod(ctx, GRPCOptions{Method: "test.Echo/Say"}, target); err != nil {
+		t.Fatal(err)
+	}
+	collector := metrics.NewCollector()
+	a.executeLoad(a.client, collector)
+	stats := collector.Calculate()
+	if stats.TotalRequests != 20 || stats.SuccessfulRequests != 20 || stats.GRPCStatus["OK"] != 20 {
+		t.Errorf("Expected 20 OK calls, got %+v", stats)
+	}
+	if len(stats.StatusCodes) != 0 {
+		t.Errorf("gRPC calls should not count HTTP status codes, got %v", stats.StatusCodes)
+	}
+
+	if a.grpc, err = a.resolveGRPCMethod(ctx, GRPCOptions{Method: "test.Echo/Fail"}, target); err != nil {
+		t.Fatal(err)
+	}
+	collector = metrics.NewCollector()
+	a.executeLoad(a.client, collector)
+	stats = collector.Calculate()
+	if stats.FailedRequests != 20 || stats.GRPCStatus["NOT_FOUND"] != 20 {
+		t.Errorf("Expected 20 NOT_FOUND calls, got %+v", stats)
+	}
+}
+
+func TestGRPCTarget(t *testing.T) {
+	tests := []struct {
+		target    string
+		plaintext bool
+		expected  string
+	}{
+		{"api.example.com:443", false, "https://api.example.com:443"},
+		{"localhost:50051", true, "http://localhost:50051"},
+		{"https://api.example.com/prefix/", false, "https://api.example.com/prefix"},
+	}
+	for _, tt := range tests {
+		got, err := grpcTarget(tt.target, tt.plaintext)
+		if err != nil || got != tt.expected {
+			t.Errorf("grpcTarget(%q, %v) = %q, %v; expected %q", tt.target, tt.plaintext, got, err, tt.expected)
+		}
+	}
+	if _, err := grpcTarget("example.com/path", false); err == nil {
+		t.Error("Expected an error for a path without a scheme")
+	}
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/output"
+)
+
+// validateH2Trace rejects --h2-trace where there is no single HTTP/2 request
+// to trace
+func validateH2Trace(config *Config) error {
+	if !config.H2Trace {
+		return nil
+	}
+	switch {
+	case config.isLoadTest():
+		return fmt.Errorf("--h2-trace traces a single request; load testing is not supported")
+	case config.Protocol == client.ProtocolHTTP1 || config.Protocol == client.ProtocolHTTP3:
+		return fmt.Errorf("--h2-trace needs HTTP/2; --http1.1 and --http3 cannot be used")
+	case config.CompareProtocols || config.CompareEncodings || config.TLSResume > 0:
+		return fmt.Errorf("--compare-protocols, --compare-encodings and --tls-resume cannot be used with --h2-trace")
+	case len(config.URLs) > 0 && client.IsWebSocketURL(config.URLs[0]):
+		return fmt.Errorf("WebSockets are upgraded over HTTP/1.1; --h2-trace cannot be used")
+	}
+	return nil
+}
+
+// writeH2Trace prints the frame trace of a single request, or a note when the
+// request did not run over HTTP/2
+func (a *App) writeH2Trace(timing *client.TimingBreakdown) {
+	if !a.config.H2Trace {
+		return
+	}
+	if timing.H2 == nil {
+		if !a.config.Quiet {
+			fmt.Fprintf(os.Stderr, "Note: --h2-trace found no HTTP/2 stream; the server did not negotiate HTTP/2 (use --http2 for h2c)\n")
+		}
+		return
+	}
+	if a.config.OutputFormat == "table" {
+		output.WriteH2Trace(os.Stdout, timing.H2, a.config.Verbose)
+	}
+}
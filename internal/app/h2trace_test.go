@@ -0,0 +1,36 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/erfi/gocurl/internal/client"
+)
+
+func TestValidateH2Trace(t *testing.T) {
+	valid := []*Config{
+		{URLs: []string{"https://example.com"}},
+		{URLs: []string{"http://localhost:8080"}, Protocol: client.ProtocolHTTP2, H2Trace: true},
+		{URLs: []string{"wss://example.com"}},
+	}
+	for _, config := range valid {
+		if err := validateH2Trace(config); err != nil {
+			t.Errorf("%+v: unexpected error %v", config, err)
+		}
+	}
+
+	invalid := map[string]*Config{
+		"load test":  {URLs: []string{"https://example.com"}, Requests: 10},
+		"duration":   {URLs: []string{"https://example.com"}, Duration: "10s"},
+		"http1.1":    {URLs: []string{"https://example.com"}, Protocol: client.ProtocolHTTP1},
+		"http3":      {URLs: []string{"https://example.com"}, Protocol: client.ProtocolHTTP3},
+		"compare":    {URLs: []string{"https://example.com"}, CompareProtocols: true},
+		"tls-resume": {URLs: []string{"https://example.com"}, TLSResume: 3},
+		"websocket":  {URLs: []string{"wss://example.com"}},
+	}
+	for name, config := range invalid {
+		config.H2Trace = true
+		if err := validateH2Trace(config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
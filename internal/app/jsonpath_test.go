@@ -0,0 +1,66 @@
+package app
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONPathLookup(t *testing.T) {
+	decoder := json.NewDecoder(strings.NewReader(`{
+		"access_token": "tok",
+		"user": {"id": 42, "score": 1.5, "active": true, "roles": ["admin", "dev"]},
+		"items": [{"sku": "a"}, {"sku": "b"}],
+		"odd key": null
+	}`))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"$.access_token", "tok"},
+		{"$.user.id", "42"},
+		{"$.user.score", "1.5"},
+		{"$.user.active", "true"},
+		{"$.user.roles[0]", "admin"},
+		{"$.user.roles[-1]", "dev"},
+		{"$.items[1].sku", "b"},
+		{"$['user']['roles']", `["admin","dev"]`},
+		{"$['odd key']", ""},
+	}
+	for _, tt := range tests {
+		path, err := parseJSONPath(tt.path)
+		if err != nil {
+			t.Fatalf("parseJSONPath(%q) failed: %v", tt.path, err)
+		}
+		value, ok := path.lookup(doc)
+		if !ok {
+			t.Errorf("%s: not found", tt.path)
+			continue
+		}
+		if got := jsonValueString(value); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.path, tt.expected, got)
+		}
+	}
+
+	for _, missing := range []string{"$.nope", "$.user.roles[2]", "$.items.sku", "$.access_token[0]"} {
+		path, err := parseJSONPath(missing)
+		if err != nil {
+			t.Fatalf("parseJSONPath(%q) failed: %v", missing, err)
+		}
+		if _, ok := path.lookup(doc); ok {
+			t.Errorf("%s: expected no match", missing)
+		}
+	}
+
+	for _, invalid := range []string{"user.id", "$..id", "$.items[x]", "$['open", "$[1"} {
+		if _, err := parseJSONPath(invalid); err == nil {
+			t.Errorf("parseJSONPath(%q): expected an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erfi/gocurl/internal/metrics"
+	"github.com/erfi/gocurl/internal/output"
+	"gopkg.in/yaml.v3"
+)
+
+// Plan is a declarative list of test cases run by "gocurl run"
+type Plan struct {
+	Name     string     `yaml:"name"`
+	BaseURL  string     `yaml:"base_url"`
+	Parallel bool       `yaml:"parallel"`
+	Defaults PlanCase   `yaml:"defaults"` // Applied to every case that leaves a field unset
+	Cases    []PlanCase `yaml:"cases"`
+}
+
+// PlanCase is one test case. Unset fields fall back to the plan defaults and
+// then to the command-line flags.
+type PlanCase struct {
+	Name        string     `yaml:"name"`
+	URL         string     `yaml:"url"`
+	Method      string     `yaml:"method"`
+	Headers     []string   `yaml:"headers"`
+	Body        string     `yaml:"body"`
+	Requests    int        `yaml:"requests"`
+	Concurrency int        `yaml:"concurrency"`
+	Duration    string     `yaml:"duration"`
+	Timeout     string     `yaml:"timeout"`
+	Thresholds  Thresholds `yaml:"thresholds"`
+}
+
+// Thresholds are the pass criteria of a case. Latency limits are durations;
+// the error rate is a fraction or a percentage and defaults to 0.
+type Thresholds struct {
+	P50          string  `yaml:"p50"`
+	P90          string  `yaml:"p90"`
+	P95          string  `yaml:"p95"`
+	P99          string  `yaml:"p99"`
+	Mean         string  `yaml:"mean"`
+	Max          string  `yaml:"max"`
+	MaxErrorRate string  `yaml:"max_error_rate"`
+	MinRPS       float64 `yaml:"min_rps"`
+	Status       []int   `yaml:"status"` // Allowed status codes, e.g. [200, 204]
+}
+
+// LoadPlan reads and validates a plan file ('-' for stdin)
+func LoadPlan(path string) (*Plan, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	var plan Plan
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&plan); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := plan.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &plan, nil
+}
+
+// validate checks that every case has a URL and parseable limits, and names
+// unnamed cases after their URL
+func (p *Plan) validate() error {
+	if len(p.Cases) == 0 {
+		return fmt.Errorf("plan has no cases")
+	}
+
+	seen := make(map[string]bool)
+	for i := range p.Cases {
+		c := p.Cases[i].withDefaults(p.Defaults)
+		if c.URL == "" && p.BaseURL == "" {
+			return fmt.Errorf("case %d: url is required", i+1)
+		}
+		if p.Cases[i].Name == "" {
+			p.Cases[i].Name = c.URL
+			if p.Cases[i].Name == "" {
+				p.Cases[i].Name = fmt.Sprintf("case %d", i+1)
+			}
+		}
+		name := p.Cases[i].Name
+		if seen[name] {
+			return fmt.Errorf("case '%s' is defined twice", name)
+		}
+		seen[name] = true
+
+		if c.Requests < 0 || c.Concurrency < 0 {
+			return fmt.Errorf("case '%s': requests and concurrency must be positive", name)
+		}
+		if c.Duration != "" {
+			if _, err := (&Config{Duration: c.Duration}).loadDuration(); err != nil {
+				return fmt.Errorf("case '%s': %w", name, err)
+			}
+		}
+		if _, err := c.Thresholds.parse(); err != nil {
+			return fmt.Errorf("case '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// withDefaults fills the fields a case leaves unset from the plan defaults.
+// Headers are merged, with the case winning for the same header name.
+func (c PlanCase) withDefaults(d PlanCase) PlanCase {
+	if c.URL == "" {
+		c.URL = d.URL
+	}
+	if c.Method == "" {
+		c.Method = d.Method
+	}
+	c.Headers = MergeHeaders(d.Headers, c.Headers)
+	if c.Body == "" {
+		c.Body = d.Body
+	}
+	if c.Requests == 0 {
+		c.Requests = d.Requests
+	}
+	if c.Concurrency == 0 {
+		c.Concurrency = d.Concurrency
+	}
+	if c.Duration == "" {
+		c.Duration = d.Duration
+	}
+	if c.Timeout == "" {
+		c.Timeout = d.Timeout
+	}
+	c.Thresholds = c.Thresholds.withDefaults(d.Thresholds)
+	return c
+}
+
+// withDefaults fills unset thresholds from the plan defaults
+func (t Thresholds) withDefaults(d Thresholds) Thresholds {
+	for _, f := range []struct{ value, fallback *string }{
+		{&t.P50, &d.P50}, {&t.P90, &d.P90}, {&t.P95, &d.P95}, {&t.P99, &d.P99},
+		{&t.Mean, &d.Mean}, {&t.Max, &d.Max}, {&t.MaxErrorRate, &d.MaxErrorRate},
+	} {
+		if *f.value == "" {
+			*f.value = *f.fallback
+		}
+	}
+	if t.MinRPS == 0 {
+		t.MinRPS = d.MinRPS
+	}
+	if t.Status == nil {
+		t.Status = d.Status
+	}
+	return t
+}
+
+// latencyLimit is one parsed latency threshold
+type latencyLimit struct {
+	name  string
+	limit time.Duration
+	value func(*metrics.Stats) metrics.Duration
+}
+
+// thresholdSet is the parsed form of Thresholds
+type thresholdSet struct {
+	latencies    []latencyLimit
+	maxErrorRate float64
+	minRPS       float64
+	status       map[int]bool
+}
+
+// parse validates the thresholds and converts them for checking
+func (t Thresholds) parse() (*thresholdSet, error) {
+	set := &thresholdSet{minRPS: t.MinRPS}
+
+	for _, l := range []struct {
+		name  string
+		raw   string
+		value func(*metrics.Stats) metrics.Duration
+	}{
+		{"p50", t.P50, func(s *metrics.Stats) metrics.Duration { return s.P50 }},
+		{"p90", t.P90, func(s *metrics.Stats) metrics.Duration { return s.P90 }},
+		{"p95", t.P95, func(s *metrics.Stats) metrics.Duration { return s.P95 }},
+		{"p99", t.P99, func(s *metrics.Stats) metrics.Duration { return s.P99 }},
+		{"mean", t.Mean, func(s *metrics.Stats) metrics.Duration { return s.MeanLatency }},
+		{"max", t.Max, func(s *metrics.Stats) metrics.Duration { return s.MaxLatency }},
+	} {
+		if l.raw == "" {
+			continue
+		}
+		limit, err := time.ParseDuration(l.raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s threshold '%s'", l.name, l.raw)
+		}
+		set.latencies = append(set.latencies, latencyLimit{l.name, limit, l.value})
+	}
+
+	if t.MaxErrorRate != "" {
+		raw := strings.TrimSpace(t.MaxErrorRate)
+		scale := 1.0
+		if strings.HasSuffix(raw, "%") {
+			raw = strings.TrimSuffix(raw, "%")
+			scale = 100
+		}
+		rate, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rate < 0 || rate/scale > 1 {
+			return nil, fmt.Errorf("invalid max_error_rate '%s': use a fraction (0.01) or a percentage (1%%)", t.MaxErrorRate)
+		}
+		set.maxErrorRate = rate / scale
+	}
+
+	if t.MinRPS < 0 {
+		return nil, fmt.Errorf("min_rps must be positive")
+	}
+	if len(t.Status) > 0 {
+		set.status = make(map[int]bool, len(t.Status))
+		for _, code := range t.Status {
+			set.status[code] = true
+		}
+	}
+	return set, nil
+}
+
+// check returns a description of every threshold the stats violate
+func (s *thresholdSet) check(stats *metrics.Stats) []string {
+	var violations []string
+	if stats.TotalRequests == 0 {
+		return []string{"no requests completed"}
+	}
+
+	if stats.ErrorRate > s.maxErrorRate {
+		violations = append(violations, fmt.Sprintf("error rate %.2f%% > %.2f%% (%d failed)",
+			stats.ErrorRate*100, s.maxErrorRate*100, stats.FailedRequests))
+	}
+	if stats.SuccessfulRequests == 0 {
+		return violations
+	}
+
+	for _, l := range s.latencies {
+		if got := time.Duration(l.value(stats)); got > l.limit {
+			violations = append(violations, fmt.Sprintf("%s %s > %s", l.name, got.Round(time.Microsecond), l.limit))
+		}
+	}
+	if s.minRPS > 0 && stats.RequestsPerSecond < s.minRPS {
+		violations = append(violations, fmt.Sprintf("%.2f req/s < %.2f req/s", stats.RequestsPerSecond, s.minRPS))
+	}
+	if s.status != nil {
+		codes := make([]int, 0, len(stats.StatusCodes))
+		for code := range stats.StatusCodes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			if !s.status[code] {
+				violations = append(violations, fmt.Sprintf("unexpected status %d (%d responses)", code, stats.StatusCodes[code]))
+			}
+		}
+	}
+	return violations
+}
+
+// caseConfig builds the app config of a case on top of the command-line config
+func (p *Plan) caseConfig(c PlanCase, base *Config) *Config {
+	config := *base
+	config.URLs = []string{ResolveURL(p.BaseURL, c.URL)}
+	config.Headers = MergeHeaders(base.Headers, c.Headers)
+	if c.Body != "" {
+		config.Data = c.Body
+		// A body without a method is a POST, as with curl -d
+		if c.Method == "" && base.Method == "GET" {
+			config.Method = "POST"
+		}
+	}
+	if c.Method != "" {
+		config.Method = strings.ToUpper(c.Method)
+	}
+	if c.Requests > 0 {
+		config.Requests = c.Requests
+	}
+	if c.Concurrency > 0 {
+		config.Concurrency = c.Concurrency
+	}
+	if c.Duration != "" {
+		config.Duration = c.Duration
+	}
+	if c.Timeout != "" {
+		config.Timeout = c.Timeout
+	}
+	// A plan only reports aggregated results
+	config.Emit = ""
+	return &config
+}
+
+// RunPlan executes every case of the plan, sequentially or in parallel, and
+// writes one report. It fails if any case fails.
+func RunPlan(plan *Plan, base *Config) error {
+	progress := !base.Quiet && base.OutputFormat != "json"
+	started := time.Now()
+
+	results := make([]output.PlanCaseResult, len(plan.Cases))
+	if plan.Parallel {
+		if progress {
+			fmt.Fprintf(os.Stderr, "Running %d cases in parallel...\n", len(plan.Cases))
+		}
+		var wg sync.WaitGroup
+		for i := range plan.Cases {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = plan.runCase(plan.Cases[i], base)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i, c := range plan.Cases {
+			if progress {
+				fmt.Fprintf(os.Stderr, "Running %s...\n", c.Name)
+			}
+			results[i] = plan.runCase(c, base)
+		}
+	}
+
+	report := output.PlanReport{
+		Name:     plan.Name,
+		Parallel: plan.Parallel,
+		Duration: metrics.Duration(time.Since(started)),
+		Cases:    results,
+		Passed:   true,
+	}
+	failed := 0
+	for _, r := range results {
+		if !r.Passed {
+			report.Passed = false
+			failed++
+		}
+	}
+
+	if base.OutputFormat == "json" {
+		if err := output.WritePlanJSON(os.Stdout, report); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	} else {
+		// Per-case details go through the regular load-test formatter
+		if base.Verbose || base.OutputFormat == "graph" {
+			formatter, _ := output.GetFormatter(base.OutputFormat, base.Verbose)
+			for _, r := range results {
+				if r.Stats == nil {
+					continue
+				}
+				fmt.Fprintf(os.Stdout, "\n--- %s ---\n", r.Name)
+				if err := formatter.WriteMultiple(os.Stdout, r.Stats); err != nil {
+					return fmt.Errorf("failed to format output: %w", err)
+				}
+			}
+			fmt.Fprintln(os.Stdout)
+		}
+		output.WritePlanTable(os.Stdout, report)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cases failed", failed, len(results))
+	}
+	return nil
+}
+
+// runCase measures one case with its own App and checks its thresholds
+func (p *Plan) runCase(c PlanCase, base *Config) output.PlanCaseResult {
+	c = c.withDefaults(p.Defaults)
+	config := p.caseConfig(c, base)
+	result := output.PlanCaseResult{Name: c.Name, Method: config.Method, URL: config.URLs[0]}
+
+	thresholds, err := c.Thresholds.parse()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	a, err := New(config)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer a.client.Close()
+
+	a.executeLoad(a.client, a.collector)
+	result.Stats = a.collector.Calculate()
+	result.Violations = thresholds.check(result.Stats)
+	result.Passed = len(result.Violations) == 0
+
+	// Name the cause when failed requests sank the case
+	if first, ok := a.failures.errors[result.URL]; ok && !result.Passed {
+		result.Violations = append(result.Violations, "first error: "+first)
+	}
+	return result
+}
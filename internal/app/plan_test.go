@@ -0,0 +1,158 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erfi/gocurl/internal/metrics"
+)
+
+func TestLoadPlanAppliesDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.yaml")
+	writeConfig(t, path, `
+name: nightly
+base_url: https://api.example.com
+defaults:
+  requests: 20
+  headers: ["X-Run: nightly", "Accept: */*"]
+  thresholds:
+    p95: 200ms
+    max_error_rate: 1%
+cases:
+  - url: /health
+  - name: search
+    url: /search
+    method: post
+    body: '{"q":"x"}'
+    headers: ["Accept: application/json"]
+    duration: 10s
+    thresholds:
+      p95: 500ms
+`)
+
+	plan, err := LoadPlan(path)
+	if err != nil {
+		t.Fatalf("LoadPlan failed: %v", err)
+	}
+	if plan.Cases[0].Name != "/health" {
+		t.Errorf("Expected an unnamed case to be named after its URL, got %q", plan.Cases[0].Name)
+	}
+
+	c := plan.Cases[1].withDefaults(plan.Defaults)
+	if c.Requests != 20 || c.Duration != "10s" {
+		t.Errorf("Unexpected requests/duration %d/%s", c.Requests, c.Duration)
+	}
+	if c.Thresholds.P95 != "500ms" || c.Thresholds.MaxErrorRate != "1%" {
+		t.Errorf("Unexpected thresholds %+v", c.Thresholds)
+	}
+
+	base := &Config{Method: "GET", Requests: 1, Concurrency: 4, Timeout: "30s", Headers: []string{"X-Run: cli", "Authorization: Bearer t"}}
+	config := plan.caseConfig(c, base)
+	if config.URLs[0] != "https://api.example.com/search" || config.Method != "POST" || config.Data != `{"q":"x"}` {
+		t.Errorf("Unexpected request %s %v %q", config.Method, config.URLs, config.Data)
+	}
+	if config.Concurrency != 4 || config.Requests != 20 {
+		t.Errorf("Expected flag values for unset fields, got concurrency %d requests %d", config.Concurrency, config.Requests)
+	}
+	expectedHeaders := []string{"Authorization: Bearer t", "X-Run: nightly", "Accept: application/json"}
+	if !reflect.DeepEqual(config.Headers, expectedHeaders) {
+		t.Errorf("Expected headers %q, got %q", expectedHeaders, config.Headers)
+	}
+	if base.Method != "GET" || base.Requests != 1 {
+		t.Error("caseConfig must not modify the base config")
+	}
+}
+
+func TestLoadPlanErrors(t *testing.T) {
+	tests := map[string]string{
+		"no cases":       "name: empty\n",
+		"missing url":    "cases:\n  - name: a\n",
+		"duplicate name": "cases:\n  - {name: a, url: http://x}\n  - {name: a, url: http://y}\n",
+		"bad duration":   "cases:\n  - {url: http://x, duration: soon}\n",
+		"bad threshold":  "cases:\n  - url: http://x\n    thresholds: {p99: fast}\n",
+		"bad error rate": "cases:\n  - url: http://x\n    thresholds: {max_error_rate: 150%}\n",
+		"unknown field":  "cases:\n  - {url: http://x, concurency: 2}\n",
+	}
+	dir := t.TempDir()
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "plan.yaml")
+			writeConfig(t, path, contents)
+			if _, err := LoadPlan(path); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestThresholdCheck(t *testing.T) {
+	stats := &metrics.Stats{
+		TotalRequests:      100,
+		SuccessfulRequests: 98,
+		FailedRequests:     2,
+		ErrorRate:          0.02,
+		RequestsPerSecond:  50,
+		P50:                metrics.Duration(10 * time.Millisecond),
+		P95:                metrics.Duration(120 * time.Millisecond),
+		MaxLatency:         metrics.Duration(300 * time.Millisecond),
+		StatusCodes:        map[int]int{200: 90, 503: 8},
+	}
+
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		expected   []string
+	}{
+		{"errors fail by default", Thresholds{}, []string{"error rate 2.00% > 0.00% (2 failed)"}},
+		{"within limits", Thresholds{P50: "20ms", P95: "150ms", Max: "1s", MaxErrorRate: "5%", MinRPS: 10}, nil},
+		{"fraction error rate", Thresholds{MaxErrorRate: "0.01"}, []string{"error rate 2.00% > 1.00% (2 failed)"}},
+		{"latency and throughput", Thresholds{P95: "100ms", MaxErrorRate: "5%", MinRPS: 100}, []string{"p95 120ms > 100ms", "50.00 req/s < 100.00 req/s"}},
+		{"status codes", Thresholds{MaxErrorRate: "5%", Status: []int{200}}, []string{"unexpected status 503 (8 responses)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := tt.thresholds.parse()
+			if err != nil {
+				t.Fatalf("parse failed: %v", err)
+			}
+			if got := set.check(stats); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunCase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Case") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	plan := &Plan{
+		BaseURL:  server.URL,
+		Defaults: PlanCase{Headers: []string{"X-Case: plan"}, Thresholds: Thresholds{Status: []int{200}}},
+	}
+	base := &Config{Method: "GET", Requests: 1, Concurrency: 1, Timeout: "5s", OutputFormat: "table"}
+
+	result := plan.runCase(PlanCase{Name: "count", URL: "/a", Requests: 5, Concurrency: 2}, base)
+	if !result.Passed || result.Stats.TotalRequests != 5 {
+		t.Errorf("Expected 5 passing requests, got %+v", result)
+	}
+
+	result = plan.runCase(PlanCase{Name: "timed", URL: "/b", Duration: "100ms", Concurrency: 2}, base)
+	if !result.Passed || result.Stats.TotalRequests < 2 {
+		t.Errorf("Expected a passing timed run, got %+v", result)
+	}
+
+	result = plan.runCase(PlanCase{Name: "no header", URL: "/c", Headers: []string{"X-Case:"}}, base)
+	if result.Passed || len(result.Violations) != 1 || !strings.Contains(result.Violations[0], "unexpected status 400") {
+		t.Errorf("Expected a status violation, got %+v", result)
+	}
+}
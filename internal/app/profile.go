@@ -0,0 +1,276 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/erfi/gocurl/internal/client"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigFile is the per-project configuration file, looked up in the
+// working directory and its parents
+const ProjectConfigFile = ".gocurl.yaml"
+
+// EnvPrefix is the prefix of environment variables that set flag values
+const EnvPrefix = "GOCURL_"
+
+// BaseURLSetting is the profile key that relative URL arguments are resolved against
+const BaseURLSetting = "base_url"
+
+// Profile is a named set of flag values. Keys are long flag names (plus
+// base_url); scalars and lists are both kept as string lists.
+type Profile map[string][]string
+
+// ConfigFile holds the profiles of the user and project configuration files
+type ConfigFile struct {
+	DefaultProfile string             `yaml:"default_profile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+	Paths          []string           `yaml:"-"` // Files that were loaded, user file first
+}
+
+// UnmarshalYAML accepts scalar and list values, e.g. "timeout: 5s" and
+// "header: [...]"
+func (p *Profile) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: a profile must be a mapping of flag names to values", node.Line)
+	}
+
+	profile := make(Profile)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch value.Kind {
+		case yaml.ScalarNode:
+			if value.Tag == "!!null" {
+				continue
+			}
+			profile[key] = []string{value.Value}
+		case yaml.SequenceNode:
+			values := make([]string, 0, len(value.Content))
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode {
+					return fmt.Errorf("line %d: %s: list items must be plain values", item.Line, key)
+				}
+				values = append(values, item.Value)
+			}
+			profile[key] = values
+		default:
+			return fmt.Errorf("line %d: %s: expected a value or a list", value.Line, key)
+		}
+	}
+	*p = profile
+	return nil
+}
+
+// UserConfigPath returns ~/.config/gocurl/config.yaml, honouring XDG_CONFIG_HOME
+func UserConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "gocurl", "config.yaml")
+}
+
+// findProjectConfig looks for .gocurl.yaml in dir and its parents
+func findProjectConfig(dir string) string {
+	for {
+		path := filepath.Join(dir, ProjectConfigFile)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadConfigFiles reads the user config file and the project file found from
+// dir. Project profiles override user profiles setting by setting. Missing
+// files are not an error.
+func LoadConfigFiles(dir string) (*ConfigFile, error) {
+	merged := &ConfigFile{Profiles: make(map[string]Profile)}
+
+	paths := []string{UserConfigPath()}
+	if dir != "" {
+		if project := findProjectConfig(dir); project != "" && project != paths[0] {
+			paths = append(paths, project)
+		}
+	}
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		file, err := readConfigFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		merged.Paths = append(merged.Paths, path)
+		if file.DefaultProfile != "" {
+			merged.DefaultProfile = file.DefaultProfile
+		}
+		for name, profile := range file.Profiles {
+			base := merged.Profiles[name]
+			if base == nil {
+				base = make(Profile)
+				merged.Profiles[name] = base
+			}
+			for key, values := range profile {
+				base[key] = values
+			}
+		}
+	}
+	return merged, nil
+}
+
+// readConfigFile parses a single configuration file
+func readConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file ConfigFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	// An empty file is an empty configuration
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// Profile returns the named profile. An empty name selects the default
+// profile, if the files define one; no profile at all is not an error.
+func (c *ConfigFile) Profile(name string) (string, Profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return "", nil, nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Paths) == 0 {
+			return "", nil, fmt.Errorf("profile '%s' not found: no config file at %s or %s", name, UserConfigPath(), ProjectConfigFile)
+		}
+		return "", nil, fmt.Errorf("profile '%s' not found (available: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	return name, profile, nil
+}
+
+// ProfileNames returns the defined profile names in order
+func (c *ConfigFile) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EnvName returns the environment variable for a flag, e.g. GOCURL_CONNECT_TO
+// for --connect-to and GOCURL_HTTP1_1 for --http1.1
+func EnvName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(flag))
+}
+
+// secretFlags hold credentials that "gocurl config show" must not print
+var secretFlags = map[string]bool{
+	"user":                 true,
+	"proxy-user":           true,
+	"oauth2-bearer":        true,
+	"oauth2-client-secret": true,
+	"cookie":               true,
+}
+
+// urlSettings take URLs that may carry a password in their userinfo
+var urlSettings = map[string]bool{
+	"proxy":        true,
+	"doh-url":      true,
+	BaseURLSetting: true,
+}
+
+// secretHeaders carry credentials in -H values
+var secretHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+}
+
+// MaskSecret hides the credential in a flag value for display. user:password
+// values keep the user name, -H values of credential headers keep the header
+// name, and URLs keep everything but the password.
+func MaskSecret(flag, value string) string {
+	const mask = "****"
+	switch {
+	case value == "":
+		return value
+	case urlSettings[flag]:
+		if u, err := url.Parse(value); err == nil {
+			return u.Redacted()
+		}
+		return value
+	case flag == "header":
+		if name, _, ok := strings.Cut(value, ":"); ok && secretHeaders[strings.ToLower(strings.TrimSpace(name))] {
+			return name + ": " + mask
+		}
+		return value
+	case !secretFlags[flag]:
+		return value
+	case flag == "user" || flag == "proxy-user":
+		if name, _, ok := strings.Cut(value, ":"); ok {
+			return name + ":" + mask
+		}
+	}
+	return mask
+}
+
+// MergeHeaders layers higher-precedence -H values over lower ones: a header
+// named (or removed) at the higher level replaces that header from below
+func MergeHeaders(lower, higher []string) []string {
+	overridden := make(map[string]bool)
+	for _, h := range client.ParseHeaders(higher) {
+		overridden[strings.ToLower(h.Name)] = true
+	}
+
+	merged := make([]string, 0, len(lower)+len(higher))
+	for _, raw := range lower {
+		// ParseHeaders skips malformed values, so match each one on its own
+		if h := client.ParseHeaders([]string{raw}); len(h) == 0 || !overridden[strings.ToLower(h[0].Name)] {
+			merged = append(merged, raw)
+		}
+	}
+	return append(merged, higher...)
+}
+
+// ResolveURL joins a relative URL argument onto a profile's base URL;
+// absolute URLs are returned unchanged
+func ResolveURL(base, ref string) string {
+	if base == "" || strings.Contains(ref, "://") {
+		return ref
+	}
+	if ref == "" {
+		return base
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(ref, "/")
+}
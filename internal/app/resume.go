@@ -0,0 +1,45 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/output"
+)
+
+// runResumption measures a full TLS handshake followed by resumed handshakes
+func (a *App) runResumption() error {
+	if len(a.config.URLs) == 0 {
+		return fmt.Errorf("no URLs provided")
+	}
+
+	clientConfig, err := buildClientConfig(a.config)
+	if err != nil {
+		return err
+	}
+	clientConfig.CookieJar = a.cookies
+	clientConfig.Auth = a.auth
+
+	result, err := client.MeasureResumption(
+		clientConfig,
+		a.config.URLs[0],
+		a.config.Method,
+		a.requestHeaders(),
+		a.config.Data,
+		a.config.TLSResume,
+	)
+	if err != nil {
+		return fmt.Errorf("resumption test failed: %w", err)
+	}
+
+	if a.config.OutputFormat == "json" {
+		if err := output.WriteResumptionJSON(os.Stdout, result); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	} else {
+		output.WriteResumptionTable(os.Stdout, result, a.config.Verbose)
+	}
+
+	return nil
+}
@@ -0,0 +1,463 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/metrics"
+	"github.com/erfi/gocurl/internal/output"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a multi-step user flow run by "gocurl scenario". Every flow
+// runs the steps in order, carrying variables extracted from one response
+// into the requests that follow.
+type Scenario struct {
+	Name      string            `yaml:"name"`
+	BaseURL   string            `yaml:"base_url"`
+	Variables map[string]string `yaml:"variables"` // Initial variables of every flow
+	Steps     []ScenarioStep    `yaml:"steps"`
+
+	steps []*scenarioStep
+}
+
+// ScenarioStep is one request of a flow. URL, headers and body may reference
+// variables as {{name}}.
+type ScenarioStep struct {
+	Name         string               `yaml:"name"`
+	Method       string               `yaml:"method"`
+	URL          string               `yaml:"url"`
+	Headers      []string             `yaml:"headers"`
+	Body         string               `yaml:"body"`
+	ExpectStatus []int                `yaml:"expect_status"` // Default: any status below 400
+	Extract      map[string]Extractor `yaml:"extract"`
+	Think        string               `yaml:"think"` // Pause after the step: "500ms", or "1s-3s" for a random pause
+}
+
+// Extractor captures a variable from a response: a JSONPath into the body, a
+// regex over the body, a header value, or a regex over a header value. A
+// regex yields its first capture group, or the whole match without one.
+type Extractor struct {
+	JSONPath string `yaml:"jsonpath"`
+	Regex    string `yaml:"regex"`
+	Header   string `yaml:"header"`
+}
+
+// scenarioBuiltins are variables set for every flow: the virtual user and
+// the flow's iteration number, both starting at 1
+var scenarioBuiltins = []string{"vu", "iteration"}
+
+// templateVariable matches {{name}} references
+var templateVariable = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// scenarioStep is a step with its think time, extractors and expected
+// statuses parsed
+type scenarioStep struct {
+	ScenarioStep
+	thinkMin   time.Duration
+	thinkMax   time.Duration
+	extractors []extractor
+	expect     map[int]bool
+}
+
+// extractor is the parsed form of an Extractor
+type extractor struct {
+	variable string
+	path     jsonPath
+	regex    *regexp.Regexp
+	header   string
+}
+
+// LoadScenario reads and validates a scenario file ('-' for stdin)
+func LoadScenario(path string) (*Scenario, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	var scenario Scenario
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&scenario); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := scenario.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &scenario, nil
+}
+
+// compile validates the steps and checks that every variable is defined
+// before the step that uses it
+func (s *Scenario) compile() error {
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("scenario has no steps")
+	}
+
+	defined := make(map[string]bool)
+	for _, name := range scenarioBuiltins {
+		defined[name] = true
+	}
+	for name := range s.Variables {
+		defined[name] = true
+	}
+
+	seen := make(map[string]bool)
+	s.steps = make([]*scenarioStep, 0, len(s.Steps))
+	for i, raw := range s.Steps {
+		if raw.Name == "" {
+			raw.Name = fmt.Sprintf("step %d", i+1)
+		}
+		if seen[raw.Name] {
+			return fmt.Errorf("step '%s' is defined twice", raw.Name)
+		}
+		seen[raw.Name] = true
+		if raw.URL == "" && s.BaseURL == "" {
+			return fmt.Errorf("step '%s': url is required", raw.Name)
+		}
+
+		for _, text := range append([]string{raw.URL, raw.Body}, raw.Headers...) {
+			for _, match := range templateVariable.FindAllStringSubmatch(text, -1) {
+				if !defined[match[1]] {
+					return fmt.Errorf("step '%s': variable '%s' is not set by an earlier step or the variables block", raw.Name, match[1])
+				}
+			}
+		}
+
+		step, err := compileStep(raw)
+		if err != nil {
+			return fmt.Errorf("step '%s': %w", raw.Name, err)
+		}
+		for _, e := range step.extractors {
+			defined[e.variable] = true
+		}
+		s.Steps[i].Name = raw.Name
+		s.steps = append(s.steps, step)
+	}
+	return nil
+}
+
+// compileStep parses a step's think time, extractors and expected statuses
+func compileStep(raw ScenarioStep) (*scenarioStep, error) {
+	step := &scenarioStep{ScenarioStep: raw}
+
+	if raw.Think != "" {
+		low, high, isRange := strings.Cut(raw.Think, "-")
+		shortest, err := time.ParseDuration(strings.TrimSpace(low))
+		longest := shortest
+		if err == nil && isRange {
+			longest, err = time.ParseDuration(strings.TrimSpace(high))
+		}
+		if err != nil || shortest < 0 || longest < shortest {
+			return nil, fmt.Errorf("invalid think time '%s': use a duration (500ms) or a range (1s-3s)", raw.Think)
+		}
+		step.thinkMin, step.thinkMax = shortest, longest
+	}
+
+	if len(raw.ExpectStatus) > 0 {
+		step.expect = make(map[int]bool, len(raw.ExpectStatus))
+		for _, code := range raw.ExpectStatus {
+			step.expect[code] = true
+		}
+	}
+
+	// Extract in a fixed order so that failures are reported consistently
+	names := make([]string, 0, len(raw.Extract))
+	for name := range raw.Extract {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		spec := raw.Extract[name]
+		e := extractor{variable: name, header: spec.Header}
+		if spec.JSONPath != "" {
+			if spec.Regex != "" || spec.Header != "" {
+				return nil, fmt.Errorf("extract %s: jsonpath cannot be combined with regex or header", name)
+			}
+			path, err := parseJSONPath(spec.JSONPath)
+			if err != nil {
+				return nil, fmt.Errorf("extract %s: %w", name, err)
+			}
+			e.path = path
+		} else if spec.Regex == "" && spec.Header == "" {
+			return nil, fmt.Errorf("extract %s: set jsonpath, regex or header", name)
+		}
+		if spec.Regex != "" {
+			re, err := regexp.Compile(spec.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("extract %s: %w", name, err)
+			}
+			e.regex = re
+		}
+		step.extractors = append(step.extractors, e)
+	}
+	return step, nil
+}
+
+// method returns the step's method; a body without one is a POST
+func (s *scenarioStep) method() string {
+	if s.Method != "" {
+		return strings.ToUpper(s.Method)
+	}
+	if s.Body != "" {
+		return "POST"
+	}
+	return "GET"
+}
+
+// statusOK checks the response status against expect_status, or below 400
+func (s *scenarioStep) statusOK(code int) bool {
+	if s.expect != nil {
+		return s.expect[code]
+	}
+	return code < 400
+}
+
+// thinkTime returns the pause after the step
+func (s *scenarioStep) thinkTime() time.Duration {
+	if s.thinkMax > s.thinkMin {
+		return s.thinkMin + time.Duration(rand.Int63n(int64(s.thinkMax-s.thinkMin)))
+	}
+	return s.thinkMin
+}
+
+// extract captures the extractor's value from a response
+func (e extractor) extract(timing *client.TimingBreakdown) (string, error) {
+	source := timing.ResponseBody
+	if e.header != "" {
+		values := timing.ResponseHeaders.Values(e.header)
+		if len(values) == 0 {
+			return "", fmt.Errorf("response has no %s header", e.header)
+		}
+		// Repeated headers such as Set-Cookie are searched one by one
+		if e.regex != nil {
+			for _, value := range values {
+				if captured, ok := e.match(value); ok {
+					return captured, nil
+				}
+			}
+			return "", fmt.Errorf("/%s/ does not match the %s header", e.regex, e.header)
+		}
+		return values[0], nil
+	}
+
+	if e.path != nil {
+		decoder := json.NewDecoder(strings.NewReader(source))
+		decoder.UseNumber()
+		var doc interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			return "", fmt.Errorf("response is not JSON: %w", err)
+		}
+		value, ok := e.path.lookup(doc)
+		if !ok {
+			return "", fmt.Errorf("JSONPath not found in the response")
+		}
+		return jsonValueString(value), nil
+	}
+
+	if captured, ok := e.match(source); ok {
+		return captured, nil
+	}
+	return "", fmt.Errorf("/%s/ does not match the response body", e.regex)
+}
+
+// match applies the regex, returning the first capture group if it has one
+func (e extractor) match(text string) (string, bool) {
+	match := e.regex.FindStringSubmatch(text)
+	if match == nil {
+		return "", false
+	}
+	if len(match) > 1 {
+		return match[1], true
+	}
+	return match[0], true
+}
+
+// expand substitutes {{name}} references
+func expand(text string, vars map[string]string) string {
+	return templateVariable.ReplaceAllStringFunc(text, func(ref string) string {
+		return vars[templateVariable.FindStringSubmatch(ref)[1]]
+	})
+}
+
+// RunScenario runs flows of the scenario with -c virtual users, either -n
+// flows in total or as many as fit in --duration, and reports per-step and
+// end-to-end statistics. It fails if any flow failed.
+func RunScenario(scenario *Scenario, config *Config) error {
+	a, err := newScenarioApp(config)
+	if err != nil {
+		return err
+	}
+	defer a.client.Close()
+
+	if !config.Quiet && config.OutputFormat != "json" {
+		runs := fmt.Sprintf("%d flows", config.Requests)
+		if config.Duration != "" {
+			runs = "flows for " + config.Duration
+		}
+		fmt.Fprintf(os.Stderr, "Running scenario: %d steps, %s with %d virtual user(s)\n",
+			len(scenario.steps), runs, config.Concurrency)
+	}
+
+	names := make([]string, len(scenario.steps))
+	for i, step := range scenario.steps {
+		names[i] = step.Name
+	}
+	collector := metrics.NewFlowCollector(names)
+	a.runFlows(scenario, collector)
+	stats := collector.Calculate()
+
+	if config.OutputFormat == "json" {
+		if err := output.WriteFlowJSON(os.Stdout, scenario.Name, stats); err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+	} else {
+		// Per-step details go through the regular load-test formatter
+		if config.Verbose || config.OutputFormat == "graph" {
+			for _, step := range stats.Steps {
+				fmt.Fprintf(os.Stdout, "\n--- %s ---\n", step.Name)
+				if err := a.formatter.WriteMultiple(os.Stdout, step.Stats); err != nil {
+					return fmt.Errorf("failed to format output: %w", err)
+				}
+			}
+			fmt.Fprintln(os.Stdout)
+		}
+		output.WriteFlowTable(os.Stdout, scenario.Name, stats)
+	}
+
+	if stats.FailedFlows > 0 {
+		return fmt.Errorf("%d of %d flows failed", stats.FailedFlows, stats.TotalFlows)
+	}
+	return nil
+}
+
+// newScenarioApp creates an App whose client captures response bodies and
+// headers so that steps can extract from them
+func newScenarioApp(config *Config) (*App, error) {
+	runConfig := *config
+	runConfig.ShowBody = true
+	runConfig.IncludeHeaders = true
+	runConfig.Emit = ""
+	return New(&runConfig)
+}
+
+// runFlows runs flows on one goroutine per virtual user until -n flows have
+// started or --duration has passed
+func (a *App) runFlows(scenario *Scenario, collector *metrics.FlowCollector) {
+	duration, _ := a.config.loadDuration()
+	deadline := time.Now().Add(duration)
+	var started int64
+
+	var wg sync.WaitGroup
+	for vu := 1; vu <= a.config.Concurrency; vu++ {
+		wg.Add(1)
+		go func(vu int) {
+			defer wg.Done()
+			for {
+				iteration := atomic.AddInt64(&started, 1)
+				if duration > 0 && !time.Now().Before(deadline) {
+					return
+				}
+				if duration == 0 && iteration > int64(a.config.Requests) {
+					return
+				}
+
+				vars := make(map[string]string, len(scenario.Variables)+len(scenarioBuiltins))
+				for name, value := range scenario.Variables {
+					vars[name] = value
+				}
+				vars["vu"] = strconv.Itoa(vu)
+				vars["iteration"] = strconv.FormatInt(iteration, 10)
+
+				// Every flow is a new session that starts from the -b cookies
+				a.runFlow(a.client.WithCookieJar(a.cookies.Clone()), scenario, vars, collector)
+			}
+		}(vu)
+	}
+	wg.Wait()
+	collector.Finalize()
+}
+
+// runFlow runs the steps of one flow, stopping at the first failing step.
+// The flow duration includes think time between steps.
+func (a *App) runFlow(httpClient *client.Client, scenario *Scenario, vars map[string]string, collector *metrics.FlowCollector) {
+	start := time.Now()
+	for i, step := range scenario.steps {
+		timing, err := a.runStep(httpClient, scenario, step, vars)
+		if timing != nil {
+			collector.RecordStep(step.Name, timing)
+		}
+		if err != nil {
+			collector.RecordFlow(time.Since(start), step.Name, err.Error())
+			return
+		}
+		if think := step.thinkTime(); think > 0 && i < len(scenario.steps)-1 {
+			time.Sleep(think)
+		}
+	}
+	collector.RecordFlow(time.Since(start), "", "")
+}
+
+// runStep sends one step's request and extracts its variables into vars.
+// Unexpected statuses and failed extractions are recorded as step errors.
+func (a *App) runStep(httpClient *client.Client, scenario *Scenario, step *scenarioStep, vars map[string]string) (*client.TimingBreakdown, error) {
+	url := ResolveURL(scenario.BaseURL, expand(step.URL, vars))
+
+	stepHeaders := make([]string, len(step.Headers))
+	for i, h := range step.Headers {
+		stepHeaders[i] = expand(h, vars)
+	}
+	headers := a.withCookie(client.ParseHeaders(MergeHeaders(a.config.Headers, stepHeaders)))
+
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(expand(step.Body, vars))
+	}
+
+	timing, err := httpClient.MeasureRequest(url, step.method(), headers, body)
+	if timing == nil {
+		return nil, err
+	}
+	// Responses are only kept long enough to extract from
+	defer func() {
+		timing.ResponseBody = ""
+		timing.ResponseHeaders = nil
+	}()
+
+	if timing.Error != "" {
+		return timing, errors.New(timing.Error)
+	}
+	if !step.statusOK(timing.StatusCode) {
+		timing.Error = fmt.Sprintf("unexpected status %d", timing.StatusCode)
+		return timing, errors.New(timing.Error)
+	}
+
+	for _, e := range step.extractors {
+		value, err := e.extract(timing)
+		if err != nil {
+			timing.Error = fmt.Sprintf("extract %s: %v", e.variable, err)
+			return timing, errors.New(timing.Error)
+		}
+		vars[e.variable] = value
+	}
+	return timing, nil
+}
@@ -0,0 +1,158 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/metrics"
+)
+
+func TestLoadScenarioErrors(t *testing.T) {
+	tests := map[string]string{
+		"no steps":          "name: empty\n",
+		"undefined var":     "steps:\n  - {url: 'http://x/{{token}}'}\n",
+		"var used too soon": "steps:\n  - {url: 'http://x/{{id}}'}\n  - url: http://x\n    extract: {id: {jsonpath: $.id}}\n",
+		"bad jsonpath":      "steps:\n  - url: http://x\n    extract: {id: {jsonpath: id}}\n",
+		"bad regex":         "steps:\n  - url: http://x\n    extract: {id: {regex: '('}}\n",
+		"empty extractor":   "steps:\n  - url: http://x\n    extract: {id: {}}\n",
+		"jsonpath + header": "steps:\n  - url: http://x\n    extract: {id: {jsonpath: $.id, header: X-Id}}\n",
+		"bad think time":    "steps:\n  - {url: http://x, think: 3s-1s}\n",
+		"duplicate step":    "steps:\n  - {name: a, url: http://x}\n  - {name: a, url: http://y}\n",
+	}
+	dir := t.TempDir()
+	for name, contents := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "flow.yaml")
+			writeConfig(t, path, contents)
+			if _, err := LoadScenario(path); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestExtractor(t *testing.T) {
+	timing := &client.TimingBreakdown{
+		ResponseBody: `{"token":"abc","next":"/page/2"}`,
+		ResponseHeaders: client.Headers{
+			{Name: "Set-Cookie", Value: "theme=dark"},
+			{Name: "Set-Cookie", Value: "sid=s42; Path=/"},
+			{Name: "X-Request-Id", Value: "req-1"},
+		},
+	}
+
+	step, err := compileStep(ScenarioStep{Extract: map[string]Extractor{
+		"token":   {JSONPath: "$.token"},
+		"page":    {Regex: `/page/(\d+)`},
+		"path":    {Regex: `/page/\d+`},
+		"sid":     {Header: "set-cookie", Regex: `sid=([^;]+)`},
+		"request": {Header: "X-Request-Id"},
+	}})
+	if err != nil {
+		t.Fatalf("compileStep failed: %v", err)
+	}
+
+	expected := map[string]string{"token": "abc", "page": "2", "path": "/page/2", "sid": "s42", "request": "req-1"}
+	for _, e := range step.extractors {
+		got, err := e.extract(timing)
+		if err != nil {
+			t.Errorf("%s: %v", e.variable, err)
+		} else if got != expected[e.variable] {
+			t.Errorf("%s: expected %q, got %q", e.variable, expected[e.variable], got)
+		}
+	}
+
+	step, _ = compileStep(ScenarioStep{Extract: map[string]Extractor{
+		"missing":  {JSONPath: "$.missing"},
+		"nomatch":  {Regex: `order-(\d+)`},
+		"noheader": {Header: "Location"},
+	}})
+	for _, e := range step.extractors {
+		if _, err := e.extract(timing); err == nil {
+			t.Errorf("%s: expected an error", e.variable)
+		}
+	}
+}
+
+func TestThinkTime(t *testing.T) {
+	step, err := compileStep(ScenarioStep{Think: "10ms-20ms"})
+	if err != nil {
+		t.Fatalf("compileStep failed: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		if d := step.thinkTime(); d < 10*time.Millisecond || d > 20*time.Millisecond {
+			t.Fatalf("Think time %v outside 10ms-20ms", d)
+		}
+	}
+
+	step, _ = compileStep(ScenarioStep{Think: "250ms"})
+	if d := step.thinkTime(); d != 250*time.Millisecond {
+		t.Errorf("Expected a fixed 250ms, got %v", d)
+	}
+}
+
+func TestRunFlows(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s1"})
+			fmt.Fprintf(w, `{"token":"t-%s"}`, r.URL.Query().Get("vu"))
+		case "/orders":
+			cookie, err := r.Cookie("sid")
+			if err != nil || cookie.Value != "s1" || !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer t-") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	scenario := &Scenario{
+		BaseURL: server.URL,
+		Steps: []ScenarioStep{
+			{Name: "login", URL: "/login?vu={{vu}}", Extract: map[string]Extractor{"token": {JSONPath: "$.token"}}},
+			{Name: "order", URL: "/orders", Method: "post", Headers: []string{"Authorization: Bearer {{token}}"}, ExpectStatus: []int{201}},
+		},
+	}
+	if err := scenario.compile(); err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	a, err := newScenarioApp(&Config{Method: "GET", Requests: 6, Concurrency: 3, Timeout: "5s", OutputFormat: "table"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.client.Close()
+
+	collector := metrics.NewFlowCollector([]string{"login", "order"})
+	a.runFlows(scenario, collector)
+	stats := collector.Calculate()
+
+	if stats.TotalFlows != 6 || stats.CompletedFlows != 6 {
+		t.Fatalf("Expected 6 completed flows, got %d/%d (%+v)", stats.CompletedFlows, stats.TotalFlows, stats.Failures)
+	}
+	if order := stats.Steps[1].Stats; order.TotalRequests != 6 || order.StatusCodes[201] != 6 {
+		t.Errorf("Unexpected order step stats: %+v", order)
+	}
+
+	// A step that misses its expected status stops the flow
+	scenario.Steps[1].ExpectStatus = []int{200}
+	if err := scenario.compile(); err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	collector = metrics.NewFlowCollector([]string{"login", "order"})
+	a.runFlows(scenario, collector)
+	stats = collector.Calculate()
+	if stats.FailedFlows != 6 || len(stats.Failures) != 1 || stats.Failures[0].FirstError != "unexpected status 201" {
+		t.Errorf("Expected every flow to stop at order, got %+v", stats.Failures)
+	}
+}
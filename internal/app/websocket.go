@@ -0,0 +1,180 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/output"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultWSMessages is the number of echo round trips when neither a script
+// nor a listen window is given
+const defaultWSMessages = 5
+
+// defaultWSPayload is sent in echo mode without --data
+const defaultWSPayload = "gocurl"
+
+// wsScriptStep is one entry of a --ws-script file
+type wsScriptStep struct {
+	Send    *string `yaml:"send"`
+	Expect  *string `yaml:"expect"`
+	Receive int     `yaml:"receive"`
+	Sleep   string  `yaml:"sleep"`
+}
+
+// LoadWebSocketScript reads a --ws-script file ('-' for stdin): a YAML list of
+// send, expect, receive and sleep steps
+func LoadWebSocketScript(path string) ([]client.WebSocketStep, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WebSocket script: %w", err)
+	}
+
+	var raw []wsScriptStep
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%s: script has no steps", path)
+	}
+
+	steps := make([]client.WebSocketStep, len(raw))
+	for i, r := range raw {
+		set := 0
+		if r.Send != nil {
+			if *r.Send == "" {
+				return nil, fmt.Errorf("%s: step %d: send needs a message", path, i+1)
+			}
+			steps[i].Send = *r.Send
+			set++
+		}
+		if r.Expect != nil {
+			if *r.Expect == "" {
+				return nil, fmt.Errorf("%s: step %d: expect needs text to match", path, i+1)
+			}
+			steps[i].Expect = *r.Expect
+			set++
+		}
+		if r.Receive != 0 {
+			if r.Receive < 0 {
+				return nil, fmt.Errorf("%s: step %d: receive must be positive", path, i+1)
+			}
+			steps[i].Receive = r.Receive
+			set++
+		}
+		if r.Sleep != "" {
+			d, err := time.ParseDuration(r.Sleep)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("%s: step %d: invalid sleep '%s'", path, i+1, r.Sleep)
+			}
+			steps[i].Sleep = d
+			set++
+		}
+		if set != 1 {
+			return nil, fmt.Errorf("%s: step %d: expected exactly one of send, expect, receive or sleep", path, i+1)
+		}
+	}
+	return steps, nil
+}
+
+// validateWebSocket rejects options that cannot apply to ws:// and wss:// URLs
+func validateWebSocket(config *Config) error {
+	if len(config.URLs) == 0 || !client.IsWebSocketURL(config.URLs[0]) {
+		if config.WSScript != "" || config.WSListen != "" || config.WSMessages != 0 {
+			return fmt.Errorf("--ws-messages, --ws-listen and --ws-script need a ws:// or wss:// URL")
+		}
+		return nil
+	}
+	switch {
+	case config.isLoadTest():
+		return fmt.Errorf("WebSocket URLs measure a single session; load testing is not supported")
+	case config.Protocol == client.ProtocolHTTP2 || config.Protocol == client.ProtocolHTTP3:
+		return fmt.Errorf("WebSockets are upgraded over HTTP/1.1; --http2 and --http3 cannot be used")
+	case config.Emit != "":
+		return fmt.Errorf("--emit does not support WebSocket URLs")
+	case config.WSMessages < 0:
+		return fmt.Errorf("--ws-messages must not be negative")
+	}
+	if config.WSListen != "" {
+		if d, err := time.ParseDuration(config.WSListen); err != nil || d <= 0 {
+			return fmt.Errorf("invalid --ws-listen '%s': expected a positive duration such as 10s", config.WSListen)
+		}
+	}
+	return nil
+}
+
+// webSocketOptions builds the message phase from the flags
+func (a *App) webSocketOptions() (client.WebSocketOptions, error) {
+	var opts client.WebSocketOptions
+	if a.config.WSScript != "" {
+		script, err := LoadWebSocketScript(a.config.WSScript)
+		if err != nil {
+			return opts, err
+		}
+		opts.Script = script
+	}
+	if a.config.WSListen != "" {
+		// Checked by validateWebSocket
+		opts.Listen, _ = time.ParseDuration(a.config.WSListen)
+	}
+
+	opts.Messages = a.config.WSMessages
+	if opts.Messages == 0 && opts.Script == nil && opts.Listen == 0 {
+		opts.Messages = defaultWSMessages
+	}
+	opts.Payload = []byte(a.config.Data)
+	if a.config.Data == "" {
+		opts.Payload = []byte(defaultWSPayload)
+	}
+	return opts, nil
+}
+
+// runWebSocket measures the upgrade and message phase of a single session
+func (a *App) runWebSocket() error {
+	url := a.config.URLs[0]
+	opts, err := a.webSocketOptions()
+	if err != nil {
+		return err
+	}
+
+	timing, err := a.client.MeasureWebSocket(context.Background(), url, a.requestHeaders(), opts)
+	if timing == nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+
+	if err := a.formatter.Write(os.Stdout, timing); err != nil {
+		return fmt.Errorf("failed to format output: %w", err)
+	}
+	if timing.WebSocket != nil && a.config.OutputFormat == "table" {
+		output.WriteWebSocketMetrics(os.Stdout, timing.WebSocket, a.config.Verbose)
+	}
+
+	if timing.Error != "" {
+		return fmt.Errorf("request error: %s", timing.Error)
+	}
+
+	// Pushed messages are judged like the chunks of a stream
+	if a.config.ExpectStreaming {
+		if timing.WebSocket.Pushed == nil {
+			return fmt.Errorf("streaming validation failed: the server pushed no messages")
+		}
+		if err := a.validateStreaming(timing.WebSocket.Pushed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+)
+
+func TestLoadWebSocketScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ws.yaml")
+	writeConfig(t, path, "- send: '{\"op\":\"subscribe\"}'\n- expect: subscribed\n- receive: 10\n- sleep: 250ms\n")
+	steps, err := LoadWebSocketScript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []client.WebSocketStep{
+		{Send: `{"op":"subscribe"}`}, {Expect: "subscribed"}, {Receive: 10}, {Sleep: 250 * time.Millisecond},
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("Expected %d steps, got %+v", len(expected), steps)
+	}
+	for i := range expected {
+		if steps[i] != expected[i] {
+			t.Errorf("Step %d: expected %+v, got %+v", i+1, expected[i], steps[i])
+		}
+	}
+
+	for name, contents := range map[string]string{
+		"empty":         "[]\n",
+		"two actions":   "- {send: a, expect: b}\n",
+		"no action":     "- {}\n",
+		"empty send":    "- send: ''\n",
+		"bad sleep":     "- sleep: soon\n",
+		"unknown field": "- wait: 1s\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			writeConfig(t, path, contents)
+			if _, err := LoadWebSocketScript(path); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestValidateWebSocket(t *testing.T) {
+	valid := []*Config{
+		{URLs: []string{"wss://example.com/feed"}, Requests: 1, WSListen: "5s"},
+		{URLs: []string{"https://example.com"}, Requests: 10},
+	}
+	for _, config := range valid {
+		if err := validateWebSocket(config); err != nil {
+			t.Errorf("%s: unexpected error %v", config.URLs[0], err)
+		}
+	}
+
+	invalid := map[string]*Config{
+		"load test":    {URLs: []string{"ws://example.com"}, Requests: 10},
+		"http2":        {URLs: []string{"wss://example.com"}, Requests: 1, Protocol: client.ProtocolHTTP2},
+		"bad listen":   {URLs: []string{"ws://example.com"}, Requests: 1, WSListen: "forever"},
+		"not a ws url": {URLs: []string{"https://example.com"}, Requests: 1, WSMessages: 3},
+	}
+	for name, config := range invalid {
+		if err := validateWebSocket(config); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
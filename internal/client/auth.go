@@ -0,0 +1,402 @@
+package client
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator adds credentials to outgoing requests. base is the transport
+// underneath authentication, for schemes that need requests of their own
+// (e.g. fetching an OAuth2 token).
+type Authenticator interface {
+	Authorize(req *http.Request, base http.RoundTripper) error
+}
+
+// Challenger is implemented by authenticators that can answer a 401 response.
+// Challenge returns true when the request should be retried with new credentials.
+type Challenger interface {
+	Challenge(resp *http.Response) bool
+}
+
+// AuthOptions selects an authentication scheme. At most one of Digest,
+// Bearer, AWSSigV4 and OAuth2TokenURL may be set; User alone means basic auth.
+type AuthOptions struct {
+	User     string // "user:password" (the access key and secret with AWSSigV4)
+	Digest   bool   // Use digest instead of basic auth for User
+	Bearer   string // Static bearer token
+	AWSSigV4 string // "provider:region:service"
+
+	OAuth2TokenURL     string
+	OAuth2ClientID     string
+	OAuth2ClientSecret string
+	OAuth2Scope        string
+}
+
+// NewAuthenticator builds the authenticator described by opts; nil when no
+// scheme is configured
+func NewAuthenticator(opts AuthOptions) (Authenticator, error) {
+	schemes := 0
+	for _, set := range []bool{opts.Digest, opts.Bearer != "", opts.AWSSigV4 != "", opts.OAuth2TokenURL != ""} {
+		if set {
+			schemes++
+		}
+	}
+	if schemes > 1 {
+		return nil, fmt.Errorf("only one of --digest, --oauth2-bearer, --aws-sigv4 and --oauth2-token-url can be used")
+	}
+
+	user, password, _ := strings.Cut(opts.User, ":")
+
+	switch {
+	case opts.AWSSigV4 != "":
+		return NewAWSSigV4(opts.AWSSigV4, user, password)
+	case opts.OAuth2TokenURL != "":
+		if opts.User != "" {
+			return nil, fmt.Errorf("--user cannot be combined with --oauth2-token-url")
+		}
+		if opts.OAuth2ClientID == "" {
+			return nil, fmt.Errorf("--oauth2-token-url requires --oauth2-client-id")
+		}
+		return NewOAuth2ClientCredentials(opts.OAuth2TokenURL, opts.OAuth2ClientID, opts.OAuth2ClientSecret, opts.OAuth2Scope), nil
+	case opts.Bearer != "":
+		if opts.User != "" {
+			return nil, fmt.Errorf("--user cannot be combined with --oauth2-bearer")
+		}
+		return &BearerAuth{Token: opts.Bearer}, nil
+	case opts.Digest:
+		if opts.User == "" {
+			return nil, fmt.Errorf("--digest requires --user")
+		}
+		return &DigestAuth{User: user, Password: password}, nil
+	case opts.User != "":
+		return &BasicAuth{User: user, Password: password}, nil
+	}
+	return nil, nil
+}
+
+// authTransport applies an Authenticator to every request and retries once
+// when a Challenger asks for it. The time lost on the rejected exchange is
+// reported as the request's auth challenge phase. Like curl without
+// --location-trusted, redirects to another origin are sent without credentials.
+type authTransport struct {
+	base http.RoundTripper
+	auth Authenticator
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !sameOriginAsFirst(req) {
+		return t.base.RoundTrip(req)
+	}
+	start := time.Now()
+
+	// A RoundTripper must not modify the caller's request
+	authed := req.Clone(req.Context())
+	if err := t.auth.Authorize(authed, t.base); err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	resp, err := t.base.RoundTrip(authed)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenger, ok := t.auth.(Challenger)
+	if !ok || !challenger.Challenge(resp) {
+		return resp, nil
+	}
+
+	// Retrying needs a fresh copy of the body
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, nil
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if tracer := tracerFromContext(req.Context()); tracer != nil {
+		tracer.markAuthChallenge(start)
+	}
+
+	if err := t.auth.Authorize(retry, t.base); err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+	return t.base.RoundTrip(retry)
+}
+
+// CloseIdleConnections forwards to the wrapped transport
+func (t *authTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// Close forwards to the wrapped transport (HTTP/3 holds UDP sockets)
+func (t *authTransport) Close() error {
+	if closer, ok := t.base.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// BasicAuth sends credentials with every request
+type BasicAuth struct {
+	User     string
+	Password string
+}
+
+// Authorize implements Authenticator
+func (a *BasicAuth) Authorize(req *http.Request, _ http.RoundTripper) error {
+	req.SetBasicAuth(a.User, a.Password)
+	return nil
+}
+
+// BearerAuth sends a static bearer token with every request
+type BearerAuth struct {
+	Token string
+}
+
+// Authorize implements Authenticator
+func (a *BearerAuth) Authorize(req *http.Request, _ http.RoundTripper) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// DigestAuth implements RFC 7616 digest authentication. The first request is
+// sent without credentials; once challenged, the nonce is reused for later
+// requests (with an increasing nonce count) until the server marks it stale.
+type DigestAuth struct {
+	User     string
+	Password string
+
+	mu        sync.Mutex
+	challenge map[string]string
+	nc        int
+}
+
+// Authorize implements Authenticator
+func (a *DigestAuth) Authorize(req *http.Request, _ http.RoundTripper) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.challenge == nil {
+		return nil
+	}
+	a.nc++
+	header, err := digestAuthorization(a.challenge, a.User, a.Password, req.Method, req.URL.RequestURI(), a.nc)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", header)
+	return nil
+}
+
+// Challenge accepts a new digest challenge. A rejected request is retried
+// only when the challenge changed, so wrong credentials fail after one retry.
+func (a *DigestAuth) Challenge(resp *http.Response) bool {
+	params := findChallenge(resp.Header.Values("WWW-Authenticate"), "Digest")
+	if params == nil {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fresh := a.challenge == nil || a.challenge["nonce"] != params["nonce"] || strings.EqualFold(params["stale"], "true")
+	a.challenge = params
+	a.nc = 0
+	return fresh
+}
+
+// digestAuthorization computes the Authorization header for a digest challenge
+func digestAuthorization(challenge map[string]string, user, password, method, uri string, nc int) (string, error) {
+	algorithm := challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm '%s'", algorithm)
+	}
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	realm, nonce := challenge["realm"], challenge["nonce"]
+	cnonce := randomHex(8)
+	ncValue := fmt.Sprintf("%08x", nc)
+
+	ha1 := h(user + ":" + realm + ":" + password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+
+	// qop may offer several options; only "auth" is supported
+	qop := ""
+	for _, option := range strings.Split(challenge["qop"], ",") {
+		if strings.TrimSpace(option) == "auth" {
+			qop = "auth"
+		}
+	}
+
+	var response string
+	if qop != "" {
+		response = h(ha1 + ":" + nonce + ":" + ncValue + ":" + cnonce + ":" + qop + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, response="%s"`,
+		user, realm, nonce, uri, algorithm, response)
+	if qop != "" {
+		fmt.Fprintf(&b, `, qop=%s, nc=%s, cnonce="%s"`, qop, ncValue, cnonce)
+	}
+	if opaque, ok := challenge["opaque"]; ok {
+		fmt.Fprintf(&b, `, opaque="%s"`, opaque)
+	}
+	return b.String(), nil
+}
+
+// findChallenge returns the parameters of the first challenge for scheme in
+// WWW-Authenticate header values, or nil
+func findChallenge(headers []string, scheme string) map[string]string {
+	prefix := strings.ToLower(scheme) + " "
+	for _, header := range headers {
+		// A challenge starts the header or follows a comma outside quotes,
+		// so a scheme name inside a quoted realm does not count
+		quoted := false
+		for i := 0; i < len(header); i++ {
+			switch c := header[i]; {
+			case quoted && c == '\\':
+				i++
+			case c == '"':
+				quoted = !quoted
+			case !quoted && (i == 0 || c == ','):
+				start := i
+				if c == ',' {
+					start++
+				}
+				rest := strings.TrimLeft(header[start:], " \t")
+				if strings.HasPrefix(strings.ToLower(rest), prefix) {
+					return parseAuthParams(rest[len(prefix):])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseAuthParams parses comma-separated key=value or key="quoted value"
+// parameters, stopping at the start of another challenge
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || strings.ContainsAny(s[:eq], " ,") {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			value = b.String()
+			s = s[min(i+1, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = value
+	}
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// readBody returns the request body and restores it so it can still be sent
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// sameOriginAsFirst reports whether a request has the scheme, host and port
+// of the request that started its redirect chain
+func sameOriginAsFirst(req *http.Request) bool {
+	first := req
+	for first.Response != nil && first.Response.Request != nil {
+		first = first.Response.Request
+	}
+	return first == req || origin(first.URL) == origin(req.URL)
+}
+
+// origin is scheme://host:port with the default port filled in
+func origin(u *url.URL) string {
+	scheme, port := strings.ToLower(u.Scheme), u.Port()
+	if port == "" {
+		port = "80"
+		if scheme == "https" {
+			port = "443"
+		}
+	}
+	return scheme + "://" + strings.ToLower(u.Hostname()) + ":" + port
+}
@@ -0,0 +1,388 @@
+package client
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    AuthOptions
+		want    string
+		wantErr bool
+	}{
+		{name: "none", opts: AuthOptions{}, want: "<nil>"},
+		{name: "basic", opts: AuthOptions{User: "alice:secret"}, want: "*client.BasicAuth"},
+		{name: "digest", opts: AuthOptions{User: "alice:secret", Digest: true}, want: "*client.DigestAuth"},
+		{name: "bearer", opts: AuthOptions{Bearer: "t0ken"}, want: "*client.BearerAuth"},
+		{name: "sigv4", opts: AuthOptions{User: "AKID:SECRET", AWSSigV4: "aws:us-east-1:s3"}, want: "*client.AWSSigV4"},
+		{name: "oauth2", opts: AuthOptions{OAuth2TokenURL: "https://auth.example.com/token", OAuth2ClientID: "id"}, want: "*client.OAuth2ClientCredentials"},
+		{name: "digest without user", opts: AuthOptions{Digest: true}, wantErr: true},
+		{name: "oauth2 without client", opts: AuthOptions{OAuth2TokenURL: "https://auth.example.com/token"}, wantErr: true},
+		{name: "two schemes", opts: AuthOptions{Bearer: "t", Digest: true, User: "a:b"}, wantErr: true},
+		{name: "bad sigv4", opts: AuthOptions{User: "AKID:SECRET", AWSSigV4: "aws"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := NewAuthenticator(tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %T", auth)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := fmt.Sprintf("%T", auth); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClientBasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "alice" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{Timeout: 5 * time.Second, Auth: &BasicAuth{User: "alice", Password: "secret"}})
+	defer client.Close()
+
+	timing, err := client.MeasureRequest(server.URL, "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("MeasureRequest failed: %v", err)
+	}
+	if timing.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", timing.StatusCode)
+	}
+}
+
+// newDigestServer is a stand-in for a server using MD5 digest auth with qop=auth
+func newDigestServer(t *testing.T, user, password string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	const realm, nonce = "test", "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+	var challenges atomic.Int32
+
+	md5Hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := findChallenge([]string{r.Header.Get("Authorization")}, "Digest")
+		if params != nil {
+			ha1 := md5Hex(user + ":" + realm + ":" + password)
+			ha2 := md5Hex(r.Method + ":" + params["uri"])
+			want := md5Hex(ha1 + ":" + nonce + ":" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+			if params["response"] == want && params["uri"] == r.URL.RequestURI() && params["opaque"] == "xyz" {
+				w.Write([]byte("ok"))
+				return
+			}
+		}
+		challenges.Add(1)
+		w.Header().Add("WWW-Authenticate", `Basic realm="test"`)
+		w.Header().Add("WWW-Authenticate", `Digest realm="test", qop="auth,auth-int", nonce="`+nonce+`", opaque="xyz"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	return server, &challenges
+}
+
+func TestClientDigestAuth(t *testing.T) {
+	server, challenges := newDigestServer(t, "alice", "secret")
+
+	client := NewClient(&Config{Timeout: 5 * time.Second, Auth: &DigestAuth{User: "alice", Password: "secret"}})
+	defer client.Close()
+
+	timing, err := client.MeasureRequest(server.URL+"/private?x=1", "POST", nil, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("MeasureRequest failed: %v", err)
+	}
+	if timing.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 after the digest challenge, got %d", timing.StatusCode)
+	}
+	if timing.AuthChallenge <= 0 || timing.AuthChallenge > timing.Total {
+		t.Errorf("Expected the challenge exchange to be timed within the total, got %v of %v", timing.AuthChallenge, timing.Total)
+	}
+
+	// Later requests reuse the nonce without another challenge
+	timing, _ = client.MeasureRequest(server.URL+"/private", "GET", nil, nil)
+	if timing.StatusCode != http.StatusOK || timing.AuthChallenge != 0 {
+		t.Errorf("Expected a preemptive digest response, got %d (challenge %v)", timing.StatusCode, timing.AuthChallenge)
+	}
+	if got := challenges.Load(); got != 1 {
+		t.Errorf("Expected exactly 1 challenge, got %d", got)
+	}
+}
+
+func TestClientDigestAuthWrongPassword(t *testing.T) {
+	server, challenges := newDigestServer(t, "alice", "secret")
+
+	client := NewClient(&Config{Timeout: 5 * time.Second, Auth: &DigestAuth{User: "alice", Password: "wrong"}})
+	defer client.Close()
+
+	timing, _ := client.MeasureRequest(server.URL, "GET", nil, nil)
+	if timing.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong password, got %d", timing.StatusCode)
+	}
+	if got := challenges.Load(); got != 2 {
+		t.Errorf("Expected a single retry (2 challenges), got %d", got)
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	params := findChallenge([]string{`Digest realm="a \"b\", c", nonce=abc, qop="auth"`}, "digest")
+	if params["realm"] != `a "b", c` || params["nonce"] != "abc" || params["qop"] != "auth" {
+		t.Errorf("Unexpected params: %v", params)
+	}
+	if findChallenge([]string{`Basic realm="x"`}, "Digest") != nil {
+		t.Error("Expected no digest challenge")
+	}
+
+	// The scheme name only counts at the start of a challenge
+	if params := findChallenge([]string{`Basic realm="digest area"`}, "Digest"); params != nil {
+		t.Errorf("Expected no digest challenge inside a quoted realm, got %v", params)
+	}
+	params = findChallenge([]string{`Basic realm="x, Digest nonce=bad", Digest realm="y", nonce=good`}, "Digest")
+	if params["realm"] != "y" || params["nonce"] != "good" {
+		t.Errorf("Expected the second challenge, got %v", params)
+	}
+}
+
+// Request and expected signatures from the AWS SigV4 test suite
+func TestAWSSigV4TestSuite(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewAWSSigV4("aws:us-east-1:service", "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+			if err != nil {
+				t.Fatalf("NewAWSSigV4 failed: %v", err)
+			}
+			signer.now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			if err := signer.Authorize(req, nil); err != nil {
+				t.Fatalf("Authorize failed: %v", err)
+			}
+
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Errorf("Authorization mismatch:\n got: %s\nwant: %s", got, want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("Expected X-Amz-Date 20150830T123600Z, got %s", got)
+			}
+		})
+	}
+}
+
+func TestAWSSigV4S3PayloadHash(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+
+	signer, err := NewAWSSigV4("aws:eu-west-1:s3", "", "")
+	if err != nil {
+		t.Fatalf("NewAWSSigV4 failed: %v", err)
+	}
+
+	req, _ := http.NewRequest("PUT", "https://bucket.s3.amazonaws.com/key", strings.NewReader("hello"))
+	if err := signer.Authorize(req, nil); err != nil {
+		t.Fatalf("Authorize failed: %v", err)
+	}
+
+	if got := req.Header.Get("X-Amz-Content-Sha256"); got != sha256Hex([]byte("hello")) {
+		t.Errorf("Expected payload hash header, got %s", got)
+	}
+	if req.Header.Get("X-Amz-Security-Token") != "session" {
+		t.Error("Expected the session token from AWS_SESSION_TOKEN")
+	}
+	auth := req.Header.Get("Authorization")
+	if !strings.Contains(auth, "Credential=AKID/") || !strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Unexpected Authorization header: %s", auth)
+	}
+}
+
+// newTokenServer is a stand-in OAuth2 token endpoint issuing numbered tokens
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var issued atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if r.Method != http.MethodPost || r.FormValue("grant_type") != "client_credentials" || id != "client" || secret != "s3cret" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		n := issued.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d}`, n, expiresIn)
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+// newBearerServer accepts only the given tokens
+func newBearerServer(t *testing.T, valid ...string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, token := range valid {
+			if r.Header.Get("Authorization") == "Bearer "+token {
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientOAuth2TokenCache(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 3600)
+	api := newBearerServer(t, "token-1")
+
+	auth := NewOAuth2ClientCredentials(tokenServer.URL, "client", "s3cret", "")
+	client := NewClient(&Config{Timeout: 5 * time.Second, Auth: auth})
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		timing, err := client.MeasureRequest(api.URL, "GET", nil, nil)
+		if err != nil || timing.StatusCode != http.StatusOK {
+			t.Fatalf("Request %d failed: %v (status %d)", i, err, timing.StatusCode)
+		}
+		// The token request must not leak into the request's phases
+		if i == 0 && (len(timing.ConnectAttempts) != 1 || timing.ConnectAttempts[0].Address != api.Listener.Addr().String()) {
+			t.Errorf("Token fetch showed up in the request timing: %+v", timing.ConnectAttempts)
+		}
+	}
+
+	if got := issued.Load(); got != 1 {
+		t.Errorf("Expected 1 token fetch for 3 requests, got %d", got)
+	}
+	fetches := auth.Fetches()
+	if len(fetches) != 1 || fetches[0].Duration <= 0 || fetches[0].StatusCode != http.StatusOK ||
+		fetches[0].ExpiresIn != Duration(time.Hour) {
+		t.Errorf("Unexpected token fetches: %+v", fetches)
+	}
+}
+
+func TestClientOAuth2RefreshBeforeExpiry(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 1)
+	api := newBearerServer(t, "token-1", "token-2")
+
+	auth := NewOAuth2ClientCredentials(tokenServer.URL, "client", "s3cret", "")
+	client := NewClient(&Config{Timeout: 5 * time.Second, Auth: auth})
+	defer client.Close()
+
+	client.MeasureRequest(api.URL, "GET", nil, nil)
+	// Within the refresh margin (10% of the 1s lifetime) of expiry
+	time.Sleep(950 * time.Millisecond)
+	timing, _ := client.MeasureRequest(api.URL, "GET", nil, nil)
+
+	if timing.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 with the refreshed token, got %d", timing.StatusCode)
+	}
+	if got := issued.Load(); got != 2 {
+		t.Errorf("Expected the token to be refreshed before expiry, got %d fetches", got)
+	}
+}
+
+func TestClientOAuth2RevokedToken(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 3600)
+	// token-1 is rejected as if it had been revoked
+	api := newBearerServer(t, "token-2")
+
+	client := NewClient(&Config{Timeout: 5 * time.Second, Auth: NewOAuth2ClientCredentials(tokenServer.URL, "client", "s3cret", "")})
+	defer client.Close()
+
+	timing, _ := client.MeasureRequest(api.URL, "GET", nil, nil)
+	if timing.StatusCode != http.StatusOK || timing.AuthChallenge <= 0 {
+		t.Errorf("Expected a retry with a new token, got %d (challenge %v)", timing.StatusCode, timing.AuthChallenge)
+	}
+	if got := issued.Load(); got != 2 {
+		t.Errorf("Expected 2 token fetches, got %d", got)
+	}
+}
+
+func TestClientOAuth2FetchError(t *testing.T) {
+	tokenServer, _ := newTokenServer(t, 3600)
+	api := newBearerServer(t)
+
+	auth := NewOAuth2ClientCredentials(tokenServer.URL, "client", "wrong", "")
+	client := NewClient(&Config{Timeout: 5 * time.Second, Auth: auth})
+	defer client.Close()
+
+	timing, err := client.MeasureRequest(api.URL, "GET", nil, nil)
+	if err == nil || !strings.Contains(timing.Error, "token endpoint returned 400") {
+		t.Errorf("Expected the token error to be reported, got %v", err)
+	}
+	if fetches := auth.Fetches(); len(fetches) != 1 || fetches[0].Error == "" {
+		t.Errorf("Expected a failed fetch to be recorded, got %+v", fetches)
+	}
+}
+
+func TestAuthNotSentAcrossOrigins(t *testing.T) {
+	var leaked atomic.Value
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked.Store(r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+	var sameHost atomic.Int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok {
+			sameHost.Add(1)
+		}
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/next", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, other.URL, http.StatusFound)
+	}))
+	defer origin.Close()
+
+	for name, auth := range map[string]Authenticator{
+		"basic":  &BasicAuth{User: "alice", Password: "secret"},
+		"bearer": &BearerAuth{Token: "topsecret"},
+	} {
+		leaked.Store("")
+		sameHost.Store(0)
+		client := NewClient(&Config{Timeout: 5 * time.Second, Auth: auth})
+		timing, err := client.MeasureRequest(origin.URL, "GET", nil, nil)
+		client.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if timing.StatusCode != http.StatusOK || len(timing.Redirects) != 2 {
+			t.Fatalf("%s: expected two redirects to a 200, got %d after %d", name, timing.StatusCode, len(timing.Redirects))
+		}
+		if got := leaked.Load().(string); got != "" {
+			t.Errorf("%s: credentials sent to another origin: %q", name, got)
+		}
+		if name == "basic" && sameHost.Load() != 2 {
+			t.Errorf("%s: expected credentials on both same-origin requests, got %d", name, sameHost.Load())
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// CertificateInfo describes one certificate of the peer chain
+type CertificateInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	DNSNames           []string  `json:"dns_names,omitempty"`
+	IPAddresses        []string  `json:"ip_addresses,omitempty"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	DaysUntilExpiry    int       `json:"days_until_expiry"`
+	KeyType            string    `json:"key_type"`
+	KeySize            int       `json:"key_size,omitempty"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	IsCA               bool      `json:"is_ca"`
+	EmbeddedSCTs       int       `json:"embedded_scts,omitempty"`
+}
+
+// oidSCTList is the X.509 extension carrying embedded Signed Certificate Timestamps (RFC 6962)
+var oidSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+
+// InspectCertificate extracts the reportable fields of a certificate
+func InspectCertificate(cert *x509.Certificate, now time.Time) CertificateInfo {
+	info := CertificateInfo{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		DNSNames:           cert.DNSNames,
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		DaysUntilExpiry:    int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24)),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		IsCA:               cert.IsCA,
+		EmbeddedSCTs:       countEmbeddedSCTs(cert),
+	}
+
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		info.KeyType = "RSA"
+		info.KeySize = key.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.KeyType = "ECDSA"
+		info.KeySize = key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		info.KeyType = "Ed25519"
+		info.KeySize = 256
+	default:
+		info.KeyType = cert.PublicKeyAlgorithm.String()
+	}
+
+	return info
+}
+
+// InspectChain extracts the peer chain, OCSP stapling and SCT count from a TLS connection
+func InspectChain(state *tls.ConnectionState, now time.Time) (chain []CertificateInfo, ocspStapled bool, sctCount int) {
+	for _, cert := range state.PeerCertificates {
+		info := InspectCertificate(cert, now)
+		chain = append(chain, info)
+		sctCount += info.EmbeddedSCTs
+	}
+
+	// SCTs can also be delivered in the TLS extension or stapled OCSP response;
+	// only the TLS extension is exposed by crypto/tls
+	sctCount += len(state.SignedCertificateTimestamps)
+
+	return chain, len(state.OCSPResponse) > 0, sctCount
+}
+
+// countEmbeddedSCTs counts the SCTs in a certificate's SCT list extension
+func countEmbeddedSCTs(cert *x509.Certificate) int {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSCTList) {
+			continue
+		}
+
+		// The extension value is an OCTET STRING wrapping a TLS-encoded
+		// SignedCertificateTimestampList: uint16 total length, then
+		// uint16-length-prefixed SCTs
+		var list []byte
+		if _, err := asn1.Unmarshal(ext.Value, &list); err != nil || len(list) < 2 {
+			return 0
+		}
+
+		total := int(binary.BigEndian.Uint16(list))
+		list = list[2:]
+		if total > len(list) {
+			return 0
+		}
+		list = list[:total]
+
+		count := 0
+		for len(list) >= 2 {
+			size := int(binary.BigEndian.Uint16(list))
+			if size+2 > len(list) {
+				break
+			}
+			list = list[size+2:]
+			count++
+		}
+		return count
+	}
+	return 0
+}
+
+// SoonestExpiry returns the certificate in the chain closest to expiry
+func SoonestExpiry(chain []CertificateInfo) (CertificateInfo, bool) {
+	if len(chain) == 0 {
+		return CertificateInfo{}, false
+	}
+
+	soonest := chain[0]
+	for _, cert := range chain[1:] {
+		if cert.NotAfter.Before(soonest.NotAfter) {
+			soonest = cert
+		}
+	}
+	return soonest, true
+}
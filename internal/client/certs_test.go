@@ -0,0 +1,118 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestInspectCertificate(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), "inspect.example.com", x509.ExtKeyUsageServerAuth)
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	info := InspectCertificate(cert, time.Now())
+
+	if info.Subject != "CN=inspect.example.com" {
+		t.Errorf("Unexpected subject: %s", info.Subject)
+	}
+
+	if len(info.DNSNames) != 1 || info.DNSNames[0] != "inspect.example.com" {
+		t.Errorf("Unexpected SANs: %v", info.DNSNames)
+	}
+
+	if info.KeyType != "ECDSA" || info.KeySize != 256 {
+		t.Errorf("Expected ECDSA 256, got %s %d", info.KeyType, info.KeySize)
+	}
+
+	if info.SignatureAlgorithm != "ECDSA-SHA256" {
+		t.Errorf("Unexpected signature algorithm: %s", info.SignatureAlgorithm)
+	}
+
+	// Test certificates are valid for 24 hours
+	if info.DaysUntilExpiry != 0 {
+		t.Errorf("Expected 0 days until expiry, got %d", info.DaysUntilExpiry)
+	}
+}
+
+func TestCountEmbeddedSCTs(t *testing.T) {
+	// Two SCTs of 3 and 2 bytes in a TLS-encoded list
+	list := []byte{0x00, 0x09, 0x00, 0x03, 0xaa, 0xbb, 0xcc, 0x00, 0x02, 0xdd, 0xee}
+	value, err := asn1.Marshal(list)
+	if err != nil {
+		t.Fatalf("Failed to marshal SCT list: %v", err)
+	}
+
+	cert := &x509.Certificate{
+		Extensions: []pkix.Extension{{Id: oidSCTList, Value: value}},
+	}
+
+	if count := countEmbeddedSCTs(cert); count != 2 {
+		t.Errorf("Expected 2 SCTs, got %d", count)
+	}
+
+	if count := countEmbeddedSCTs(&x509.Certificate{}); count != 0 {
+		t.Errorf("Expected 0 SCTs without extension, got %d", count)
+	}
+}
+
+func TestSoonestExpiry(t *testing.T) {
+	now := time.Now()
+	chain := []CertificateInfo{
+		{Subject: "leaf", NotAfter: now.Add(90 * 24 * time.Hour)},
+		{Subject: "intermediate", NotAfter: now.Add(30 * 24 * time.Hour)},
+		{Subject: "root", NotAfter: now.Add(3650 * 24 * time.Hour)},
+	}
+
+	soonest, ok := SoonestExpiry(chain)
+	if !ok || soonest.Subject != "intermediate" {
+		t.Errorf("Expected intermediate, got %q", soonest.Subject)
+	}
+
+	if _, ok := SoonestExpiry(nil); ok {
+		t.Error("Expected no result for empty chain")
+	}
+}
+
+func TestClientReportsCertificateChain(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{Timeout: 5 * time.Second, Insecure: true})
+	defer client.Close()
+
+	timing, err := client.MeasureRequest(server.URL, "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("MeasureRequest failed: %v", err)
+	}
+
+	if len(timing.TLSCertificates) != 1 {
+		t.Fatalf("Expected 1 certificate, got %d", len(timing.TLSCertificates))
+	}
+
+	leaf := timing.TLSCertificates[0]
+	if !leaf.NotAfter.Equal(server.Certificate().NotAfter) {
+		t.Errorf("Expected NotAfter %v, got %v", server.Certificate().NotAfter, leaf.NotAfter)
+	}
+
+	if leaf.KeyType != "RSA" {
+		t.Errorf("Expected RSA key, got %s", leaf.KeyType)
+	}
+
+	if timing.TLSOCSPStapled {
+		t.Error("httptest server does not staple OCSP")
+	}
+}
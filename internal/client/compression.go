@@ -0,0 +1,165 @@
+package client
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings understood by --compressed
+const (
+	EncodingGzip     = "gzip"
+	EncodingBrotli   = "br"
+	EncodingZstd     = "zstd"
+	EncodingDeflate  = "deflate"
+	EncodingIdentity = "identity"
+)
+
+// SupportedEncodings lists the encodings offered by --compressed, in order of preference
+var SupportedEncodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip, EncodingDeflate}
+
+// ParseEncodings validates a comma-separated --compressed value; "all" (or an
+// empty value) selects every supported encoding
+func ParseEncodings(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "all" {
+		return SupportedEncodings, nil
+	}
+
+	var encodings []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case EncodingGzip, EncodingBrotli, EncodingZstd, EncodingDeflate, EncodingIdentity:
+			encodings = append(encodings, name)
+		default:
+			return nil, fmt.Errorf("unsupported encoding '%s': expected gzip, br, zstd, deflate or identity", name)
+		}
+	}
+	return encodings, nil
+}
+
+// meteredReader counts the bytes read from the network and the time spent waiting for them
+type meteredReader struct {
+	r       io.Reader
+	n       int64
+	waiting time.Duration
+}
+
+func (m *meteredReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := m.r.Read(p)
+	m.waiting += time.Since(start)
+	m.n += int64(n)
+	return n, err
+}
+
+// decodingReader decodes a compressed body while keeping wire and decoded
+// sizes apart. Decompression time is the time spent in the decoder minus the
+// time it spent waiting for the network.
+type decodingReader struct {
+	wire     *meteredReader
+	encoding string
+	decoder  io.Reader
+	close    func()
+	err      error
+	decoded  int64
+	elapsed  time.Duration
+}
+
+// newDecodingReader wraps the body of resp when it carries a Content-Encoding
+// the transport did not already decode; otherwise it returns nil
+func newDecodingReader(resp *http.Response, body io.Reader) *decodingReader {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if resp.Uncompressed || encoding == "" {
+		return nil
+	}
+	return &decodingReader{
+		wire:     &meteredReader{r: body},
+		encoding: encoding,
+	}
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	defer func() { d.elapsed += time.Since(start) }()
+
+	// The decoder is created on first use so that reading its header is timed
+	if d.decoder == nil && d.err == nil {
+		d.decoder, d.close, d.err = newDecoder(d.encoding, d.wire)
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	n, err := d.decoder.Read(p)
+	d.decoded += int64(n)
+	return n, err
+}
+
+// Close releases decoder resources (zstd decoders hold goroutines)
+func (d *decodingReader) Close() {
+	if d.close != nil {
+		d.close()
+	}
+}
+
+// apply records sizes and decompression time in timing
+func (d *decodingReader) apply(timing *TimingBreakdown) {
+	timing.ContentEncoding = d.encoding
+	timing.WireSize = d.wire.n
+	timing.DecodedSize = d.decoded
+	if d.wire.n > 0 {
+		timing.CompressionRatio = float64(d.decoded) / float64(d.wire.n)
+	}
+	if decode := d.elapsed - d.wire.waiting; decode > 0 {
+		timing.Decompression = Duration(decode)
+	}
+}
+
+// newDecoder returns a reader decoding the given content coding
+func newDecoder(encoding string, r io.Reader) (io.Reader, func(), error) {
+	switch encoding {
+	case EncodingGzip, "x-gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("gzip: %w", err)
+		}
+		return zr, func() { zr.Close() }, nil
+	case EncodingBrotli:
+		return brotli.NewReader(r), nil, nil
+	case EncodingZstd:
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, nil, fmt.Errorf("zstd: %w", err)
+		}
+		return zr, zr.Close, nil
+	case EncodingDeflate:
+		// "deflate" should be zlib-wrapped, but some servers send raw DEFLATE
+		br := bufio.NewReader(r)
+		header, err := br.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, nil, fmt.Errorf("deflate: %w", err)
+			}
+			return zr, func() { zr.Close() }, nil
+		}
+		fr := flate.NewReader(br)
+		return fr, func() { fr.Close() }, nil
+	default:
+		// identity, or a coding we cannot decode: the body is left as received
+		return r, nil, nil
+	}
+}
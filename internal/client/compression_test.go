@@ -0,0 +1,173 @@
+package client
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestParseEncodings(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "zstd,br,gzip,deflate", false},
+		{"all", "zstd,br,gzip,deflate", false},
+		{"br, ZSTD", "br,zstd", false},
+		{"gzip,,identity", "gzip,identity", false},
+		{"compress", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseEncodings(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseEncodings(%q): expected an error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseEncodings(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("ParseEncodings(%q) = %v, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+// compress encodes body with the given content coding; "deflate-raw" is
+// DEFLATE without the zlib wrapper, as sent by some servers
+func compress(t *testing.T, encoding string, body []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var w interface {
+		Write([]byte) (int, error)
+		Close() error
+	}
+	switch encoding {
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case EncodingBrotli:
+		w = brotli.NewWriter(&buf)
+	case EncodingZstd:
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatalf("zstd writer: %v", err)
+		}
+		w = zw
+	case EncodingDeflate:
+		w = zlib.NewWriter(&buf)
+	case "deflate-raw":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			t.Fatalf("flate writer: %v", err)
+		}
+		w = fw
+	default:
+		t.Fatalf("unknown encoding %s", encoding)
+	}
+	if _, err := w.Write(body); err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestCompressedResponses(t *testing.T) {
+	body := []byte(strings.Repeat("gocurl measures every phase of the request. ", 500))
+
+	for _, encoding := range []string{EncodingGzip, EncodingBrotli, EncodingZstd, EncodingDeflate, "deflate-raw"} {
+		t.Run(encoding, func(t *testing.T) {
+			encoded := compress(t, encoding, body)
+			var acceptEncoding string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				acceptEncoding = r.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Encoding", strings.TrimSuffix(encoding, "-raw"))
+				w.Write(encoded)
+			}))
+			defer server.Close()
+
+			client := NewClient(&Config{Timeout: 5 * time.Second, AcceptEncoding: SupportedEncodings})
+			defer client.Close()
+
+			timing, err := client.MeasureRequest(server.URL, "GET", nil, nil)
+			if err != nil {
+				t.Fatalf("MeasureRequest failed: %v", err)
+			}
+			if timing.Error != "" {
+				t.Fatalf("Unexpected error: %s", timing.Error)
+			}
+
+			if acceptEncoding != "zstd, br, gzip, deflate" {
+				t.Errorf("Expected all encodings to be offered, got %q", acceptEncoding)
+			}
+			if timing.ContentEncoding != strings.TrimSuffix(encoding, "-raw") {
+				t.Errorf("Expected content encoding %s, got %s", encoding, timing.ContentEncoding)
+			}
+			if timing.WireSize != int64(len(encoded)) {
+				t.Errorf("Expected wire size %d, got %d", len(encoded), timing.WireSize)
+			}
+			if timing.DecodedSize != int64(len(body)) || timing.ResponseSize != int64(len(body)) {
+				t.Errorf("Expected decoded size %d, got %d (response size %d)", len(body), timing.DecodedSize, timing.ResponseSize)
+			}
+			want := float64(len(body)) / float64(len(encoded))
+			if timing.CompressionRatio != want {
+				t.Errorf("Expected compression ratio %.2f, got %.2f", want, timing.CompressionRatio)
+			}
+		})
+	}
+}
+
+func TestCompressedSingleEncoding(t *testing.T) {
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Write([]byte("plain"))
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{Timeout: 5 * time.Second, AcceptEncoding: []string{EncodingIdentity}})
+	defer client.Close()
+
+	timing, err := client.MeasureRequest(server.URL, "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("MeasureRequest failed: %v", err)
+	}
+	if acceptEncoding != "identity" {
+		t.Errorf("Expected Accept-Encoding identity, got %q", acceptEncoding)
+	}
+	if timing.ContentEncoding != "" || timing.WireSize != 0 || timing.ResponseSize != 5 {
+		t.Errorf("Expected an unencoded 5 byte body, got encoding %q wire %d size %d",
+			timing.ContentEncoding, timing.WireSize, timing.ResponseSize)
+	}
+}
+
+func TestCompressedCorruptBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte("not gzip"))
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{Timeout: 5 * time.Second, AcceptEncoding: []string{EncodingGzip}})
+	defer client.Close()
+
+	timing, err := client.MeasureRequest(server.URL, "GET", nil, nil)
+	if err == nil && (timing == nil || timing.Error == "") {
+		t.Error("Expected a decoding error for a corrupt gzip body")
+	}
+}
@@ -0,0 +1,334 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// netscapeHeader starts every cookie file written by CookieJar, as curl does
+const netscapeHeader = "# Netscape HTTP Cookie File\n# Written by gocurl. Edit at your own risk.\n\n"
+
+// httpOnlyPrefix marks HttpOnly cookies in the domain column of a cookie file
+const httpOnlyPrefix = "#HttpOnly_"
+
+// CookieJar is an http.CookieJar that can be loaded from and saved to
+// Netscape cookie files (the format used by curl -b/-c). Cookie matching is
+// delegated to net/http/cookiejar; the jar keeps its own copy of each cookie's
+// attributes because cookiejar does not expose them.
+type CookieJar struct {
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	entries map[string]*cookieEntry
+}
+
+// cookieEntry is one stored cookie, keyed by domain, path and name
+type cookieEntry struct {
+	Domain   string
+	HostOnly bool
+	Path     string
+	Secure   bool
+	HttpOnly bool
+	Expires  time.Time // zero for session cookies
+	Name     string
+	Value    string
+}
+
+// NewCookieJar creates an empty in-memory cookie jar
+func NewCookieJar() *CookieJar {
+	jar, _ := cookiejar.New(nil)
+	return &CookieJar{
+		jar:     jar,
+		entries: make(map[string]*cookieEntry),
+	}
+}
+
+// SetCookies implements http.CookieJar
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	for _, c := range cookies {
+		entry, ok := newCookieEntry(u, c, now)
+		if !ok {
+			continue
+		}
+		j.jar.SetCookies(u, []*http.Cookie{c})
+
+		key := entry.key()
+		if c.MaxAge < 0 || (!entry.Expires.IsZero() && !entry.Expires.After(now)) {
+			delete(j.entries, key)
+			continue
+		}
+		j.entries[key] = entry
+	}
+}
+
+// Cookies implements http.CookieJar
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Len returns the number of unexpired cookies in the jar
+func (j *CookieJar) Len() int {
+	return len(j.list())
+}
+
+// Clone returns an independent jar holding the same cookies
+func (j *CookieJar) Clone() *CookieJar {
+	clone := NewCookieJar()
+	clone.Merge(j)
+	return clone
+}
+
+// Merge copies every cookie of other into the jar, replacing cookies with the
+// same domain, path and name
+func (j *CookieJar) Merge(other *CookieJar) {
+	for _, entry := range other.list() {
+		j.add(entry)
+	}
+}
+
+// add stores an entry as if it had been received from its own domain
+func (j *CookieJar) add(entry *cookieEntry) {
+	scheme := "http"
+	if entry.Secure {
+		scheme = "https"
+	}
+	u := &url.URL{Scheme: scheme, Host: entry.Domain, Path: entry.Path}
+
+	c := &http.Cookie{
+		Name:     entry.Name,
+		Value:    entry.Value,
+		Path:     entry.Path,
+		Secure:   entry.Secure,
+		HttpOnly: entry.HttpOnly,
+		Expires:  entry.Expires,
+	}
+	if !entry.HostOnly {
+		c.Domain = entry.Domain
+	}
+	j.SetCookies(u, []*http.Cookie{c})
+}
+
+// list returns the unexpired cookies sorted by domain, path and name
+func (j *CookieJar) list() []*cookieEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	entries := make([]*cookieEntry, 0, len(j.entries))
+	for _, entry := range j.entries {
+		if !entry.Expires.IsZero() && !entry.Expires.After(now) {
+			continue
+		}
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].key() < entries[b].key()
+	})
+	return entries
+}
+
+// Load reads cookies in Netscape format. Expired cookies are skipped.
+func (j *CookieJar) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		} else if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 {
+			// A cookie with an empty value may lose its trailing tab
+			fields = append(fields, "")
+		}
+		if len(fields) != 7 {
+			return fmt.Errorf("cookie file line %d: expected 7 tab-separated fields, got %d", lineNum, len(fields))
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return fmt.Errorf("cookie file line %d: invalid expiry '%s'", lineNum, fields[4])
+		}
+
+		entry := &cookieEntry{
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+			Name:     fields[5],
+			Value:    fields[6],
+		}
+		if expires > 0 {
+			entry.Expires = time.Unix(expires, 0)
+		}
+		if entry.Domain == "" || entry.Name == "" {
+			return fmt.Errorf("cookie file line %d: missing domain or name", lineNum)
+		}
+		if !entry.Expires.IsZero() && entry.Expires.Before(time.Now()) {
+			continue
+		}
+		j.add(entry)
+	}
+	return scanner.Err()
+}
+
+// LoadFile reads a Netscape cookie file
+func (j *CookieJar) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read cookie file: %w", err)
+	}
+	defer f.Close()
+
+	if err := j.Load(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Save writes the jar in Netscape format. Session cookies get an expiry of 0.
+func (j *CookieJar) Save(w io.Writer) error {
+	if _, err := io.WriteString(w, netscapeHeader); err != nil {
+		return err
+	}
+
+	for _, entry := range j.list() {
+		domain := entry.Domain
+		if !entry.HostOnly {
+			domain = "." + domain
+		}
+		if entry.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if !entry.Expires.IsZero() {
+			expires = entry.Expires.Unix()
+		}
+
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(!entry.HostOnly), entry.Path, netscapeBool(entry.Secure),
+			expires, entry.Name, entry.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveFile writes the jar to path, readable only by the current user
+func (j *CookieJar) SaveFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write cookie jar: %w", err)
+	}
+	if err := j.Save(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cookie jar: %w", err)
+	}
+	return f.Close()
+}
+
+// IsCookieString reports whether a -b value is literal cookies ("name=value;
+// ...") rather than a file name, using curl's rule: it contains '='
+func IsCookieString(value string) bool {
+	return strings.Contains(value, "=")
+}
+
+// newCookieEntry derives the stored form of a cookie received from u. It
+// returns false for cookies the jar would reject for their domain.
+func newCookieEntry(u *url.URL, c *http.Cookie, now time.Time) (*cookieEntry, bool) {
+	host := strings.ToLower(u.Hostname())
+	entry := &cookieEntry{
+		Domain:   host,
+		HostOnly: true,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		Name:     c.Name,
+		Value:    c.Value,
+	}
+
+	if c.Domain != "" {
+		domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+		if domain != host {
+			// Domain cookies must domain-match the host and cannot be set on IPs
+			if net.ParseIP(host) != nil || !strings.HasSuffix(host, "."+domain) {
+				return nil, false
+			}
+			entry.Domain = domain
+			entry.HostOnly = false
+		} else if net.ParseIP(host) == nil {
+			entry.HostOnly = false
+		}
+	}
+
+	if entry.Path == "" || !strings.HasPrefix(entry.Path, "/") {
+		entry.Path = defaultCookiePath(u.Path)
+	}
+
+	switch {
+	case c.MaxAge > 0:
+		entry.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+	case !c.Expires.IsZero():
+		entry.Expires = c.Expires
+	}
+	return entry, true
+}
+
+// defaultCookiePath is the RFC 6265 default path: the request path up to its last '/'
+func defaultCookiePath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// key identifies a cookie by domain, path and name
+func (e *cookieEntry) key() string {
+	return e.Domain + "\x00" + e.Path + "\x00" + e.Name
+}
+
+// netscapeBool formats a boolean column of a cookie file
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// recordCookies copies the cookies sent with resp.Request and set by resp into timing
+func recordCookies(timing *TimingBreakdown, resp *http.Response) {
+	if resp.Request != nil {
+		for _, header := range resp.Request.Header.Values("Cookie") {
+			for _, part := range strings.Split(header, ";") {
+				if part = strings.TrimSpace(part); part != "" {
+					timing.CookiesSent = append(timing.CookiesSent, part)
+				}
+			}
+		}
+	}
+	timing.CookiesSet = append(timing.CookiesSet, resp.Header.Values("Set-Cookie")...)
+}
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCookieJarLoad(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+	file := "# Netscape HTTP Cookie File\n" +
+		"\n" +
+		".example.com\tTRUE\t/\tFALSE\t" + strconv.FormatInt(expires, 10) + "\tsession\tabc\n" +
+		"#HttpOnly_api.example.com\tFALSE\t/v1\tTRUE\t0\ttoken\txyz\n" +
+		"old.example.com\tFALSE\t/\tFALSE\t1\texpired\tgone\n"
+
+	jar := NewCookieJar()
+	if err := jar.Load(strings.NewReader(file)); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if jar.Len() != 2 {
+		t.Fatalf("Expected 2 cookies (expired one skipped), got %d", jar.Len())
+	}
+
+	// Domain cookie applies to subdomains; the secure host-only cookie only to https api.example.com/v1
+	got := cookieNames(jar.Cookies(mustParseURL(t, "https://api.example.com/v1/users")))
+	if got != "session,token" && got != "token,session" {
+		t.Errorf("Expected session and token for api.example.com/v1, got %s", got)
+	}
+	if got := cookieNames(jar.Cookies(mustParseURL(t, "http://www.example.com/"))); got != "session" {
+		t.Errorf("Expected only session for www.example.com, got %s", got)
+	}
+}
+
+func TestCookieJarLoadInvalid(t *testing.T) {
+	jar := NewCookieJar()
+	if err := jar.Load(strings.NewReader("example.com\tFALSE\t/\n")); err == nil {
+		t.Error("Expected an error for a line with missing fields")
+	}
+	if err := jar.Load(strings.NewReader("example.com\tFALSE\t/\tFALSE\tsoon\tname\tvalue\n")); err == nil {
+		t.Error("Expected an error for a non-numeric expiry")
+	}
+}
+
+func TestCookieJarSaveRoundTrip(t *testing.T) {
+	jar := NewCookieJar()
+	u := mustParseURL(t, "https://shop.example.com/cart/items")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "cart", Value: "42"},
+		{Name: "sid", Value: "s1", Domain: "example.com", Path: "/", Secure: true, HttpOnly: true, MaxAge: 3600},
+	})
+
+	var buf bytes.Buffer
+	if err := jar.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	saved := buf.String()
+
+	if !strings.HasPrefix(saved, "# Netscape HTTP Cookie File") {
+		t.Errorf("Missing Netscape header:\n%s", saved)
+	}
+	if !strings.Contains(saved, "shop.example.com\tFALSE\t/cart\tFALSE\t0\tcart\t42\n") {
+		t.Errorf("Expected host-only session cookie with default path:\n%s", saved)
+	}
+	if !strings.Contains(saved, "#HttpOnly_.example.com\tTRUE\t/\tTRUE\t") {
+		t.Errorf("Expected HttpOnly domain cookie:\n%s", saved)
+	}
+
+	reloaded := NewCookieJar()
+	if err := reloaded.Load(strings.NewReader(saved)); err != nil {
+		t.Fatalf("Reloading saved jar failed: %v", err)
+	}
+	var again bytes.Buffer
+	reloaded.Save(&again)
+	if again.String() != saved {
+		t.Errorf("Round trip changed the jar:\n%s\nvs\n%s", saved, again.String())
+	}
+}
+
+func TestCookieJarDeletesExpired(t *testing.T) {
+	jar := NewCookieJar()
+	u := mustParseURL(t, "http://example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "1"}})
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "", MaxAge: -1}})
+
+	if jar.Len() != 0 || len(jar.Cookies(u)) != 0 {
+		t.Errorf("Expected cookie to be deleted, jar has %d", jar.Len())
+	}
+}
+
+func TestCookieJarRejectsForeignDomain(t *testing.T) {
+	jar := NewCookieJar()
+	jar.SetCookies(mustParseURL(t, "http://example.com/"), []*http.Cookie{{Name: "a", Value: "1", Domain: "other.com"}})
+
+	if jar.Len() != 0 {
+		t.Errorf("Expected cookie for a foreign domain to be rejected, jar has %d", jar.Len())
+	}
+}
+
+func TestCookieJarSaveFile(t *testing.T) {
+	jar := NewCookieJar()
+	jar.SetCookies(mustParseURL(t, "http://example.com/"), []*http.Cookie{{Name: "a", Value: "1"}})
+
+	path := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := jar.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	loaded := NewCookieJar()
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if loaded.Len() != 1 {
+		t.Errorf("Expected 1 cookie after reload, got %d", loaded.Len())
+	}
+}
+
+func TestIsCookieString(t *testing.T) {
+	if !IsCookieString("a=1; b=2") {
+		t.Error("Expected 'a=1; b=2' to be a cookie string")
+	}
+	if IsCookieString("cookies.txt") {
+		t.Error("Expected 'cookies.txt' to be a file name")
+	}
+}
+
+// newLoginServer sets a session cookie on /login and redirects to /me, which
+// echoes the session it receives
+func newLoginServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: r.URL.Query().Get("user"), Path: "/"})
+		http.Redirect(w, r, "/me", http.StatusFound)
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err == nil {
+			w.Write([]byte(c.Value))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientCookieJar(t *testing.T) {
+	server := newLoginServer(t)
+
+	jar := NewCookieJar()
+	client := NewClient(&Config{Timeout: 5 * time.Second, CookieJar: jar})
+	defer client.Close()
+
+	timing, err := client.MeasureRequest(server.URL+"/login?user=alice", "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("MeasureRequest failed: %v", err)
+	}
+	if timing.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the redirected request to carry the session, got %d", timing.StatusCode)
+	}
+
+	if len(timing.Redirects) != 1 || len(timing.Redirects[0].CookiesSet) != 1 ||
+		!strings.HasPrefix(timing.Redirects[0].CookiesSet[0], "session=alice") {
+		t.Errorf("Expected the login hop to record Set-Cookie, got %+v", timing.Redirects)
+	}
+	if len(timing.CookiesSent) != 1 || timing.CookiesSent[0] != "session=alice" {
+		t.Errorf("Expected session=alice to be sent on the final hop, got %v", timing.CookiesSent)
+	}
+
+	// The cookie persists for later requests on the same client
+	timing, _ = client.MeasureRequest(server.URL+"/me", "GET", nil, nil)
+	if timing.StatusCode != http.StatusOK {
+		t.Errorf("Expected the session to persist across requests, got %d", timing.StatusCode)
+	}
+}
+
+func TestClientWithCookieJar(t *testing.T) {
+	server := newLoginServer(t)
+
+	client := NewClient(&Config{Timeout: 5 * time.Second, CookieJar: NewCookieJar()})
+	defer client.Close()
+
+	alice := client.WithCookieJar(NewCookieJar())
+	bob := client.WithCookieJar(NewCookieJar())
+
+	alice.MeasureRequest(server.URL+"/login?user=alice", "GET", nil, nil)
+	bob.MeasureRequest(server.URL+"/login?user=bob", "GET", nil, nil)
+
+	timing, _ := alice.MeasureRequest(server.URL+"/me", "GET", nil, nil)
+	if len(timing.CookiesSent) != 1 || timing.CookiesSent[0] != "session=alice" {
+		t.Errorf("Expected alice's session to be kept separately, got %v", timing.CookiesSent)
+	}
+
+	// The original client's jar was not touched
+	timing, _ = client.MeasureRequest(server.URL+"/me", "GET", nil, nil)
+	if timing.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the base client to have no session, got %d", timing.StatusCode)
+	}
+}
+
+func TestCookieJarCloneAndMerge(t *testing.T) {
+	u := mustParseURL(t, "http://example.com/")
+	base := NewCookieJar()
+	base.SetCookies(u, []*http.Cookie{{Name: "a", Value: "1"}})
+
+	clone := base.Clone()
+	clone.SetCookies(u, []*http.Cookie{{Name: "b", Value: "2"}})
+	if base.Len() != 1 {
+		t.Errorf("Clone should not share cookies with the original, base has %d", base.Len())
+	}
+
+	base.Merge(clone)
+	if got := cookieNames(base.Cookies(u)); got != "a,b" {
+		t.Errorf("Expected a,b after merge, got %s", got)
+	}
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("invalid URL %s: %v", raw, err)
+	}
+	return u
+}
+
+func cookieNames(cookies []*http.Cookie) string {
+	names := make([]string, len(cookies))
+	for i, c := range cookies {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ",")
+}
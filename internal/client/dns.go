@@ -0,0 +1,389 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ResolverOptions selects the DNS servers used instead of the system resolver
+type ResolverOptions struct {
+	Servers    []string    // host[:port] of plain DNS (or DoT) servers
+	DoHURL     string      // DNS-over-HTTPS endpoint (RFC 8484)
+	DNSOverTLS bool        // Query Servers over TLS (RFC 7858)
+	TLSConfig  *tls.Config // TLS configuration for DoT; nil uses defaults
+	Timeout    time.Duration
+}
+
+// NewResolver creates a resolver that sends queries to the configured servers.
+// The Go resolver's own lookup logic is kept, so httptrace DNS hooks still fire
+// and DNSLookup timings stay comparable with the system resolver. Returns nil
+// when no custom resolver is configured.
+func NewResolver(opts ResolverOptions) (*net.Resolver, error) {
+	if opts.DoHURL != "" && len(opts.Servers) > 0 {
+		return nil, fmt.Errorf("--doh-url and --dns-servers cannot be combined")
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	if opts.DoHURL != "" {
+		u, err := url.Parse(opts.DoHURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("invalid --doh-url '%s': expected an http(s) URL", opts.DoHURL)
+		}
+
+		httpClient := &http.Client{Timeout: timeout}
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				conn := &dohConn{ctx: ctx, client: httpClient, url: opts.DoHURL}
+				return sniffDNS(ctx, conn), nil
+			},
+		}, nil
+	}
+
+	if len(opts.Servers) == 0 {
+		if opts.DNSOverTLS {
+			return nil, fmt.Errorf("--dns-over-tls requires --dns-servers")
+		}
+		return nil, nil
+	}
+
+	defaultPort := "53"
+	if opts.DNSOverTLS {
+		defaultPort = "853"
+	}
+
+	servers := make([]string, 0, len(opts.Servers))
+	for _, server := range opts.Servers {
+		addr, err := normalizeServer(server, defaultPort)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, addr)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var next atomic.Uint32
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			// Rotate through servers so the resolver's retries reach the next one
+			server := servers[int(next.Add(1)-1)%len(servers)]
+
+			if !opts.DNSOverTLS {
+				conn, err := dialer.DialContext(ctx, network, server)
+				if err != nil {
+					return nil, err
+				}
+				return sniffDNS(ctx, conn), nil
+			}
+
+			tlsConfig := &tls.Config{}
+			if opts.TLSConfig != nil {
+				tlsConfig = opts.TLSConfig.Clone()
+			}
+			if tlsConfig.ServerName == "" {
+				host, _, _ := net.SplitHostPort(server)
+				tlsConfig.ServerName = host
+			}
+			tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsConfig}
+			// A non-packet conn makes the Go resolver use TCP framing, which is DoT's wire format
+			conn, err := tlsDialer.DialContext(ctx, "tcp", server)
+			if err != nil {
+				return nil, err
+			}
+			return sniffDNS(ctx, conn), nil
+		},
+	}, nil
+}
+
+// normalizeServer ensures a DNS server address carries a port
+func normalizeServer(server, defaultPort string) (string, error) {
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return "", fmt.Errorf("empty DNS server address")
+	}
+
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server, nil
+	}
+
+	// Bare IPv6 addresses have colons but no port
+	host := strings.TrimSuffix(strings.TrimPrefix(server, "["), "]")
+	if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid DNS server address '%s'", server)
+	}
+	return net.JoinHostPort(host, defaultPort), nil
+}
+
+// DNSRecord is an answer record seen by a custom resolver
+type DNSRecord struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	TTL   uint32 `json:"ttl"`
+}
+
+// sniffDNS wraps a resolver connection so that the responses read by the Go
+// resolver are also reported to the request's Tracer. Packet connections carry
+// one message per read and must stay net.PacketConn, or the resolver would
+// switch to TCP framing; stream connections carry 2-byte length-prefixed messages.
+func sniffDNS(ctx context.Context, conn net.Conn) net.Conn {
+	tracer := tracerFromContext(ctx)
+	if tracer == nil {
+		return conn
+	}
+	if pc, ok := conn.(net.PacketConn); ok {
+		return &dnsSniffPacketConn{dnsSniffConn: dnsSniffConn{Conn: conn, tracer: tracer}, pc: pc}
+	}
+	return &dnsSniffConn{Conn: conn, tracer: tracer}
+}
+
+// dnsSniffConn copies length-prefixed DNS responses to a Tracer as they are read
+type dnsSniffConn struct {
+	net.Conn
+	tracer *Tracer
+	buf    []byte
+}
+
+func (c *dnsSniffConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n == 0 {
+		return n, err
+	}
+
+	c.buf = append(c.buf, b[:n]...)
+	for len(c.buf) >= 2 {
+		size := int(binary.BigEndian.Uint16(c.buf))
+		if len(c.buf) < size+2 {
+			break
+		}
+		c.tracer.recordDNSResponse(c.buf[2 : size+2])
+		c.buf = c.buf[size+2:]
+	}
+	return n, err
+}
+
+// dnsSniffPacketConn copies datagram DNS responses to a Tracer as they are read
+type dnsSniffPacketConn struct {
+	dnsSniffConn
+	pc net.PacketConn
+}
+
+func (c *dnsSniffPacketConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.tracer.recordDNSResponse(b[:n])
+	}
+	return n, err
+}
+
+func (c *dnsSniffPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.pc.ReadFrom(b)
+	if n > 0 {
+		c.tracer.recordDNSResponse(b[:n])
+	}
+	return n, addr, err
+}
+
+func (c *dnsSniffPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.pc.WriteTo(b, addr)
+}
+
+// parseDNSRecords extracts the A, AAAA and CNAME answers of a DNS response
+func parseDNSRecords(msg []byte) []DNSRecord {
+	var parser dnsmessage.Parser
+	if _, err := parser.Start(msg); err != nil {
+		return nil
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil
+	}
+
+	var records []DNSRecord
+	for {
+		header, err := parser.AnswerHeader()
+		if err != nil {
+			break
+		}
+
+		record := DNSRecord{Name: header.Name.String(), TTL: header.TTL}
+		switch header.Type {
+		case dnsmessage.TypeA:
+			r, err := parser.AResource()
+			if err != nil {
+				return records
+			}
+			record.Type = "A"
+			record.Value = net.IP(r.A[:]).String()
+		case dnsmessage.TypeAAAA:
+			r, err := parser.AAAAResource()
+			if err != nil {
+				return records
+			}
+			record.Type = "AAAA"
+			record.Value = net.IP(r.AAAA[:]).String()
+		case dnsmessage.TypeCNAME:
+			r, err := parser.CNAMEResource()
+			if err != nil {
+				return records
+			}
+			record.Type = "CNAME"
+			record.Value = r.CNAME.String()
+		default:
+			if err := parser.SkipAnswer(); err != nil {
+				return records
+			}
+			continue
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// containsRecord reports whether records already holds r (TTL ignored)
+func containsRecord(records []DNSRecord, r DNSRecord) bool {
+	for _, existing := range records {
+		if existing.Name == r.Name && existing.Type == r.Type && existing.Value == r.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// cnameChain orders the CNAME records from the queried name to the final target
+func cnameChain(records []DNSRecord) []string {
+	targets := make(map[string]string)
+	isTarget := make(map[string]bool)
+	for _, r := range records {
+		if r.Type == "CNAME" {
+			targets[r.Name] = r.Value
+			isTarget[r.Value] = true
+		}
+	}
+
+	for name := range targets {
+		if isTarget[name] {
+			continue
+		}
+		chain := []string{name}
+		for next, ok := targets[name]; ok && len(chain) <= len(targets); next, ok = targets[next] {
+			chain = append(chain, next)
+		}
+		return chain
+	}
+	return nil
+}
+
+// dohConn carries DNS queries from the Go resolver over HTTPS.
+//
+// It presents itself as a stream connection, so the resolver writes a
+// length-prefixed query and reads a length-prefixed answer, which avoids the
+// 1232-byte limit the resolver applies to datagram responses.
+type dohConn struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+
+	mu       sync.Mutex
+	pending  bytes.Buffer
+	deadline time.Time
+}
+
+// Write sends one length-prefixed DNS query and buffers the answer
+func (c *dohConn) Write(b []byte) (int, error) {
+	if len(b) < 2 {
+		return 0, fmt.Errorf("doh: short query")
+	}
+	query := b[2:]
+
+	c.mu.Lock()
+	deadline := c.deadline
+	c.mu.Unlock()
+
+	ctx := c.ctx
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(query))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("doh: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("doh: server returned %s", resp.Status)
+	}
+
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return 0, fmt.Errorf("doh: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var length [2]byte
+	binary.BigEndian.PutUint16(length[:], uint16(len(answer)))
+	c.pending.Write(length[:])
+	c.pending.Write(answer)
+
+	return len(b), nil
+}
+
+// Read returns buffered answer bytes
+func (c *dohConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending.Read(b)
+}
+
+func (c *dohConn) Close() error { return nil }
+
+func (c *dohConn) LocalAddr() net.Addr { return dohAddr("local") }
+
+func (c *dohConn) RemoteAddr() net.Addr { return dohAddr(c.url) }
+
+func (c *dohConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *dohConn) SetReadDeadline(t time.Time) error { return nil }
+
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return c.SetDeadline(t) }
+
+// dohAddr is the net.Addr of a DoH endpoint
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+
+func (a dohAddr) String() string { return string(a) }
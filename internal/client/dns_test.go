@@ -0,0 +1,352 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// answerDNS builds a response that resolves every A question to ip,
+// optionally through a chain of CNAMEs
+func answerDNS(t *testing.T, query []byte, ip net.IP, cnames ...string) []byte {
+	t.Helper()
+
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		t.Errorf("Failed to parse DNS query: %v", err)
+		return nil
+	}
+	question, err := parser.Question()
+	if err != nil {
+		t.Errorf("Failed to parse DNS question: %v", err)
+		return nil
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true})
+	builder.EnableCompression()
+	builder.StartQuestions()
+	builder.Question(question)
+	builder.StartAnswers()
+	name := question.Name
+	for _, cname := range cnames {
+		target := dnsmessage.MustNewName(cname)
+		builder.CNAMEResource(dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: 300}, dnsmessage.CNAMEResource{CNAME: target})
+		name = target
+	}
+	if question.Type == dnsmessage.TypeA {
+		var a [4]byte
+		copy(a[:], ip.To4())
+		builder.AResource(dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: 60}, dnsmessage.AResource{A: a})
+	}
+	response, err := builder.Finish()
+	if err != nil {
+		t.Errorf("Failed to build DNS response: %v", err)
+	}
+	return response
+}
+
+// startUDPDNSServer answers every A query with ip
+func startUDPDNSServer(t *testing.T, ip net.IP, cnames ...string) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(answerDNS(t, buf[:n], ip, cnames...), addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// startDoTServer answers length-prefixed A queries over TLS with ip
+func startDoTServer(t *testing.T, ip net.IP) string {
+	t.Helper()
+
+	certFile, keyFile := writeTestCertificate(t, t.TempDir(), "dns.test", x509.ExtKeyUsageServerAuth)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					var length [2]byte
+					if _, err := io.ReadFull(conn, length[:]); err != nil {
+						return
+					}
+					query := make([]byte, binary.BigEndian.Uint16(length[:]))
+					if _, err := io.ReadFull(conn, query); err != nil {
+						return
+					}
+					response := answerDNS(t, query, ip)
+					binary.BigEndian.PutUint16(length[:], uint16(len(response)))
+					conn.Write(append(length[:], response...))
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestNewResolverNotConfigured(t *testing.T) {
+	resolver, err := NewResolver(ResolverOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resolver != nil {
+		t.Error("Expected nil resolver when nothing is configured")
+	}
+}
+
+func TestNewResolverErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ResolverOptions
+	}{
+		{"doh with servers", ResolverOptions{Servers: []string{"1.1.1.1"}, DoHURL: "https://dns.example/dns-query"}},
+		{"dot without servers", ResolverOptions{DNSOverTLS: true}},
+		{"invalid doh url", ResolverOptions{DoHURL: "dns.example"}},
+		{"empty server", ResolverOptions{Servers: []string{" "}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewResolver(tt.opts); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestNormalizeServer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.1.1.1", "1.1.1.1:53"},
+		{"1.1.1.1:5353", "1.1.1.1:5353"},
+		{"dns.example", "dns.example:53"},
+		{"2606:4700::1111", "[2606:4700::1111]:53"},
+		{"[2606:4700::1111]", "[2606:4700::1111]:53"},
+		{"[2606:4700::1111]:5353", "[2606:4700::1111]:5353"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := normalizeServer(tt.input, "53")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestResolverPlainDNS(t *testing.T) {
+	server := startUDPDNSServer(t, net.ParseIP("192.0.2.10"))
+
+	resolver, err := NewResolver(ResolverOptions{Servers: []string{server}, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+
+	addrs, err := resolver.LookupHost(context.Background(), "gocurl-test.example")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if len(addrs) != 1 || addrs[0] != "192.0.2.10" {
+		t.Errorf("Expected [192.0.2.10], got %v", addrs)
+	}
+}
+
+func TestResolverDNSOverTLS(t *testing.T) {
+	server := startDoTServer(t, net.ParseIP("192.0.2.20"))
+
+	resolver, err := NewResolver(ResolverOptions{
+		Servers:    []string{server},
+		DNSOverTLS: true,
+		TLSConfig:  &tls.Config{InsecureSkipVerify: true},
+		Timeout:    2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+
+	addrs, err := resolver.LookupHost(context.Background(), "gocurl-test.example")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if len(addrs) != 1 || addrs[0] != "192.0.2.20" {
+		t.Errorf("Expected [192.0.2.20], got %v", addrs)
+	}
+}
+
+func TestResolverDoH(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		query, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(answerDNS(t, query, net.ParseIP("192.0.2.30")))
+	}))
+	defer server.Close()
+
+	resolver, err := NewResolver(ResolverOptions{DoHURL: server.URL + "/dns-query", Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+
+	addrs, err := resolver.LookupHost(context.Background(), "gocurl-test.example")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if len(addrs) != 1 || addrs[0] != "192.0.2.30" {
+		t.Errorf("Expected [192.0.2.30], got %v", addrs)
+	}
+}
+
+func TestClientCustomResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	dnsServer := startUDPDNSServer(t, net.ParseIP("127.0.0.1"))
+	resolver, err := NewResolver(ResolverOptions{Servers: []string{dnsServer}, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+
+	serverURL, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(serverURL.Host)
+
+	c := NewClient(&Config{Timeout: 5 * time.Second, Resolver: resolver})
+	defer c.Close()
+
+	timing, err := c.MeasureRequest("http://gocurl-test.example:"+port+"/", "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if timing.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", timing.StatusCode)
+	}
+	if timing.DNSLookup <= 0 {
+		t.Errorf("Expected DNS lookup time to be recorded, got %v", timing.DNSLookup)
+	}
+}
+
+func TestClientReportsDNSDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	dnsServer := startUDPDNSServer(t, net.ParseIP("127.0.0.1"), "edge.cdn.example.", "pop1.cdn.example.")
+	resolver, err := NewResolver(ResolverOptions{Servers: []string{dnsServer}, Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+
+	serverURL, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(serverURL.Host)
+
+	c := NewClient(&Config{Timeout: 5 * time.Second, Resolver: resolver})
+	defer c.Close()
+
+	timing, err := c.MeasureRequest("http://www.gocurl-test.example:"+port+"/", "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	if len(timing.DNSAddresses) != 1 || timing.DNSAddresses[0] != "127.0.0.1" {
+		t.Errorf("Expected DNS addresses [127.0.0.1], got %v", timing.DNSAddresses)
+	}
+	if timing.RemoteAddr != "127.0.0.1:"+port {
+		t.Errorf("Expected remote address 127.0.0.1:%s, got %s", port, timing.RemoteAddr)
+	}
+
+	expectedChain := []string{"www.gocurl-test.example.", "edge.cdn.example.", "pop1.cdn.example."}
+	if len(timing.DNSCNAMEChain) != len(expectedChain) {
+		t.Fatalf("Expected CNAME chain %v, got %v", expectedChain, timing.DNSCNAMEChain)
+	}
+	for i, name := range expectedChain {
+		if timing.DNSCNAMEChain[i] != name {
+			t.Errorf("Expected CNAME chain %v, got %v", expectedChain, timing.DNSCNAMEChain)
+			break
+		}
+	}
+
+	var sawA bool
+	for _, record := range timing.DNSRecords {
+		if record.Type == "A" {
+			sawA = true
+			if record.Value != "127.0.0.1" || record.TTL != 60 {
+				t.Errorf("Unexpected A record: %+v", record)
+			}
+		}
+	}
+	if !sawA {
+		t.Errorf("Expected an A record, got %+v", timing.DNSRecords)
+	}
+}
+
+func TestClientReportsRemoteAddrOnReuse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	c := NewClient(&Config{Timeout: 5 * time.Second})
+	defer c.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	for i := 0; i < 2; i++ {
+		timing, err := c.MeasureRequest(server.URL, "GET", nil, nil)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		if timing.RemoteAddr != serverURL.Host {
+			t.Errorf("Request %d: expected remote address %s, got %s", i, serverURL.Host, timing.RemoteAddr)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gRPC status codes used here (https://grpc.github.io/grpc/core/md_doc_statuscodes.html)
+const (
+	GRPCCodeOK               = 0
+	GRPCCodeUnknown          = 2
+	GRPCCodePermissionDenied = 7
+	GRPCCodeUnimplemented    = 12
+	GRPCCodeInternal         = 13
+	GRPCCodeUnavailable      = 14
+	GRPCCodeUnauthenticated  = 16
+)
+
+// grpcCodeNames are indexed by status code
+var grpcCodeNames = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED", "NOT_FOUND",
+	"ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED", "FAILED_PRECONDITION",
+	"ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED", "INTERNAL", "UNAVAILABLE", "DATA_LOSS",
+	"UNAUTHENTICATED",
+}
+
+// GRPCCodeName returns the name of a gRPC status code, e.g. "UNAVAILABLE"
+func GRPCCodeName(code int) string {
+	if code >= 0 && code < len(grpcCodeNames) {
+		return grpcCodeNames[code]
+	}
+	return "CODE_" + strconv.Itoa(code)
+}
+
+// grpcMaxMessage bounds a single response message
+const grpcMaxMessage = 64 << 20
+
+// GRPCCall is one invocation of a unary or server-streaming method
+type GRPCCall struct {
+	Method          string // Full method path, "/package.Service/Method"
+	Message         []byte // Encoded request message
+	ServerStreaming bool   // Analyse the response messages as a stream
+}
+
+// GRPCMetrics describes a gRPC call. Code and Status replace the HTTP status,
+// which is 200 for any call the server answered.
+type GRPCMetrics struct {
+	Method             string            `json:"method"`
+	Code               int               `json:"code"`
+	Status             string            `json:"status"` // Name of Code, e.g. "OK"
+	Message            string            `json:"message,omitempty"`
+	Encoding           string            `json:"encoding,omitempty"` // grpc-encoding of compressed responses
+	RequestSize        int               `json:"request_size"`
+	Messages           int               `json:"messages"` // Response messages received
+	TimeToFirstMessage Duration          `json:"time_to_first_message,omitempty"`
+	Responses          []json.RawMessage `json:"responses,omitempty"` // Decoded responses, filled in by the caller
+	RawResponses       [][]byte          `json:"-"`                   // Kept with ShowBody
+}
+
+// MeasureGRPC invokes a method on target, the http:// or https:// base URL of
+// the server, over HTTP/2. Every response message is timed as it arrives. A
+// non-OK status is reported in the timing's Error.
+func (c *Client) MeasureGRPC(ctx context.Context, target string, headers Headers, call GRPCCall) (*TimingBreakdown, error) {
+	tracer := NewTracer()
+	req, err := c.newGRPCRequest(tracer.WithContext(ctx), target, headers, call.Method, call.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	tracer.Start()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		tracer.End()
+		timing := tracer.Timing()
+		timing.Error = err.Error()
+		timing.H2 = c.h2Trace(ctx, tracer)
+		return timing, err
+	}
+	defer resp.Body.Close()
+
+	m := &GRPCMetrics{Method: call.Method, RequestSize: len(call.Message), Encoding: resp.Header.Get("Grpc-Encoding")}
+	var arrivals []messageArrival
+	var received int64
+	code, message, readErr := readGRPCResponse(resp, func(msg []byte, wire int) {
+		arrivals = append(arrivals, messageArrival{size: len(msg), at: time.Now()})
+		received += int64(wire)
+		if c.config.ShowBody {
+			m.RawResponses = append(m.RawResponses, msg)
+		}
+	})
+	tracer.End()
+
+	timing := tracer.Timing()
+	timing.StatusCode = resp.StatusCode
+	timing.Protocol = resp.Proto
+	timing.ContentLength = resp.ContentLength
+	timing.ResponseSize = received
+	if c.config.IncludeHeaders {
+		timing.ResponseHeaders = headersFromResponse(resp.Header)
+		timing.ResponseHeaders = append(timing.ResponseHeaders, headersFromResponse(resp.Trailer)...)
+	}
+
+	m.Code, m.Status, m.Message = code, GRPCCodeName(code), message
+	m.Messages = len(arrivals)
+	if len(arrivals) > 0 {
+		m.TimeToFirstMessage = Duration(arrivals[0].at.Sub(start))
+	}
+	timing.GRPC = m
+	if call.ServerStreaming {
+		timing.Streaming = c.messageStreamMetrics("gRPC", start, arrivals, timing)
+	}
+	// Streams stay timed by message, which is what the caller sees
+	timing.H2 = c.h2Trace(ctx, tracer)
+	if timing.H2 != nil && timing.Streaming != nil {
+		timing.Streaming.StreamID = timing.H2.StreamID
+	}
+
+	switch {
+	case readErr != nil:
+		timing.Error = readErr.Error()
+	case code != GRPCCodeOK:
+		timing.Error = fmt.Sprintf("grpc-status %d %s", code, m.Status)
+		if message != "" {
+			timing.Error += ": " + message
+		}
+	}
+	return timing, nil
+}
+
+// newGRPCRequest builds the POST for a call with one request message
+func (c *Client) newGRPCRequest(ctx context.Context, target string, headers Headers, method string, message []byte) (*http.Request, error) {
+	var body bytes.Buffer
+	body.Grow(5 + len(message))
+	body.WriteByte(0)
+	binary.Write(&body, binary.BigEndian, uint32(len(message)))
+	body.Write(message)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(target, "/")+method, &body)
+	if err != nil {
+		return nil, err
+	}
+	headers.apply(req)
+	if _, ok := req.Header["User-Agent"]; !ok {
+		req.Header.Set("User-Agent", DefaultUserAgent)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	if c.config.Timeout > 0 {
+		req.Header.Set("Grpc-Timeout", grpcTimeout(c.config.Timeout))
+	}
+	return req, nil
+}
+
+// grpcTimeout encodes a deadline for the grpc-timeout header, which allows at
+// most eight digits
+func grpcTimeout(d time.Duration) string {
+	if ms := d.Milliseconds(); ms < 1e8 {
+		return strconv.FormatInt(max(ms, 1), 10) + "m"
+	}
+	return strconv.FormatInt(min(int64(d.Seconds()), 1e8-1), 10) + "S"
+}
+
+// readGRPCResponse reads the length-prefixed messages of a response, passing
+// each to onMessage with its size on the wire, and returns the call status
+func readGRPCResponse(resp *http.Response, onMessage func(msg []byte, wire int)) (int, string, error) {
+	if resp.StatusCode != http.StatusOK {
+		return grpcCodeFromHTTP(resp.StatusCode), "HTTP " + resp.Status, nil
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/grpc") {
+		return GRPCCodeUnknown, "", fmt.Errorf("not a gRPC response: Content-Type %q", ct)
+	}
+
+	var header [5]byte
+	for {
+		if _, err := io.ReadFull(resp.Body, header[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return GRPCCodeUnknown, "", fmt.Errorf("reading gRPC message: %w", err)
+		}
+		size := binary.BigEndian.Uint32(header[1:])
+		if size > grpcMaxMessage {
+			return GRPCCodeUnknown, "", fmt.Errorf("gRPC message of %d bytes exceeds the %d byte limit", size, grpcMaxMessage)
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(resp.Body, msg); err != nil {
+			return GRPCCodeUnknown, "", fmt.Errorf("reading gRPC message: %w", err)
+		}
+		wire := len(header) + len(msg)
+		if header[0]&1 != 0 {
+			var err error
+			if msg, err = decompressGRPC(resp.Header.Get("Grpc-Encoding"), msg); err != nil {
+				return GRPCCodeUnknown, "", err
+			}
+		}
+		onMessage(msg, wire)
+	}
+
+	// Trailers-only responses carry the status in the headers
+	status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	if status == "" {
+		return GRPCCodeUnknown, "", errors.New("gRPC response has no grpc-status")
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return GRPCCodeUnknown, "", fmt.Errorf("invalid grpc-status %q", status)
+	}
+	if unescaped, err := url.PathUnescape(message); err == nil {
+		message = unescaped
+	}
+	return code, message, nil
+}
+
+// decompressGRPC decodes a compressed message
+func decompressGRPC(encoding string, msg []byte) ([]byte, error) {
+	if encoding != "gzip" {
+		return nil, fmt.Errorf("gRPC message compressed with unsupported encoding %q", encoding)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(io.LimitReader(zr, grpcMaxMessage))
+}
+
+// grpcCodeFromHTTP maps the HTTP status of a response that never reached a
+// gRPC server (e.g. a proxy error) as the gRPC spec describes
+func grpcCodeFromHTTP(status int) int {
+	switch status {
+	case http.StatusBadRequest:
+		return GRPCCodeInternal
+	case http.StatusUnauthorized:
+		return GRPCCodeUnauthenticated
+	case http.StatusForbidden:
+		return GRPCCodePermissionDenied
+	case http.StatusNotFound:
+		return GRPCCodeUnimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return GRPCCodeUnavailable
+	default:
+		return GRPCCodeUnknown
+	}
+}
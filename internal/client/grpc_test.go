@@ -0,0 +1,184 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newGRPCServer starts an h2c server that hands each call's request message
+// to handle
+func newGRPCServer(t *testing.T, handle func(w http.ResponseWriter, r *http.Request, msg []byte)) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.Header.Get("Content-Type") != "application/grpc" || r.Header.Get("TE") != "trailers" {
+			http.Error(w, "not a gRPC request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+			t.Errorf("Malformed request message %x", body)
+		}
+		handle(w, r, body[5:])
+	}))
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// writeGRPCMessage writes one length-prefixed message and flushes it
+func writeGRPCMessage(w http.ResponseWriter, msg []byte, compressed bool) {
+	var header [5]byte
+	if compressed {
+		header[0] = 1
+	}
+	binary.BigEndian.PutUint32(header[1:], uint32(len(msg)))
+	w.Write(header[:])
+	w.Write(msg)
+	w.(http.Flusher).Flush()
+}
+
+func newGRPCClient() *Client {
+	return NewClient(&Config{Timeout: 5 * time.Second, Protocol: ProtocolHTTP2, ShowBody: true, IncludeHeaders: true})
+}
+
+func TestMeasureGRPCUnary(t *testing.T) {
+	srv := newGRPCServer(t, func(w http.ResponseWriter, r *http.Request, msg []byte) {
+		if r.URL.Path != "/test.Echo/Say" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Grpc-Timeout") != "5000m" {
+			t.Errorf("Expected grpc-timeout 5000m, got %q", r.Header.Get("Grpc-Timeout"))
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		writeGRPCMessage(w, msg, false)
+		w.Header().Set("Grpc-Status", "0")
+	})
+
+	timing, err := newGRPCClient().MeasureGRPC(context.Background(), srv.URL, nil, GRPCCall{Method: "/test.Echo/Say", Message: []byte("hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing.Error != "" {
+		t.Fatalf("Unexpected error %s", timing.Error)
+	}
+	m := timing.GRPC
+	if m.Code != GRPCCodeOK || m.Status != "OK" || m.Messages != 1 || m.RequestSize != 5 {
+		t.Errorf("Unexpected metrics %+v", m)
+	}
+	if len(m.RawResponses) != 1 || string(m.RawResponses[0]) != "hello" {
+		t.Errorf("Expected the echoed message, got %q", m.RawResponses)
+	}
+	if timing.ResponseSize != 10 || timing.Protocol != "HTTP/2.0" || timing.Streaming != nil {
+		t.Errorf("Unexpected timing: size %d, protocol %s, streaming %v", timing.ResponseSize, timing.Protocol, timing.Streaming)
+	}
+	found := false
+	for _, h := range timing.ResponseHeaders {
+		found = found || strings.EqualFold(h.Name, "Grpc-Status")
+	}
+	if !found {
+		t.Error("Expected the trailers among the response headers")
+	}
+}
+
+func TestMeasureGRPCServerStreaming(t *testing.T) {
+	srv := newGRPCServer(t, func(w http.ResponseWriter, r *http.Request, msg []byte) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Encoding", "gzip")
+		w.Header().Set("Trailer", "Grpc-Status")
+		for i := 0; i < 4; i++ {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			zw.Write([]byte("tick"))
+			zw.Close()
+			writeGRPCMessage(w, buf.Bytes(), true)
+			time.Sleep(30 * time.Millisecond)
+		}
+		w.Header().Set("Grpc-Status", "0")
+	})
+
+	timing, err := newGRPCClient().MeasureGRPC(context.Background(), srv.URL, nil, GRPCCall{Method: "/test.Clock/Watch", ServerStreaming: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing.Error != "" {
+		t.Fatalf("Unexpected error %s", timing.Error)
+	}
+	if timing.GRPC.Messages != 4 || timing.GRPC.Encoding != "gzip" || string(timing.GRPC.RawResponses[3]) != "tick" {
+		t.Errorf("Unexpected metrics %+v", timing.GRPC)
+	}
+	s := timing.Streaming
+	if s == nil || s.TotalChunks != 4 || s.BufferingAnalysis == nil {
+		t.Fatalf("Expected stream metrics for 4 messages, got %+v", s)
+	}
+	if s.BufferingAnalysis.BufferingDetected {
+		t.Errorf("Messages 30ms apart should not look buffered: %+v", s.BufferingAnalysis)
+	}
+	if timing.GRPC.TimeToFirstMessage <= 0 || s.LastChunkTime < Duration(90*time.Millisecond) {
+		t.Errorf("Unexpected message timing: first %v, last %v", timing.GRPC.TimeToFirstMessage, s.LastChunkTime)
+	}
+}
+
+func TestMeasureGRPCStatus(t *testing.T) {
+	t.Run("trailers only", func(t *testing.T) {
+		srv := newGRPCServer(t, func(w http.ResponseWriter, r *http.Request, msg []byte) {
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "no such user%3A 7")
+		})
+		timing, err := newGRPCClient().MeasureGRPC(context.Background(), srv.URL, nil, GRPCCall{Method: "/test.Users/Get"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if timing.StatusCode != 200 || timing.GRPC.Code != 5 || timing.GRPC.Message != "no such user: 7" {
+			t.Errorf("Unexpected status: HTTP %d, %+v", timing.StatusCode, timing.GRPC)
+		}
+		if timing.Error != "grpc-status 5 NOT_FOUND: no such user: 7" {
+			t.Errorf("Unexpected error %q", timing.Error)
+		}
+	})
+
+	t.Run("http error", func(t *testing.T) {
+		srv := newGRPCServer(t, func(w http.ResponseWriter, r *http.Request, msg []byte) {
+			http.Error(w, "upstream down", http.StatusServiceUnavailable)
+		})
+		timing, _ := newGRPCClient().MeasureGRPC(context.Background(), srv.URL, nil, GRPCCall{Method: "/test.Users/Get"})
+		if timing.GRPC.Code != GRPCCodeUnavailable {
+			t.Errorf("Expected UNAVAILABLE, got %+v", timing.GRPC)
+		}
+	})
+
+	t.Run("missing status", func(t *testing.T) {
+		srv := newGRPCServer(t, func(w http.ResponseWriter, r *http.Request, msg []byte) {
+			w.Header().Set("Content-Type", "application/grpc")
+			writeGRPCMessage(w, msg, false)
+		})
+		timing, _ := newGRPCClient().MeasureGRPC(context.Background(), srv.URL, nil, GRPCCall{Method: "/test.Users/Get"})
+		if !strings.Contains(timing.Error, "no grpc-status") {
+			t.Errorf("Expected a missing status error, got %q", timing.Error)
+		}
+	})
+}
+
+func TestGRPCTimeout(t *testing.T) {
+	tests := map[time.Duration]string{
+		30 * time.Second:   "30000m",
+		time.Microsecond:   "1m",
+		48 * time.Hour:     "172800S",
+		100000 * time.Hour: "99999999S",
+	}
+	for d, expected := range tests {
+		if got := grpcTimeout(d); got != expected {
+			t.Errorf("grpcTimeout(%v) = %s, expected %s", d, got, expected)
+		}
+	}
+}
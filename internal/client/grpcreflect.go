@@ -0,0 +1,239 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Server reflection methods, newest first. Both versions share the message
+// layout, so requests and responses are encoded by hand rather than pulling
+// in generated code.
+var reflectionMethods = []string{
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+}
+
+// Field numbers of ServerReflectionRequest and ServerReflectionResponse
+const (
+	reflectFileByFilename     protowire.Number = 3
+	reflectFileContaining     protowire.Number = 4
+	reflectListServices       protowire.Number = 7
+	reflectFileDescriptorResp protowire.Number = 4
+	reflectListServicesResp   protowire.Number = 6
+	reflectErrorResp          protowire.Number = 7
+)
+
+// GRPCListServices lists the services target exposes through server reflection
+func (c *Client) GRPCListServices(ctx context.Context, target string, headers Headers) ([]string, error) {
+	resp, err := c.reflect(ctx, target, headers, reflectListServices, "*")
+	if err != nil {
+		return nil, err
+	}
+	var services []string
+	for _, service := range resp.listServices {
+		// ServiceResponse.name is field 1
+		if err := consumeFields(service, func(num protowire.Number, value []byte) {
+			if num == 1 {
+				services = append(services, string(value))
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// GRPCFileDescriptors fetches through server reflection the file that defines
+// symbol, a fully qualified service or message name, and every file it imports
+func (c *Client) GRPCFileDescriptors(ctx context.Context, target string, headers Headers, symbol string) (*descriptorpb.FileDescriptorSet, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+
+	add := func(raw [][]byte) error {
+		for _, b := range raw {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(b, fd); err != nil {
+				return fmt.Errorf("invalid file descriptor from server reflection: %w", err)
+			}
+			if !seen[fd.GetName()] {
+				seen[fd.GetName()] = true
+				set.File = append(set.File, fd)
+			}
+		}
+		return nil
+	}
+
+	resp, err := c.reflect(ctx, target, headers, reflectFileContaining, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := add(resp.files); err != nil {
+		return nil, err
+	}
+
+	// Servers may leave out imports they consider already sent, so fetch any
+	// that are missing by name
+	for i := 0; i < len(set.File); i++ {
+		for _, dep := range set.File[i].GetDependency() {
+			if seen[dep] {
+				continue
+			}
+			resp, err := c.reflect(ctx, target, headers, reflectFileByFilename, dep)
+			if err != nil {
+				return nil, err
+			}
+			if err := add(resp.files); err != nil {
+				return nil, err
+			}
+			if !seen[dep] {
+				return nil, fmt.Errorf("server reflection did not return %s", dep)
+			}
+		}
+	}
+	return set, nil
+}
+
+// reflectionResponse holds the parts of a ServerReflectionResponse we use
+type reflectionResponse struct {
+	files        [][]byte // Serialized FileDescriptorProtos
+	listServices [][]byte // Serialized ServiceResponses
+}
+
+// reflect sends one reflection request on its own stream, falling back to the
+// v1alpha service for servers that only implement that
+func (c *Client) reflect(ctx context.Context, target string, headers Headers, field protowire.Number, value string) (*reflectionResponse, error) {
+	request := protowire.AppendTag(nil, field, protowire.BytesType)
+	request = protowire.AppendString(request, value)
+
+	var lastErr error
+	for _, method := range reflectionMethods {
+		var messages [][]byte
+		code, message, err := c.invokeGRPC(ctx, target, headers, method, request, func(msg []byte) {
+			messages = append(messages, msg)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if code == GRPCCodeUnimplemented {
+			lastErr = errors.New("server does not support reflection; use --proto or --protoset")
+			continue
+		}
+		if code != GRPCCodeOK {
+			return nil, fmt.Errorf("server reflection failed: %s: %s", GRPCCodeName(code), message)
+		}
+		if len(messages) == 0 {
+			return nil, errors.New("server reflection returned no response")
+		}
+		return parseReflectionResponse(messages[0])
+	}
+	return nil, lastErr
+}
+
+// invokeGRPC makes an unmeasured call, passing each response message to onMessage
+func (c *Client) invokeGRPC(ctx context.Context, target string, headers Headers, method string, message []byte, onMessage func([]byte)) (int, string, error) {
+	req, err := c.newGRPCRequest(ctx, target, headers, method, message)
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	defer io.Copy(io.Discard, resp.Body)
+	return readGRPCResponse(resp, func(msg []byte, _ int) { onMessage(msg) })
+}
+
+// parseReflectionResponse decodes a ServerReflectionResponse
+func parseReflectionResponse(b []byte) (*reflectionResponse, error) {
+	resp := &reflectionResponse{}
+	var errorResp []byte
+	err := consumeFields(b, func(num protowire.Number, value []byte) {
+		switch num {
+		case reflectFileDescriptorResp:
+			// FileDescriptorResponse.file_descriptor_proto is field 1
+			consumeFields(value, func(num protowire.Number, value []byte) {
+				if num == 1 {
+					resp.files = append(resp.files, value)
+				}
+			})
+		case reflectListServicesResp:
+			// ListServiceResponse.service is field 1
+			consumeFields(value, func(num protowire.Number, value []byte) {
+				if num == 1 {
+					resp.listServices = append(resp.listServices, value)
+				}
+			})
+		case reflectErrorResp:
+			errorResp = value
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid server reflection response: %w", err)
+	}
+
+	if errorResp != nil {
+		// ErrorResponse is error_code (1) and error_message (2)
+		var code uint64
+		var message string
+		b := errorResp
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				break
+			}
+			b = b[n:]
+			switch {
+			case num == 1 && typ == protowire.VarintType:
+				code, n = protowire.ConsumeVarint(b)
+			case num == 2 && typ == protowire.BytesType:
+				var v []byte
+				v, n = protowire.ConsumeBytes(b)
+				message = string(v)
+			default:
+				n = protowire.ConsumeFieldValue(num, typ, b)
+			}
+			if n < 0 {
+				break
+			}
+			b = b[n:]
+		}
+		return nil, fmt.Errorf("server reflection: %s: %s", GRPCCodeName(int(code)), message)
+	}
+	return resp, nil
+}
+
+// consumeFields calls fn for every length-delimited field of a message,
+// skipping fields of other wire types
+func consumeFields(b []byte, fn func(num protowire.Number, value []byte)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ == protowire.BytesType {
+			value, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fn(num, value)
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
@@ -0,0 +1,570 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// H2Trace is the frame-level view of the HTTP/2 connection a request ran on
+type H2Trace struct {
+	StreamID       uint32      `json:"stream_id"`
+	ClientSettings []H2Setting `json:"client_settings,omitempty"`
+	ServerSettings []H2Setting `json:"server_settings,omitempty"`
+	SettingsAck    Duration    `json:"settings_ack,omitempty"` // From our SETTINGS to the server's ACK
+	PingRTT        Duration    `json:"ping_rtt,omitempty"`     // PING sent after the response
+
+	DataFrames            int   `json:"data_frames"` // DATA frames received on the stream
+	DataBytes             int64 `json:"data_bytes"`
+	MaxDataFrame          int   `json:"max_data_frame"`
+	WindowUpdatesSent     int   `json:"window_updates_sent"`
+	WindowUpdatesReceived int   `json:"window_updates_received"`
+
+	Reset  string    `json:"rst_stream,omitempty"` // Error code of a RST_STREAM on the stream
+	GoAway *H2GoAway `json:"goaway,omitempty"`
+	Stalls []H2Stall `json:"stalls,omitempty"`
+	Frames []H2Frame `json:"frames"` // Connection and stream frames since the request started
+
+	data []messageArrival // DATA frames of the stream since the last hop started
+}
+
+// H2Setting is one SETTINGS parameter
+type H2Setting struct {
+	Name  string `json:"name"`
+	Value uint32 `json:"value"`
+}
+
+// H2GoAway is a GOAWAY frame sent by the server
+type H2GoAway struct {
+	LastStreamID uint32 `json:"last_stream_id"`
+	ErrorCode    string `json:"error_code"`
+	Debug        string `json:"debug,omitempty"`
+}
+
+// H2Frame is one frame of the traced connection
+type H2Frame struct {
+	At       Duration `json:"at"` // Offset from the start of the request
+	Sent     bool     `json:"sent"`
+	Type     string   `json:"type"`
+	StreamID uint32   `json:"stream_id"`
+	Flags    string   `json:"flags,omitempty"`
+	Length   int      `json:"length"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// H2Stall is a gap between DATA frames of the stream longer than the stall
+// threshold. Window is what flow control still allowed the server to send
+// when the gap began; with less than a full frame the stall is put down to
+// flow control, otherwise to the server.
+type H2Stall struct {
+	Start    Duration `json:"start"`
+	Duration Duration `json:"duration"`
+	Window   int64    `json:"window"`
+	Cause    string   `json:"cause"` // "flow-control" or "server"
+}
+
+// h2BlockedWindow is the receive window below which the server cannot send a
+// full frame of the default maximum size
+const h2BlockedWindow = 16384
+
+// h2MaxPayload bounds the payload kept per frame; only control frames are
+// decoded, and their interesting fields come first
+const h2MaxPayload = 256
+
+// h2RawFrame is a frame as it passed through the connection
+type h2RawFrame struct {
+	at      time.Time
+	sent    bool
+	typ     http2.FrameType
+	flags   http2.Flags
+	stream  uint32
+	length  int
+	payload []byte // At most h2MaxPayload bytes
+}
+
+// h2FrameParser decodes the frames of one direction of a connection as the
+// bytes pass through
+type h2FrameParser struct {
+	sent    bool
+	preface int    // Bytes of the client connection preface still to skip
+	header  []byte // Partial frame header
+	frame   h2RawFrame
+	left    int // Payload bytes of frame still to come
+}
+
+// feed consumes b, calling emit for every frame completed by it
+func (p *h2FrameParser) feed(b []byte, now time.Time, emit func(h2RawFrame)) {
+	for len(b) > 0 {
+		if p.preface > 0 {
+			n := min(p.preface, len(b))
+			p.preface -= n
+			b = b[n:]
+			continue
+		}
+		if p.left == 0 && len(p.header) < 9 {
+			n := min(9-len(p.header), len(b))
+			p.header = append(p.header, b[:n]...)
+			b = b[n:]
+			if len(p.header) < 9 {
+				return
+			}
+			h := p.header
+			p.frame = h2RawFrame{
+				sent:   p.sent,
+				typ:    http2.FrameType(h[3]),
+				flags:  http2.Flags(h[4]),
+				stream: binary.BigEndian.Uint32(h[5:9]) & (1<<31 - 1),
+				length: int(h[0])<<16 | int(h[1])<<8 | int(h[2]),
+			}
+			p.left = p.frame.length
+		}
+		n := min(p.left, len(b))
+		if keep := min(n, h2MaxPayload-len(p.frame.payload)); keep > 0 {
+			p.frame.payload = append(p.frame.payload, b[:keep]...)
+		}
+		p.left -= n
+		b = b[n:]
+		if p.left == 0 {
+			p.frame.at = now
+			emit(p.frame)
+			p.header = p.header[:0]
+		}
+	}
+}
+
+// h2FrameConn passes an HTTP/2 connection through while recording its frames
+type h2FrameConn struct {
+	net.Conn
+	mu     sync.Mutex
+	frames []h2RawFrame
+	in     h2FrameParser
+	out    h2FrameParser
+	cc     *http2.ClientConn
+}
+
+func newH2FrameConn(conn net.Conn) *h2FrameConn {
+	return &h2FrameConn{
+		Conn: conn,
+		in:   h2FrameParser{},
+		out:  h2FrameParser{sent: true, preface: len(http2.ClientPreface)},
+	}
+}
+
+func (c *h2FrameConn) record(f h2RawFrame) {
+	c.frames = append(c.frames, f)
+}
+
+func (c *h2FrameConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		now := time.Now()
+		c.mu.Lock()
+		c.in.feed(p[:n], now, c.record)
+		c.mu.Unlock()
+	}
+	return n, err
+}
+
+func (c *h2FrameConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		now := time.Now()
+		c.mu.Lock()
+		c.out.feed(p[:n], now, c.record)
+		c.mu.Unlock()
+	}
+	return n, err
+}
+
+// snapshot returns the frames recorded so far
+func (c *h2FrameConn) snapshot() []h2RawFrame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]h2RawFrame(nil), c.frames...)
+}
+
+// h2FrameTLSConn reports the TLS state of a traced connection to the HTTP/2
+// client, which takes it from the connection
+type h2FrameTLSConn struct {
+	*h2FrameConn
+	tls *tls.Conn
+}
+
+func (c *h2FrameTLSConn) ConnectionState() tls.ConnectionState {
+	return c.tls.ConnectionState()
+}
+
+// h2FrameLog keeps the traced connections of a client
+type h2FrameLog struct {
+	mu    sync.Mutex
+	conns []*h2FrameConn
+}
+
+func (l *h2FrameLog) add(c *h2FrameConn) {
+	l.mu.Lock()
+	l.conns = append(l.conns, c)
+	l.mu.Unlock()
+}
+
+// close closes the traced connections; the frames stay readable
+func (l *h2FrameLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range l.conns {
+		c.mu.Lock()
+		if c.cc != nil {
+			c.cc.Close()
+		}
+		c.mu.Unlock()
+	}
+}
+
+// erringH2RoundTripper reports a failed HTTP/2 setup to http.Transport,
+// which closes the connection and fails the request
+type erringH2RoundTripper struct{ err error }
+
+func (rt erringH2RoundTripper) RoundTripErr() error                             { return rt.err }
+func (rt erringH2RoundTripper) RoundTrip(*http.Request) (*http.Response, error) { return nil, rt.err }
+
+// h2TracedRoundTripper runs the requests http.Transport hands to a traced
+// connection. It has no Close, so the connection outlives the request
+// even without keep-alives and the PING can follow the response; the frame
+// log closes it.
+type h2TracedRoundTripper struct{ cc *http2.ClientConn }
+
+func (rt h2TracedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !rt.cc.CanTakeNewRequest() {
+		// Makes http.Transport drop the connection and dial another
+		return nil, h2NoCachedConnError{}
+	}
+	return rt.cc.RoundTrip(req)
+}
+
+// h2NoCachedConnError is recognised by http.Transport as a pooled HTTP/2
+// connection that can no longer be used
+type h2NoCachedConnError struct{}
+
+func (h2NoCachedConnError) IsHTTP2NoCachedConnError() {}
+func (h2NoCachedConnError) Error() string             { return "http2: no cached connection was available" }
+
+// traceH2Frames makes transport run HTTP/2 over connections that record their
+// frames. http.Transport still dials, tunnels and negotiates TLS, so the
+// connection phases are traced as usual; it hands every HTTP/2 connection to
+// an x/net client connection of its own.
+func traceH2Frames(transport *http.Transport, pinned bool) (*h2FrameLog, error) {
+	if _, err := http2.ConfigureTransports(transport); err != nil {
+		return nil, err
+	}
+	if pinned {
+		transport.TLSClientConfig.NextProtos = []string{http2.NextProtoTLS}
+	}
+	// Not linked to transport, which would make every connection single-use
+	// when keep-alives are disabled
+	t2 := &http2.Transport{
+		DisableCompression: transport.DisableCompression,
+		IdleConnTimeout:    transport.IdleConnTimeout,
+	}
+
+	log := &h2FrameLog{}
+	clientConn := func(conn net.Conn, fc *h2FrameConn) http.RoundTripper {
+		cc, err := t2.NewClientConn(conn)
+		if err != nil {
+			go conn.Close()
+			return erringH2RoundTripper{err}
+		}
+		fc.mu.Lock()
+		fc.cc = cc
+		fc.mu.Unlock()
+		log.add(fc)
+		return h2TracedRoundTripper{cc}
+	}
+	transport.TLSNextProto[http2.NextProtoTLS] = func(_ string, c *tls.Conn) http.RoundTripper {
+		fc := newH2FrameConn(c)
+		return clientConn(&h2FrameTLSConn{h2FrameConn: fc, tls: c}, fc)
+	}
+	// Prior-knowledge h2c arrives wrapped in a *tls.Conn that carries no TLS
+	transport.TLSNextProto["unencrypted_http2"] = func(_ string, c *tls.Conn) http.RoundTripper {
+		plain, ok := c.NetConn().(interface{ UnencryptedNetConn() net.Conn })
+		if !ok {
+			go c.Close()
+			return erringH2RoundTripper{fmt.Errorf("h2c connection of unexpected type %T", c.NetConn())}
+		}
+		fc := newH2FrameConn(plain.UnencryptedNetConn())
+		return clientConn(fc, fc)
+	}
+	return log, nil
+}
+
+// h2Trace builds the frame trace of the request timed by tracer: the stream
+// whose HEADERS were sent last since the request started. Tracing is meant
+// for one request at a time. A PING is sent once the response is complete to
+// measure the round trip. Returns nil when no HTTP/2 stream was traced.
+func (c *Client) h2Trace(ctx context.Context, tracer *Tracer) *H2Trace {
+	if c.frames == nil {
+		return nil
+	}
+	start, hopStart := tracer.startTimes()
+
+	var conn *h2FrameConn
+	var headers h2RawFrame
+	c.frames.mu.Lock()
+	conns := append([]*h2FrameConn(nil), c.frames.conns...)
+	c.frames.mu.Unlock()
+	for _, fc := range conns {
+		for _, f := range fc.snapshot() {
+			if f.sent && f.typ == http2.FrameHeaders && !f.at.Before(hopStart) && f.at.After(headers.at) {
+				conn, headers = fc, f
+			}
+		}
+	}
+	if conn == nil {
+		return nil
+	}
+
+	trace := &H2Trace{StreamID: headers.stream}
+	conn.mu.Lock()
+	cc := conn.cc
+	conn.mu.Unlock()
+	if cc != nil {
+		timeout := c.config.Timeout
+		if timeout <= 0 || timeout > 5*time.Second {
+			timeout = 5 * time.Second
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		pingStart := time.Now()
+		if err := cc.Ping(pingCtx); err == nil {
+			trace.PingRTT = Duration(time.Since(pingStart))
+		}
+		cancel()
+	}
+
+	summarizeH2Frames(trace, conn.snapshot(), start, hopStart, c.stallThreshold())
+	// Without keep-alives the connections are done with once traced
+	if c.config.DisableKeepAlive {
+		c.frames.close()
+	}
+	return trace
+}
+
+// summarizeH2Frames fills trace from the frames of its connection. The whole
+// connection history is replayed to track the flow-control windows, but only
+// frames of the stream and the connection since hopStart are listed.
+func summarizeH2Frames(trace *H2Trace, frames []h2RawFrame, start, hopStart time.Time, threshold time.Duration) {
+	stream := trace.StreamID
+	connWindow, streamWindow := int64(65535), int64(65535)
+	var settingsSent time.Time
+	// The response's last frame, the window left when it arrived, and when a
+	// WINDOW_UPDATE of ours next let the server send a full frame again
+	var lastData, unblocked time.Time
+	var gapWindow int64
+
+	for _, f := range frames {
+		ours := f.stream == stream
+		// DATA of other streams still uses up the connection window
+		if f.stream != 0 && !ours && (f.sent || f.typ != http2.FrameData) {
+			continue
+		}
+
+		switch f.typ {
+		case http2.FrameSettings:
+			if f.flags.Has(http2.FlagSettingsAck) {
+				if !f.sent && !settingsSent.IsZero() && trace.SettingsAck == 0 {
+					trace.SettingsAck = Duration(f.at.Sub(settingsSent))
+				}
+				break
+			}
+			settings := parseH2Settings(f.payload)
+			if f.sent {
+				if settingsSent.IsZero() {
+					settingsSent = f.at
+				}
+				trace.ClientSettings = append(trace.ClientSettings, settings...)
+				// Our initial window is the server's send window for the stream
+				for _, s := range settings {
+					if s.Name == http2.SettingInitialWindowSize.String() {
+						streamWindow = int64(s.Value)
+					}
+				}
+			} else {
+				trace.ServerSettings = append(trace.ServerSettings, settings...)
+			}
+
+		case http2.FrameWindowUpdate:
+			if len(f.payload) < 4 {
+				break
+			}
+			increment := int64(binary.BigEndian.Uint32(f.payload) & (1<<31 - 1))
+			if !f.sent {
+				trace.WindowUpdatesReceived++
+				break
+			}
+			trace.WindowUpdatesSent++
+			if f.stream == 0 {
+				connWindow += increment
+			} else {
+				streamWindow += increment
+			}
+			if !lastData.IsZero() && unblocked.IsZero() && min(connWindow, streamWindow) >= h2BlockedWindow {
+				unblocked = f.at
+			}
+
+		case http2.FrameData:
+			if f.sent {
+				break
+			}
+			connWindow -= int64(f.length)
+			if !ours {
+				break
+			}
+			streamWindow -= int64(f.length)
+			size := h2DataSize(f)
+			trace.DataFrames++
+			trace.DataBytes += int64(size)
+			trace.MaxDataFrame = max(trace.MaxDataFrame, size)
+			if size > 0 && !f.at.Before(hopStart) {
+				trace.data = append(trace.data, messageArrival{size: size, at: f.at})
+			}
+
+		case http2.FrameRSTStream:
+			if ours && len(f.payload) >= 4 {
+				trace.Reset = http2.ErrCode(binary.BigEndian.Uint32(f.payload)).String()
+			}
+
+		case http2.FrameGoAway:
+			if !f.sent && len(f.payload) >= 8 {
+				trace.GoAway = &H2GoAway{
+					LastStreamID: binary.BigEndian.Uint32(f.payload) & (1<<31 - 1),
+					ErrorCode:    http2.ErrCode(binary.BigEndian.Uint32(f.payload[4:])).String(),
+					Debug:        string(f.payload[8:]),
+				}
+			}
+		}
+
+		// Gaps in the response are measured from its HEADERS to the first
+		// DATA frame and between DATA frames. A gap is put down to flow
+		// control while the window was too small for a full frame, unless
+		// the server still took the stall threshold to send once it had
+		// room again.
+		if ours && !f.sent && (f.typ == http2.FrameHeaders || f.typ == http2.FrameData) {
+			if f.typ == http2.FrameData && !lastData.IsZero() {
+				if gap := f.at.Sub(lastData); gap >= threshold {
+					cause := "server"
+					if gapWindow < h2BlockedWindow && (unblocked.IsZero() || f.at.Sub(unblocked) < threshold) {
+						cause = "flow-control"
+					}
+					trace.Stalls = append(trace.Stalls, H2Stall{
+						Start:    Duration(lastData.Sub(start)),
+						Duration: Duration(gap),
+						Window:   gapWindow,
+						Cause:    cause,
+					})
+				}
+			}
+			lastData = f.at
+			gapWindow = min(connWindow, streamWindow)
+			unblocked = time.Time{}
+		}
+
+		if !f.at.Before(hopStart) && (ours || f.stream == 0) {
+			trace.Frames = append(trace.Frames, H2Frame{
+				At:       Duration(f.at.Sub(start)),
+				Sent:     f.sent,
+				Type:     f.typ.String(),
+				StreamID: f.stream,
+				Flags:    h2FlagNames(f.typ, f.flags),
+				Length:   f.length,
+				Detail:   h2FrameDetail(f),
+			})
+		}
+	}
+}
+
+// h2DataSize is the payload of a DATA frame without its padding
+func h2DataSize(f h2RawFrame) int {
+	if f.flags.Has(http2.FlagDataPadded) && len(f.payload) > 0 {
+		return max(f.length-1-int(f.payload[0]), 0)
+	}
+	return f.length
+}
+
+// h2SettingNames names settings defined after RFC 7540, which x/net reports
+// as unknown
+var h2SettingNames = map[http2.SettingID]string{
+	0x8: "ENABLE_CONNECT_PROTOCOL", // RFC 8441
+	0x9: "NO_RFC7540_PRIORITIES",   // RFC 9218
+}
+
+// parseH2Settings decodes the parameters of a SETTINGS frame
+func parseH2Settings(payload []byte) []H2Setting {
+	var settings []H2Setting
+	for ; len(payload) >= 6; payload = payload[6:] {
+		id := http2.SettingID(binary.BigEndian.Uint16(payload))
+		name, ok := h2SettingNames[id]
+		if !ok {
+			name = id.String()
+		}
+		settings = append(settings, H2Setting{Name: name, Value: binary.BigEndian.Uint32(payload[2:])})
+	}
+	return settings
+}
+
+// h2FlagNames names the flags defined for a frame type
+func h2FlagNames(typ http2.FrameType, flags http2.Flags) string {
+	var names []string
+	add := func(flag http2.Flags, name string) {
+		if flags.Has(flag) {
+			names = append(names, name)
+		}
+	}
+	switch typ {
+	case http2.FrameData:
+		add(http2.FlagDataEndStream, "END_STREAM")
+		add(http2.FlagDataPadded, "PADDED")
+	case http2.FrameHeaders:
+		add(http2.FlagHeadersEndStream, "END_STREAM")
+		add(http2.FlagHeadersEndHeaders, "END_HEADERS")
+		add(http2.FlagHeadersPadded, "PADDED")
+		add(http2.FlagHeadersPriority, "PRIORITY")
+	case http2.FrameSettings:
+		add(http2.FlagSettingsAck, "ACK")
+	case http2.FramePing:
+		add(http2.FlagPingAck, "ACK")
+	case http2.FrameContinuation:
+		add(http2.FlagContinuationEndHeaders, "END_HEADERS")
+	}
+	return strings.Join(names, "|")
+}
+
+// h2FrameDetail describes the decoded fields of a control frame
+func h2FrameDetail(f h2RawFrame) string {
+	switch f.typ {
+	case http2.FrameSettings:
+		var parts []string
+		for _, s := range parseH2Settings(f.payload) {
+			parts = append(parts, fmt.Sprintf("%s=%d", s.Name, s.Value))
+		}
+		return strings.Join(parts, " ")
+	case http2.FrameWindowUpdate:
+		if len(f.payload) >= 4 {
+			return fmt.Sprintf("+%d", binary.BigEndian.Uint32(f.payload)&(1<<31-1))
+		}
+	case http2.FrameRSTStream:
+		if len(f.payload) >= 4 {
+			return http2.ErrCode(binary.BigEndian.Uint32(f.payload)).String()
+		}
+	case http2.FrameGoAway:
+		if len(f.payload) >= 8 {
+			return fmt.Sprintf("last stream %d, %s", binary.BigEndian.Uint32(f.payload)&(1<<31-1),
+				http2.ErrCode(binary.BigEndian.Uint32(f.payload[4:])))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,213 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+func TestH2FrameParser(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString(http2.ClientPreface)
+	fr := http2.NewFramer(&buf, nil)
+	fr.WriteSettings(http2.Setting{ID: http2.SettingInitialWindowSize, Val: 1 << 20})
+	fr.WriteWindowUpdate(0, 1000)
+	fr.WriteData(1, true, bytes.Repeat([]byte("x"), 1000))
+	wire := buf.Bytes()
+
+	// Frames split across writes are put back together
+	for _, step := range []int{len(wire), 7, 1} {
+		p := h2FrameParser{sent: true, preface: len(http2.ClientPreface)}
+		var frames []h2RawFrame
+		for b := wire; len(b) > 0; {
+			n := min(step, len(b))
+			p.feed(b[:n], time.Now(), func(f h2RawFrame) { frames = append(frames, f) })
+			b = b[n:]
+		}
+		if len(frames) != 3 {
+			t.Fatalf("step %d: expected 3 frames, got %d", step, len(frames))
+		}
+		if frames[0].typ != http2.FrameSettings || frames[1].typ != http2.FrameWindowUpdate || frames[2].typ != http2.FrameData {
+			t.Errorf("step %d: unexpected frame types %v %v %v", step, frames[0].typ, frames[1].typ, frames[2].typ)
+		}
+		if s := parseH2Settings(frames[0].payload); len(s) != 1 || s[0].Name != "INITIAL_WINDOW_SIZE" || s[0].Value != 1<<20 {
+			t.Errorf("step %d: unexpected settings %+v", step, s)
+		}
+		data := frames[2]
+		if data.stream != 1 || data.length != 1000 || len(data.payload) != h2MaxPayload || !data.flags.Has(http2.FlagDataEndStream) {
+			t.Errorf("step %d: unexpected DATA frame %+v", step, data)
+		}
+	}
+}
+
+func TestSummarizeH2Frames(t *testing.T) {
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	frame := func(ms int, sent bool, typ http2.FrameType, stream uint32, length int, payload ...byte) h2RawFrame {
+		return h2RawFrame{at: at(ms), sent: sent, typ: typ, stream: stream, length: length, payload: payload}
+	}
+	update := func(ms int, stream uint32, increment byte) h2RawFrame {
+		return frame(ms, true, http2.FrameWindowUpdate, stream, 4, 0, 0, increment, 0)
+	}
+
+	settingsAck := frame(6, false, http2.FrameSettings, 0, 0)
+	settingsAck.flags = http2.FlagSettingsAck
+	frames := []h2RawFrame{
+		frame(0, true, http2.FrameSettings, 0, 6, 0, 4, 0, 0, 0x80, 0), // INITIAL_WINDOW_SIZE=32768
+		frame(1, true, http2.FrameHeaders, 1, 20),
+		frame(5, false, http2.FrameSettings, 0, 6, 0, 3, 0, 0, 0, 100), // MAX_CONCURRENT_STREAMS=100
+		settingsAck,
+		frame(10, false, http2.FrameHeaders, 1, 30),
+		frame(20, false, http2.FrameData, 1, 16384),
+		frame(21, false, http2.FrameData, 3, 100), // Another stream, left out of the list
+		frame(22, false, http2.FrameData, 1, 16384),
+		// The stream window is used up until we send an update at 400ms
+		update(400, 1, 0x80),
+		frame(420, false, http2.FrameData, 1, 1000),
+		// The window is open, so this gap is the server's
+		frame(800, false, http2.FrameData, 1, 1000),
+		frame(810, false, http2.FrameRSTStream, 1, 4, 0, 0, 0, 8),
+	}
+
+	trace := &H2Trace{StreamID: 1}
+	summarizeH2Frames(trace, frames, start, start, 100*time.Millisecond)
+
+	if trace.SettingsAck != Duration(6*time.Millisecond) {
+		t.Errorf("Expected SETTINGS ACK after 6ms, got %v", trace.SettingsAck)
+	}
+	if len(trace.ClientSettings) != 1 || trace.ClientSettings[0].Value != 32768 || len(trace.ServerSettings) != 1 {
+		t.Errorf("Unexpected settings %+v / %+v", trace.ClientSettings, trace.ServerSettings)
+	}
+	if trace.DataFrames != 4 || trace.DataBytes != 34768 || trace.MaxDataFrame != 16384 || trace.WindowUpdatesSent != 1 {
+		t.Errorf("Unexpected DATA summary %+v", trace)
+	}
+	if trace.Reset != "CANCEL" {
+		t.Errorf("Expected RST_STREAM CANCEL, got %q", trace.Reset)
+	}
+	if len(trace.Frames) != len(frames)-1 {
+		t.Errorf("Expected %d frames listed, got %d", len(frames)-1, len(trace.Frames))
+	}
+	if len(trace.data) != 4 || !trace.data[2].at.Equal(at(420)) {
+		t.Errorf("Unexpected DATA arrivals %+v", trace.data)
+	}
+
+	if len(trace.Stalls) != 2 {
+		t.Fatalf("Expected 2 stalls, got %+v", trace.Stalls)
+	}
+	if s := trace.Stalls[0]; s.Cause != "flow-control" || s.Window != 0 || s.Start != Duration(22*time.Millisecond) {
+		t.Errorf("Expected a flow-control stall at 22ms, got %+v", s)
+	}
+	if s := trace.Stalls[1]; s.Cause != "server" || s.Duration != Duration(380*time.Millisecond) {
+		t.Errorf("Expected a 380ms server stall, got %+v", s)
+	}
+}
+
+// newH2CServer starts an h2c server that writes chunks of a body interval
+// apart, flushing each
+func newH2CServer(t *testing.T, chunks int, interval time.Duration) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < chunks; i++ {
+			if i > 0 {
+				time.Sleep(interval)
+			}
+			fmt.Fprintf(w, "chunk %d\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMeasureRequestWithH2Trace(t *testing.T) {
+	srv := newH2CServer(t, 4, 150*time.Millisecond)
+	c := NewClient(&Config{Timeout: 5 * time.Second, Protocol: ProtocolHTTP2, H2Trace: true, StallThreshold: 100 * time.Millisecond})
+	defer c.Close()
+
+	timing, streamMetrics, err := c.MeasureRequestWithStreaming(context.Background(), srv.URL, "GET", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := timing.H2
+	if trace == nil {
+		t.Fatal("Expected an HTTP/2 frame trace")
+	}
+	if trace.StreamID != 1 || streamMetrics.StreamID != 1 {
+		t.Errorf("Expected stream 1, got %d (stream metrics %d)", trace.StreamID, streamMetrics.StreamID)
+	}
+	if len(trace.ClientSettings) == 0 || len(trace.ServerSettings) == 0 || trace.PingRTT <= 0 {
+		t.Errorf("Expected settings and a PING RTT, got %+v", trace)
+	}
+	if trace.DataBytes != 32 || len(trace.Stalls) != 3 {
+		t.Fatalf("Expected 32 bytes with 3 stalls, got %d bytes, stalls %+v", trace.DataBytes, trace.Stalls)
+	}
+	for _, stall := range trace.Stalls {
+		if stall.Cause != "server" {
+			t.Errorf("Expected the server to cause the stall, got %+v", stall)
+		}
+	}
+
+	// Chunks are the DATA frames that carried the body
+	if streamMetrics.TotalChunks != len(trace.data) || streamMetrics.TotalBytes != 32 {
+		t.Errorf("Expected a chunk per DATA frame, got %d chunks for %d frames", streamMetrics.TotalChunks, trace.DataFrames)
+	}
+	if len(streamMetrics.Stalls) != 3 {
+		t.Errorf("Expected 3 chunk stalls, got %+v", streamMetrics.Stalls)
+	}
+
+	// A second request on the connection is traced by MeasureRequest too
+	timing, err = c.MeasureRequest(srv.URL, "GET", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing.H2 == nil || timing.H2.StreamID != 3 || timing.H2.DataBytes != 32 {
+		t.Errorf("Expected a trace of the second request, got %+v", timing.H2)
+	}
+}
+
+func TestH2TraceTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	// Single requests run without keep-alives, which must not close the
+	// connection before the PING
+	c := NewClient(&Config{Timeout: 5 * time.Second, Insecure: true, H2Trace: true, DisableKeepAlive: true})
+	defer c.Close()
+	timing, err := c.MeasureRequest(srv.URL, "GET", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing.Protocol != "HTTP/2.0" || timing.TLSHandshake <= 0 {
+		t.Errorf("Expected a traced TLS handshake over HTTP/2, got %s, TLS %v", timing.Protocol, timing.TLSHandshake)
+	}
+	if timing.H2 == nil || timing.H2.DataBytes != 2 || timing.H2.PingRTT <= 0 {
+		t.Errorf("Expected a frame trace with a PING RTT, got %+v", timing.H2)
+	}
+}
+
+func TestH2TraceHTTP1(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	c := NewClient(&Config{Timeout: 5 * time.Second, H2Trace: true})
+	defer c.Close()
+	timing, err := c.MeasureRequest(srv.URL, "GET", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing.H2 != nil {
+		t.Errorf("An HTTP/1.1 request has no frame trace, got %+v", timing.H2)
+	}
+}
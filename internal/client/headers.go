@@ -0,0 +1,128 @@
+package client
+
+import (
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+)
+
+// Header is a single header field. Repeated fields are kept as separate
+// entries, in the order they were given or received.
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Remove drops the header, including defaults such as User-Agent (-H 'Name:')
+	Remove bool `json:"remove,omitempty"`
+}
+
+// Headers is an ordered list of header fields
+type Headers []Header
+
+// ParseHeaders converts a slice of -H values into ordered headers, following
+// curl: "Name: value" adds a field, "Name:" removes the header (defaults
+// included) and "Name;" sends it with an empty value
+func ParseHeaders(headerSlice []string) Headers {
+	headers := make(Headers, 0, len(headerSlice))
+	for _, h := range headerSlice {
+		if name, value, ok := strings.Cut(h, ":"); ok {
+			name = strings.TrimSpace(name)
+			value = strings.TrimSpace(value)
+			if name == "" || strings.ContainsAny(name, " \t") {
+				continue
+			}
+			headers = append(headers, Header{Name: name, Value: value, Remove: value == ""})
+			continue
+		}
+
+		if name, ok := strings.CutSuffix(strings.TrimSpace(h), ";"); ok {
+			name = strings.TrimSpace(name)
+			if name != "" && !strings.ContainsAny(name, " \t") {
+				headers = append(headers, Header{Name: name})
+			}
+		}
+	}
+	return headers
+}
+
+// Get returns the first value of the named header (case-insensitive)
+func (h Headers) Get(name string) string {
+	for _, field := range h {
+		if !field.Remove && strings.EqualFold(field.Name, name) {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Values returns every value of the named header in order
+func (h Headers) Values(name string) []string {
+	var values []string
+	for _, field := range h {
+		if !field.Remove && strings.EqualFold(field.Name, name) {
+			values = append(values, field.Value)
+		}
+	}
+	return values
+}
+
+// Removes reports whether the named header is removed (-H 'Name:')
+func (h Headers) Removes(name string) bool {
+	for _, field := range h {
+		if field.Remove && strings.EqualFold(field.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Del returns the headers without any field of the given name
+func (h Headers) Del(name string) Headers {
+	headers := make(Headers, 0, len(h))
+	for _, field := range h {
+		if !strings.EqualFold(field.Name, name) {
+			headers = append(headers, field)
+		}
+	}
+	return headers
+}
+
+// apply sets the headers on req. Host overrides the request's Host rather
+// than being added as a field, which net/http would ignore. A removed header
+// is left present but empty so net/http does not add its own default; the
+// transport's gzip is the exception and needs Config.NoCompression.
+func (h Headers) apply(req *http.Request) {
+	for _, field := range h {
+		key := textproto.CanonicalMIMEHeaderKey(field.Name)
+		switch {
+		case key == "Host" && !field.Remove:
+			req.Host = field.Value
+		case key == "Host":
+			// There is always a Host; removing it means "use the URL's"
+			req.Host = ""
+		case field.Remove:
+			req.Header[key] = nil
+		default:
+			req.Header.Add(key, field.Value)
+		}
+	}
+}
+
+// headersFromResponse converts response headers into ordered fields. net/http
+// does not keep the wire order across names, so names are sorted; values of
+// a repeated header (e.g. Set-Cookie) keep their order and are not joined.
+func headersFromResponse(header http.Header) Headers {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	headers := make(Headers, 0, len(header))
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, Header{Name: name, Value: value})
+		}
+	}
+	return headers
+}
@@ -7,7 +7,6 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"net/http/httptrace"
 	"strings"
 	"time"
 
@@ -21,8 +20,9 @@ type HTTPClient interface {
 
 // Client wraps the standard HTTP client with performance measurement capabilities
 type Client struct {
-	client  *http.Client
-	config  *Config
+	client *http.Client
+	config *Config
+	frames *h2FrameLog // HTTP/2 connections traced with H2Trace
 }
 
 // Config contains configuration for the HTTP client
@@ -38,14 +38,43 @@ type Config struct {
 	ResolveMap       map[string]string // "host:port" -> "ip"
 	ConnectToMap     map[string]string // "host:port" -> "newhost:newport"
 	StallThreshold   time.Duration     // Threshold for detecting stalls
+	Protocol         string            // Pin the HTTP version: "" (negotiate), ProtocolHTTP1, ProtocolHTTP2 or ProtocolHTTP3
+	TLSConfig        *tls.Config       // Base TLS configuration (see BuildTLSConfig); nil uses defaults
+	Resolver         *net.Resolver     // Custom DNS resolver (see NewResolver); nil uses the system resolver
+	IPVersion        int               // Restrict connections to IPv4 (4) or IPv6 (6); 0 allows both
+	Proxy            string            // Proxy URL (http, https, socks5, socks5h); empty uses HTTP(S)_PROXY
+	ProxyUser        string            // "user:password" for proxy authentication
+	NoProxy          string            // Comma-separated hosts that bypass the proxy; overrides NO_PROXY
+	MaxRedirects     int               // Redirects to follow; 0 uses DefaultMaxRedirects
+	NoFollow         bool              // Return redirect responses instead of following them
+	CookieJar        http.CookieJar    // Cookies kept across requests (see CookieJar); nil disables cookies
+	Auth             Authenticator     // Adds credentials to every request (see NewAuthenticator)
+	AcceptEncoding   []string          // Encodings to request and decode ourselves; nil lets the transport handle gzip
+	NoCompression    bool              // Keep the transport from asking for gzip, e.g. when -H removes Accept-Encoding
+	StreamFormat     string            // Decode model output deltas of this format (see StreamFormats); empty disables
+	H2Trace          bool              // Record the HTTP/2 frames of each request (see H2Trace)
 }
 
+// DefaultMaxRedirects is the number of redirects followed when Config.MaxRedirects is 0
+const DefaultMaxRedirects = 10
+
+// DefaultUserAgent is sent unless the request sets or removes User-Agent
+const DefaultUserAgent = "gocurl/1.0"
+
+// Protocol identifiers accepted by Config.Protocol
+const (
+	ProtocolHTTP1 = "http1.1"
+	ProtocolHTTP2 = "http2"
+	ProtocolHTTP3 = "http3"
+)
+
 // NewClient creates a new HTTP client with the specified configuration
 func NewClient(config *Config) *Client {
 	// Create default dialer
 	dialer := &net.Dialer{
 		Timeout:   30 * time.Second,
 		KeepAlive: 30 * time.Second,
+		Resolver:  config.Resolver,
 	}
 
 	transport := &http.Transport{
@@ -53,64 +82,185 @@ func NewClient(config *Config) *Client {
 		MaxIdleConnsPerHost: config.MaxIdlePerHost,
 		IdleConnTimeout:     90 * time.Second,
 		DisableKeepAlives:   config.DisableKeepAlive,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: config.Insecure,
-		},
+		TLSClientConfig:     newTLSClientConfig(config),
+		// Decoding is done by MeasureRequest so wire and decoded sizes can be told apart
+		DisableCompression: config.NoCompression || len(config.AcceptEncoding) > 0,
 	}
 
-	// Set up custom DialContext if --resolve or --connect-to are used
-	if len(config.ConnectToMap) > 0 || len(config.ResolveMap) > 0 {
-		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-			// Check --connect-to mappings first
-			if newAddr, ok := config.ConnectToMap[addr]; ok {
-				// Connect to different host:port
-				return dialer.DialContext(ctx, network, newAddr)
-			}
+	configureProxy(config, transport)
 
-			// Check --resolve mappings
-			if ip, ok := config.ResolveMap[addr]; ok {
-				// Extract port from addr
-				host, port, err := net.SplitHostPort(addr)
-				if err != nil {
-					return nil, fmt.Errorf("failed to parse address %s: %w", addr, err)
-				}
-				_ = host // host is replaced with ip from resolve map
+	// Apply --resolve and --connect-to mappings before dialing
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialAddr, err := mapDialAddress(config, addr)
+		if err != nil {
+			return nil, err
+		}
+		network = restrictNetwork(network, config.IPVersion)
 
-				// Connect to resolved IP with original port
-				resolvedAddr := net.JoinHostPort(ip, port)
-				return dialer.DialContext(ctx, network, resolvedAddr)
-			}
+		socksURL, err := socksProxyFor(ctx, config, addr)
+		if err != nil {
+			return nil, err
+		}
+		if socksURL != nil {
+			return dialSOCKS(ctx, config, dialer, network, socksURL, dialAddr)
+		}
+		return dialer.DialContext(ctx, network, dialAddr)
+	}
 
-			// No mapping found, use default dialer
-			return dialer.DialContext(ctx, network, addr)
+	var roundTripper http.RoundTripper = transport
+	var frames *h2FrameLog
+
+	switch config.Protocol {
+	case ProtocolHTTP1:
+		// Restrict ALPN to HTTP/1.1
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		transport.Protocols = protocols
+	case ProtocolHTTP2:
+		// Require HTTP/2, using prior knowledge (h2c) for cleartext URLs
+		protocols := new(http.Protocols)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		transport.Protocols = protocols
+		if config.H2Trace {
+			frames, _ = traceH2Frames(transport, true)
+		}
+	case ProtocolHTTP3:
+		roundTripper = newHTTP3Transport(config, transport.TLSClientConfig)
+	default:
+		// Enable HTTP/2 support
+		if config.H2Trace {
+			frames, _ = traceH2Frames(transport, false)
+		} else {
+			http2.ConfigureTransport(transport)
 		}
 	}
 
-	// Enable HTTP/2 support
-	http2.ConfigureTransport(transport)
+	if config.Auth != nil {
+		roundTripper = &authTransport{base: roundTripper, auth: config.Auth}
+	}
 
 	return &Client{
 		client: &http.Client{
-			Transport: transport,
+			Transport: roundTripper,
 			Timeout:   config.Timeout,
+			Jar:       config.CookieJar,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
+				maxRedirects := config.MaxRedirects
+				if maxRedirects <= 0 {
+					maxRedirects = DefaultMaxRedirects
+				}
+				// Stop here and report the redirect response itself
+				if config.NoFollow {
 					return http.ErrUseLastResponse
 				}
+				// Like curl's --max-redirs, running out of redirects is a failure
+				if len(via) > maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				if tracer := tracerFromContext(req.Context()); tracer != nil && req.Response != nil {
+					tracer.nextHop(req.Response)
+				}
 				return nil
 			},
 		},
 		config: config,
+		frames: frames,
+	}
+}
+
+// restrictNetwork narrows "tcp" or "udp" to a single address family for -4/-6
+func restrictNetwork(network string, ipVersion int) string {
+	switch ipVersion {
+	case 4:
+		return network + "4"
+	case 6:
+		return network + "6"
+	default:
+		return network
+	}
+}
+
+// newTLSClientConfig derives the transport's TLS configuration from Config
+func newTLSClientConfig(config *Config) *tls.Config {
+	tlsConfig := &tls.Config{}
+	if config.TLSConfig != nil {
+		tlsConfig = config.TLSConfig.Clone()
+	}
+	tlsConfig.InsecureSkipVerify = tlsConfig.InsecureSkipVerify || config.Insecure
+
+	// Select the client certificate ourselves so the tracer can report
+	// whether the server actually requested it
+	if len(tlsConfig.Certificates) > 0 && tlsConfig.GetClientCertificate == nil {
+		certs := tlsConfig.Certificates
+		tlsConfig.GetClientCertificate = func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if tracer := tracerFromContext(info.Context()); tracer != nil {
+				tracer.markClientCertSent()
+			}
+			for i := range certs {
+				if info.SupportsCertificate(&certs[i]) == nil {
+					return &certs[i], nil
+				}
+			}
+			// Send the first certificate anyway and let the server decide
+			return &certs[0], nil
+		}
+	}
+
+	return tlsConfig
+}
+
+// mapDialAddress applies --connect-to and --resolve mappings to a dial address
+func mapDialAddress(config *Config, addr string) (string, error) {
+	// Check --connect-to mappings first
+	if newAddr, ok := config.ConnectToMap[addr]; ok {
+		// Connect to different host:port
+		return newAddr, nil
+	}
+
+	// Check --resolve mappings
+	if ip, ok := config.ResolveMap[addr]; ok {
+		// Extract port from addr; host is replaced with ip from resolve map
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse address %s: %w", addr, err)
+		}
+
+		// Connect to resolved IP with original port
+		return net.JoinHostPort(ip, port), nil
+	}
+
+	// No mapping found
+	return addr, nil
+}
+
+// Close releases idle connections and any transport-level resources
+func (c *Client) Close() {
+	c.client.CloseIdleConnections()
+	if c.frames != nil {
+		c.frames.close()
+	}
+	if closer, ok := c.client.Transport.(io.Closer); ok {
+		closer.Close()
 	}
 }
 
+// WithCookieJar returns a client that shares c's transport and connection pool
+// but keeps its own cookies, e.g. one session per load-test worker. Only the
+// original client should be closed.
+func (c *Client) WithCookieJar(jar http.CookieJar) *Client {
+	httpClient := *c.client
+	httpClient.Jar = jar
+	return &Client{client: &httpClient, config: c.config, frames: c.frames}
+}
+
 // Do executes an HTTP request with timing measurement
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
 	return c.client.Do(req)
 }
 
 // MeasureRequest executes a single HTTP request and captures detailed timing information
-func (c *Client) MeasureRequest(url, method string, headers map[string]string, body io.Reader) (*TimingBreakdown, error) {
+func (c *Client) MeasureRequest(url, method string, headers Headers, body io.Reader) (*TimingBreakdown, error) {
 	tracer := NewTracer()
 
 	// Create request
@@ -119,19 +269,11 @@ func (c *Client) MeasureRequest(url, method string, headers map[string]string, b
 		return nil, err
 	}
 
-	// Add custom headers
-	for key, value := range headers {
-		req.Header.Set(key, value)
-	}
-
-	// Set default User-Agent if not provided
-	if req.Header.Get("User-Agent") == "" {
-		req.Header.Set("User-Agent", "gocurl/1.0")
-	}
+	headers.apply(req)
+	c.setDefaultHeaders(req)
 
 	// Attach the tracer to the request context
-	ctx := httptrace.WithClientTrace(req.Context(), tracer.ClientTrace())
-	req = req.WithContext(ctx)
+	req = req.WithContext(tracer.WithContext(req.Context()))
 
 	// Start timing and execute request
 	tracer.Start()
@@ -140,38 +282,36 @@ func (c *Client) MeasureRequest(url, method string, headers map[string]string, b
 		tracer.End()
 		timing := tracer.Timing()
 		timing.Error = err.Error()
+		timing.H2 = c.h2Trace(req.Context(), tracer)
 		return timing, err
 	}
 	defer resp.Body.Close()
 
 	// Capture response headers if requested
 	if c.config.IncludeHeaders {
-		timing := tracer.Timing()
-		timing.ResponseHeaders = make(map[string]string)
-		for key, values := range resp.Header {
-			// Join multiple values with comma (per HTTP spec)
-			timing.ResponseHeaders[key] = values[0]
-			if len(values) > 1 {
-				for i := 1; i < len(values); i++ {
-					timing.ResponseHeaders[key] += ", " + values[i]
-				}
-			}
-		}
+		tracer.Timing().ResponseHeaders = headersFromResponse(resp.Header)
 	}
 
-	// Read the response body
+	// Read the response body, decoding it if we asked for compression
 	var written int64
 	var bodyBytes []byte
+	var reader io.Reader = resp.Body
+
+	decoder := newDecodingReader(resp, resp.Body)
+	if decoder != nil {
+		defer decoder.Close()
+		reader = decoder
+	}
 
 	shouldCaptureBody := c.config.ShowBody || (c.config.ShowErrorBody && resp.StatusCode >= 400)
 
 	if shouldCaptureBody {
 		// Read body into memory
-		bodyBytes, err = io.ReadAll(resp.Body)
+		bodyBytes, err = io.ReadAll(reader)
 		written = int64(len(bodyBytes))
 	} else {
 		// Discard body
-		written, err = io.Copy(io.Discard, resp.Body)
+		written, err = io.Copy(io.Discard, reader)
 	}
 
 	tracer.End()
@@ -179,8 +319,15 @@ func (c *Client) MeasureRequest(url, method string, headers map[string]string, b
 	// Populate response information
 	timing := tracer.Timing()
 	timing.StatusCode = resp.StatusCode
+	timing.Protocol = resp.Proto
 	timing.ContentLength = resp.ContentLength
 	timing.ResponseSize = written
+	recordCookies(timing, resp)
+	if decoder != nil {
+		decoder.apply(timing)
+	}
+	recordFinalURL(timing, resp)
+	timing.H2 = c.h2Trace(req.Context(), tracer)
 
 	if shouldCaptureBody && len(bodyBytes) > 0 {
 		timing.ResponseBody = string(bodyBytes)
@@ -193,18 +340,15 @@ func (c *Client) MeasureRequest(url, method string, headers map[string]string, b
 	return timing, nil
 }
 
-// ParseHeaders converts a slice of "key: value" strings into a map
-func ParseHeaders(headerSlice []string) map[string]string {
-	headers := make(map[string]string)
-	for _, h := range headerSlice {
-		parts := strings.SplitN(h, ":", 2)
-		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-			headers[key] = value
-		}
+// setDefaultHeaders fills in the User-Agent and, with AcceptEncoding, the
+// Accept-Encoding header unless the caller set or removed them
+func (c *Client) setDefaultHeaders(req *http.Request) {
+	if _, ok := req.Header["User-Agent"]; !ok {
+		req.Header.Set("User-Agent", DefaultUserAgent)
+	}
+	if _, ok := req.Header["Accept-Encoding"]; !ok && len(c.config.AcceptEncoding) > 0 {
+		req.Header.Set("Accept-Encoding", strings.Join(c.config.AcceptEncoding, ", "))
 	}
-	return headers
 }
 
 // ParseResolveHosts converts --resolve format (host:port:addr) into a map
@@ -252,3 +396,24 @@ func ParseConnectToHosts(connectToSlice []string) (map[string]string, error) {
 	}
 	return connectMap, nil
 }
+
+// recordFinalURL sets the URL of the final response when it followed
+// redirects or is itself a redirect (--no-follow), with its Location
+func recordFinalURL(timing *TimingBreakdown, resp *http.Response) {
+	if len(timing.Redirects) > 0 || isRedirect(resp.StatusCode) {
+		timing.URL = resp.Request.URL.String()
+	}
+	if isRedirect(resp.StatusCode) {
+		timing.Location = resp.Header.Get("Location")
+	}
+}
+
+// isRedirect reports whether a status code is one http.Client follows
+func isRedirect(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
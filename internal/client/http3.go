@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"strconv"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// newHTTP3Transport creates an HTTP/3 round tripper.
+//
+// QUIC has no separate TCP phase, so the dial function reports the combined
+// transport and crypto handshake through the TLS hooks of the request's
+// httptrace.ClientTrace. DNS timing is reported by the resolver itself.
+// Whether 0-RTT early data was accepted is only known once the handshake
+// completes, so it is probed when the tracer finishes.
+func newHTTP3Transport(config *Config, tlsConfig *tls.Config) *http3.Transport {
+	return &http3.Transport{
+		TLSClientConfig:    tlsConfig.Clone(),
+		DisableCompression: config.NoCompression || len(config.AcceptEncoding) > 0,
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, quicCfg *quic.Config) (*quic.Conn, error) {
+			dialAddr, err := mapDialAddress(config, addr)
+			if err != nil {
+				return nil, err
+			}
+
+			udpAddr, err := resolveUDPAddr(ctx, config.Resolver, dialAddr, config.IPVersion)
+			if err != nil {
+				return nil, err
+			}
+
+			trace := httptrace.ContextClientTrace(ctx)
+			if trace != nil && trace.TLSHandshakeStart != nil {
+				trace.TLSHandshakeStart()
+			}
+
+			// DialAddrEarly allows 0-RTT when a session ticket is cached
+			conn, err := quic.DialAddrEarly(ctx, udpAddr.String(), tlsCfg, quicCfg)
+			if err != nil {
+				if trace != nil && trace.TLSHandshakeDone != nil {
+					trace.TLSHandshakeDone(tls.ConnectionState{}, err)
+				}
+				return nil, err
+			}
+
+			if tracer := tracerFromContext(ctx); tracer != nil {
+				tracer.setEarlyDataProbe(func() bool {
+					return conn.ConnectionState().Used0RTT
+				})
+			}
+
+			if trace != nil && trace.TLSHandshakeDone != nil {
+				reportHandshake := func() {
+					trace.TLSHandshakeDone(conn.ConnectionState().TLS, nil)
+				}
+				select {
+				case <-conn.HandshakeComplete():
+					reportHandshake()
+				default:
+					// With 0-RTT the connection is usable before the handshake
+					// completes; report the handshake when it actually finishes
+					go func() {
+						select {
+						case <-conn.HandshakeComplete():
+							reportHandshake()
+						case <-conn.Context().Done():
+						}
+					}()
+				}
+			}
+
+			return conn, err
+		},
+	}
+}
+
+// resolveUDPAddr resolves host:port using the context-aware resolver so that
+// DNS hooks fire for HTTP/3 requests the same way they do for TCP dials.
+// A non-zero ipVersion keeps only addresses of that family.
+func resolveUDPAddr(ctx context.Context, resolver *net.Resolver, addr string, ipVersion int) (*net.UDPAddr, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse address %s: %w", addr, err)
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in address %s: %w", addr, err)
+	}
+
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ips, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range ips {
+		isIPv4 := ip.IP.To4() != nil
+		if (ipVersion == 4 && !isIPv4) || (ipVersion == 6 && isIPv4) {
+			continue
+		}
+		return &net.UDPAddr{IP: ip.IP, Port: port, Zone: ip.Zone}, nil
+	}
+
+	if ipVersion != 0 {
+		return nil, fmt.Errorf("no IPv%d addresses found for %s", ipVersion, host)
+	}
+	return nil, fmt.Errorf("no addresses found for %s", host)
+}
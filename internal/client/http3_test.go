@@ -0,0 +1,81 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestClientHTTP3(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("h3 response"))
+	})
+
+	// Borrow the self-signed certificate from an httptest TLS server
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP not available: %v", err)
+	}
+	defer udpConn.Close()
+
+	server := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(tlsServer.TLS.Clone()),
+	}
+	go server.Serve(udpConn)
+	defer server.Close()
+
+	client := NewClient(&Config{
+		Timeout:  5 * time.Second,
+		Insecure: true,
+		Protocol: ProtocolHTTP3,
+	})
+	defer client.Close()
+
+	url := "https://" + udpConn.LocalAddr().String() + "/"
+	timing, err := client.MeasureRequest(url, "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("MeasureRequest failed: %v", err)
+	}
+
+	if timing.Protocol != "HTTP/3.0" {
+		t.Errorf("Expected protocol HTTP/3.0, got %s", timing.Protocol)
+	}
+
+	if timing.TLSHandshake == 0 {
+		t.Error("Expected QUIC handshake to be reported as TLS handshake")
+	}
+
+	if timing.TLSVersion != "TLS 1.3" {
+		t.Errorf("Expected TLS 1.3, got %s", timing.TLSVersion)
+	}
+
+	if timing.ResponseSize != int64(len("h3 response")) {
+		t.Errorf("Expected response size %d, got %d", len("h3 response"), timing.ResponseSize)
+	}
+}
+
+func TestClientHTTP3RejectsCleartext(t *testing.T) {
+	client := NewClient(&Config{
+		Timeout:  time.Second,
+		Protocol: ProtocolHTTP3,
+	})
+	defer client.Close()
+
+	timing, err := client.MeasureRequest("http://127.0.0.1:1/", "GET", nil, nil)
+	if err == nil {
+		t.Fatal("Expected error for cleartext HTTP/3 request")
+	}
+
+	if timing == nil || !strings.Contains(timing.Error, "http") {
+		t.Errorf("Expected timing with error, got %+v", timing)
+	}
+}
@@ -84,8 +84,8 @@ func TestClientMeasureRequestWithHeaders(t *testing.T) {
 		Timeout: 5 * time.Second,
 	}
 
-	headers := map[string]string{
-		"X-Test-Header": "test-value",
+	headers := Headers{
+		{Name: "X-Test-Header", Value: "test-value"},
 	}
 
 	client := NewClient(config)
@@ -157,18 +157,18 @@ func TestParseHeaders(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    []string
-		expected map[string]string
+		expected Headers
 	}{
 		{
 			name:     "empty",
 			input:    []string{},
-			expected: map[string]string{},
+			expected: Headers{},
 		},
 		{
 			name:  "single header",
 			input: []string{"Content-Type: application/json"},
-			expected: map[string]string{
-				"Content-Type": "application/json",
+			expected: Headers{
+				{Name: "Content-Type", Value: "application/json"},
 			},
 		},
 		{
@@ -177,28 +177,51 @@ func TestParseHeaders(t *testing.T) {
 				"Content-Type: application/json",
 				"Authorization: Bearer token",
 			},
-			expected: map[string]string{
-				"Content-Type":  "application/json",
-				"Authorization": "Bearer token",
+			expected: Headers{
+				{Name: "Content-Type", Value: "application/json"},
+				{Name: "Authorization", Value: "Bearer token"},
+			},
+		},
+		{
+			name:  "repeated header keeps every value in order",
+			input: []string{"Accept: text/html", "X-Other: 1", "Accept: application/json"},
+			expected: Headers{
+				{Name: "Accept", Value: "text/html"},
+				{Name: "X-Other", Value: "1"},
+				{Name: "Accept", Value: "application/json"},
 			},
 		},
 		{
 			name:  "header with spaces",
 			input: []string{"  X-Custom-Header  :  value with spaces  "},
-			expected: map[string]string{
-				"X-Custom-Header": "value with spaces",
+			expected: Headers{
+				{Name: "X-Custom-Header", Value: "value with spaces"},
 			},
 		},
 		{
 			name:     "invalid header",
 			input:    []string{"Invalid Header Without Colon"},
-			expected: map[string]string{},
+			expected: Headers{},
 		},
 		{
 			name:  "header with colon in value",
 			input: []string{"X-URL: https://example.com"},
-			expected: map[string]string{
-				"X-URL": "https://example.com",
+			expected: Headers{
+				{Name: "X-URL", Value: "https://example.com"},
+			},
+		},
+		{
+			name:  "empty value removes the header",
+			input: []string{"User-Agent:"},
+			expected: Headers{
+				{Name: "User-Agent", Remove: true},
+			},
+		},
+		{
+			name:  "semicolon sends an empty header",
+			input: []string{"X-Empty;"},
+			expected: Headers{
+				{Name: "X-Empty"},
 			},
 		},
 	}
@@ -208,20 +231,81 @@ func TestParseHeaders(t *testing.T) {
 			result := ParseHeaders(tt.input)
 
 			if len(result) != len(tt.expected) {
-				t.Errorf("Expected %d headers, got %d", len(tt.expected), len(result))
+				t.Fatalf("Expected %d headers, got %d: %v", len(tt.expected), len(result), result)
 			}
 
-			for key, expectedValue := range tt.expected {
-				if actualValue, ok := result[key]; !ok {
-					t.Errorf("Missing header %q", key)
-				} else if actualValue != expectedValue {
-					t.Errorf("Header %q: expected %q, got %q", key, expectedValue, actualValue)
+			for i, expected := range tt.expected {
+				if result[i] != expected {
+					t.Errorf("Header %d: expected %+v, got %+v", i, expected, result[i])
 				}
 			}
 		})
 	}
 }
 
+func TestClientHeaderOrderAndRemoval(t *testing.T) {
+	var received http.Header
+	var host string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		host = r.Host
+		w.Header().Add("Set-Cookie", "a=1; Path=/")
+		w.Header().Add("Set-Cookie", "b=2; Expires=Wed, 21 Oct 2015 07:28:00 GMT")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{Timeout: 5 * time.Second, IncludeHeaders: true})
+	headers := ParseHeaders([]string{
+		"Accept: text/html",
+		"Accept: application/json",
+		"User-Agent:",
+		"Host: api.example.test",
+	})
+
+	timing, err := client.MeasureRequest(server.URL, "GET", headers, nil)
+	if err != nil {
+		t.Fatalf("MeasureRequest failed: %v", err)
+	}
+
+	if got := received.Values("Accept"); len(got) != 2 || got[0] != "text/html" || got[1] != "application/json" {
+		t.Errorf("Expected both Accept values in order, got %v", got)
+	}
+	if _, ok := received["User-Agent"]; ok {
+		t.Errorf("Expected no User-Agent, got %q", received.Get("User-Agent"))
+	}
+	if host != "api.example.test" {
+		t.Errorf("Expected Host override api.example.test, got %s", host)
+	}
+
+	// Set-Cookie values contain commas and must not be joined
+	cookies := timing.ResponseHeaders.Values("Set-Cookie")
+	if len(cookies) != 2 || cookies[1] != "b=2; Expires=Wed, 21 Oct 2015 07:28:00 GMT" {
+		t.Errorf("Expected two separate Set-Cookie values, got %v", cookies)
+	}
+}
+
+func TestClientRemoveAcceptEncoding(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	headers := ParseHeaders([]string{"Accept-Encoding:"})
+	if !headers.Removes("Accept-Encoding") {
+		t.Fatal("Expected Accept-Encoding to be removed")
+	}
+
+	client := NewClient(&Config{Timeout: 5 * time.Second, NoCompression: true})
+	if _, err := client.MeasureRequest(server.URL, "GET", headers, nil); err != nil {
+		t.Fatalf("MeasureRequest failed: %v", err)
+	}
+	if _, ok := received["Accept-Encoding"]; ok {
+		t.Errorf("Expected no Accept-Encoding, got %q", received.Get("Accept-Encoding"))
+	}
+}
+
 func TestClientTimeout(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		time.Sleep(100 * time.Millisecond)
@@ -277,8 +361,8 @@ func TestClientCustomUserAgent(t *testing.T) {
 		Timeout: 5 * time.Second,
 	}
 
-	headers := map[string]string{
-		"User-Agent": "custom-agent/2.0",
+	headers := Headers{
+		{Name: "User-Agent", Value: "custom-agent/2.0"},
 	}
 
 	client := NewClient(config)
@@ -491,3 +575,254 @@ func TestParseConnectToHosts(t *testing.T) {
 		})
 	}
 }
+
+func TestClientProtocolPinning(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		protocol string
+		expected string
+	}{
+		{ProtocolHTTP1, "HTTP/1.1"},
+		{ProtocolHTTP2, "HTTP/2.0"},
+		{"", "HTTP/2.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected+"/"+tt.protocol, func(t *testing.T) {
+			client := NewClient(&Config{
+				Timeout:  5 * time.Second,
+				Insecure: true,
+				Protocol: tt.protocol,
+			})
+			defer client.Close()
+
+			timing, err := client.MeasureRequest(server.URL, "GET", nil, nil)
+			if err != nil {
+				t.Fatalf("MeasureRequest failed: %v", err)
+			}
+
+			if timing.Protocol != tt.expected {
+				t.Errorf("Expected protocol %s, got %s", tt.expected, timing.Protocol)
+			}
+
+			if timing.TLSHandshake == 0 {
+				t.Error("Expected TLS handshake timing")
+			}
+		})
+	}
+}
+
+func TestClientHTTP2PriorKnowledge(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	client := NewClient(&Config{
+		Timeout:  5 * time.Second,
+		Protocol: ProtocolHTTP2,
+	})
+	defer client.Close()
+
+	timing, err := client.MeasureRequest(server.URL, "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("MeasureRequest failed: %v", err)
+	}
+
+	if timing.Protocol != "HTTP/2.0" {
+		t.Errorf("Expected h2c to negotiate HTTP/2.0, got %s", timing.Protocol)
+	}
+}
+
+func TestClientIPVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// httptest listens on 127.0.0.1
+	ipv4 := NewClient(&Config{Timeout: 5 * time.Second, IPVersion: 4})
+	defer ipv4.Close()
+
+	timing, err := ipv4.MeasureRequest(server.URL, "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("IPv4 request failed: %v", err)
+	}
+	if len(timing.ConnectAttempts) != 1 || timing.ConnectAttempts[0].Family != "IPv4" {
+		t.Errorf("Expected a single IPv4 connect attempt, got %+v", timing.ConnectAttempts)
+	}
+
+	ipv6 := NewClient(&Config{Timeout: 5 * time.Second, IPVersion: 6})
+	defer ipv6.Close()
+
+	if _, err := ipv6.MeasureRequest(server.URL, "GET", nil, nil); err == nil {
+		t.Error("Expected IPv6-only client to refuse an IPv4 address")
+	}
+}
+
+func TestRestrictNetwork(t *testing.T) {
+	tests := []struct {
+		network   string
+		ipVersion int
+		expected  string
+	}{
+		{"tcp", 0, "tcp"},
+		{"tcp", 4, "tcp4"},
+		{"tcp", 6, "tcp6"},
+		{"udp", 4, "udp4"},
+	}
+
+	for _, tt := range tests {
+		if result := restrictNetwork(tt.network, tt.ipVersion); result != tt.expected {
+			t.Errorf("restrictNetwork(%q, %d) = %q, expected %q", tt.network, tt.ipVersion, result, tt.expected)
+		}
+	}
+}
+
+// newRedirectChain starts a server that redirects /a → /b → /c
+func newRedirectChain(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientRedirectHops(t *testing.T) {
+	server := newRedirectChain(t)
+
+	client := NewClient(&Config{Timeout: 5 * time.Second})
+	defer client.Close()
+
+	timing, err := client.MeasureRequest(server.URL+"/a", "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("MeasureRequest failed: %v", err)
+	}
+
+	if timing.StatusCode != http.StatusOK || timing.URL != server.URL+"/c" {
+		t.Errorf("Expected final 200 from /c, got %d from %s", timing.StatusCode, timing.URL)
+	}
+	if len(timing.Redirects) != 2 {
+		t.Fatalf("Expected 2 redirect hops, got %d", len(timing.Redirects))
+	}
+
+	expected := []struct {
+		url      string
+		status   int
+		location string
+	}{
+		{server.URL + "/a", http.StatusFound, "/b"},
+		{server.URL + "/b", http.StatusMovedPermanently, "/c"},
+	}
+
+	var hopTotal Duration
+	for i, want := range expected {
+		hop := timing.Redirects[i]
+		if hop.URL != want.url || hop.StatusCode != want.status || hop.Location != want.location {
+			t.Errorf("Hop %d: expected %s %d → %s, got %s %d → %s",
+				i, want.url, want.status, want.location, hop.URL, hop.StatusCode, hop.Location)
+		}
+		if hop.Total <= 0 || hop.ServerProcessing <= 0 {
+			t.Errorf("Hop %d: expected its own phase breakdown, got %+v", i, hop)
+		}
+		hopTotal += hop.Total
+	}
+
+	// Only the first hop opens a connection; later hops reuse it
+	if timing.Redirects[0].TCPConnection <= 0 {
+		t.Error("Expected the first hop to carry the TCP connection time")
+	}
+	if !timing.Redirects[1].ConnectionReused || timing.Redirects[1].TCPConnection != 0 {
+		t.Errorf("Expected the second hop to reuse the connection, got %+v", timing.Redirects[1])
+	}
+	if timing.TCPConnection != 0 {
+		t.Errorf("Final hop should not inherit earlier connection time, got %v", timing.TCPConnection)
+	}
+	if timing.Total < hopTotal {
+		t.Errorf("Total %v should cover all hops (%v)", timing.Total, hopTotal)
+	}
+}
+
+func TestClientRedirectLimits(t *testing.T) {
+	server := newRedirectChain(t)
+
+	tests := []struct {
+		name      string
+		config    Config
+		status    int
+		redirects int
+		location  string
+	}{
+		{"no follow", Config{NoFollow: true}, http.StatusFound, 0, "/b"},
+		{"max two", Config{MaxRedirects: 2}, http.StatusOK, 2, ""},
+		{"default", Config{}, http.StatusOK, 2, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Timeout = 5 * time.Second
+			client := NewClient(&config)
+			defer client.Close()
+
+			timing, err := client.MeasureRequest(server.URL+"/a", "GET", nil, nil)
+			if err != nil {
+				t.Fatalf("MeasureRequest failed: %v", err)
+			}
+			if timing.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, timing.StatusCode)
+			}
+			if len(timing.Redirects) != tt.redirects {
+				t.Errorf("Expected %d redirect hops, got %d", tt.redirects, len(timing.Redirects))
+			}
+			if timing.Location != tt.location {
+				t.Errorf("Expected Location %q, got %q", tt.location, timing.Location)
+			}
+		})
+	}
+
+	// A redirect response that is not followed is reported with its target
+	t.Run("no follow reports location", func(t *testing.T) {
+		client := NewClient(&Config{Timeout: 5 * time.Second, NoFollow: true})
+		defer client.Close()
+		timing, err := client.MeasureRequest(server.URL+"/b", "GET", nil, nil)
+		if err != nil {
+			t.Fatalf("MeasureRequest failed: %v", err)
+		}
+		if timing.StatusCode != http.StatusMovedPermanently || timing.URL != server.URL+"/b" || timing.Location != "/c" {
+			t.Errorf("Expected 301 from /b → /c, got %d from %s → %s", timing.StatusCode, timing.URL, timing.Location)
+		}
+	})
+
+	// Running out of redirects fails the request, as with curl --max-redirs
+	t.Run("max one", func(t *testing.T) {
+		client := NewClient(&Config{Timeout: 5 * time.Second, MaxRedirects: 1})
+		defer client.Close()
+		timing, err := client.MeasureRequest(server.URL+"/a", "GET", nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "stopped after 1 redirects") {
+			t.Fatalf("Expected a redirect limit error, got %v", err)
+		}
+		if timing == nil || timing.Error == "" {
+			t.Errorf("Expected the error on the timing, got %+v", timing)
+		}
+	})
+}
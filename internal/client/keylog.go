@@ -0,0 +1,58 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// KeyLogWriter appends TLS secrets in NSS key log format to a file.
+// Writes are serialized so concurrent handshakes during load tests never
+// interleave partial lines.
+type KeyLogWriter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+var (
+	keyLogsMu sync.Mutex
+	keyLogs   = make(map[string]*KeyLogWriter)
+)
+
+// OpenKeyLog returns the key log writer for path, opening it in append mode on
+// first use. Clients sharing a path share one writer.
+func OpenKeyLog(path string) (*KeyLogWriter, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key log path: %w", err)
+	}
+
+	keyLogsMu.Lock()
+	defer keyLogsMu.Unlock()
+
+	if w, ok := keyLogs[abs]; ok {
+		return w, nil
+	}
+
+	file, err := os.OpenFile(abs, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open TLS key log: %w", err)
+	}
+
+	w := &KeyLogWriter{file: file}
+	keyLogs[abs] = w
+	return w, nil
+}
+
+// Write implements io.Writer
+func (w *KeyLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Write(p)
+}
+
+// Path returns the file the secrets are written to
+func (w *KeyLogWriter) Path() string {
+	return w.file.Name()
+}
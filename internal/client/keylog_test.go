@@ -0,0 +1,89 @@
+package client
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOpenKeyLogSharesWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.log")
+
+	first, err := OpenKeyLog(path)
+	if err != nil {
+		t.Fatalf("OpenKeyLog failed: %v", err)
+	}
+
+	second, err := OpenKeyLog(path)
+	if err != nil {
+		t.Fatalf("OpenKeyLog failed: %v", err)
+	}
+
+	if first != second {
+		t.Error("Expected the same writer for the same path")
+	}
+
+	if _, err := OpenKeyLog(filepath.Join(t.TempDir(), "missing", "keys.log")); err == nil {
+		t.Error("Expected error for unwritable path")
+	}
+}
+
+func TestKeyLogConcurrentHandshakes(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "keys.log")
+	tlsConfig, err := BuildTLSConfig(true, TLSOptions{KeyLogFile: path})
+	if err != nil {
+		t.Fatalf("BuildTLSConfig failed: %v", err)
+	}
+
+	client := NewClient(&Config{
+		Timeout:          5 * time.Second,
+		TLSConfig:        tlsConfig,
+		DisableKeepAlive: true,
+	})
+	defer client.Close()
+
+	const handshakes = 10
+	var wg sync.WaitGroup
+	for i := 0; i < handshakes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.MeasureRequest(server.URL, "GET", nil, nil); err != nil {
+				t.Errorf("MeasureRequest failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open key log: %v", err)
+	}
+	defer file.Close()
+
+	// TLS 1.3 logs several secrets per handshake; every line must be whole
+	clientRandoms := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			t.Fatalf("Malformed key log line: %q", scanner.Text())
+		}
+		clientRandoms[fields[1]] = true
+	}
+
+	if len(clientRandoms) != handshakes {
+		t.Errorf("Expected secrets for %d handshakes, got %d", handshakes, len(clientRandoms))
+	}
+}
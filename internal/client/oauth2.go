@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTokenRefreshMargin caps how long before expiry a cached token is refreshed
+const maxTokenRefreshMargin = 30 * time.Second
+
+// TokenFetch describes one request to the OAuth2 token endpoint. Token
+// fetches are not part of any request's TimingBreakdown.
+type TokenFetch struct {
+	Duration   Duration `json:"duration"`
+	StatusCode int      `json:"status_code,omitempty"`
+	ExpiresIn  Duration `json:"expires_in,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// OAuth2ClientCredentials authenticates with a bearer token obtained through
+// the OAuth2 client credentials grant. The token is cached and refreshed
+// shortly before it expires, or when a request is rejected with 401.
+type OAuth2ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scope        string
+
+	mu      sync.Mutex
+	token   string
+	expiry  time.Time // zero when the server gave no lifetime
+	margin  time.Duration
+	fetches []TokenFetch
+}
+
+// NewOAuth2ClientCredentials creates a token source for the given endpoint and client
+func NewOAuth2ClientCredentials(tokenURL, clientID, clientSecret, scope string) *OAuth2ClientCredentials {
+	return &OAuth2ClientCredentials{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scope:        scope,
+	}
+}
+
+// Authorize implements Authenticator
+func (o *OAuth2ClientCredentials) Authorize(req *http.Request, base http.RoundTripper) error {
+	token, err := o.Token(req.Context(), base)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Challenge drops the cached token if the server rejected it
+func (o *OAuth2ClientCredentials) Challenge(resp *http.Response) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Another request may already have replaced the token
+	if resp.Request != nil && resp.Request.Header.Get("Authorization") == "Bearer "+o.token {
+		o.token = ""
+	}
+	return true
+}
+
+// Token returns the cached token, fetching a new one when it is missing or
+// about to expire. Concurrent callers wait for a single fetch.
+func (o *OAuth2ClientCredentials) Token(ctx context.Context, base http.RoundTripper) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token != "" && (o.expiry.IsZero() || time.Now().Add(o.margin).Before(o.expiry)) {
+		return o.token, nil
+	}
+
+	start := time.Now()
+	token, expiresIn, status, err := o.fetch(ctx, base)
+	fetch := TokenFetch{
+		Duration:   Duration(time.Since(start)),
+		StatusCode: status,
+		ExpiresIn:  Duration(expiresIn),
+	}
+	if err != nil {
+		fetch.Error = err.Error()
+		o.fetches = append(o.fetches, fetch)
+		return "", err
+	}
+	o.fetches = append(o.fetches, fetch)
+
+	o.token = token
+	o.expiry = time.Time{}
+	if expiresIn > 0 {
+		o.expiry = start.Add(expiresIn)
+		o.margin = min(expiresIn/10, maxTokenRefreshMargin)
+	}
+	return token, nil
+}
+
+// Fetches returns every token request made so far
+func (o *OAuth2ClientCredentials) Fetches() []TokenFetch {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]TokenFetch(nil), o.fetches...)
+}
+
+// fetch requests a token from the token endpoint
+func (o *OAuth2ClientCredentials) fetch(ctx context.Context, base http.RoundTripper) (string, time.Duration, int, error) {
+	// Keep cancellation but drop the tracer, so the token request does not
+	// show up in the phases of the request being authorized
+	fetchCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if o.Scope != "" {
+		form.Set("scope", o.Scope)
+	}
+
+	req, err := http.NewRequestWithContext(fetchCtx, http.MethodPost, o.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("invalid token URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+
+	resp, err := (&http.Client{Transport: base}).Do(req)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, resp.StatusCode, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, resp.StatusCode, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, truncate(strings.TrimSpace(string(body)), 200))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", 0, resp.StatusCode, fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", 0, resp.StatusCode, fmt.Errorf("token response has no access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return "", 0, resp.StatusCode, fmt.Errorf("unsupported token type '%s'", token.TokenType)
+	}
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, resp.StatusCode, nil
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
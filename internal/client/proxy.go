@@ -0,0 +1,256 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// ParseProxyURL validates a --proxy value. URLs without a scheme are HTTP proxies.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy '%s'", raw)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%s': expected http, https, socks5 or socks5h", u.Scheme)
+	}
+
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), defaultProxyPort(u.Scheme))
+	}
+	return u, nil
+}
+
+// defaultProxyPort returns the conventional port of a proxy scheme
+func defaultProxyPort(scheme string) string {
+	switch scheme {
+	case "https":
+		return "443"
+	case "socks5", "socks5h":
+		return "1080"
+	default:
+		return "80"
+	}
+}
+
+// isSOCKS reports whether a proxy URL is a SOCKS5 proxy
+func isSOCKS(u *url.URL) bool {
+	return u.Scheme == "socks5" || u.Scheme == "socks5h"
+}
+
+// newProxyFunc chooses the proxy for a request URL: --proxy if given, otherwise
+// HTTP_PROXY/HTTPS_PROXY/NO_PROXY from the environment. --noproxy overrides NO_PROXY.
+func newProxyFunc(config *Config) func(*url.URL) (*url.URL, error) {
+	var choose func(*url.URL) (*url.URL, error)
+
+	if config.Proxy != "" {
+		proxyURL, err := ParseProxyURL(config.Proxy)
+		choose = func(reqURL *url.URL) (*url.URL, error) {
+			if err != nil {
+				return nil, err
+			}
+			if matchNoProxy(reqURL.Hostname(), config.NoProxy) {
+				return nil, nil
+			}
+			return proxyURL, nil
+		}
+	} else {
+		env := httpproxy.FromEnvironment()
+		if config.NoProxy != "" {
+			env.NoProxy = config.NoProxy
+		}
+		choose = env.ProxyFunc()
+	}
+
+	return func(reqURL *url.URL) (*url.URL, error) {
+		proxyURL, err := choose(reqURL)
+		if err != nil || proxyURL == nil {
+			return nil, err
+		}
+
+		// --proxy-user takes precedence over credentials embedded in the URL
+		if config.ProxyUser != "" {
+			proxyURL = cloneURL(proxyURL)
+			user, password, hasPassword := strings.Cut(config.ProxyUser, ":")
+			if hasPassword {
+				proxyURL.User = url.UserPassword(user, password)
+			} else {
+				proxyURL.User = url.User(user)
+			}
+		}
+		return proxyURL, nil
+	}
+}
+
+// matchNoProxy reports whether host is excluded from proxying by a
+// comma-separated list of hosts, domain suffixes, IPs, CIDRs or "*"
+func matchNoProxy(host, noProxy string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	ip := net.ParseIP(host)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		// Entries may carry a port; only the host part is compared
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		entry = strings.TrimPrefix(entry, "*")
+		domain := strings.TrimPrefix(entry, ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneURL returns a copy of u that can be modified safely
+func cloneURL(u *url.URL) *url.URL {
+	clone := *u
+	if u.User != nil {
+		user := *u.User
+		clone.User = &user
+	}
+	return &clone
+}
+
+// configureProxy installs the proxy selection and proxy-phase timing hooks on
+// the transport. HTTP and HTTPS proxies are handled by net/http (CONNECT for
+// https:// targets); SOCKS5 proxies are dialed by dialSOCKS so that the
+// handshake can be timed and socks5:// can resolve names locally.
+func configureProxy(config *Config, transport *http.Transport) {
+	choose := newProxyFunc(config)
+
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := choose(req.URL)
+		if err != nil || proxyURL == nil {
+			return nil, err
+		}
+		if tracer := tracerFromContext(req.Context()); tracer != nil {
+			tracer.setProxy(proxyURL)
+		}
+		if isSOCKS(proxyURL) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}
+
+	// The CONNECT request is sent right after this is called
+	transport.GetProxyConnectHeader = func(ctx context.Context, _ *url.URL, _ string) (http.Header, error) {
+		if tracer := tracerFromContext(ctx); tracer != nil {
+			tracer.markProxyTunnelStart()
+		}
+		return nil, nil
+	}
+
+	transport.OnProxyConnectResponse = func(ctx context.Context, _ *url.URL, _ *http.Request, _ *http.Response) error {
+		if tracer := tracerFromContext(ctx); tracer != nil {
+			tracer.markProxyTunnelDone()
+		}
+		return nil
+	}
+}
+
+// socksProxyFor returns the SOCKS proxy chosen for the request being dialed, if any
+func socksProxyFor(ctx context.Context, config *Config, addr string) (*url.URL, error) {
+	if tracer := tracerFromContext(ctx); tracer != nil {
+		proxyURL := tracer.proxyURL()
+		if proxyURL == nil || !isSOCKS(proxyURL) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}
+
+	// Requests sent without a tracer (Client.Do) choose the proxy here
+	proxyURL, err := newProxyFunc(config)(&url.URL{Scheme: "https", Host: addr})
+	if err != nil || proxyURL == nil || !isSOCKS(proxyURL) {
+		return nil, err
+	}
+	return proxyURL, nil
+}
+
+// dialSOCKS connects to addr through a SOCKS5 proxy. With socks5:// the target
+// is resolved locally (and timed as DNS); socks5h:// leaves it to the proxy.
+func dialSOCKS(ctx context.Context, config *Config, dialer *net.Dialer, network string, proxyURL *url.URL, addr string) (net.Conn, error) {
+	tracer := tracerFromContext(ctx)
+
+	if proxyURL.Scheme == "socks5" {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse address %s: %w", addr, err)
+		}
+		if net.ParseIP(host) == nil {
+			udpAddr, err := resolveUDPAddr(ctx, config.Resolver, addr, config.IPVersion)
+			if err != nil {
+				return nil, err
+			}
+			addr = net.JoinHostPort(udpAddr.IP.String(), port)
+		}
+	}
+
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+	}
+
+	forward := socksForwardDialer(func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err == nil && tracer != nil {
+			tracer.markProxyTunnelStart()
+		}
+		return conn, err
+	})
+
+	socksDialer, err := proxy.SOCKS5(network, proxyURL.Host, auth, forward)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := socksDialer.(proxy.ContextDialer).DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("socks proxy %s: %w", proxyURL.Host, err)
+	}
+	if tracer != nil {
+		tracer.markProxyTunnelDone()
+	}
+	return conn, nil
+}
+
+// socksForwardDialer adapts a dial function to proxy.Dialer and proxy.ContextDialer
+type socksForwardDialer func(ctx context.Context, network, address string) (net.Conn, error)
+
+func (d socksForwardDialer) Dial(network, address string) (net.Conn, error) {
+	return d(context.Background(), network, address)
+}
+
+func (d socksForwardDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d(ctx, network, address)
+}
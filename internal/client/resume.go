@@ -0,0 +1,109 @@
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ResumptionResult summarizes a full TLS handshake followed by resumption attempts
+type ResumptionResult struct {
+	Full    *TimingBreakdown   `json:"full"`
+	Resumed []*TimingBreakdown `json:"resumed"`
+
+	TLSVersion           string   `json:"tls_version"`
+	Attempts             int      `json:"attempts"`
+	Resumptions          int      `json:"resumptions"`
+	SuccessRate          float64  `json:"success_rate"`
+	EarlyDataAccepted    int      `json:"early_data_accepted"`
+	FullHandshake        Duration `json:"full_handshake"`
+	MeanResumedHandshake Duration `json:"mean_resumed_handshake"`
+	MinResumedHandshake  Duration `json:"min_resumed_handshake"`
+	MaxResumedHandshake  Duration `json:"max_resumed_handshake"`
+}
+
+// MeasureResumption performs one full TLS handshake followed by n handshakes
+// that try to resume the session from a shared tls.ClientSessionCache. Every
+// attempt uses a new client, and therefore a fresh TCP or QUIC connection.
+func MeasureResumption(config *Config, url, method string, headers Headers, data string, n int) (*ResumptionResult, error) {
+	cfg := *config
+	cfg.DisableKeepAlive = true
+
+	tlsConfig := &tls.Config{}
+	if config.TLSConfig != nil {
+		tlsConfig = config.TLSConfig.Clone()
+	}
+	tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	cfg.TLSConfig = tlsConfig
+
+	measure := func() (*TimingBreakdown, error) {
+		var body io.Reader
+		if data != "" {
+			body = strings.NewReader(data)
+		}
+		c := NewClient(&cfg)
+		defer c.Close()
+		return c.MeasureRequest(url, method, headers, body)
+	}
+
+	full, err := measure()
+	if err != nil {
+		return nil, fmt.Errorf("full handshake failed: %w", err)
+	}
+	if full.TLSVersion == "" {
+		return nil, fmt.Errorf("no TLS handshake took place (is the URL https://?)")
+	}
+
+	result := &ResumptionResult{
+		Full:          full,
+		Resumed:       make([]*TimingBreakdown, 0, n),
+		TLSVersion:    full.TLSVersion,
+		FullHandshake: full.TLSHandshake,
+	}
+
+	for i := 0; i < n; i++ {
+		// Failed attempts still count against the success rate
+		timing, _ := measure()
+		if timing != nil {
+			result.Resumed = append(result.Resumed, timing)
+		}
+		result.Attempts++
+	}
+
+	result.summarize()
+	return result, nil
+}
+
+// summarize computes the resumption rate and handshake statistics
+func (r *ResumptionResult) summarize() {
+	var total time.Duration
+
+	for _, t := range r.Resumed {
+		if t.Error != "" || !t.TLSResumed {
+			continue
+		}
+
+		handshake := t.TLSHandshake
+		if r.Resumptions == 0 || handshake < r.MinResumedHandshake {
+			r.MinResumedHandshake = handshake
+		}
+		if handshake > r.MaxResumedHandshake {
+			r.MaxResumedHandshake = handshake
+		}
+		total += time.Duration(handshake)
+		r.Resumptions++
+
+		if t.TLSEarlyData {
+			r.EarlyDataAccepted++
+		}
+	}
+
+	if r.Resumptions > 0 {
+		r.MeanResumedHandshake = Duration(total / time.Duration(r.Resumptions))
+	}
+	if r.Attempts > 0 {
+		r.SuccessRate = float64(r.Resumptions) / float64(r.Attempts)
+	}
+}
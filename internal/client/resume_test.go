@@ -0,0 +1,103 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestMeasureResumption(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	for _, version := range []string{"1.2", "1.3"} {
+		t.Run("TLS "+version, func(t *testing.T) {
+			tlsConfig, err := BuildTLSConfig(true, TLSOptions{MinVersion: version, MaxVersion: version})
+			if err != nil {
+				t.Fatalf("BuildTLSConfig failed: %v", err)
+			}
+
+			config := &Config{Timeout: 5 * time.Second, TLSConfig: tlsConfig}
+			result, err := MeasureResumption(config, server.URL, "GET", nil, "", 3)
+			if err != nil {
+				t.Fatalf("MeasureResumption failed: %v", err)
+			}
+
+			if result.Full.TLSResumed {
+				t.Error("First handshake should be a full handshake")
+			}
+
+			if result.Attempts != 3 || result.Resumptions != 3 {
+				t.Errorf("Expected 3/3 resumptions, got %d/%d", result.Resumptions, result.Attempts)
+			}
+
+			if result.SuccessRate != 1 {
+				t.Errorf("Expected success rate 1, got %.2f", result.SuccessRate)
+			}
+
+			if result.MeanResumedHandshake == 0 || result.FullHandshake == 0 {
+				t.Error("Expected handshake durations to be recorded")
+			}
+
+			// Go's crypto/tls client never sends early data over TCP
+			if result.EarlyDataAccepted != 0 {
+				t.Errorf("Expected no early data over TCP, got %d", result.EarlyDataAccepted)
+			}
+		})
+	}
+}
+
+func TestMeasureResumptionRequiresTLS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	if _, err := MeasureResumption(&Config{Timeout: 5 * time.Second}, server.URL, "GET", nil, "", 2); err == nil {
+		t.Error("Expected error for cleartext URL")
+	}
+}
+
+func TestMeasureResumptionHTTP3EarlyData(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP not available: %v", err)
+	}
+	defer udpConn.Close()
+
+	server := &http3.Server{
+		Handler:    handler,
+		TLSConfig:  http3.ConfigureTLSConfig(tlsServer.TLS.Clone()),
+		QUICConfig: &quic.Config{Allow0RTT: true},
+	}
+	go server.Serve(udpConn)
+	defer server.Close()
+
+	config := &Config{Timeout: 5 * time.Second, Insecure: true, Protocol: ProtocolHTTP3}
+	url := "https://" + udpConn.LocalAddr().String() + "/"
+
+	result, err := MeasureResumption(config, url, "GET", nil, "", 2)
+	if err != nil {
+		t.Fatalf("MeasureResumption failed: %v", err)
+	}
+
+	if result.Resumptions != 2 {
+		t.Errorf("Expected 2 resumptions, got %d", result.Resumptions)
+	}
+
+	if result.EarlyDataAccepted != 2 {
+		t.Errorf("Expected 0-RTT on both resumed connections, got %d", result.EarlyDataAccepted)
+	}
+}
@@ -0,0 +1,254 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// SSEMetrics describes the events of a text/event-stream response
+type SSEMetrics struct {
+	Events      int            `json:"events"`
+	EventTypes  map[string]int `json:"event_types"`
+	Comments    int            `json:"comments"` // Comment lines, usually keep-alives
+	LastEventID string         `json:"last_event_id,omitempty"`
+	Retry       int            `json:"retry_ms,omitempty"` // Last reconnection time the server asked for
+
+	// Timing, measured from when the request was sent
+	TimeToFirstEvent Duration `json:"time_to_first_event"`
+	LastEvent        Duration `json:"last_event"`
+
+	// Gaps between consecutive events
+	MeanGap Duration `json:"mean_gap"`
+	P50Gap  Duration `json:"p50_gap"`
+	P90Gap  Duration `json:"p90_gap"`
+	P99Gap  Duration `json:"p99_gap"`
+	MaxGap  Duration `json:"max_gap"`
+
+	// Events completed by the same read arrived together; when most events
+	// arrive that way something between the server and client is buffering
+	CoalescedReads    int  `json:"coalesced_reads"`  // Reads that completed more than one event
+	CoalescedEvents   int  `json:"coalesced_events"` // Events completed by those reads
+	BufferingDetected bool `json:"buffering_detected"`
+
+	EventTimings []SSEEvent `json:"event_timings,omitempty"`
+}
+
+// SSEEvent is one dispatched event
+type SSEEvent struct {
+	Sequence int      `json:"sequence"`
+	Type     string   `json:"type"`
+	ID       string   `json:"id,omitempty"`
+	Size     int      `json:"size"` // Bytes of data
+	Elapsed  Duration `json:"elapsed_time"`
+	Read     int      `json:"read"` // Sequence number of the read that completed the event
+	Data     string   `json:"-"`
+}
+
+// IsEventStream reports whether a response is a Server-Sent Events stream
+func IsEventStream(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// SSEParser splits an event stream into events as bytes arrive, following
+// the WHATWG event stream format
+type SSEParser struct {
+	line        []byte // Partial line carried over between writes
+	skipLF      bool   // The last write ended in CR, so a leading LF ends no line
+	started     bool
+	data        bytes.Buffer
+	hasData     bool
+	eventType   string
+	lastEventID string
+	retry       int
+	comments    int
+	events      []SSEEvent
+}
+
+// Feed parses the next bytes of the stream; events they complete are
+// stamped with elapsed and the read number
+func (p *SSEParser) Feed(b []byte, elapsed time.Duration, read int) {
+	if !p.started && len(b) > 0 {
+		// Drop a UTF-8 byte order mark at the start of the stream
+		p.started = true
+		b = bytes.TrimPrefix(b, []byte("\xEF\xBB\xBF"))
+	}
+
+	for len(b) > 0 {
+		if p.skipLF {
+			p.skipLF = false
+			if b[0] == '\n' {
+				b = b[1:]
+				continue
+			}
+		}
+		end := bytes.IndexAny(b, "\r\n")
+		if end < 0 {
+			p.line = append(p.line, b...)
+			return
+		}
+		line := b[:end]
+		if len(p.line) > 0 {
+			line = append(p.line, line...)
+			p.line = nil
+		}
+		p.processLine(line, elapsed, read)
+		if b[end] == '\r' {
+			p.skipLF = true
+		}
+		b = b[end+1:]
+	}
+}
+
+func (p *SSEParser) processLine(line []byte, elapsed time.Duration, read int) {
+	if len(line) == 0 {
+		p.dispatch(elapsed, read)
+		return
+	}
+	if line[0] == ':' {
+		p.comments++
+		return
+	}
+
+	field, value := line, []byte(nil)
+	if i := bytes.IndexByte(line, ':'); i >= 0 {
+		field, value = line[:i], line[i+1:]
+		value = bytes.TrimPrefix(value, []byte(" "))
+	}
+	switch string(field) {
+	case "event":
+		p.eventType = string(value)
+	case "data":
+		p.data.Write(value)
+		p.data.WriteByte('\n')
+		p.hasData = true
+	case "id":
+		if bytes.IndexByte(value, 0) < 0 {
+			p.lastEventID = string(value)
+		}
+	case "retry":
+		if n, err := strconv.Atoi(string(value)); err == nil && n >= 0 {
+			p.retry = n
+		}
+	}
+}
+
+// dispatch ends the current event; one without data is dropped
+func (p *SSEParser) dispatch(elapsed time.Duration, read int) {
+	defer func() {
+		p.data.Reset()
+		p.hasData = false
+		p.eventType = ""
+	}()
+	if !p.hasData {
+		return
+	}
+
+	eventType := p.eventType
+	if eventType == "" {
+		eventType = "message"
+	}
+	data := bytes.TrimSuffix(p.data.Bytes(), []byte("\n"))
+	p.events = append(p.events, SSEEvent{
+		Sequence: len(p.events),
+		Type:     eventType,
+		ID:       p.lastEventID,
+		Size:     len(data),
+		Elapsed:  Duration(elapsed),
+		Read:     read,
+		Data:     string(data),
+	})
+}
+
+// Events returns the events dispatched so far
+func (p *SSEParser) Events() []SSEEvent {
+	return p.events
+}
+
+// Metrics summarizes the events dispatched so far
+func (p *SSEParser) Metrics() *SSEMetrics {
+	m := &SSEMetrics{
+		Events:       len(p.events),
+		EventTypes:   make(map[string]int),
+		Comments:     p.comments,
+		LastEventID:  p.lastEventID,
+		Retry:        p.retry,
+		EventTimings: p.events,
+	}
+	if len(p.events) == 0 {
+		return m
+	}
+
+	m.TimeToFirstEvent = p.events[0].Elapsed
+	m.LastEvent = p.events[len(p.events)-1].Elapsed
+
+	perRead := make(map[int]int)
+	for _, e := range p.events {
+		m.EventTypes[e.Type]++
+		perRead[e.Read]++
+	}
+	for _, n := range perRead {
+		if n > 1 {
+			m.CoalescedReads++
+			m.CoalescedEvents += n
+		}
+	}
+	m.BufferingDetected = m.Events > 1 && m.CoalescedEvents*2 > m.Events
+
+	if len(p.events) < 2 {
+		return m
+	}
+	gaps := make([]time.Duration, len(p.events)-1)
+	var total time.Duration
+	for i := 1; i < len(p.events); i++ {
+		gaps[i-1] = time.Duration(p.events[i].Elapsed - p.events[i-1].Elapsed)
+		total += gaps[i-1]
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	m.MeanGap = Duration(total / time.Duration(len(gaps)))
+	m.P50Gap = Duration(percentileDuration(gaps, 50))
+	m.P90Gap = Duration(percentileDuration(gaps, 90))
+	m.P99Gap = Duration(percentileDuration(gaps, 99))
+	m.MaxGap = Duration(gaps[len(gaps)-1])
+	return m
+}
+
+// percentileDuration interpolates the p-th percentile of sorted values
+func percentileDuration(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := (p / 100.0) * float64(len(sorted)-1)
+	lower := int(index)
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	weight := index - float64(lower)
+	return sorted[lower] + time.Duration(weight*float64(sorted[lower+1]-sorted[lower]))
+}
+
+// sseReader parses the decoded body as it is read, stamping each event with
+// the raw read of the StreamingReader that delivered it
+type sseReader struct {
+	reader io.Reader
+	raw    *StreamingReader
+	start  time.Time
+	parser SSEParser
+}
+
+func newSSEReader(reader io.Reader, raw *StreamingReader, start time.Time) *sseReader {
+	return &sseReader{reader: reader, raw: raw, start: start}
+}
+
+func (r *sseReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.parser.Feed(p[:n], time.Since(r.start), r.raw.chunkNumber-1)
+	}
+	return n, err
+}
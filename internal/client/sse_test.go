@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/erfi/gocurl/internal/server"
+)
+
+func TestSSEParser(t *testing.T) {
+	var p SSEParser
+	// Frames split at awkward places, with every line ending the format allows
+	feeds := []string{
+		"\xEF\xBB\xBF: keep-alive\r\n",
+		"retry: 3000\nid: 1\nda",
+		"ta: hello\r",
+		"\ndata: world\n\n",
+		"event: delta\rdata:{\"x\":1}\r\r",
+		"event: ignored\nid: 2\n\n",
+		"data: last\n\n",
+		"data: never finished",
+	}
+	for i, feed := range feeds {
+		p.Feed([]byte(feed), time.Duration(i)*time.Millisecond, i)
+	}
+
+	expected := []SSEEvent{
+		{Type: "message", ID: "1", Data: "hello\nworld", Read: 3},
+		{Type: "delta", ID: "1", Data: `{"x":1}`, Read: 4},
+		{Type: "message", ID: "2", Data: "last", Read: 6},
+	}
+	events := p.Events()
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(expected), len(events), events)
+	}
+	for i, e := range expected {
+		got := events[i]
+		if got.Type != e.Type || got.ID != e.ID || got.Data != e.Data || got.Read != e.Read || got.Size != len(e.Data) {
+			t.Errorf("Event %d: expected %+v, got %+v", i, e, got)
+		}
+	}
+
+	m := p.Metrics()
+	if m.Comments != 1 || m.Retry != 3000 || m.LastEventID != "2" {
+		t.Errorf("Unexpected metadata: %+v", m)
+	}
+	if m.EventTypes["message"] != 2 || m.EventTypes["delta"] != 1 {
+		t.Errorf("Unexpected event types: %v", m.EventTypes)
+	}
+	if m.TimeToFirstEvent != Duration(3*time.Millisecond) || m.MaxGap != Duration(2*time.Millisecond) {
+		t.Errorf("Unexpected timing: first %v, max gap %v", m.TimeToFirstEvent, m.MaxGap)
+	}
+}
+
+func TestSSEMetricsCoalescing(t *testing.T) {
+	var p SSEParser
+	p.Feed([]byte("data: a\n\n"), 10*time.Millisecond, 0)
+	p.Feed([]byte("data: b\n\ndata: c\n\ndata: d\n\n"), 50*time.Millisecond, 1)
+
+	m := p.Metrics()
+	if m.CoalescedReads != 1 || m.CoalescedEvents != 3 || !m.BufferingDetected {
+		t.Errorf("Expected 3 of 4 events coalesced, got %+v", m)
+	}
+	if m.P50Gap != 0 || m.MaxGap != Duration(40*time.Millisecond) {
+		t.Errorf("Unexpected gaps: p50 %v, max %v", m.P50Gap, m.MaxGap)
+	}
+
+	p = SSEParser{}
+	for i := 0; i < 4; i++ {
+		p.Feed([]byte("data: x\n\n"), time.Duration(i)*10*time.Millisecond, i)
+	}
+	if m := p.Metrics(); m.CoalescedEvents != 0 || m.BufferingDetected || m.P50Gap != Duration(10*time.Millisecond) {
+		t.Errorf("Expected evenly spaced events, got %+v", m)
+	}
+}
+
+func TestMeasureEventStream(t *testing.T) {
+	srv := httptest.NewServer(server.NewHandler(nil))
+	defer srv.Close()
+	client := NewClient(&Config{Timeout: 5 * time.Second})
+
+	_, metrics, err := client.MeasureRequestWithStreaming(context.Background(), srv.URL+"/?format=sse&chunks=5&interval=30ms", "GET", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sse := metrics.SSE
+	if sse == nil || sse.Events != 5 || sse.LastEventID != "4" {
+		t.Fatalf("Expected 5 events, got %+v", sse)
+	}
+	if sse.BufferingDetected || time.Duration(sse.P50Gap) < 20*time.Millisecond {
+		t.Errorf("Expected events about 30ms apart, got %+v", sse)
+	}
+
+	_, metrics, err = client.MeasureRequestWithStreaming(context.Background(), srv.URL+"/?format=sse&chunks=5&interval=30ms&buffer=true", "GET", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sse := metrics.SSE; sse.Events != 5 || !sse.BufferingDetected {
+		t.Errorf("Expected coalesced events from a buffered stream, got %+v", sse)
+	}
+
+	_, metrics, err = client.MeasureRequestWithStreaming(context.Background(), srv.URL+"/?chunks=2", "GET", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrics.SSE != nil {
+		t.Error("Expected no event analysis for a plain text response")
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"io"
 	"math"
 	"net/http"
-	"net/http/httptrace"
 	"strings"
 	"time"
 )
@@ -21,22 +20,25 @@ type StreamMetrics struct {
 	AverageChunkSize int64         `json:"average_chunk_size"`
 
 	// HTTP/2 specific
-	Protocol         string        `json:"protocol"` // "HTTP/2", "HTTP/1.1", etc.
-	StreamID         uint32        `json:"stream_id,omitempty"`
+	Protocol string `json:"protocol"` // "HTTP/2", "HTTP/1.1", etc.
+	StreamID uint32 `json:"stream_id,omitempty"`
 
 	// Streaming analysis
-	StreamingInfo    *StreamingInfo    `json:"streaming_info,omitempty"`
+	StreamingInfo     *StreamingInfo     `json:"streaming_info,omitempty"`
 	BufferingAnalysis *BufferingAnalysis `json:"buffering_analysis,omitempty"`
-	Stalls           []StallInfo        `json:"stalls,omitempty"`
+	Stalls            []StallInfo        `json:"stalls,omitempty"`
+
+	// Server-Sent Events, for text/event-stream responses
+	SSE *SSEMetrics `json:"sse,omitempty"`
 }
 
 // StreamingInfo contains HTTP response header analysis for streaming detection
 type StreamingInfo struct {
 	TransferEncoding  string `json:"transfer_encoding"`
-	ContentLength     *int64 `json:"content_length"`     // nil = unknown length (streaming likely)
+	ContentLength     *int64 `json:"content_length"` // nil = unknown length (streaming likely)
 	ContentType       string `json:"content_type"`
 	CacheControl      string `json:"cache_control"`
-	XAccelBuffering   string `json:"x_accel_buffering"`  // nginx buffering control
+	XAccelBuffering   string `json:"x_accel_buffering"` // nginx buffering control
 	IsChunked         bool   `json:"is_chunked"`
 	IsStreamingLikely bool   `json:"is_streaming_likely"` // heuristic
 }
@@ -44,22 +46,22 @@ type StreamingInfo struct {
 // BufferingAnalysis contains analysis of buffering behavior
 type BufferingAnalysis struct {
 	TimeToFirstByte   Duration `json:"time_to_first_byte"`
-	FirstChunkGap     Duration `json:"first_chunk_gap"`      // Gap between first and second chunk
-	ChunkPattern      string   `json:"chunk_pattern"`        // "steady", "burst", "stalled", "buffered"
+	FirstChunkGap     Duration `json:"first_chunk_gap"` // Gap between first and second chunk
+	ChunkPattern      string   `json:"chunk_pattern"`   // "steady", "burst", "stalled", "buffered"
 	StallCount        int      `json:"stall_count"`
 	TotalStallTime    Duration `json:"total_stall_time"`
-	ChunkTimingCV     float64  `json:"chunk_timing_cv"`      // Coefficient of variation
+	ChunkTimingCV     float64  `json:"chunk_timing_cv"` // Coefficient of variation
 	BufferingDetected bool     `json:"buffering_detected"`
 
 	// Statistical metrics (objective)
-	MeanDelay         float64  `json:"mean_delay_ms"`        // Mean inter-chunk delay in milliseconds
-	StdDevDelay       float64  `json:"stddev_delay_ms"`      // Standard deviation in milliseconds
-	MinDelay          float64  `json:"min_delay_ms"`         // Minimum delay in milliseconds
-	MaxDelay          float64  `json:"max_delay_ms"`         // Maximum delay in milliseconds
+	MeanDelay   float64 `json:"mean_delay_ms"`   // Mean inter-chunk delay in milliseconds
+	StdDevDelay float64 `json:"stddev_delay_ms"` // Standard deviation in milliseconds
+	MinDelay    float64 `json:"min_delay_ms"`    // Minimum delay in milliseconds
+	MaxDelay    float64 `json:"max_delay_ms"`    // Maximum delay in milliseconds
 
 	// Deprecated: Use objective metrics instead
-	StreamingQuality  string   `json:"streaming_quality,omitempty"` // Deprecated: subjective assessment
-	Confidence        float64  `json:"confidence"`           // 0-1 confidence score based on sample size
+	StreamingQuality string  `json:"streaming_quality,omitempty"` // Deprecated: subjective assessment
+	Confidence       float64 `json:"confidence"`                  // 0-1 confidence score based on sample size
 }
 
 // StallInfo represents a pause in data delivery
@@ -162,7 +164,7 @@ func (sr *StreamingReader) Metrics() *StreamMetrics {
 }
 
 // MeasureRequestWithStreaming executes a request and captures progressive delivery metrics
-func (c *Client) MeasureRequestWithStreaming(ctx context.Context, url, method string, headers map[string]string, body io.Reader) (*TimingBreakdown, *StreamMetrics, error) {
+func (c *Client) MeasureRequestWithStreaming(ctx context.Context, url, method string, headers Headers, body io.Reader) (*TimingBreakdown, *StreamMetrics, error) {
 	tracer := NewTracer()
 
 	req, err := http.NewRequest(method, url, body)
@@ -170,26 +172,21 @@ func (c *Client) MeasureRequestWithStreaming(ctx context.Context, url, method st
 		return nil, nil, err
 	}
 
-	// Add custom headers
-	for key, value := range headers {
-		req.Header.Set(key, value)
-	}
-
-	if req.Header.Get("User-Agent") == "" {
-		req.Header.Set("User-Agent", "gocurl/1.0")
-	}
+	headers.apply(req)
+	c.setDefaultHeaders(req)
 
 	// Attach tracer
-	traceCtx := httptrace.WithClientTrace(ctx, tracer.ClientTrace())
-	req = req.WithContext(traceCtx)
+	req = req.WithContext(tracer.WithContext(ctx))
 
 	// Execute request
+	start := time.Now()
 	tracer.Start()
 	resp, err := c.client.Do(req)
 	if err != nil {
 		tracer.End()
 		timing := tracer.Timing()
 		timing.Error = err.Error()
+		timing.H2 = c.h2Trace(ctx, tracer)
 		return timing, nil, err
 	}
 	defer resp.Body.Close()
@@ -200,17 +197,42 @@ func (c *Client) MeasureRequestWithStreaming(ctx context.Context, url, method st
 	// Capture protocol info
 	protocol := resp.Proto // "HTTP/2.0", "HTTP/1.1", etc.
 
-	// Wrap response body with streaming reader
+	// Wrap response body with streaming reader; chunks are timed as they
+	// arrive, before any decoding
 	streamReader := NewStreamingReader(resp.Body, protocol)
+	var reader io.Reader = streamReader
+
+	decoder := newDecodingReader(resp, streamReader)
+	if decoder != nil {
+		defer decoder.Close()
+		reader = decoder
+	}
+
+	// Event streams are parsed after decoding, with each event tied to the
+	// raw read that delivered it. Token streams in an SSE format are parsed
+	// whatever their Content-Type, since gateways often mislabel them.
+	format := c.config.StreamFormat
+	var sse *sseReader
+	if IsEventStream(resp) || format == StreamFormatOpenAI || format == StreamFormatAnthropic {
+		sse = newSSEReader(reader, streamReader, start)
+		reader = sse
+	}
+	var tokens *tokenDecoder
+	if format != "" {
+		tokens = newTokenDecoder(format)
+		if format == StreamFormatNDJSON {
+			reader = &lineReader{reader: reader, start: start, decoder: tokens}
+		}
+	}
 
 	// Read body through streaming reader
 	var bodyBytes []byte
 	shouldCaptureBody := c.config.ShowBody || (c.config.ShowErrorBody && resp.StatusCode >= 400)
 
 	if shouldCaptureBody {
-		bodyBytes, err = io.ReadAll(streamReader)
+		bodyBytes, err = io.ReadAll(reader)
 	} else {
-		_, err = io.Copy(io.Discard, streamReader)
+		_, err = io.Copy(io.Discard, reader)
 	}
 
 	tracer.End()
@@ -219,19 +241,43 @@ func (c *Client) MeasureRequestWithStreaming(ctx context.Context, url, method st
 	streamMetrics := streamReader.Metrics()
 	timing := tracer.Timing()
 	timing.StatusCode = resp.StatusCode
+	timing.Protocol = resp.Proto
 	timing.ContentLength = resp.ContentLength
 	timing.ResponseSize = streamMetrics.TotalBytes
+	recordCookies(timing, resp)
+	if decoder != nil {
+		decoder.apply(timing)
+		timing.ResponseSize = timing.DecodedSize
+	}
+	recordFinalURL(timing, resp)
+
+	// With a frame trace the chunks are the DATA frames as they arrived,
+	// rather than the reads, which depend on how the transport buffers them
+	timing.H2 = c.h2Trace(ctx, tracer)
+	if timing.H2 != nil {
+		streamMetrics = c.messageStreamMetrics(protocol, start, timing.H2.data, timing)
+		if streamMetrics == nil {
+			streamMetrics = &StreamMetrics{Protocol: protocol}
+		}
+		streamMetrics.StreamID = timing.H2.StreamID
+	}
 
 	// Add streaming info and buffering analysis
 	streamMetrics.StreamingInfo = streamingInfo
-	if len(streamMetrics.ChunkTimings) > 0 {
+	if timing.H2 == nil && len(streamMetrics.ChunkTimings) > 0 {
 		streamMetrics.BufferingAnalysis = AnalyzeBuffering(streamMetrics, timing)
-		// Use configured stall threshold or default to 500ms
-		threshold := c.config.StallThreshold
-		if threshold == 0 {
-			threshold = 500 * time.Millisecond
+		streamMetrics.Stalls = DetectStalls(streamMetrics, c.stallThreshold())
+	}
+	if sse != nil {
+		streamMetrics.SSE = sse.parser.Metrics()
+	}
+	if tokens != nil {
+		if sse != nil {
+			for _, event := range sse.parser.Events() {
+				tokens.decode([]byte(event.Data), time.Duration(event.Elapsed))
+			}
 		}
-		streamMetrics.Stalls = DetectStalls(streamMetrics, threshold)
+		timing.Tokens = tokens.finish()
 	}
 
 	if shouldCaptureBody && len(bodyBytes) > 0 {
@@ -241,10 +287,64 @@ func (c *Client) MeasureRequestWithStreaming(ctx context.Context, url, method st
 	if err != nil {
 		timing.Error = err.Error()
 	}
+	// An error event ends a token stream early, so the request failed
+	if timing.Tokens != nil && timing.Tokens.Error != "" && timing.Error == "" {
+		timing.Error = "stream error: " + timing.Tokens.Error
+	}
 
 	return timing, streamMetrics, nil
 }
 
+// stallThreshold is the configured stall threshold, or 500ms
+func (c *Client) stallThreshold() time.Duration {
+	if c.config.StallThreshold == 0 {
+		return 500 * time.Millisecond
+	}
+	return c.config.StallThreshold
+}
+
+// messageArrival is one complete message of a message-based protocol
+type messageArrival struct {
+	size int
+	at   time.Time
+}
+
+// messageStreamMetrics analyses whole messages (WebSocket pushes, gRPC stream
+// responses) like the chunks of a streamed body, timed from start; nil
+// without messages
+func (c *Client) messageStreamMetrics(protocol string, start time.Time, messages []messageArrival, timing *TimingBreakdown) *StreamMetrics {
+	if len(messages) == 0 {
+		return nil
+	}
+	sm := &StreamMetrics{Protocol: protocol, ChunkTimings: make([]ChunkTiming, 0, len(messages))}
+	last := start
+	for i, msg := range messages {
+		var throughput float64
+		if gap := msg.at.Sub(last).Seconds(); gap > 0 {
+			throughput = float64(msg.size) * 8 / (gap * 1_000_000)
+		}
+		sm.ChunkTimings = append(sm.ChunkTimings, ChunkTiming{
+			SequenceNumber: i,
+			Size:           msg.size,
+			ElapsedTime:    Duration(msg.at.Sub(start)),
+			Timestamp:      msg.at,
+			Throughput:     throughput,
+		})
+		sm.TotalBytes += int64(msg.size)
+		last = msg.at
+	}
+	sm.TotalChunks = len(sm.ChunkTimings)
+	sm.AverageChunkSize = sm.TotalBytes / int64(sm.TotalChunks)
+	sm.FirstChunkTime = sm.ChunkTimings[0].ElapsedTime
+	sm.LastChunkTime = sm.ChunkTimings[sm.TotalChunks-1].ElapsedTime
+	if seconds := time.Duration(sm.LastChunkTime).Seconds(); seconds > 0 {
+		sm.BytesPerSecond = float64(sm.TotalBytes) / seconds
+	}
+	sm.BufferingAnalysis = AnalyzeBuffering(sm, timing)
+	sm.Stalls = DetectStalls(sm, c.stallThreshold())
+	return sm
+}
+
 // AnalyzeStreamingHeaders examines HTTP response headers for streaming indicators
 func AnalyzeStreamingHeaders(resp *http.Response) *StreamingInfo {
 	info := &StreamingInfo{
@@ -2,10 +2,14 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/erfi/gocurl/internal/server"
 )
 
 func TestStreamingReader(t *testing.T) {
@@ -585,3 +589,50 @@ func TestCalculateConfidence(t *testing.T) {
 		})
 	}
 }
+
+// TestStreamingCalibration checks the streaming analysis against responses
+// with a known shape from the calibration server
+func TestStreamingCalibration(t *testing.T) {
+	srv := httptest.NewServer(server.NewHandler(nil))
+	defer srv.Close()
+	client := NewClient(&Config{Timeout: 5 * time.Second})
+
+	measure := func(t *testing.T, query string) (*TimingBreakdown, *StreamMetrics) {
+		t.Helper()
+		timing, metrics, err := client.MeasureRequestWithStreaming(context.Background(), srv.URL+"/?"+query, "GET", nil, nil)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return timing, metrics
+	}
+
+	t.Run("steady", func(t *testing.T) {
+		_, metrics := measure(t, "chunks=8&interval=50ms")
+		if metrics.TotalChunks < 8 || metrics.TotalBytes != 8*128 {
+			t.Fatalf("Expected 8 chunks of 128 bytes, got %d chunks, %d bytes", metrics.TotalChunks, metrics.TotalBytes)
+		}
+		if metrics.BufferingAnalysis.BufferingDetected || len(metrics.Stalls) != 0 {
+			t.Errorf("Expected an unbuffered stream without stalls, got %+v", metrics.BufferingAnalysis)
+		}
+	})
+
+	t.Run("stall", func(t *testing.T) {
+		_, metrics := measure(t, "chunks=6&interval=20ms&stall=600ms")
+		if len(metrics.Stalls) != 1 {
+			t.Fatalf("Expected one stall, got %d", len(metrics.Stalls))
+		}
+		if d := time.Duration(metrics.Stalls[0].Duration); d < 600*time.Millisecond {
+			t.Errorf("Expected a stall of at least 600ms, got %v", d)
+		}
+	})
+
+	t.Run("buffered", func(t *testing.T) {
+		timing, metrics := measure(t, "chunks=6&interval=120ms&buffer=true")
+		if wait := time.Duration(timing.ServerProcessing); wait < 600*time.Millisecond {
+			t.Errorf("Expected the production time before the first byte, got %v", wait)
+		}
+		if spread := time.Duration(metrics.LastChunkTime - metrics.FirstChunkTime); spread > 100*time.Millisecond {
+			t.Errorf("Expected the body at once, got it over %v", spread)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TLSOptions contains the user-facing TLS settings (--cert, --cacert, --tls-min, ...)
+type TLSOptions struct {
+	CertFile   string   // Client certificate (PEM)
+	KeyFile    string   // Client private key (PEM); defaults to CertFile
+	CAFile     string   // CA bundle replacing the system roots
+	CAPath     string   // Directory of PEM CA certificates
+	MinVersion string   // "1.0", "1.1", "1.2" or "1.3"
+	MaxVersion string   // "1.0", "1.1", "1.2" or "1.3"
+	Ciphers    []string // Cipher suite names (TLS 1.0-1.2 only)
+	Curves     []string // Key exchange groups
+	ServerName string   // SNI override
+	KeyLogFile string   // Append TLS secrets here (NSS key log format)
+}
+
+// BuildTLSConfig creates a tls.Config from the given options
+func BuildTLSConfig(insecure bool, opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: insecure,
+		ServerName:         opts.ServerName,
+	}
+
+	// Client certificate for mTLS
+	if opts.CertFile != "" {
+		keyFile := opts.KeyFile
+		if keyFile == "" {
+			keyFile = opts.CertFile
+		}
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	} else if opts.KeyFile != "" {
+		return nil, fmt.Errorf("--key requires --cert")
+	}
+
+	// Private CAs replace the system roots, like curl
+	if opts.CAFile != "" || opts.CAPath != "" {
+		pool, err := loadCertPool(opts.CAFile, opts.CAPath)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.MinVersion != "" {
+		version, err := ParseTLSVersion(opts.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		config.MinVersion = version
+	}
+
+	if opts.MaxVersion != "" {
+		version, err := ParseTLSVersion(opts.MaxVersion)
+		if err != nil {
+			return nil, err
+		}
+		config.MaxVersion = version
+	}
+
+	if config.MinVersion != 0 && config.MaxVersion != 0 && config.MinVersion > config.MaxVersion {
+		return nil, fmt.Errorf("--tls-min %s is higher than --tls-max %s", opts.MinVersion, opts.MaxVersion)
+	}
+
+	if len(opts.Ciphers) > 0 {
+		suites, err := ParseCipherSuites(opts.Ciphers)
+		if err != nil {
+			return nil, err
+		}
+		config.CipherSuites = suites
+	}
+
+	if len(opts.Curves) > 0 {
+		curves, err := ParseCurves(opts.Curves)
+		if err != nil {
+			return nil, err
+		}
+		config.CurvePreferences = curves
+	}
+
+	if opts.KeyLogFile != "" {
+		keyLog, err := OpenKeyLog(opts.KeyLogFile)
+		if err != nil {
+			return nil, err
+		}
+		config.KeyLogWriter = keyLog
+	}
+
+	return config, nil
+}
+
+// loadCertPool reads PEM certificates from a bundle file and/or a directory
+func loadCertPool(caFile, caPath string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", caFile)
+		}
+	}
+
+	if caPath != "" {
+		entries, err := os.ReadDir(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA path: %w", err)
+		}
+
+		found := false
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(caPath, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			// Non-PEM files (hash symlink targets, READMEs) are skipped
+			if pool.AppendCertsFromPEM(data) {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no PEM certificates found in %s", caPath)
+		}
+	}
+
+	return pool, nil
+}
+
+// ParseTLSVersion converts "1.2", "tls1.2" or "TLSv1.2" into a tls.Version constant
+func ParseTLSVersion(s string) (uint16, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	v = strings.TrimPrefix(v, "tlsv")
+	v = strings.TrimPrefix(v, "tls")
+
+	switch v {
+	case "1.0", "1":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid TLS version '%s': expected 1.0, 1.1, 1.2 or 1.3", s)
+	}
+}
+
+// ParseCipherSuites converts IANA cipher suite names into IDs.
+// Go does not allow configuring TLS 1.3 suites, so only TLS 1.0-1.2 suites are accepted.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or unsupported cipher suite '%s'", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// curveNames maps accepted --curves names to tls.CurveID values
+var curveNames = map[string]tls.CurveID{
+	"x25519":         tls.X25519,
+	"p-256":          tls.CurveP256,
+	"p256":           tls.CurveP256,
+	"prime256v1":     tls.CurveP256,
+	"p-384":          tls.CurveP384,
+	"p384":           tls.CurveP384,
+	"secp384r1":      tls.CurveP384,
+	"p-521":          tls.CurveP521,
+	"p521":           tls.CurveP521,
+	"secp521r1":      tls.CurveP521,
+	"x25519mlkem768": tls.X25519MLKEM768,
+}
+
+// ParseCurves converts key exchange group names into tls.CurveID values
+func ParseCurves(names []string) ([]tls.CurveID, error) {
+	curves := make([]tls.CurveID, 0, len(names))
+	for _, name := range names {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key == "" {
+			continue
+		}
+		curve, ok := curveNames[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown curve '%s'", name)
+		}
+		curves = append(curves, curve)
+	}
+	return curves, nil
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate creates a self-signed certificate and key and writes
+// them as PEM files into dir
+func writeTestCertificate(t *testing.T, dir, name string, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{usage},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected uint16
+		wantErr  bool
+	}{
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"tls1.1", tls.VersionTLS11, false},
+		{"TLSv1.0", tls.VersionTLS10, false},
+		{"1.4", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			version, err := ParseTLSVersion(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if version != tt.expected {
+				t.Errorf("Expected %x, got %x", tt.expected, version)
+			}
+		})
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	ids, err := ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", " tls_ecdhe_rsa_with_aes_256_gcm_sha384 "})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(ids) != 2 || ids[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 || ids[1] != tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 {
+		t.Errorf("Unexpected cipher suite IDs: %v", ids)
+	}
+
+	if _, err := ParseCipherSuites([]string{"NOT_A_CIPHER"}); err == nil {
+		t.Error("Expected error for unknown cipher suite")
+	}
+}
+
+func TestParseCurves(t *testing.T) {
+	curves, err := ParseCurves([]string{"X25519", "P-256", "secp384r1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384}
+	for i, curve := range expected {
+		if curves[i] != curve {
+			t.Errorf("Curve %d: expected %v, got %v", i, curve, curves[i])
+		}
+	}
+
+	if _, err := ParseCurves([]string{"brainpool"}); err == nil {
+		t.Error("Expected error for unknown curve")
+	}
+}
+
+func TestBuildTLSConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts TLSOptions
+	}{
+		{"key without cert", TLSOptions{KeyFile: "client.key"}},
+		{"missing cert", TLSOptions{CertFile: "/nonexistent/client.crt"}},
+		{"missing cacert", TLSOptions{CAFile: "/nonexistent/ca.pem"}},
+		{"min above max", TLSOptions{MinVersion: "1.3", MaxVersion: "1.2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildTLSConfig(false, tt.opts); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestClientCustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)
+
+	for _, opts := range []TLSOptions{{CAFile: caFile}, {CAPath: dir}} {
+		opts.ServerName = "example.com"
+		opts.MaxVersion = "1.2"
+		tlsConfig, err := BuildTLSConfig(false, opts)
+		if err != nil {
+			t.Fatalf("BuildTLSConfig failed: %v", err)
+		}
+
+		client := NewClient(&Config{Timeout: 5 * time.Second, TLSConfig: tlsConfig})
+		timing, err := client.MeasureRequest(server.URL, "GET", nil, nil)
+		client.Close()
+		if err != nil {
+			t.Fatalf("Verification against custom CA failed: %v", err)
+		}
+
+		if timing.TLSVersion != "TLS 1.2" {
+			t.Errorf("Expected pinned TLS 1.2, got %s", timing.TLSVersion)
+		}
+
+		if timing.TLSServerName != "example.com" {
+			t.Errorf("Expected SNI override example.com, got %s", timing.TLSServerName)
+		}
+	}
+}
+
+func TestClientMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir, "client", x509.ExtKeyUsageClientAuth)
+
+	clientCAs := x509.NewCertPool()
+	certPEM, _ := os.ReadFile(certFile)
+	clientCAs.AppendCertsFromPEM(certPEM)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	// Without a certificate the handshake must fail
+	client := NewClient(&Config{Timeout: 5 * time.Second, Insecure: true})
+	if _, err := client.MeasureRequest(server.URL, "GET", nil, nil); err == nil {
+		t.Error("Expected request without client certificate to fail")
+	}
+	client.Close()
+
+	tlsConfig, err := BuildTLSConfig(true, TLSOptions{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("BuildTLSConfig failed: %v", err)
+	}
+
+	client = NewClient(&Config{Timeout: 5 * time.Second, TLSConfig: tlsConfig})
+	defer client.Close()
+
+	timing, err := client.MeasureRequest(server.URL, "GET", nil, nil)
+	if err != nil {
+		t.Fatalf("mTLS request failed: %v", err)
+	}
+
+	if !timing.TLSClientCertSent {
+		t.Error("Expected client certificate to be reported as sent")
+	}
+
+	if timing.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", timing.StatusCode)
+	}
+}
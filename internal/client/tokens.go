@@ -0,0 +1,327 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Token stream formats accepted by Config.StreamFormat
+const (
+	StreamFormatOpenAI    = "openai"    // OpenAI-compatible chat/completions SSE stream
+	StreamFormatAnthropic = "anthropic" // Anthropic Messages SSE stream
+	StreamFormatNDJSON    = "ndjson"    // Newline-delimited JSON, as Ollama streams
+)
+
+// StreamFormats lists the token stream formats
+var StreamFormats = []string{StreamFormatOpenAI, StreamFormatAnthropic, StreamFormatNDJSON}
+
+// ParseStreamFormat validates a --stream-format value
+func ParseStreamFormat(value string) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(value))
+	for _, known := range StreamFormats {
+		if format == known {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown stream format '%s' (use %s)", value, strings.Join(StreamFormats, ", "))
+}
+
+// TokenMetrics describes a streamed model response, one delta at a time
+type TokenMetrics struct {
+	Format        string `json:"format"`
+	Model         string `json:"model,omitempty"`
+	Deltas        int    `json:"deltas"`        // Stream messages that carried output
+	OutputTokens  int    `json:"output_tokens"` // From the usage block when reported, else the delta count
+	InputTokens   int    `json:"input_tokens,omitempty"`
+	UsageReported bool   `json:"usage_reported"`
+	StopReason    string `json:"stop_reason,omitempty"`
+	Error         string `json:"error,omitempty"`     // Error reported inside the stream
+	Malformed     int    `json:"malformed,omitempty"` // Messages that were not valid JSON
+
+	// Timing, measured from when the request was sent
+	TimeToFirstToken Duration `json:"time_to_first_token"`
+	LastToken        Duration `json:"last_token"`
+
+	// Rates over the generation, from the first to the last token
+	TokensPerSecond float64 `json:"tokens_per_second"`
+	DeltasPerSecond float64 `json:"deltas_per_second"`
+
+	// Inter-token latency: the gaps between consecutive deltas
+	InterTokenMean Duration   `json:"inter_token_mean"`
+	InterTokenP50  Duration   `json:"inter_token_p50"`
+	InterTokenP90  Duration   `json:"inter_token_p90"`
+	InterTokenP99  Duration   `json:"inter_token_p99"`
+	InterTokenMax  Duration   `json:"inter_token_max"`
+	InterTokenGaps []Duration `json:"-"` // Unsorted, for aggregation across requests
+}
+
+// tokenDecoder turns stream messages into TokenMetrics
+type tokenDecoder struct {
+	metrics TokenMetrics
+	arrived []time.Duration // Arrival time of each delta
+}
+
+func newTokenDecoder(format string) *tokenDecoder {
+	return &tokenDecoder{metrics: TokenMetrics{Format: format}}
+}
+
+// decode reads one stream message (an SSE event's data or an NDJSON line)
+// that arrived elapsed after the request was sent
+func (d *tokenDecoder) decode(data []byte, elapsed time.Duration) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "[DONE]" {
+		return
+	}
+
+	var delta bool
+	var err error
+	switch d.metrics.Format {
+	case StreamFormatOpenAI:
+		delta, err = d.openAI(data)
+	case StreamFormatAnthropic:
+		delta, err = d.anthropic(data)
+	case StreamFormatNDJSON:
+		delta, err = d.ndjson(data)
+	}
+	if err != nil {
+		d.metrics.Malformed++
+		return
+	}
+	if delta {
+		d.metrics.Deltas++
+		d.arrived = append(d.arrived, elapsed)
+	}
+}
+
+type streamError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (e *streamError) String() string {
+	if e.Type != "" && e.Message != "" {
+		return e.Type + ": " + e.Message
+	}
+	return e.Type + e.Message
+}
+
+// openAI reads a chat.completion.chunk or text_completion chunk
+func (d *tokenDecoder) openAI(data []byte) (bool, error) {
+	var chunk struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Text  string `json:"text"`
+			Delta struct {
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"`
+				ToolCalls        []struct {
+					Function struct {
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"delta"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage *struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+		Error *streamError `json:"error"`
+	}
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return false, err
+	}
+
+	if chunk.Model != "" {
+		d.metrics.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		d.usage(chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens)
+	}
+	if chunk.Error != nil {
+		d.metrics.Error = chunk.Error.String()
+	}
+
+	delta := false
+	for _, choice := range chunk.Choices {
+		if choice.FinishReason != "" {
+			d.metrics.StopReason = choice.FinishReason
+		}
+		if choice.Text != "" || choice.Delta.Content != "" || choice.Delta.ReasoningContent != "" {
+			delta = true
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			if call.Function.Arguments != "" {
+				delta = true
+			}
+		}
+	}
+	return delta, nil
+}
+
+// anthropic reads a Messages API stream event
+func (d *tokenDecoder) anthropic(data []byte) (bool, error) {
+	var event struct {
+		Type    string `json:"type"`
+		Message struct {
+			Model string `json:"model"`
+			Usage struct {
+				InputTokens int `json:"input_tokens"`
+			} `json:"usage"`
+		} `json:"message"`
+		Delta struct {
+			Text        string `json:"text"`
+			PartialJSON string `json:"partial_json"`
+			Thinking    string `json:"thinking"`
+			StopReason  string `json:"stop_reason"`
+		} `json:"delta"`
+		Usage *struct {
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+		Error *streamError `json:"error"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return false, err
+	}
+
+	switch event.Type {
+	case "message_start":
+		d.metrics.Model = event.Message.Model
+		d.metrics.InputTokens = event.Message.Usage.InputTokens
+	case "content_block_delta":
+		return event.Delta.Text != "" || event.Delta.PartialJSON != "" || event.Delta.Thinking != "", nil
+	case "message_delta":
+		if event.Delta.StopReason != "" {
+			d.metrics.StopReason = event.Delta.StopReason
+		}
+		if event.Usage != nil {
+			d.usage(d.metrics.InputTokens, event.Usage.OutputTokens)
+		}
+	case "error":
+		if event.Error != nil {
+			d.metrics.Error = event.Error.String()
+		}
+	}
+	return false, nil
+}
+
+// ndjson reads one line of an Ollama generate or chat stream
+func (d *tokenDecoder) ndjson(data []byte) (bool, error) {
+	var line struct {
+		Model    string `json:"model"`
+		Response string `json:"response"`
+		Message  struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Done            bool   `json:"done"`
+		DoneReason      string `json:"done_reason"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
+		Error           string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &line); err != nil {
+		return false, err
+	}
+
+	if line.Model != "" {
+		d.metrics.Model = line.Model
+	}
+	if line.Error != "" {
+		d.metrics.Error = line.Error
+	}
+	if line.Done {
+		d.metrics.StopReason = line.DoneReason
+		if line.EvalCount > 0 {
+			d.usage(line.PromptEvalCount, line.EvalCount)
+		}
+	}
+	return line.Response != "" || line.Message.Content != "", nil
+}
+
+// usage records a usage block; the last one in the stream wins
+func (d *tokenDecoder) usage(input, output int) {
+	d.metrics.InputTokens = input
+	d.metrics.OutputTokens = output
+	d.metrics.UsageReported = true
+}
+
+// finish computes the timing statistics once the stream has ended
+func (d *tokenDecoder) finish() *TokenMetrics {
+	m := &d.metrics
+	if !m.UsageReported {
+		m.OutputTokens = m.Deltas
+	}
+	if len(d.arrived) == 0 {
+		return m
+	}
+
+	m.TimeToFirstToken = Duration(d.arrived[0])
+	m.LastToken = Duration(d.arrived[len(d.arrived)-1])
+	if len(d.arrived) < 2 {
+		return m
+	}
+
+	// The first token's latency is in TTFT, so rates count what followed it
+	generation := time.Duration(m.LastToken - m.TimeToFirstToken).Seconds()
+	if generation > 0 {
+		m.DeltasPerSecond = float64(m.Deltas-1) / generation
+		m.TokensPerSecond = float64(m.OutputTokens-1) / generation
+	}
+
+	gaps := make([]time.Duration, len(d.arrived)-1)
+	var total time.Duration
+	for i := 1; i < len(d.arrived); i++ {
+		gaps[i-1] = d.arrived[i] - d.arrived[i-1]
+		total += gaps[i-1]
+		m.InterTokenGaps = append(m.InterTokenGaps, Duration(gaps[i-1]))
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	m.InterTokenMean = Duration(total / time.Duration(len(gaps)))
+	m.InterTokenP50 = Duration(percentileDuration(gaps, 50))
+	m.InterTokenP90 = Duration(percentileDuration(gaps, 90))
+	m.InterTokenP99 = Duration(percentileDuration(gaps, 99))
+	m.InterTokenMax = Duration(gaps[len(gaps)-1])
+	return m
+}
+
+// lineReader hands each complete line of the body to a token decoder as it
+// is read
+type lineReader struct {
+	reader  io.Reader
+	start   time.Time
+	decoder *tokenDecoder
+	partial []byte
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		elapsed := time.Since(r.start)
+		b := p[:n]
+		for {
+			i := bytes.IndexByte(b, '\n')
+			if i < 0 {
+				r.partial = append(r.partial, b...)
+				break
+			}
+			line := b[:i]
+			if len(r.partial) > 0 {
+				line = append(r.partial, line...)
+				r.partial = nil
+			}
+			r.decoder.decode(line, elapsed)
+			b = b[i+1:]
+		}
+	}
+	if err == io.EOF && len(r.partial) > 0 {
+		// The last line may end without a newline
+		r.decoder.decode(r.partial, time.Since(r.start))
+		r.partial = nil
+	}
+	return n, err
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erfi/gocurl/internal/server"
+)
+
+func TestParseStreamFormat(t *testing.T) {
+	if format, err := ParseStreamFormat(" OpenAI "); err != nil || format != StreamFormatOpenAI {
+		t.Errorf("Expected openai, got %q (%v)", format, err)
+	}
+	if _, err := ParseStreamFormat("grpc"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestTokenDecoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		messages []string
+		expected TokenMetrics
+	}{
+		{
+			name:   "openai with usage",
+			format: StreamFormatOpenAI,
+			messages: []string{
+				`{"model":"m1","choices":[{"delta":{"role":"assistant"}}]}`,
+				`{"choices":[{"delta":{"content":"Hel"}}]}`,
+				`{"choices":[{"delta":{"reasoning_content":"hmm"}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"function":{"arguments":"{\"a\":"}}]}}]}`,
+				`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+				`{"choices":[],"usage":{"prompt_tokens":7,"completion_tokens":9}}`,
+				`[DONE]`,
+			},
+			expected: TokenMetrics{Model: "m1", Deltas: 3, OutputTokens: 9, InputTokens: 7, UsageReported: true, StopReason: "tool_calls"},
+		},
+		{
+			name:     "openai completions without usage",
+			format:   StreamFormatOpenAI,
+			messages: []string{`{"choices":[{"text":"a"}]}`, `{"choices":[{"text":"b","finish_reason":"length"}]}`, `not json`},
+			expected: TokenMetrics{Deltas: 2, OutputTokens: 2, StopReason: "length", Malformed: 1},
+		},
+		{
+			name:   "anthropic",
+			format: StreamFormatAnthropic,
+			messages: []string{
+				`{"type":"message_start","message":{"model":"m2","usage":{"input_tokens":5,"output_tokens":1}}}`,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"thinking"}}`,
+				`{"type":"content_block_delta","delta":{"type":"thinking_delta","thinking":"x"}}`,
+				`{"type":"content_block_delta","delta":{"type":"text_delta","text":"Hi"}}`,
+				`{"type":"ping"}`,
+				`{"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":4}}`,
+				`{"type":"message_stop"}`,
+			},
+			expected: TokenMetrics{Model: "m2", Deltas: 2, OutputTokens: 4, InputTokens: 5, UsageReported: true, StopReason: "max_tokens"},
+		},
+		{
+			name:   "anthropic error",
+			format: StreamFormatAnthropic,
+			messages: []string{
+				`{"type":"content_block_delta","delta":{"text":"Hi"}}`,
+				`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			},
+			expected: TokenMetrics{Deltas: 1, OutputTokens: 1, Error: "overloaded_error: Overloaded"},
+		},
+		{
+			name:   "ollama chat",
+			format: StreamFormatNDJSON,
+			messages: []string{
+				`{"model":"llama","message":{"role":"assistant","content":"Hel"},"done":false}`,
+				`{"model":"llama","message":{"role":"assistant","content":"lo"},"done":false}`,
+				`{"model":"llama","message":{"content":""},"done":true,"done_reason":"stop","prompt_eval_count":3,"eval_count":2}`,
+			},
+			expected: TokenMetrics{Model: "llama", Deltas: 2, OutputTokens: 2, InputTokens: 3, UsageReported: true, StopReason: "stop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTokenDecoder(tt.format)
+			for i, msg := range tt.messages {
+				d.decode([]byte(msg), time.Duration(i+1)*10*time.Millisecond)
+			}
+			m := d.finish()
+			e := tt.expected
+			if m.Model != e.Model || m.Deltas != e.Deltas || m.OutputTokens != e.OutputTokens ||
+				m.InputTokens != e.InputTokens || m.UsageReported != e.UsageReported ||
+				m.StopReason != e.StopReason || m.Error != e.Error || m.Malformed != e.Malformed {
+				t.Errorf("Expected %+v, got %+v", e, *m)
+			}
+		})
+	}
+}
+
+func TestTokenTiming(t *testing.T) {
+	d := newTokenDecoder(StreamFormatNDJSON)
+	for _, at := range []time.Duration{100, 110, 130, 160} {
+		d.decode([]byte(`{"response":"x"}`), at*time.Millisecond)
+	}
+	m := d.finish()
+	if m.TimeToFirstToken != Duration(100*time.Millisecond) || m.LastToken != Duration(160*time.Millisecond) {
+		t.Errorf("Unexpected first/last token: %v, %v", m.TimeToFirstToken, m.LastToken)
+	}
+	if m.InterTokenP50 != Duration(20*time.Millisecond) || m.InterTokenMax != Duration(30*time.Millisecond) || len(m.InterTokenGaps) != 3 {
+		t.Errorf("Unexpected inter-token latency: %+v", m)
+	}
+	// Three tokens after the first in 60ms
+	if m.TokensPerSecond < 49.9 || m.TokensPerSecond > 50.1 {
+		t.Errorf("Expected 50 tokens/s, got %.2f", m.TokensPerSecond)
+	}
+}
+
+func TestMeasureTokenStream(t *testing.T) {
+	srv := httptest.NewServer(server.NewHandler(nil))
+	defer srv.Close()
+
+	for _, format := range StreamFormats {
+		t.Run(format, func(t *testing.T) {
+			client := NewClient(&Config{Timeout: 5 * time.Second, StreamFormat: format})
+			url := srv.URL + "/?chunks=6&interval=20ms&latency=50ms&format=" + format
+			timing, _, err := client.MeasureRequestWithStreaming(context.Background(), url, "GET", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := timing.Tokens
+			if m == nil || m.Deltas != 6 || m.OutputTokens != 6 || !m.UsageReported || m.StopReason == "" {
+				t.Fatalf("Unexpected token metrics: %+v", m)
+			}
+			if ttft := time.Duration(m.TimeToFirstToken); ttft < 50*time.Millisecond {
+				t.Errorf("Expected a TTFT of at least 50ms, got %v", ttft)
+			}
+			if p50 := time.Duration(m.InterTokenP50); p50 < 15*time.Millisecond || p50 > 60*time.Millisecond {
+				t.Errorf("Expected tokens about 20ms apart, got %v", p50)
+			}
+		})
+	}
+}
+
+func TestMeasureTokenStreamError(t *testing.T) {
+	srv := httptest.NewServer(server.NewHandler(nil))
+	defer srv.Close()
+
+	// A plain body in an SSE format yields no deltas and no error
+	client := NewClient(&Config{Timeout: 5 * time.Second, StreamFormat: StreamFormatOpenAI})
+	timing, _, err := client.MeasureRequestWithStreaming(context.Background(), srv.URL+"/?format=sse&chunks=2", "GET", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing.Tokens.Deltas != 0 || timing.Tokens.Malformed != 2 || timing.Error != "" {
+		t.Errorf("Expected two malformed messages, got %+v (error %q)", timing.Tokens, timing.Error)
+	}
+
+	d := newTokenDecoder(StreamFormatOpenAI)
+	d.decode([]byte(`{"error":{"message":"rate limited"}}`), time.Millisecond)
+	if m := d.finish(); !strings.Contains(m.Error, "rate limited") {
+		t.Errorf("Expected the stream error, got %q", m.Error)
+	}
+}
@@ -1,8 +1,12 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
+	"net"
+	"net/http"
 	"net/http/httptrace"
+	"net/url"
 	"sync"
 	"time"
 )
@@ -20,37 +24,129 @@ type TimingBreakdown struct {
 	ConnectionIdle   bool     `json:"connection_idle"`
 	IdleTime         Duration `json:"idle_time"`
 
-	StatusCode       int               `json:"status_code"`
-	ContentLength    int64             `json:"content_length"`
-	ResponseSize     int64             `json:"response_size"`
-	ResponseHeaders  map[string]string `json:"response_headers,omitempty"`
-	ResponseBody     string            `json:"response_body,omitempty"`
-	TLSVersion       string            `json:"tls_version,omitempty"`
-	TLSCipherSuite   string            `json:"tls_cipher_suite,omitempty"`
-	TLSServerName    string            `json:"tls_server_name,omitempty"`
-	Error            string            `json:"error,omitempty"`
+	DNSAddresses  []string    `json:"dns_addresses,omitempty"`
+	DNSCoalesced  bool        `json:"dns_coalesced,omitempty"`
+	DNSCNAMEChain []string    `json:"dns_cname_chain,omitempty"`
+	DNSRecords    []DNSRecord `json:"dns_records,omitempty"`
+	RemoteAddr    string      `json:"remote_addr,omitempty"`
+
+	// Proxy phases: TCPConnection is the connection to the proxy, TLSHandshake
+	// the origin handshake inside the tunnel
+	Proxy             string   `json:"proxy,omitempty"`
+	ProxyTLSHandshake Duration `json:"proxy_tls_handshake,omitempty"`
+	ProxyTunnel       Duration `json:"proxy_tunnel,omitempty"`
+
+	// Time spent on an exchange rejected with an auth challenge (digest, expired
+	// OAuth2 token) before the request was retried; part of Total
+	AuthChallenge Duration `json:"auth_challenge,omitempty"`
+
+	// Every dial attempt, including Happy Eyeballs fallbacks that lost the race
+	ConnectAttempts []ConnectAttempt `json:"connect_attempts,omitempty"`
+
+	// URL and Location are set for redirect hops; the final response carries the
+	// earlier hops in Redirects, while its own phases describe the last hop only
+	URL       string            `json:"url,omitempty"`
+	Location  string            `json:"location,omitempty"`
+	Redirects []TimingBreakdown `json:"redirects,omitempty"`
+
+	// Cookies sent as "name=value" and raw Set-Cookie values received
+	CookiesSent []string `json:"cookies_sent,omitempty"`
+	CookiesSet  []string `json:"cookies_set,omitempty"`
+
+	// Set when the body was decoded from a Content-Encoding (see
+	// Config.AcceptEncoding); ResponseSize is then the decoded size
+	ContentEncoding  string   `json:"content_encoding,omitempty"`
+	WireSize         int64    `json:"wire_size,omitempty"`
+	DecodedSize      int64    `json:"decoded_size,omitempty"`
+	CompressionRatio float64  `json:"compression_ratio,omitempty"`
+	Decompression    Duration `json:"decompression,omitempty"`
+
+	StatusCode        int               `json:"status_code"`
+	Protocol          string            `json:"protocol,omitempty"`
+	ContentLength     int64             `json:"content_length"`
+	ResponseSize      int64             `json:"response_size"`
+	ResponseHeaders   Headers           `json:"response_headers,omitempty"`
+	ResponseBody      string            `json:"response_body,omitempty"`
+	TLSVersion        string            `json:"tls_version,omitempty"`
+	TLSCipherSuite    string            `json:"tls_cipher_suite,omitempty"`
+	TLSServerName     string            `json:"tls_server_name,omitempty"`
+	TLSALPN           string            `json:"tls_alpn,omitempty"`
+	TLSClientCertSent bool              `json:"tls_client_cert_sent,omitempty"`
+	TLSCertificates   []CertificateInfo `json:"tls_certificates,omitempty"`
+	TLSOCSPStapled    bool              `json:"tls_ocsp_stapled,omitempty"`
+	TLSSCTCount       int               `json:"tls_sct_count,omitempty"`
+	TLSResumed        bool              `json:"tls_resumed,omitempty"`
+	TLSEarlyData      bool              `json:"tls_early_data,omitempty"`
+	Error             string            `json:"error,omitempty"`
 
 	// Streaming metrics (populated when --streaming flag is used)
-	Streaming        *StreamMetrics    `json:"streaming,omitempty"`
+	Streaming *StreamMetrics `json:"streaming,omitempty"`
+
+	// Token streaming metrics (populated when a stream format is set)
+	Tokens *TokenMetrics `json:"tokens,omitempty"`
+
+	// WebSocket message phase (populated for ws:// and wss:// URLs)
+	WebSocket *WebSocketMetrics `json:"websocket,omitempty"`
+
+	// gRPC status and messages (populated by gocurl grpc)
+	GRPC *GRPCMetrics `json:"grpc,omitempty"`
+
+	// HTTP/2 frame trace (populated with --h2-trace)
+	H2 *H2Trace `json:"h2,omitempty"`
+}
+
+// ConnectAttempt describes one TCP connect attempt to a resolved address
+type ConnectAttempt struct {
+	Address  string   `json:"address"`
+	Family   string   `json:"family"`
+	Started  Duration `json:"started"` // Offset from the first attempt
+	Duration Duration `json:"duration"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// connectAttempt is the raw form of a ConnectAttempt while the request runs
+type connectAttempt struct {
+	addr  string
+	start time.Time
+	end   time.Time
+	err   error
 }
 
 // Tracer captures detailed timing information during HTTP request execution
 type Tracer struct {
-	mu           sync.Mutex
-	dnsStart     time.Time
-	dnsEnd       time.Time
-	connStart    time.Time
-	connEnd      time.Time
-	tlsStart     time.Time
-	tlsEnd       time.Time
-	reqStart     time.Time
-	respStart    time.Time
-	respEnd      time.Time
-	totalStart   time.Time
+	mu         sync.Mutex
+	dnsStart   time.Time
+	dnsEnd     time.Time
+	connStart  time.Time
+	connEnd    time.Time
+	tlsStart   time.Time
+	tlsEnd     time.Time
+	reqStart   time.Time
+	respStart  time.Time
+	respEnd    time.Time
+	totalStart time.Time
+	hopStart   time.Time
+
+	dnsAddrs     []string
+	dnsCoalesced bool
+	dnsRecords   []DNSRecord
+	remoteAddr   string
+	attempts     []connectAttempt
+
+	proxy         *url.URL
+	proxyTLSStart time.Time
+	proxyTLSEnd   time.Time
+	tunnelStart   time.Time
+	tunnelEnd     time.Time
 
-	tlsState     *tls.ConnectionState
+	authChallenge time.Duration
 
-	timing       *TimingBreakdown
+	tlsState       *tls.ConnectionState
+	clientCertSent bool
+	earlyDataProbe func() bool
+
+	timing *TimingBreakdown
+	hops   []TimingBreakdown
 }
 
 // NewTracer creates a new Tracer instance
@@ -60,6 +156,113 @@ func NewTracer() *Tracer {
 	}
 }
 
+// tracerKey is the context key under which a request's Tracer is stored
+type tracerKey struct{}
+
+// WithContext attaches the tracer and its httptrace hooks to ctx
+func (t *Tracer) WithContext(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, tracerKey{}, t)
+	return httptrace.WithClientTrace(ctx, t.ClientTrace())
+}
+
+// tracerFromContext returns the Tracer attached with WithContext, if any.
+// Used by transport callbacks that httptrace does not cover.
+func tracerFromContext(ctx context.Context) *Tracer {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(tracerKey{}).(*Tracer)
+	return t
+}
+
+// markClientCertSent records that the server requested and received a client certificate
+func (t *Tracer) markClientCertSent() {
+	t.mu.Lock()
+	t.clientCertSent = true
+	t.mu.Unlock()
+}
+
+// setEarlyDataProbe registers a callback reporting whether 0-RTT data was accepted
+func (t *Tracer) setEarlyDataProbe(probe func() bool) {
+	t.mu.Lock()
+	t.earlyDataProbe = probe
+	t.mu.Unlock()
+}
+
+// setProxy records the proxy chosen for the request
+func (t *Tracer) setProxy(proxyURL *url.URL) {
+	t.mu.Lock()
+	t.proxy = proxyURL
+	t.mu.Unlock()
+}
+
+// startTimes returns when the request and its last hop started
+func (t *Tracer) startTimes() (time.Time, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.hopStart.IsZero() {
+		return t.totalStart, t.totalStart
+	}
+	return t.totalStart, t.hopStart
+}
+
+// proxyURL returns the proxy chosen for the request, if any
+func (t *Tracer) proxyURL() *url.URL {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.proxy
+}
+
+// markProxyTunnelStart records the start of the CONNECT request or SOCKS handshake
+func (t *Tracer) markProxyTunnelStart() {
+	t.mu.Lock()
+	t.tunnelStart = time.Now()
+	t.mu.Unlock()
+}
+
+// markProxyTunnelDone records that the proxy tunnel to the origin is established
+func (t *Tracer) markProxyTunnelDone() {
+	t.mu.Lock()
+	t.tunnelEnd = time.Now()
+	t.mu.Unlock()
+}
+
+// markAuthChallenge records the time lost on an exchange rejected with an
+// auth challenge. It is counted from when the rejected request was written, so
+// it does not overlap the connection phases; start is the fallback.
+func (t *Tracer) markAuthChallenge(start time.Time) {
+	t.mu.Lock()
+	if t.reqStart.After(start) {
+		start = t.reqStart
+	}
+	t.authChallenge = time.Since(start)
+	t.mu.Unlock()
+}
+
+// isProxyHandshake reports whether a TLS handshake starting now is the one
+// with an HTTPS proxy, which always precedes the tunnel; must be called with mu held
+func (t *Tracer) isProxyHandshake() bool {
+	return t.proxy != nil && t.proxy.Scheme == "https" && t.tunnelStart.IsZero() &&
+		(t.proxyTLSStart.IsZero() || t.proxyTLSEnd.IsZero())
+}
+
+// recordDNSResponse keeps the answer records of a DNS response seen by a
+// custom resolver; duplicates from the parallel A and AAAA queries are dropped
+func (t *Tracer) recordDNSResponse(msg []byte) {
+	records := parseDNSRecords(msg)
+	if len(records) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, record := range records {
+		if !containsRecord(t.dnsRecords, record) {
+			t.dnsRecords = append(t.dnsRecords, record)
+		}
+	}
+}
+
 // ClientTrace returns an httptrace.ClientTrace configured to capture timing information
 func (t *Tracer) ClientTrace() *httptrace.ClientTrace {
 	return &httptrace.ClientTrace{
@@ -68,30 +271,62 @@ func (t *Tracer) ClientTrace() *httptrace.ClientTrace {
 			t.dnsStart = time.Now()
 			t.mu.Unlock()
 		},
-		DNSDone: func(_ httptrace.DNSDoneInfo) {
+		DNSDone: func(info httptrace.DNSDoneInfo) {
 			t.mu.Lock()
 			t.dnsEnd = time.Now()
+			t.dnsCoalesced = info.Coalesced
+			t.dnsAddrs = t.dnsAddrs[:0]
+			for _, addr := range info.Addrs {
+				t.dnsAddrs = append(t.dnsAddrs, addr.String())
+			}
 			t.mu.Unlock()
 		},
-		ConnectStart: func(_, _ string) {
+		// With dual-stack hosts these fire once per address. The connection phase
+		// runs from the first attempt until one succeeds, so slow fallbacks count.
+		ConnectStart: func(_, addr string) {
 			t.mu.Lock()
-			t.connStart = time.Now()
+			now := time.Now()
+			if t.connStart.IsZero() {
+				t.connStart = now
+			}
+			t.attempts = append(t.attempts, connectAttempt{addr: addr, start: now})
 			t.mu.Unlock()
 		},
-		ConnectDone: func(_, _ string, _ error) {
+		ConnectDone: func(_, addr string, err error) {
 			t.mu.Lock()
-			t.connEnd = time.Now()
+			now := time.Now()
+			for i := range t.attempts {
+				if t.attempts[i].addr == addr && t.attempts[i].end.IsZero() {
+					t.attempts[i].end = now
+					t.attempts[i].err = err
+					break
+				}
+			}
+			if err == nil {
+				t.connEnd = now
+				t.remoteAddr = addr
+			} else if t.remoteAddr == "" {
+				t.connEnd = now
+			}
 			t.mu.Unlock()
 		},
 		TLSHandshakeStart: func() {
 			t.mu.Lock()
-			t.tlsStart = time.Now()
+			if t.isProxyHandshake() {
+				t.proxyTLSStart = time.Now()
+			} else {
+				t.tlsStart = time.Now()
+			}
 			t.mu.Unlock()
 		},
 		TLSHandshakeDone: func(state tls.ConnectionState, _ error) {
 			t.mu.Lock()
-			t.tlsEnd = time.Now()
-			t.tlsState = &state
+			if t.isProxyHandshake() {
+				t.proxyTLSEnd = time.Now()
+			} else {
+				t.tlsEnd = time.Now()
+				t.tlsState = &state
+			}
 			t.mu.Unlock()
 		},
 		WroteRequest: func(_ httptrace.WroteRequestInfo) {
@@ -106,9 +341,17 @@ func (t *Tracer) ClientTrace() *httptrace.ClientTrace {
 		},
 		GotConn: func(info httptrace.GotConnInfo) {
 			t.mu.Lock()
-			t.timing.ConnectionReused = info.Reused
-			t.timing.ConnectionIdle = info.WasIdle
-			t.timing.IdleTime = Duration(info.IdleTime)
+			// An auth retry reuses the connection of the rejected exchange;
+			// keep the state of the connection the request started on
+			if t.authChallenge == 0 {
+				t.timing.ConnectionReused = info.Reused
+				t.timing.ConnectionIdle = info.WasIdle
+				t.timing.IdleTime = Duration(info.IdleTime)
+			}
+			// Reused connections never reach ConnectDone
+			if info.Conn != nil && info.Conn.RemoteAddr() != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
 			t.mu.Unlock()
 		},
 	}
@@ -129,11 +372,65 @@ func (t *Tracer) End() {
 	t.calculateDurations()
 }
 
+// nextHop closes the current hop when the client follows a redirect. The hop's
+// breakdown is kept and the tracer starts over for the next request, so phases
+// of different hops are never mixed.
+func (t *Tracer) nextHop(resp *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.respEnd = now
+	t.fillPhases()
+
+	hop := t.timing
+	start := t.hopStart
+	if start.IsZero() {
+		start = t.totalStart
+	}
+	if !start.IsZero() {
+		hop.Total = Duration(now.Sub(start))
+	}
+	hop.StatusCode = resp.StatusCode
+	hop.Protocol = resp.Proto
+	hop.URL = resp.Request.URL.String()
+	hop.Location = resp.Header.Get("Location")
+	recordCookies(hop, resp)
+	t.hops = append(t.hops, *hop)
+
+	t.dnsStart, t.dnsEnd = time.Time{}, time.Time{}
+	t.connStart, t.connEnd = time.Time{}, time.Time{}
+	t.tlsStart, t.tlsEnd = time.Time{}, time.Time{}
+	t.reqStart, t.respStart, t.respEnd = time.Time{}, time.Time{}, time.Time{}
+	t.dnsAddrs, t.dnsCoalesced, t.dnsRecords = nil, false, nil
+	t.remoteAddr, t.attempts = "", nil
+	t.proxy = nil
+	t.proxyTLSStart, t.proxyTLSEnd = time.Time{}, time.Time{}
+	t.tunnelStart, t.tunnelEnd = time.Time{}, time.Time{}
+	t.authChallenge = 0
+	t.tlsState, t.clientCertSent, t.earlyDataProbe = nil, false, nil
+	t.timing = &TimingBreakdown{}
+	t.hopStart = now
+}
+
 // calculateDurations computes all timing durations from captured timestamps
 func (t *Tracer) calculateDurations() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	t.fillPhases()
+
+	// Total always spans every hop
+	if !t.totalStart.IsZero() {
+		t.timing.Total = Duration(time.Since(t.totalStart))
+	}
+	if len(t.hops) > 0 {
+		t.timing.Redirects = t.hops
+	}
+}
+
+// fillPhases computes the current hop's phases and connection details; must be called with mu held
+func (t *Tracer) fillPhases() {
 	// Calculate individual phase durations
 	if !t.dnsStart.IsZero() && !t.dnsEnd.IsZero() {
 		t.timing.DNSLookup = Duration(t.dnsEnd.Sub(t.dnsStart))
@@ -155,15 +452,87 @@ func (t *Tracer) calculateDurations() {
 		t.timing.ContentTransfer = Duration(t.respEnd.Sub(t.respStart))
 	}
 
-	if !t.totalStart.IsZero() {
-		t.timing.Total = Duration(time.Since(t.totalStart))
+	// DNS results and the address actually connected to
+	if len(t.dnsAddrs) > 0 {
+		t.timing.DNSAddresses = t.dnsAddrs
+	}
+	t.timing.DNSCoalesced = t.dnsCoalesced
+	if len(t.dnsRecords) > 0 {
+		t.timing.DNSRecords = t.dnsRecords
+		t.timing.DNSCNAMEChain = cnameChain(t.dnsRecords)
 	}
+	t.timing.RemoteAddr = t.remoteAddr
+	t.timing.ConnectAttempts = t.connectAttempts()
+
+	if t.proxy != nil {
+		t.timing.Proxy = t.proxy.Scheme + "://" + t.proxy.Host
+	}
+	if !t.proxyTLSStart.IsZero() && !t.proxyTLSEnd.IsZero() {
+		t.timing.ProxyTLSHandshake = Duration(t.proxyTLSEnd.Sub(t.proxyTLSStart))
+	}
+	if !t.tunnelStart.IsZero() && !t.tunnelEnd.IsZero() {
+		t.timing.ProxyTunnel = Duration(t.tunnelEnd.Sub(t.tunnelStart))
+	}
+	t.timing.AuthChallenge = Duration(t.authChallenge)
 
 	// Populate TLS information if available
 	if t.tlsState != nil {
 		t.timing.TLSVersion = tlsVersionString(t.tlsState.Version)
 		t.timing.TLSCipherSuite = tls.CipherSuiteName(t.tlsState.CipherSuite)
 		t.timing.TLSServerName = t.tlsState.ServerName
+		t.timing.TLSALPN = t.tlsState.NegotiatedProtocol
+		t.timing.TLSCertificates, t.timing.TLSOCSPStapled, t.timing.TLSSCTCount = InspectChain(t.tlsState, time.Now())
+		t.timing.TLSResumed = t.tlsState.DidResume
+	}
+	if t.earlyDataProbe != nil {
+		t.timing.TLSEarlyData = t.earlyDataProbe()
+	}
+	t.timing.TLSClientCertSent = t.clientCertSent
+}
+
+// connectAttempts converts the recorded dial attempts; must be called with mu held
+func (t *Tracer) connectAttempts() []ConnectAttempt {
+	if len(t.attempts) == 0 {
+		return nil
+	}
+
+	first := t.attempts[0].start
+	attempts := make([]ConnectAttempt, 0, len(t.attempts))
+	for _, a := range t.attempts {
+		attempt := ConnectAttempt{
+			Address: a.addr,
+			Family:  addressFamily(a.addr),
+			Started: Duration(a.start.Sub(first)),
+		}
+		switch {
+		case a.end.IsZero():
+			// Still pending when the request finished, i.e. it lost the race
+			attempt.Error = "abandoned"
+		case a.err != nil:
+			attempt.Duration = Duration(a.end.Sub(a.start))
+			attempt.Error = a.err.Error()
+		default:
+			attempt.Duration = Duration(a.end.Sub(a.start))
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts
+}
+
+// addressFamily returns "IPv4" or "IPv6" for an ip:port address
+func addressFamily(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return "IPv4"
+	default:
+		return "IPv6"
 	}
 }
 
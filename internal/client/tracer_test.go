@@ -1,6 +1,7 @@
 package client
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -98,3 +99,57 @@ func TestTracerConnectionInfo(t *testing.T) {
 		t.Error("New connection should have zero idle time")
 	}
 }
+
+func TestTracerConnectAttempts(t *testing.T) {
+	tracer := NewTracer()
+	trace := tracer.ClientTrace()
+
+	// IPv6 attempt stalls, IPv4 fallback starts later and wins
+	trace.ConnectStart("tcp", "[2001:db8::1]:443")
+	time.Sleep(20 * time.Millisecond)
+	trace.ConnectStart("tcp", "192.0.2.1:443")
+	trace.ConnectDone("tcp", "[2001:db8::1]:443", errors.New("connect: network is unreachable"))
+	trace.ConnectDone("tcp", "192.0.2.1:443", nil)
+
+	tracer.calculateDurations()
+	timing := tracer.Timing()
+
+	if len(timing.ConnectAttempts) != 2 {
+		t.Fatalf("Expected 2 connect attempts, got %d", len(timing.ConnectAttempts))
+	}
+
+	v6, v4 := timing.ConnectAttempts[0], timing.ConnectAttempts[1]
+	if v6.Family != "IPv6" || v6.Error == "" {
+		t.Errorf("Expected failed IPv6 attempt, got %+v", v6)
+	}
+	if v4.Family != "IPv4" || v4.Error != "" {
+		t.Errorf("Expected successful IPv4 attempt, got %+v", v4)
+	}
+	if v4.Started < Duration(20*time.Millisecond) {
+		t.Errorf("Expected IPv4 attempt to start after the IPv6 one, got +%v", v4.Started)
+	}
+
+	// The connection phase includes the time lost on the failed attempt
+	if timing.TCPConnection < Duration(20*time.Millisecond) {
+		t.Errorf("TCP connection should include the fallback delay, got %v", timing.TCPConnection)
+	}
+	if timing.RemoteAddr != "192.0.2.1:443" {
+		t.Errorf("Expected remote address 192.0.2.1:443, got %s", timing.RemoteAddr)
+	}
+}
+
+func TestTracerAbandonedConnectAttempt(t *testing.T) {
+	tracer := NewTracer()
+	trace := tracer.ClientTrace()
+
+	trace.ConnectStart("tcp", "[2001:db8::1]:443")
+	trace.ConnectStart("tcp", "192.0.2.1:443")
+	trace.ConnectDone("tcp", "192.0.2.1:443", nil)
+
+	tracer.calculateDurations()
+	attempts := tracer.Timing().ConnectAttempts
+
+	if len(attempts) != 2 || attempts[0].Error != "abandoned" {
+		t.Errorf("Expected the pending IPv6 attempt to be reported as abandoned, got %+v", attempts)
+	}
+}
@@ -0,0 +1,635 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes (RFC 6455 section 5.2)
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// wsGUID is appended to the handshake key to derive Sec-WebSocket-Accept
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsMaxMessage bounds a single received message so a misbehaving server cannot
+// exhaust memory
+const wsMaxMessage = 64 << 20
+
+// wsCloseTimeout bounds the wait for the server's close frame
+const wsCloseTimeout = 5 * time.Second
+
+// WebSocketOptions describes the message phase that follows the upgrade
+type WebSocketOptions struct {
+	Messages int             // Echo round trips to time; each sends Payload and waits for the next message
+	Payload  []byte          // Body of each echo message, sent as text unless Binary
+	Binary   bool            // Send binary instead of text frames
+	Script   []WebSocketStep // Scripted steps, run after the echo round trips
+	Listen   time.Duration   // Collect server-pushed messages for this long before closing
+}
+
+// WebSocketStep is one step of a scripted session. Exactly one field is set.
+type WebSocketStep struct {
+	Send    string        `json:"send,omitempty"`    // Send a text message
+	Expect  string        `json:"expect,omitempty"`  // Wait for a message containing this text; timed from the last send
+	Receive int           `json:"receive,omitempty"` // Wait for this many server-pushed messages
+	Sleep   time.Duration `json:"sleep,omitempty"`   // Pause before the next step
+}
+
+// WebSocketMetrics describes the message phase of a WebSocket session. The
+// handshake itself is in the surrounding TimingBreakdown, whose ServerProcessing
+// is the wait for the 101 response.
+type WebSocketMetrics struct {
+	Subprotocol string `json:"subprotocol,omitempty"`
+	Extensions  string `json:"extensions,omitempty"`
+
+	MessagesSent     int   `json:"messages_sent"`
+	MessagesReceived int   `json:"messages_received"`
+	BytesSent        int64 `json:"bytes_sent"`
+	BytesReceived    int64 `json:"bytes_received"`
+
+	// Round trips from a send to the reply (echo mode or a scripted expect)
+	RoundTrips        int        `json:"round_trips"`
+	MinRTT            Duration   `json:"min_rtt"`
+	MeanRTT           Duration   `json:"mean_rtt"`
+	P50RTT            Duration   `json:"p50_rtt"`
+	P90RTT            Duration   `json:"p90_rtt"`
+	P99RTT            Duration   `json:"p99_rtt"`
+	MaxRTT            Duration   `json:"max_rtt"`
+	MessagesPerSecond float64    `json:"messages_per_second"` // Round trips completed per second of the echo phase
+	RTTs              []Duration `json:"-"`
+
+	// Messages the server sent on its own (listen and receive steps), analysed
+	// like the chunks of a streamed body, timed from the end of the upgrade
+	Pushed *StreamMetrics `json:"pushed,omitempty"`
+
+	Session        Duration `json:"session"`              // Upgrade to closed connection
+	CloseHandshake Duration `json:"close_handshake"`      // Our close frame to the server's
+	CloseCode      int      `json:"close_code,omitempty"` // Code in the server's close frame
+	CloseReason    string   `json:"close_reason,omitempty"`
+	ClosedByServer bool     `json:"closed_by_server,omitempty"` // The server closed before we were done
+}
+
+// IsWebSocketURL reports whether rawURL has a ws or wss scheme
+func IsWebSocketURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (strings.EqualFold(u.Scheme, "ws") || strings.EqualFold(u.Scheme, "wss"))
+}
+
+// MeasureWebSocket opens a WebSocket, runs the message phase described by opts
+// and closes it. The upgrade goes through the client's transport so DNS, TCP,
+// TLS, proxy and auth behave as for any request; it always uses HTTP/1.1.
+// Failures after the upgrade are reported in the timing's Error.
+func (c *Client) MeasureWebSocket(ctx context.Context, rawURL string, headers Headers, opts WebSocketOptions) (*TimingBreakdown, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	default:
+		return nil, fmt.Errorf("not a WebSocket URL: %s", rawURL)
+	}
+
+	key := make([]byte, 16)
+	rand.Read(key)
+	challenge := base64.StdEncoding.EncodeToString(key)
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	headers.apply(req)
+	if _, ok := req.Header["User-Agent"]; !ok {
+		req.Header.Set("User-Agent", DefaultUserAgent)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", challenge)
+	if jar := c.client.Jar; jar != nil {
+		for _, cookie := range jar.Cookies(req.URL) {
+			req.AddCookie(cookie)
+		}
+	}
+
+	// http.Client hides the writable body of a 101 behind its timeout wrapper,
+	// so the upgrade goes straight to the transport with the timeout applied to
+	// the handshake only
+	tracer := NewTracer()
+	handshakeCtx, cancel := ctx, context.CancelFunc(func() {})
+	if c.config.Timeout > 0 {
+		handshakeCtx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+	}
+	defer cancel()
+	req = req.WithContext(tracer.WithContext(handshakeCtx))
+
+	tracer.Start()
+	resp, err := c.client.Transport.RoundTrip(req)
+	tracer.End()
+	timing := tracer.Timing()
+	if err != nil {
+		timing.Error = err.Error()
+		return timing, err
+	}
+	timing.StatusCode = resp.StatusCode
+	timing.Protocol = resp.Proto
+	timing.ContentLength = resp.ContentLength
+	if c.config.IncludeHeaders {
+		timing.ResponseHeaders = headersFromResponse(resp.Header)
+	}
+	recordCookies(timing, resp)
+	if jar := c.client.Jar; jar != nil {
+		if cookies := resp.Cookies(); len(cookies) > 0 {
+			jar.SetCookies(req.URL, cookies)
+		}
+	}
+
+	if err := checkUpgrade(resp, challenge); err != nil {
+		if c.config.ShowBody || c.config.ShowErrorBody {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+			timing.ResponseBody = string(body)
+		}
+		resp.Body.Close()
+		timing.Error = err.Error()
+		return timing, err
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		timing.Error = "upgraded connection is not writable"
+		return timing, errors.New(timing.Error)
+	}
+
+	conn := newWSConn(rwc, true)
+	session := newWSSession(conn, c.config.Timeout)
+	m := &WebSocketMetrics{
+		Subprotocol: resp.Header.Get("Sec-WebSocket-Protocol"),
+		Extensions:  resp.Header.Get("Sec-WebSocket-Extensions"),
+	}
+	if err := session.run(ctx, opts, m); err != nil {
+		timing.Error = err.Error()
+	}
+	session.close(m)
+
+	m.Pushed = c.messageStreamMetrics("WebSocket", session.start, session.pushed, timing)
+	m.MessagesReceived = session.received
+	m.BytesReceived = session.bytesReceived
+	timing.ResponseSize = m.BytesReceived
+	timing.WebSocket = m
+	return timing, nil
+}
+
+// checkUpgrade verifies the server accepted the upgrade for our key
+func checkUpgrade(resp *http.Response, challenge string) error {
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("WebSocket upgrade refused: %s", resp.Status)
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		return fmt.Errorf("WebSocket upgrade refused: Upgrade header is %q", resp.Header.Get("Upgrade"))
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != wsAccept(challenge) {
+		return fmt.Errorf("WebSocket upgrade refused: Sec-WebSocket-Accept %q does not match the key", got)
+	}
+	return nil
+}
+
+// wsAccept derives the Sec-WebSocket-Accept value for a handshake key
+func wsAccept(key string) string {
+	sum := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// wsMessage is a complete data or close message with its arrival time
+type wsMessage struct {
+	opcode byte
+	data   []byte
+	at     time.Time
+}
+
+// wsConn reads and writes frames. Clients mask what they send; servers (only
+// used by tests here) do not.
+type wsConn struct {
+	rwc  io.ReadWriteCloser
+	r    *bufio.Reader
+	mask bool
+	mu   sync.Mutex // Serialises writes; pongs are sent from the reader
+}
+
+func newWSConn(rwc io.ReadWriteCloser, mask bool) *wsConn {
+	return &wsConn{rwc: rwc, r: bufio.NewReader(rwc), mask: mask}
+}
+
+// writeFrame sends a single final frame
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	header := make([]byte, 2, 14)
+	header[0] = 0x80 | opcode
+	var maskBit byte
+	if c.mask {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = maskBit | byte(n)
+	case n <= 0xFFFF:
+		header[1] = maskBit | 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = maskBit | 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	var frame []byte
+	if c.mask {
+		var key [4]byte
+		rand.Read(key[:])
+		frame = append(header, key[:]...)
+		for i, b := range payload {
+			frame = append(frame, b^key[i%4])
+		}
+	} else {
+		frame = append(header, payload...)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.rwc.Write(frame)
+	return err
+}
+
+// readFrame reads one frame, unmasking its payload
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(c.r, h[:]); err != nil {
+		return
+	}
+	fin = h[0]&0x80 != 0
+	opcode = h[0] & 0x0F
+	if h[0]&0x70 != 0 {
+		err = errors.New("WebSocket frame uses an extension we did not negotiate")
+		return
+	}
+
+	n := uint64(h[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.r, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxMessage {
+		err = fmt.Errorf("WebSocket frame of %d bytes exceeds the %d byte limit", n, wsMaxMessage)
+		return
+	}
+
+	var key [4]byte
+	masked := h[1]&0x80 != 0
+	if masked {
+		if _, err = io.ReadFull(c.r, key[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return
+}
+
+// readMessage returns the next data or close message, reassembling fragments
+// and answering pings along the way
+func (c *wsConn) readMessage() (opcode byte, data []byte, err error) {
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			return wsClose, payload, nil
+		case wsContinuation:
+			if opcode == 0 {
+				return 0, nil, errors.New("WebSocket continuation frame without a message")
+			}
+		case wsText, wsBinary:
+			if opcode != 0 {
+				return 0, nil, errors.New("WebSocket message started inside a fragmented message")
+			}
+			opcode = op
+		default:
+			return 0, nil, fmt.Errorf("unknown WebSocket opcode %#x", op)
+		}
+		if len(data)+len(payload) > wsMaxMessage {
+			return 0, nil, fmt.Errorf("WebSocket message exceeds the %d byte limit", wsMaxMessage)
+		}
+		data = append(data, payload...)
+		if fin {
+			return opcode, data, nil
+		}
+	}
+}
+
+// closePayload builds the body of a close frame
+func closePayload(code int, reason string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...)
+}
+
+// wsSession drives the message phase. A reader goroutine stamps each message
+// as it arrives so waiting on a step never skews its timing.
+type wsSession struct {
+	conn     *wsConn
+	timeout  time.Duration
+	start    time.Time
+	messages chan wsMessage
+	readErr  error // Set before messages is closed
+
+	closeMsg      *wsMessage // The server's close frame, once seen
+	received      int
+	bytesReceived int64
+	pushed        []messageArrival // Server-pushed messages, see Pushed
+}
+
+func newWSSession(conn *wsConn, timeout time.Duration) *wsSession {
+	s := &wsSession{conn: conn, timeout: timeout, start: time.Now(), messages: make(chan wsMessage, 64)}
+	go func() {
+		defer close(s.messages)
+		for {
+			opcode, data, err := conn.readMessage()
+			if err != nil {
+				s.readErr = err
+				return
+			}
+			s.messages <- wsMessage{opcode: opcode, data: data, at: time.Now()}
+			if opcode == wsClose {
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// next waits for the next data message. A zero timeout waits until ctx ends.
+func (s *wsSession) next(ctx context.Context, timeout time.Duration) (wsMessage, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case msg, ok := <-s.messages:
+		if !ok {
+			if s.readErr != nil {
+				return wsMessage{}, s.readErr
+			}
+			return wsMessage{}, io.ErrUnexpectedEOF
+		}
+		if msg.opcode == wsClose {
+			s.closeMsg = &msg
+			return wsMessage{}, errServerClosed
+		}
+		s.received++
+		s.bytesReceived += int64(len(msg.data))
+		return msg, nil
+	case <-expired:
+		return wsMessage{}, errWaitTimeout
+	case <-ctx.Done():
+		return wsMessage{}, ctx.Err()
+	}
+}
+
+var (
+	errServerClosed = errors.New("server closed the WebSocket")
+	errWaitTimeout  = errors.New("timed out waiting for a WebSocket message")
+)
+
+// send writes a message, counting it
+func (s *wsSession) send(m *WebSocketMetrics, opcode byte, data []byte) (time.Time, error) {
+	sent := time.Now()
+	if err := s.conn.writeFrame(opcode, data); err != nil {
+		return sent, err
+	}
+	m.MessagesSent++
+	m.BytesSent += int64(len(data))
+	return sent, nil
+}
+
+// run performs the echo round trips, the script and the listen window
+func (s *wsSession) run(ctx context.Context, opts WebSocketOptions, m *WebSocketMetrics) error {
+	opcode := byte(wsText)
+	if opts.Binary {
+		opcode = wsBinary
+	}
+
+	echoStart := time.Now()
+	for i := 0; i < opts.Messages; i++ {
+		sent, err := s.send(m, opcode, opts.Payload)
+		if err != nil {
+			return err
+		}
+		reply, err := s.next(ctx, s.timeout)
+		if err != nil {
+			return s.stepError(fmt.Sprintf("echo %d", i+1), err)
+		}
+		m.RTTs = append(m.RTTs, Duration(reply.at.Sub(sent)))
+	}
+	if opts.Messages > 0 {
+		if elapsed := time.Since(echoStart).Seconds(); elapsed > 0 {
+			m.MessagesPerSecond = float64(opts.Messages) / elapsed
+		}
+	}
+
+	var lastSend time.Time
+	for i, step := range opts.Script {
+		name := fmt.Sprintf("step %d", i+1)
+		switch {
+		case step.Send != "":
+			sent, err := s.send(m, wsText, []byte(step.Send))
+			if err != nil {
+				return err
+			}
+			lastSend = sent
+		case step.Expect != "":
+			for {
+				msg, err := s.next(ctx, s.timeout)
+				if err != nil {
+					return s.stepError(fmt.Sprintf("%s (expect %q)", name, step.Expect), err)
+				}
+				if strings.Contains(string(msg.data), step.Expect) {
+					if !lastSend.IsZero() {
+						m.RTTs = append(m.RTTs, Duration(msg.at.Sub(lastSend)))
+						lastSend = time.Time{}
+					}
+					break
+				}
+				s.pushed = append(s.pushed, messageArrival{size: len(msg.data), at: msg.at})
+			}
+		case step.Receive > 0:
+			for n := 0; n < step.Receive; n++ {
+				msg, err := s.next(ctx, s.timeout)
+				if err != nil {
+					return s.stepError(fmt.Sprintf("%s (receive %d of %d)", name, n+1, step.Receive), err)
+				}
+				s.pushed = append(s.pushed, messageArrival{size: len(msg.data), at: msg.at})
+			}
+		case step.Sleep > 0:
+			select {
+			case <-time.After(step.Sleep):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	if opts.Listen > 0 {
+		deadline := time.Now().Add(opts.Listen)
+		for {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				break
+			}
+			msg, err := s.next(ctx, remaining)
+			// A server that closes after pushing simply ends the window
+			if errors.Is(err, errWaitTimeout) || errors.Is(err, errServerClosed) {
+				break
+			}
+			if err != nil {
+				return s.stepError("listen", err)
+			}
+			s.pushed = append(s.pushed, messageArrival{size: len(msg.data), at: msg.at})
+		}
+	}
+
+	m.summarizeRTTs()
+	return nil
+}
+
+// stepError names the step a session failed in; a server close is reported
+// with its code rather than as an error of the step
+func (s *wsSession) stepError(step string, err error) error {
+	if errors.Is(err, errServerClosed) {
+		code, reason := parseClose(s.closeMsg.data)
+		if reason != "" {
+			return fmt.Errorf("%s: server closed the WebSocket (%d %s)", step, code, reason)
+		}
+		return fmt.Errorf("%s: server closed the WebSocket (%d)", step, code)
+	}
+	return fmt.Errorf("%s: %w", step, err)
+}
+
+// close performs the close handshake, then closes the connection
+func (s *wsSession) close(m *WebSocketMetrics) {
+	defer func() {
+		s.conn.rwc.Close()
+		m.Session = Duration(time.Since(s.start))
+	}()
+
+	if s.closeMsg != nil {
+		// Echo the server's close as the protocol requires
+		m.ClosedByServer = true
+		m.CloseCode, m.CloseReason = parseClose(s.closeMsg.data)
+		s.conn.writeFrame(wsClose, closePayload(m.CloseCode, ""))
+		return
+	}
+
+	sent := time.Now()
+	if err := s.conn.writeFrame(wsClose, closePayload(1000, "")); err != nil {
+		return
+	}
+	timeout := wsCloseTimeout
+	if s.timeout > 0 && s.timeout < timeout {
+		timeout = s.timeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case msg, ok := <-s.messages:
+			if !ok {
+				return
+			}
+			if msg.opcode == wsClose {
+				m.CloseHandshake = Duration(msg.at.Sub(sent))
+				m.CloseCode, m.CloseReason = parseClose(msg.data)
+				return
+			}
+			// Data still in flight when we closed
+			s.received++
+			s.bytesReceived += int64(len(msg.data))
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+// parseClose reads the status code and reason of a close frame; 1005 means
+// the frame carried no code
+func parseClose(payload []byte) (int, string) {
+	if len(payload) < 2 {
+		return 1005, ""
+	}
+	return int(binary.BigEndian.Uint16(payload)), string(payload[2:])
+}
+
+// summarizeRTTs fills in the round trip percentiles
+func (m *WebSocketMetrics) summarizeRTTs() {
+	m.RoundTrips = len(m.RTTs)
+	if m.RoundTrips == 0 {
+		return
+	}
+	sorted := make([]time.Duration, len(m.RTTs))
+	var total time.Duration
+	for i, rtt := range m.RTTs {
+		sorted[i] = time.Duration(rtt)
+		total += sorted[i]
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	m.MinRTT = Duration(sorted[0])
+	m.MeanRTT = Duration(total / time.Duration(len(sorted)))
+	m.P50RTT = Duration(percentileDuration(sorted, 50))
+	m.P90RTT = Duration(percentileDuration(sorted, 90))
+	m.P99RTT = Duration(percentileDuration(sorted, 99))
+	m.MaxRTT = Duration(sorted[len(sorted)-1])
+}
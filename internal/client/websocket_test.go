@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newWSServer upgrades every request and hands the connection to handle
+func newWSServer(t *testing.T, handle func(conn *wsConn)) *httptest.Server {
+	return httptest.NewServer(wsHandler(t, handle))
+}
+
+func wsHandler(t *testing.T, handle func(conn *wsConn)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+			http.Error(w, "not a WebSocket request", http.StatusBadRequest)
+			return
+		}
+		rwc, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Hijack failed: %v", err)
+			return
+		}
+		defer rwc.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + wsAccept(key) + "\r\n\r\n")
+		buf.Flush()
+		handle(newWSConn(rwc, false))
+	})
+}
+
+// echo replies to every data message until the client closes
+func echo(conn *wsConn) {
+	for {
+		opcode, data, err := conn.readMessage()
+		if err != nil {
+			return
+		}
+		if opcode == wsClose {
+			conn.writeFrame(wsClose, data)
+			return
+		}
+		conn.writeFrame(opcode, data)
+	}
+}
+
+func wsURL(srv *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// newPipeConns returns a client and a server end of an in-memory connection
+func newPipeConns() (*wsConn, *wsConn) {
+	a, b := net.Pipe()
+	return newWSConn(a, true), newWSConn(b, false)
+}
+
+func TestWebSocketAccept(t *testing.T) {
+	// Example from RFC 6455 section 1.3
+	if got := wsAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept value %q", got)
+	}
+}
+
+func TestWebSocketFrames(t *testing.T) {
+	for _, size := range []int{0, 125, 126, 70000} {
+		client, server := newPipeConns()
+		payload := []byte(strings.Repeat("x", size))
+		go client.writeFrame(wsBinary, payload)
+		fin, opcode, got, err := server.readFrame()
+		if err != nil || !fin || opcode != wsBinary || string(got) != string(payload) {
+			t.Errorf("%d bytes: fin %v, opcode %d, %d bytes, %v", size, fin, opcode, len(got), err)
+		}
+	}
+}
+
+func TestMeasureWebSocketEcho(t *testing.T) {
+	srv := newWSServer(t, func(conn *wsConn) {
+		// Ping first so the client must answer it mid-session
+		conn.writeFrame(wsPing, []byte("hi"))
+		echo(conn)
+	})
+	defer srv.Close()
+
+	// The session outlives the timeout, which only bounds the handshake and each wait
+	c := NewClient(&Config{Timeout: 200 * time.Millisecond})
+	script := []WebSocketStep{{Sleep: 300 * time.Millisecond}, {Send: "late"}, {Expect: "late"}}
+	timing, err := c.MeasureWebSocket(context.Background(), wsURL(srv), nil,
+		WebSocketOptions{Messages: 10, Payload: []byte("hello"), Script: script})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := timing.WebSocket
+	if timing.StatusCode != http.StatusSwitchingProtocols || timing.Error != "" {
+		t.Fatalf("Unexpected handshake: %d %q", timing.StatusCode, timing.Error)
+	}
+	if m.MessagesSent != 11 || m.MessagesReceived != 11 || m.BytesSent != 54 || m.RoundTrips != 11 {
+		t.Errorf("Unexpected counts: %+v", m)
+	}
+	if m.MinRTT <= 0 || m.MinRTT > m.P50RTT || m.P50RTT > m.MaxRTT || m.MessagesPerSecond <= 0 {
+		t.Errorf("Unexpected round trips: %+v", m)
+	}
+	if m.CloseCode != 1000 || m.ClosedByServer || m.Pushed != nil {
+		t.Errorf("Expected a clean close from our side, got %+v", m)
+	}
+}
+
+func TestMeasureWebSocketTLS(t *testing.T) {
+	// The server offers h2, but the upgrade must negotiate HTTP/1.1
+	srv := httptest.NewUnstartedServer(wsHandler(t, echo))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	c := NewClient(&Config{Timeout: 2 * time.Second, Insecure: true})
+	timing, err := c.MeasureWebSocket(context.Background(), wsURL(srv), nil, WebSocketOptions{Messages: 2, Payload: []byte("x")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing.TLSALPN == "h2" || timing.TLSHandshake <= 0 || timing.WebSocket.RoundTrips != 2 {
+		t.Errorf("Unexpected session: ALPN %q, TLS %v, %+v", timing.TLSALPN, timing.TLSHandshake, timing.WebSocket)
+	}
+}
+
+func TestMeasureWebSocketScript(t *testing.T) {
+	srv := newWSServer(t, func(conn *wsConn) {
+		if _, data, err := conn.readMessage(); err != nil || string(data) != "subscribe" {
+			return
+		}
+		conn.writeFrame(wsText, []byte(`{"status":"subscribed"}`))
+		for i := 0; i < 5; i++ {
+			time.Sleep(20 * time.Millisecond)
+			conn.writeFrame(wsText, []byte("tick "+strconv.Itoa(i)))
+		}
+		conn.writeFrame(wsClose, closePayload(4000, "done"))
+		conn.readMessage()
+	})
+	defer srv.Close()
+
+	c := NewClient(&Config{Timeout: 2 * time.Second})
+	script := []WebSocketStep{{Send: "subscribe"}, {Expect: "subscribed"}, {Receive: 3}}
+	timing, err := c.MeasureWebSocket(context.Background(), wsURL(srv), nil,
+		WebSocketOptions{Script: script, Listen: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := timing.WebSocket
+	if timing.Error != "" {
+		t.Fatalf("Unexpected error: %s", timing.Error)
+	}
+	if m.RoundTrips != 1 || m.Pushed == nil || m.Pushed.TotalChunks != 5 {
+		t.Fatalf("Expected one round trip and five pushed messages, got %+v", m)
+	}
+	if mean := m.Pushed.BufferingAnalysis.MeanDelay; mean < 10 || mean > 60 {
+		t.Errorf("Expected pushes about 20ms apart, got %.0fms", mean)
+	}
+	if !m.ClosedByServer || m.CloseCode != 4000 || m.CloseReason != "done" {
+		t.Errorf("Expected the server's close, got %+v", m)
+	}
+}
+
+func TestMeasureWebSocketErrors(t *testing.T) {
+	srv := newWSServer(t, func(conn *wsConn) { conn.readMessage(); conn.readMessage() })
+	defer srv.Close()
+
+	c := NewClient(&Config{Timeout: 100 * time.Millisecond})
+	timing, err := c.MeasureWebSocket(context.Background(), wsURL(srv), nil, WebSocketOptions{Messages: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(timing.Error, "echo 1: timed out") {
+		t.Errorf("Expected the unanswered echo to time out, got %q", timing.Error)
+	}
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer plain.Close()
+	timing, err = c.MeasureWebSocket(context.Background(), wsURL(plain), nil, WebSocketOptions{})
+	if err == nil || timing.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected the refused upgrade to fail, got %v (%d)", err, timing.StatusCode)
+	}
+
+	if _, err := c.MeasureWebSocket(context.Background(), plain.URL, nil, WebSocketOptions{}); err == nil {
+		t.Error("Expected an error for an http URL")
+	}
+}
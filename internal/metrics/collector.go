@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"net"
 	"sort"
 	"sync"
 	"time"
@@ -48,6 +49,7 @@ func (c *Collector) Calculate() *Stats {
 	stats := &Stats{
 		TotalRequests: len(c.timings),
 		StatusCodes:   make(map[int]int),
+		Protocols:     make(map[string]int),
 	}
 
 	// Collect latencies and other metrics
@@ -61,9 +63,23 @@ func (c *Collector) Calculate() *Stats {
 		totalLatency += latency
 		totalBytes += t.ResponseSize
 
+		// gRPC calls are answered with HTTP 200 whatever their outcome, so
+		// every call is counted by its grpc-status instead
+		if t.GRPC != nil {
+			if stats.GRPCStatus == nil {
+				stats.GRPCStatus = make(map[string]int)
+			}
+			stats.GRPCStatus[t.GRPC.Status]++
+		}
+
 		if t.Error == "" {
 			stats.SuccessfulRequests++
-			stats.StatusCodes[t.StatusCode]++
+			if t.GRPC == nil {
+				stats.StatusCodes[t.StatusCode]++
+			}
+			if t.Protocol != "" {
+				stats.Protocols[t.Protocol]++
+			}
 		} else {
 			stats.FailedRequests++
 		}
@@ -96,6 +112,12 @@ func (c *Collector) Calculate() *Stats {
 	// Create histogram
 	stats.Histogram = createHistogram(latencies)
 
+	// Average each phase over successful requests
+	stats.Phases = calculatePhases(c.timings)
+
+	// Break latency down per backend to spot a bad node behind round-robin DNS
+	stats.RemoteIPs = calculateRemoteIPs(c.timings)
+
 	// Calculate throughput
 	duration := c.endTime.Sub(c.startTime)
 	stats.Duration = Duration(duration)
@@ -104,6 +126,9 @@ func (c *Collector) Calculate() *Stats {
 	stats.TotalBytes = totalBytes
 	stats.BytesPerSecond = float64(totalBytes) / duration.Seconds()
 
+	// Token streaming, when a stream format was set
+	stats.Tokens = calculateTokens(c.timings, duration)
+
 	return stats
 }
 
@@ -132,6 +157,90 @@ func percentile(sorted []time.Duration, p float64) time.Duration {
 	return time.Duration(float64(sorted[lower])*(1-weight) + float64(sorted[upper])*weight)
 }
 
+// calculatePhases computes mean phase durations across successful requests
+func calculatePhases(timings []*client.TimingBreakdown) PhaseStats {
+	var sum [5]time.Duration
+	count := 0
+
+	for _, t := range timings {
+		if t.Error != "" {
+			continue
+		}
+		sum[0] += time.Duration(t.DNSLookup)
+		sum[1] += time.Duration(t.TCPConnection)
+		sum[2] += time.Duration(t.TLSHandshake)
+		sum[3] += time.Duration(t.ServerProcessing)
+		sum[4] += time.Duration(t.ContentTransfer)
+		count++
+	}
+
+	if count == 0 {
+		return PhaseStats{}
+	}
+
+	n := time.Duration(count)
+	return PhaseStats{
+		DNSLookup:        Duration(sum[0] / n),
+		TCPConnection:    Duration(sum[1] / n),
+		TLSHandshake:     Duration(sum[2] / n),
+		ServerProcessing: Duration(sum[3] / n),
+		ContentTransfer:  Duration(sum[4] / n),
+	}
+}
+
+// calculateRemoteIPs groups requests by the remote IP they were sent to
+func calculateRemoteIPs(timings []*client.TimingBreakdown) []RemoteIPStats {
+	latencies := make(map[string][]time.Duration)
+	failed := make(map[string]int)
+
+	for _, t := range timings {
+		if t.RemoteAddr == "" {
+			continue
+		}
+		ip := t.RemoteAddr
+		if host, _, err := net.SplitHostPort(t.RemoteAddr); err == nil {
+			ip = host
+		}
+		latencies[ip] = append(latencies[ip], time.Duration(t.Total))
+		if t.Error != "" {
+			failed[ip]++
+		}
+	}
+
+	if len(latencies) == 0 {
+		return nil
+	}
+
+	result := make([]RemoteIPStats, 0, len(latencies))
+	for ip, values := range latencies {
+		sort.Slice(values, func(i, j int) bool {
+			return values[i] < values[j]
+		})
+
+		var total time.Duration
+		for _, v := range values {
+			total += v
+		}
+
+		result = append(result, RemoteIPStats{
+			IP:          ip,
+			Requests:    len(values),
+			Failed:      failed[ip],
+			MinLatency:  Duration(values[0]),
+			MeanLatency: Duration(total / time.Duration(len(values))),
+			P50:         Duration(percentile(values, 50)),
+			P90:         Duration(percentile(values, 90)),
+			P99:         Duration(percentile(values, 99)),
+			MaxLatency:  Duration(values[len(values)-1]),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].IP < result[j].IP
+	})
+	return result
+}
+
 // Reset clears all collected data
 func (c *Collector) Reset() {
 	c.mu.Lock()
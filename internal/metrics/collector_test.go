@@ -378,3 +378,81 @@ func TestCollectorThroughput(t *testing.T) {
 		t.Errorf("Expected 10240 total bytes, got %d", stats.TotalBytes)
 	}
 }
+
+func TestCollectorPhaseAverages(t *testing.T) {
+	collector := NewCollector()
+
+	collector.Record(&client.TimingBreakdown{
+		DNSLookup:        client.Duration(10 * time.Millisecond),
+		TCPConnection:    client.Duration(20 * time.Millisecond),
+		ServerProcessing: client.Duration(40 * time.Millisecond),
+		Total:            client.Duration(70 * time.Millisecond),
+		StatusCode:       200,
+		Protocol:         "HTTP/2.0",
+	})
+	collector.Record(&client.TimingBreakdown{
+		DNSLookup:        client.Duration(30 * time.Millisecond),
+		TCPConnection:    client.Duration(40 * time.Millisecond),
+		ServerProcessing: client.Duration(60 * time.Millisecond),
+		Total:            client.Duration(130 * time.Millisecond),
+		StatusCode:       200,
+		Protocol:         "HTTP/2.0",
+	})
+	// Failed requests are excluded from phase averages
+	collector.Record(&client.TimingBreakdown{
+		DNSLookup: client.Duration(500 * time.Millisecond),
+		Total:     client.Duration(500 * time.Millisecond),
+		Error:     "connection refused",
+	})
+
+	collector.Finalize()
+	stats := collector.Calculate()
+
+	if stats.Phases.DNSLookup != client.Duration(20*time.Millisecond) {
+		t.Errorf("Expected mean DNS 20ms, got %v", stats.Phases.DNSLookup)
+	}
+
+	if stats.Phases.TCPConnection != client.Duration(30*time.Millisecond) {
+		t.Errorf("Expected mean TCP 30ms, got %v", stats.Phases.TCPConnection)
+	}
+
+	if stats.Phases.ServerProcessing != client.Duration(50*time.Millisecond) {
+		t.Errorf("Expected mean server processing 50ms, got %v", stats.Phases.ServerProcessing)
+	}
+
+	if stats.Protocols["HTTP/2.0"] != 2 {
+		t.Errorf("Expected 2 HTTP/2.0 responses, got %d", stats.Protocols["HTTP/2.0"])
+	}
+}
+
+func TestCollectorRemoteIPs(t *testing.T) {
+	collector := NewCollector()
+
+	ms := func(n int) client.Duration { return client.Duration(time.Duration(n) * time.Millisecond) }
+	collector.Record(&client.TimingBreakdown{Total: ms(10), StatusCode: 200, RemoteAddr: "192.0.2.1:443"})
+	collector.Record(&client.TimingBreakdown{Total: ms(20), StatusCode: 200, RemoteAddr: "192.0.2.1:443"})
+	collector.Record(&client.TimingBreakdown{Total: ms(400), StatusCode: 200, RemoteAddr: "192.0.2.2:443"})
+	collector.Record(&client.TimingBreakdown{Total: ms(900), Error: "timeout", RemoteAddr: "192.0.2.2:443"})
+	collector.Record(&client.TimingBreakdown{Total: ms(5), Error: "dns failure"})
+	collector.Finalize()
+
+	stats := collector.Calculate()
+
+	if len(stats.RemoteIPs) != 2 {
+		t.Fatalf("Expected 2 remote IPs, got %d", len(stats.RemoteIPs))
+	}
+
+	first, second := stats.RemoteIPs[0], stats.RemoteIPs[1]
+	if first.IP != "192.0.2.1" || first.Requests != 2 || first.Failed != 0 {
+		t.Errorf("Unexpected stats for first IP: %+v", first)
+	}
+	if first.MeanLatency != ms(15) || first.MaxLatency != ms(20) {
+		t.Errorf("Expected mean 15ms and max 20ms, got %v and %v", first.MeanLatency, first.MaxLatency)
+	}
+	if second.IP != "192.0.2.2" || second.Requests != 2 || second.Failed != 1 {
+		t.Errorf("Unexpected stats for second IP: %+v", second)
+	}
+	if second.MinLatency != ms(400) {
+		t.Errorf("Expected min 400ms, got %v", second.MinLatency)
+	}
+}
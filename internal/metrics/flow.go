@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+)
+
+// FlowCollector aggregates multi-step flows: a Collector per step plus the
+// end-to-end duration of every flow
+type FlowCollector struct {
+	mu        sync.Mutex
+	steps     []string
+	collector map[string]*Collector
+	flows     []flowRecord
+	startTime time.Time
+	endTime   time.Time
+}
+
+// flowRecord is one completed or aborted flow
+type flowRecord struct {
+	duration   time.Duration
+	failedStep string // Empty when every step succeeded
+	err        string
+}
+
+// FlowStats contains per-step and end-to-end statistics of a scenario run
+type FlowStats struct {
+	TotalFlows     int            `json:"total_flows"`
+	CompletedFlows int            `json:"completed_flows"`
+	FailedFlows    int            `json:"failed_flows"`
+	Duration       Duration       `json:"duration"`
+	FlowsPerSecond float64        `json:"flows_per_second"`
+	MinFlow        Duration       `json:"min_flow"`
+	MeanFlow       Duration       `json:"mean_flow"`
+	P50            Duration       `json:"p50"`
+	P90            Duration       `json:"p90"`
+	P95            Duration       `json:"p95"`
+	P99            Duration       `json:"p99"`
+	MaxFlow        Duration       `json:"max_flow"`
+	Steps          []StepStats    `json:"steps"`
+	Failures       []FlowFailures `json:"failures,omitempty"`
+}
+
+// StepStats holds the request statistics of one step
+type StepStats struct {
+	Name  string `json:"name"`
+	Stats *Stats `json:"stats"`
+}
+
+// FlowFailures counts the flows that stopped at a step
+type FlowFailures struct {
+	Step       string `json:"step"`
+	Count      int    `json:"count"`
+	FirstError string `json:"first_error"`
+}
+
+// NewFlowCollector creates a collector for flows made of the given steps
+func NewFlowCollector(steps []string) *FlowCollector {
+	c := &FlowCollector{
+		steps:     steps,
+		collector: make(map[string]*Collector, len(steps)),
+		startTime: time.Now(),
+	}
+	for _, step := range steps {
+		c.collector[step] = NewCollector()
+	}
+	return c
+}
+
+// RecordStep adds the measurement of one step's request
+func (c *FlowCollector) RecordStep(step string, timing *client.TimingBreakdown) {
+	if collector, ok := c.collector[step]; ok {
+		collector.Record(timing)
+	}
+}
+
+// RecordFlow adds one flow's end-to-end duration. failedStep names the step
+// that stopped the flow, or is empty if the flow completed.
+func (c *FlowCollector) RecordFlow(duration time.Duration, failedStep, err string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flows = append(c.flows, flowRecord{duration: duration, failedStep: failedStep, err: err})
+}
+
+// Finalize marks the end of data collection
+func (c *FlowCollector) Finalize() {
+	c.endTime = time.Now()
+	for _, collector := range c.collector {
+		collector.endTime = c.endTime
+	}
+}
+
+// Calculate computes step and flow statistics. Flow percentiles cover
+// completed flows only, since aborted flows skip the remaining steps.
+func (c *FlowCollector) Calculate() *FlowStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := &FlowStats{TotalFlows: len(c.flows)}
+	for _, step := range c.steps {
+		stats.Steps = append(stats.Steps, StepStats{Name: step, Stats: c.collector[step].Calculate()})
+	}
+
+	failures := make(map[string]*FlowFailures)
+	durations := make([]time.Duration, 0, len(c.flows))
+	var total time.Duration
+	for _, f := range c.flows {
+		if f.failedStep != "" {
+			stats.FailedFlows++
+			if failures[f.failedStep] == nil {
+				failures[f.failedStep] = &FlowFailures{Step: f.failedStep, FirstError: f.err}
+			}
+			failures[f.failedStep].Count++
+			continue
+		}
+		durations = append(durations, f.duration)
+		total += f.duration
+	}
+	stats.CompletedFlows = len(durations)
+
+	// Report failures in step order
+	for _, step := range c.steps {
+		if f := failures[step]; f != nil {
+			stats.Failures = append(stats.Failures, *f)
+		}
+	}
+
+	duration := c.endTime.Sub(c.startTime)
+	stats.Duration = Duration(duration)
+	if duration > 0 {
+		stats.FlowsPerSecond = float64(stats.CompletedFlows) / duration.Seconds()
+	}
+
+	if len(durations) == 0 {
+		return stats
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	stats.MinFlow = Duration(durations[0])
+	stats.MaxFlow = Duration(durations[len(durations)-1])
+	stats.MeanFlow = Duration(total / time.Duration(len(durations)))
+	stats.P50 = Duration(percentile(durations, 50))
+	stats.P90 = Duration(percentile(durations, 90))
+	stats.P95 = Duration(percentile(durations, 95))
+	stats.P99 = Duration(percentile(durations, 99))
+	return stats
+}
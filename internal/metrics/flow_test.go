@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+)
+
+func TestFlowCollector(t *testing.T) {
+	collector := NewFlowCollector([]string{"login", "profile", "order"})
+
+	for i := 1; i <= 4; i++ {
+		collector.RecordStep("login", &client.TimingBreakdown{Total: client.Duration(10 * time.Millisecond), StatusCode: 200})
+		collector.RecordStep("profile", &client.TimingBreakdown{Total: client.Duration(20 * time.Millisecond), StatusCode: 200})
+		if i == 4 {
+			collector.RecordStep("order", &client.TimingBreakdown{Total: client.Duration(5 * time.Millisecond), Error: "unexpected status 500"})
+			collector.RecordFlow(35*time.Millisecond, "order", "unexpected status 500")
+			continue
+		}
+		collector.RecordStep("order", &client.TimingBreakdown{Total: client.Duration(30 * time.Millisecond), StatusCode: 201})
+		collector.RecordFlow(time.Duration(i)*100*time.Millisecond, "", "")
+	}
+	collector.RecordFlow(time.Millisecond, "login", "connection refused")
+	collector.Finalize()
+
+	stats := collector.Calculate()
+
+	if stats.TotalFlows != 5 || stats.CompletedFlows != 3 || stats.FailedFlows != 2 {
+		t.Errorf("Expected 5 flows with 3 completed and 2 failed, got %d/%d/%d",
+			stats.TotalFlows, stats.CompletedFlows, stats.FailedFlows)
+	}
+	if stats.MinFlow != Duration(100*time.Millisecond) || stats.MaxFlow != Duration(300*time.Millisecond) {
+		t.Errorf("Flow durations should cover completed flows only, got min %v max %v", stats.MinFlow, stats.MaxFlow)
+	}
+	if stats.MeanFlow != Duration(200*time.Millisecond) || stats.P50 != Duration(200*time.Millisecond) {
+		t.Errorf("Expected mean and p50 of 200ms, got %v and %v", stats.MeanFlow, stats.P50)
+	}
+
+	if len(stats.Steps) != 3 || stats.Steps[0].Name != "login" || stats.Steps[2].Name != "order" {
+		t.Fatalf("Expected steps in declaration order, got %+v", stats.Steps)
+	}
+	order := stats.Steps[2].Stats
+	if order.TotalRequests != 4 || order.FailedRequests != 1 || order.StatusCodes[201] != 3 {
+		t.Errorf("Unexpected order step stats: %+v", order)
+	}
+
+	if len(stats.Failures) != 2 || stats.Failures[0].Step != "login" || stats.Failures[1].FirstError != "unexpected status 500" {
+		t.Errorf("Expected failures in step order, got %+v", stats.Failures)
+	}
+}
+
+func TestFlowCollectorEmpty(t *testing.T) {
+	collector := NewFlowCollector([]string{"only"})
+	collector.Finalize()
+
+	stats := collector.Calculate()
+	if stats.TotalFlows != 0 || stats.Steps[0].Stats.TotalRequests != 0 {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+}
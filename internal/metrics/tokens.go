@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+)
+
+// TokenStats aggregates token streaming metrics across successful requests
+type TokenStats struct {
+	Requests      int `json:"requests"`
+	OutputTokens  int `json:"output_tokens"`
+	InputTokens   int `json:"input_tokens"`
+	UsageReported int `json:"usage_reported"` // Requests whose counts came from a usage block
+
+	TokensPerSecond     float64 `json:"tokens_per_second"`      // All output tokens over the test duration
+	MeanTokensPerSecond float64 `json:"mean_tokens_per_second"` // Mean generation rate of a single request
+
+	MinTTFT  Duration `json:"min_ttft"`
+	MeanTTFT Duration `json:"mean_ttft"`
+	TTFTP50  Duration `json:"ttft_p50"`
+	TTFTP90  Duration `json:"ttft_p90"`
+	TTFTP99  Duration `json:"ttft_p99"`
+	MaxTTFT  Duration `json:"max_ttft"`
+
+	// Inter-token latency over the gaps of every request
+	InterTokenMean Duration `json:"inter_token_mean"`
+	InterTokenP50  Duration `json:"inter_token_p50"`
+	InterTokenP90  Duration `json:"inter_token_p90"`
+	InterTokenP99  Duration `json:"inter_token_p99"`
+	InterTokenMax  Duration `json:"inter_token_max"`
+
+	StopReasons  map[string]int `json:"stop_reasons,omitempty"`
+	StreamErrors int            `json:"stream_errors,omitempty"` // Requests with an error inside the stream
+}
+
+// calculateTokens aggregates the token metrics of successful requests; nil
+// when no request decoded a token stream
+func calculateTokens(timings []*client.TimingBreakdown, duration time.Duration) *TokenStats {
+	var ts *TokenStats
+	var ttfts, gaps []time.Duration
+	var totalTTFT, totalGaps time.Duration
+	var rates float64
+	var rated int
+
+	for _, t := range timings {
+		if t.Tokens == nil {
+			continue
+		}
+		if ts == nil {
+			ts = &TokenStats{StopReasons: make(map[string]int)}
+		}
+		if t.Tokens.Error != "" {
+			ts.StreamErrors++
+		}
+		if t.Error != "" {
+			continue
+		}
+
+		m := t.Tokens
+		ts.Requests++
+		ts.OutputTokens += m.OutputTokens
+		ts.InputTokens += m.InputTokens
+		if m.UsageReported {
+			ts.UsageReported++
+		}
+		if m.StopReason != "" {
+			ts.StopReasons[m.StopReason]++
+		}
+		if m.Deltas > 0 {
+			ttft := time.Duration(m.TimeToFirstToken)
+			ttfts = append(ttfts, ttft)
+			totalTTFT += ttft
+		}
+		if m.TokensPerSecond > 0 {
+			rates += m.TokensPerSecond
+			rated++
+		}
+		for _, gap := range m.InterTokenGaps {
+			gaps = append(gaps, time.Duration(gap))
+			totalGaps += time.Duration(gap)
+		}
+	}
+	if ts == nil {
+		return nil
+	}
+
+	if duration > 0 {
+		ts.TokensPerSecond = float64(ts.OutputTokens) / duration.Seconds()
+	}
+	if rated > 0 {
+		ts.MeanTokensPerSecond = rates / float64(rated)
+	}
+
+	if len(ttfts) > 0 {
+		sort.Slice(ttfts, func(i, j int) bool { return ttfts[i] < ttfts[j] })
+		ts.MinTTFT = Duration(ttfts[0])
+		ts.MeanTTFT = Duration(totalTTFT / time.Duration(len(ttfts)))
+		ts.TTFTP50 = Duration(percentile(ttfts, 50))
+		ts.TTFTP90 = Duration(percentile(ttfts, 90))
+		ts.TTFTP99 = Duration(percentile(ttfts, 99))
+		ts.MaxTTFT = Duration(ttfts[len(ttfts)-1])
+	}
+
+	if len(gaps) > 0 {
+		sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+		ts.InterTokenMean = Duration(totalGaps / time.Duration(len(gaps)))
+		ts.InterTokenP50 = Duration(percentile(gaps, 50))
+		ts.InterTokenP90 = Duration(percentile(gaps, 90))
+		ts.InterTokenP99 = Duration(percentile(gaps, 99))
+		ts.InterTokenMax = Duration(gaps[len(gaps)-1])
+	}
+	return ts
+}
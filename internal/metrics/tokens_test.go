@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+)
+
+func ms(n int) client.Duration {
+	return client.Duration(time.Duration(n) * time.Millisecond)
+}
+
+func TestCalculateTokens(t *testing.T) {
+	if calculateTokens([]*client.TimingBreakdown{{StatusCode: 200}}, time.Second) != nil {
+		t.Error("Expected no token stats without token metrics")
+	}
+
+	timings := []*client.TimingBreakdown{
+		{Tokens: &client.TokenMetrics{Deltas: 3, OutputTokens: 10, InputTokens: 4, UsageReported: true, StopReason: "stop",
+			TimeToFirstToken: ms(100), TokensPerSecond: 40, InterTokenGaps: []client.Duration{ms(10), ms(30)}}},
+		{Tokens: &client.TokenMetrics{Deltas: 2, OutputTokens: 2, StopReason: "length",
+			TimeToFirstToken: ms(300), TokensPerSecond: 20, InterTokenGaps: []client.Duration{ms(20)}}},
+		// Failed requests count their stream error but nothing else
+		{Error: "stream error: overloaded", Tokens: &client.TokenMetrics{Deltas: 1, OutputTokens: 1, Error: "overloaded",
+			TimeToFirstToken: ms(5)}},
+	}
+
+	ts := calculateTokens(timings, 2*time.Second)
+	if ts.Requests != 2 || ts.OutputTokens != 12 || ts.InputTokens != 4 || ts.UsageReported != 1 || ts.StreamErrors != 1 {
+		t.Errorf("Unexpected counts: %+v", ts)
+	}
+	if ts.TokensPerSecond != 6 || ts.MeanTokensPerSecond != 30 {
+		t.Errorf("Expected 6 tokens/s overall and 30 per request, got %.1f and %.1f", ts.TokensPerSecond, ts.MeanTokensPerSecond)
+	}
+	if ts.MinTTFT != ms(100) || ts.MaxTTFT != ms(300) || ts.MeanTTFT != ms(200) {
+		t.Errorf("Unexpected TTFT: %v/%v/%v", ts.MinTTFT, ts.MeanTTFT, ts.MaxTTFT)
+	}
+	if ts.InterTokenP50 != ms(20) || ts.InterTokenMax != ms(30) || ts.InterTokenMean != ms(20) {
+		t.Errorf("Unexpected inter-token latency: %+v", ts)
+	}
+	if ts.StopReasons["stop"] != 1 || ts.StopReasons["length"] != 1 {
+		t.Errorf("Unexpected stop reasons: %v", ts.StopReasons)
+	}
+}
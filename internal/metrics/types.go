@@ -24,8 +24,35 @@ type Stats struct {
 	P999               Duration           `json:"p99_9,omitempty"`
 	P9999              Duration           `json:"p99_99,omitempty"`
 	StatusCodes        map[int]int        `json:"status_codes"`
+	GRPCStatus         map[string]int     `json:"grpc_status,omitempty"`
 	ErrorRate          float64            `json:"error_rate"`
 	TotalBytes         int64              `json:"total_bytes"`
 	BytesPerSecond     float64            `json:"bytes_per_second"`
 	Histogram          map[int]int        `json:"histogram,omitempty"`
+	Phases             PhaseStats         `json:"phases"`
+	Protocols          map[string]int     `json:"protocols,omitempty"`
+	RemoteIPs          []RemoteIPStats    `json:"remote_ips,omitempty"`
+	Tokens             *TokenStats        `json:"tokens,omitempty"`
+}
+
+// PhaseStats contains mean per-phase durations across successful requests
+type PhaseStats struct {
+	DNSLookup        Duration `json:"dns_lookup"`
+	TCPConnection    Duration `json:"tcp_connection"`
+	TLSHandshake     Duration `json:"tls_handshake"`
+	ServerProcessing Duration `json:"server_processing"`
+	ContentTransfer  Duration `json:"content_transfer"`
+}
+
+// RemoteIPStats contains the latency distribution of requests sent to one remote IP
+type RemoteIPStats struct {
+	IP          string   `json:"ip"`
+	Requests    int      `json:"requests"`
+	Failed      int      `json:"failed"`
+	MinLatency  Duration `json:"min_latency"`
+	MeanLatency Duration `json:"mean_latency"`
+	P50         Duration `json:"p50"`
+	P90         Duration `json:"p90"`
+	P99         Duration `json:"p99"`
+	MaxLatency  Duration `json:"max_latency"`
 }
@@ -0,0 +1,55 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/fatih/color"
+)
+
+// WriteTokenFetches summarises the OAuth2 token requests made during a run.
+// Token fetches are kept out of the request timings, so they get their own line.
+func WriteTokenFetches(w io.Writer, fetches []client.TokenFetch) {
+	if len(fetches) == 0 {
+		return
+	}
+
+	if len(fetches) == 1 {
+		fetch := fetches[0]
+		if fetch.Error != "" {
+			fmt.Fprintf(w, "%s failed after %s: %s\n", color.RedString("OAuth2 token:"),
+				formatTimeDuration(time.Duration(fetch.Duration)), fetch.Error)
+			return
+		}
+		line := fmt.Sprintf("fetched in %s", formatTimeDuration(time.Duration(fetch.Duration)))
+		if fetch.ExpiresIn > 0 {
+			line += fmt.Sprintf(" (expires in %s)", time.Duration(fetch.ExpiresIn))
+		}
+		fmt.Fprintf(w, "%s %s\n", color.CyanString("OAuth2 token:"), line)
+		return
+	}
+
+	var total, maxDuration client.Duration
+	minDuration := fetches[0].Duration
+	failed := 0
+	for _, fetch := range fetches {
+		total += fetch.Duration
+		minDuration = min(minDuration, fetch.Duration)
+		maxDuration = max(maxDuration, fetch.Duration)
+		if fetch.Error != "" {
+			failed++
+		}
+	}
+	mean := total / client.Duration(len(fetches))
+
+	summary := fmt.Sprintf("%d fetches", len(fetches))
+	if failed > 0 {
+		summary += color.RedString(" (%d failed)", failed)
+	}
+	fmt.Fprintf(w, "%s %s, min/mean/max %s/%s/%s\n", color.CyanString("OAuth2 token:"), summary,
+		formatTimeDuration(time.Duration(minDuration)),
+		formatTimeDuration(time.Duration(mean)),
+		formatTimeDuration(time.Duration(maxDuration)))
+}
@@ -0,0 +1,117 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/metrics"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// ProtocolResult holds the outcome of running a workload pinned to one protocol.
+// Timing is set for single requests, Stats for load tests.
+type ProtocolResult struct {
+	Protocol string                  `json:"protocol"`
+	Timing   *client.TimingBreakdown `json:"timing,omitempty"`
+	Stats    *metrics.Stats          `json:"stats,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+// WriteComparisonJSON writes protocol comparison results as JSON
+func WriteComparisonJSON(w io.Writer, results []ProtocolResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"comparison": results,
+	})
+}
+
+// WriteComparisonTable writes protocol comparison results side by side
+func WriteComparisonTable(w io.Writer, results []ProtocolResult, load bool) {
+	fmt.Fprintf(w, "%s\n", color.CyanString("=== Protocol Comparison ==="))
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetTitle("Phase Breakdown")
+
+	if load {
+		t.AppendHeader(table.Row{"Protocol", "Negotiated", "OK/Total", "DNS", "TCP", "TLS", "Server", "Transfer",
+			"P50", "P90", "P95", "P99", "Req/s"})
+		for _, r := range results {
+			if r.Stats == nil || r.Stats.SuccessfulRequests == 0 {
+				t.AppendRow(table.Row{r.Protocol, "-", failedCount(r.Stats)})
+				continue
+			}
+			s := r.Stats
+			t.AppendRow(table.Row{
+				r.Protocol,
+				negotiatedProtocols(s.Protocols),
+				fmt.Sprintf("%d/%d", s.SuccessfulRequests, s.TotalRequests),
+				formatDuration(s.Phases.DNSLookup),
+				formatDuration(s.Phases.TCPConnection),
+				formatDuration(s.Phases.TLSHandshake),
+				formatDuration(s.Phases.ServerProcessing),
+				formatDuration(s.Phases.ContentTransfer),
+				formatDuration(s.P50),
+				formatDuration(s.P90),
+				formatDuration(s.P95),
+				formatDuration(s.P99),
+				fmt.Sprintf("%.2f", s.RequestsPerSecond),
+			})
+		}
+	} else {
+		t.AppendHeader(table.Row{"Protocol", "Negotiated", "Status", "DNS", "TCP", "TLS", "Server", "Transfer", "Total"})
+		for _, r := range results {
+			if r.Timing == nil || r.Error != "" {
+				t.AppendRow(table.Row{r.Protocol, "-", "failed"})
+				continue
+			}
+			timing := r.Timing
+			t.AppendRow(table.Row{
+				r.Protocol,
+				timing.Protocol,
+				timing.StatusCode,
+				formatTimeDuration(time.Duration(timing.DNSLookup)),
+				formatTimeDuration(time.Duration(timing.TCPConnection)),
+				formatTimeDuration(time.Duration(timing.TLSHandshake)),
+				formatTimeDuration(time.Duration(timing.ServerProcessing)),
+				formatTimeDuration(time.Duration(timing.ContentTransfer)),
+				formatTimeDuration(time.Duration(timing.Total)),
+			})
+		}
+	}
+
+	t.SetStyle(table.StyleLight)
+	t.Render()
+
+	// List failures below the table so long error messages don't widen it
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s %s: %s\n", color.YellowString("⚠"), r.Protocol, r.Error)
+		}
+	}
+}
+
+// failedCount renders the success ratio for a load test with no successful requests
+func failedCount(stats *metrics.Stats) string {
+	if stats == nil {
+		return "0/0"
+	}
+	return fmt.Sprintf("0/%d", stats.TotalRequests)
+}
+
+// negotiatedProtocols renders the protocols actually observed in responses
+func negotiatedProtocols(protocols map[string]int) string {
+	names := make([]string, 0, len(protocols))
+	for name := range protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// Setting is one effective configuration value and where it came from
+type Setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// ConfigReport is the effective configuration printed by "gocurl config show"
+type ConfigReport struct {
+	Profile  string    `json:"profile,omitempty"`
+	Files    []string  `json:"files"`
+	Settings []Setting `json:"settings"`
+}
+
+// WriteConfigJSON writes the effective configuration as JSON
+func WriteConfigJSON(w io.Writer, report ConfigReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// WriteConfigTable writes the effective configuration with the source of each value
+func WriteConfigTable(w io.Writer, report ConfigReport) {
+	fmt.Fprintf(w, "%s\n", color.CyanString("=== Effective Configuration ==="))
+
+	profile := report.Profile
+	if profile == "" {
+		profile = "(none)"
+	}
+	fmt.Fprintf(w, "Profile: %s\n", profile)
+	if len(report.Files) == 0 {
+		fmt.Fprintf(w, "Config files: %s\n", color.YellowString("none found"))
+	} else {
+		fmt.Fprintf(w, "Config files: %s\n", strings.Join(report.Files, ", "))
+	}
+
+	if len(report.Settings) == 0 {
+		fmt.Fprintln(w, "All settings are at their defaults")
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"Setting", "Value", "Source"})
+	for _, s := range report.Settings {
+		source := s.Source
+		if source == "default" {
+			source = color.New(color.Faint).Sprint(source)
+		}
+		t.AppendRow(table.Row{s.Name, s.Value, source})
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+}
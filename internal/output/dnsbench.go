@@ -0,0 +1,68 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/erfi/gocurl/internal/metrics"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// ResolverResult holds the lookup statistics of one resolver in dns-bench
+type ResolverResult struct {
+	Resolver  string         `json:"resolver"`
+	Stats     *metrics.Stats `json:"stats"`
+	Addresses []string       `json:"addresses,omitempty"`
+	LastError string         `json:"last_error,omitempty"`
+}
+
+// WriteDNSBenchJSON writes resolver benchmark results as JSON
+func WriteDNSBenchJSON(w io.Writer, host string, results []ResolverResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"host":      host,
+		"resolvers": results,
+	})
+}
+
+// WriteDNSBenchTable writes resolver lookup-latency percentiles side by side
+func WriteDNSBenchTable(w io.Writer, host string, results []ResolverResult) {
+	fmt.Fprintf(w, "%s\n", color.CyanString("=== DNS Resolver Benchmark: %s ===", host))
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetTitle("Lookup Latency")
+	t.AppendHeader(table.Row{"Resolver", "OK/Total", "Min", "Mean", "P50", "P90", "P99", "Max"})
+	for _, r := range results {
+		s := r.Stats
+		if s.SuccessfulRequests == 0 {
+			t.AppendRow(table.Row{r.Resolver, fmt.Sprintf("0/%d", s.TotalRequests)})
+			continue
+		}
+		t.AppendRow(table.Row{
+			r.Resolver,
+			fmt.Sprintf("%d/%d", s.SuccessfulRequests, s.TotalRequests),
+			formatDuration(s.MinLatency),
+			formatDuration(s.MeanLatency),
+			formatDuration(s.P50),
+			formatDuration(s.P90),
+			formatDuration(s.P99),
+			formatDuration(s.MaxLatency),
+		})
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+
+	for _, r := range results {
+		if len(r.Addresses) > 0 {
+			fmt.Fprintf(w, "  %s → %s\n", r.Resolver, strings.Join(r.Addresses, ", "))
+		}
+		if r.LastError != "" {
+			fmt.Fprintf(w, "%s %s: %s\n", color.YellowString("⚠"), r.Resolver, r.LastError)
+		}
+	}
+}
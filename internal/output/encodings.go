@@ -0,0 +1,101 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// EncodingResult holds the outcome of a request that offered a single content encoding
+type EncodingResult struct {
+	Encoding string                  `json:"encoding"`
+	Timing   *client.TimingBreakdown `json:"timing,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+// WriteEncodingComparisonJSON writes encoding comparison results as JSON
+func WriteEncodingComparisonJSON(w io.Writer, results []EncodingResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"encodings": results,
+	})
+}
+
+// WriteEncodingComparisonTable writes wire size, ratio and timing per encoding.
+// Savings are relative to the identity (uncompressed) request when it was made.
+func WriteEncodingComparisonTable(w io.Writer, results []EncodingResult) {
+	fmt.Fprintf(w, "%s\n", color.CyanString("=== Encoding Comparison ==="))
+
+	var identityWire int64
+	for _, r := range results {
+		if r.Encoding == client.EncodingIdentity && r.Timing != nil && r.Error == "" {
+			identityWire = wireSize(r.Timing)
+		}
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetTitle("Compression")
+	t.AppendHeader(table.Row{"Requested", "Received", "Status", "Wire", "Decoded", "Ratio", "Saved", "Decompress", "Transfer", "Total"})
+
+	for _, r := range results {
+		if r.Timing == nil || r.Error != "" {
+			t.AppendRow(table.Row{r.Encoding, "-", "failed"})
+			continue
+		}
+		timing := r.Timing
+
+		received := timing.ContentEncoding
+		if received == "" {
+			received = client.EncodingIdentity
+		}
+		// A server ignoring the requested encoding is worth pointing out
+		if received != r.Encoding {
+			received = color.YellowString(received)
+		}
+
+		ratio, saved := "-", "-"
+		if timing.CompressionRatio > 0 {
+			ratio = fmt.Sprintf("%.2fx", timing.CompressionRatio)
+		}
+		if identityWire > 0 && r.Encoding != client.EncodingIdentity {
+			saved = fmt.Sprintf("%.1f%%", (1-float64(wireSize(timing))/float64(identityWire))*100)
+		}
+
+		t.AppendRow(table.Row{
+			r.Encoding,
+			received,
+			timing.StatusCode,
+			formatBytes(wireSize(timing)),
+			formatBytes(timing.ResponseSize),
+			ratio,
+			saved,
+			formatTimeDuration(time.Duration(timing.Decompression)),
+			formatTimeDuration(time.Duration(timing.ContentTransfer)),
+			formatTimeDuration(time.Duration(timing.Total)),
+		})
+	}
+
+	t.SetStyle(table.StyleLight)
+	t.Render()
+
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s %s: %s\n", color.YellowString("⚠"), r.Encoding, r.Error)
+		}
+	}
+}
+
+// wireSize is the number of body bytes received; uncompressed responses have no separate wire size
+func wireSize(timing *client.TimingBreakdown) int64 {
+	if timing.ContentEncoding != "" {
+		return timing.WireSize
+	}
+	return timing.ResponseSize
+}
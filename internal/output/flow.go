@@ -0,0 +1,80 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/erfi/gocurl/internal/metrics"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// WriteFlowJSON writes scenario statistics as JSON
+func WriteFlowJSON(w io.Writer, name string, stats *metrics.FlowStats) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"scenario": name,
+		"flows":    stats,
+	})
+}
+
+// WriteFlowTable writes one row per step plus the end-to-end flow duration,
+// followed by where failed flows stopped
+func WriteFlowTable(w io.Writer, name string, stats *metrics.FlowStats) {
+	title := "=== Scenario ==="
+	if name != "" {
+		title = fmt.Sprintf("=== Scenario: %s ===", name)
+	}
+	fmt.Fprintf(w, "%s\n", color.CyanString(title))
+	fmt.Fprintf(w, "Flows: %d/%d completed in %s (%.2f flows/sec)\n", stats.CompletedFlows, stats.TotalFlows,
+		formatTimeDuration(time.Duration(stats.Duration)), stats.FlowsPerSecond)
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"Step", "OK/Total", "Mean", "P50", "P90", "P95", "P99", "Max"})
+	for _, step := range stats.Steps {
+		s := step.Stats
+		if s.SuccessfulRequests == 0 {
+			t.AppendRow(table.Row{step.Name, failedCount(s), "-", "-", "-", "-", "-", "-"})
+			continue
+		}
+		t.AppendRow(table.Row{
+			step.Name,
+			fmt.Sprintf("%d/%d", s.SuccessfulRequests, s.TotalRequests),
+			formatDuration(s.MeanLatency),
+			formatDuration(s.P50),
+			formatDuration(s.P90),
+			formatDuration(s.P95),
+			formatDuration(s.P99),
+			formatDuration(s.MaxLatency),
+		})
+	}
+
+	t.AppendSeparator()
+	flow := color.New(color.Bold).Sprint("End-to-end flow")
+	if stats.CompletedFlows == 0 {
+		t.AppendRow(table.Row{flow, fmt.Sprintf("0/%d", stats.TotalFlows), "-", "-", "-", "-", "-", "-"})
+	} else {
+		t.AppendRow(table.Row{
+			flow,
+			fmt.Sprintf("%d/%d", stats.CompletedFlows, stats.TotalFlows),
+			formatDuration(stats.MeanFlow),
+			formatDuration(stats.P50),
+			formatDuration(stats.P90),
+			formatDuration(stats.P95),
+			formatDuration(stats.P99),
+			formatDuration(stats.MaxFlow),
+		})
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+
+	// List failures below the table so long error messages don't widen it
+	for _, f := range stats.Failures {
+		fmt.Fprintf(w, "%s %s: %d flow(s) stopped here, first error: %s\n",
+			color.YellowString("⚠"), f.Step, f.Count, f.FirstError)
+	}
+}
@@ -91,6 +91,25 @@ func (f *GraphFormatter) WriteMultiple(w io.Writer, stats *metrics.Stats) error
 		fmt.Fprintln(w)
 	}
 
+	if len(stats.GRPCStatus) > 0 {
+		fmt.Fprintf(w, "%s\n", color.YellowString("gRPC Status Distribution:"))
+		for _, status := range sortedKeys(stats.GRPCStatus) {
+			count := stats.GRPCStatus[status]
+			pct := (float64(count) / float64(stats.TotalRequests)) * 100
+			bar := f.createBar(int(pct), 50)
+			statusColor := color.GreenString
+			if status != "OK" {
+				statusColor = color.RedString
+			}
+			fmt.Fprintf(w, "  %s %s %s (%.1f%%)\n",
+				statusColor("%-17s", status),
+				bar,
+				fmt.Sprintf("%5d", count),
+				pct)
+		}
+		fmt.Fprintln(w)
+	}
+
 	return nil
 }
 
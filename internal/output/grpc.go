@@ -0,0 +1,63 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/fatih/color"
+)
+
+// WriteGRPCMetrics outputs the status and response messages of a gRPC call;
+// the connection phases are covered by the regular timing table
+func WriteGRPCMetrics(w io.Writer, m *client.GRPCMetrics) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s\n", color.CyanString("gRPC:"))
+	fmt.Fprintf(w, "  Method: %s\n", m.Method)
+	if m.Code == client.GRPCCodeOK {
+		fmt.Fprintf(w, "  Status: %s\n", color.GreenString("%d %s", m.Code, m.Status))
+	} else {
+		fmt.Fprintf(w, "  Status: %s\n", color.RedString("%d %s", m.Code, m.Status))
+	}
+	if m.Message != "" {
+		fmt.Fprintf(w, "  Message: %s\n", m.Message)
+	}
+	if m.Encoding != "" {
+		fmt.Fprintf(w, "  Encoding: %s\n", m.Encoding)
+	}
+	fmt.Fprintf(w, "  Request: %s\n", formatBytes(int64(m.RequestSize)))
+	fmt.Fprintf(w, "  Responses: %d message(s)", m.Messages)
+	if m.Messages > 0 {
+		fmt.Fprintf(w, ", first after %s", formatDuration(m.TimeToFirstMessage))
+	}
+	fmt.Fprintln(w)
+
+	for i, resp := range m.Responses {
+		fmt.Fprintln(w)
+		if len(m.Responses) > 1 {
+			fmt.Fprintf(w, "%s\n", color.CyanString("Response #%d:", i+1))
+		} else {
+			fmt.Fprintf(w, "%s\n", color.CyanString("Response:"))
+		}
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, resp, "  ", "  "); err != nil {
+			buf.Reset()
+			buf.Write(resp)
+		}
+		fmt.Fprintf(w, "  %s\n", strings.TrimSpace(buf.String()))
+	}
+}
+
+// sortedKeys returns the keys of a count map in order
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
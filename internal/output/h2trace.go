@@ -0,0 +1,107 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// WriteH2Trace outputs the HTTP/2 frame trace of a request: the settings
+// exchanged, flow control, and stalls with their cause; verbose lists every
+// frame
+func WriteH2Trace(w io.Writer, trace *client.H2Trace, verbose bool) {
+	if trace == nil {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s\n", color.CyanString("HTTP/2 Frames:"))
+	fmt.Fprintf(w, "  Stream: %d\n", trace.StreamID)
+	if len(trace.ClientSettings) > 0 {
+		fmt.Fprintf(w, "  Client SETTINGS: %s\n", formatH2Settings(trace.ClientSettings))
+	}
+	if len(trace.ServerSettings) > 0 {
+		fmt.Fprintf(w, "  Server SETTINGS: %s\n", formatH2Settings(trace.ServerSettings))
+	}
+	if trace.SettingsAck > 0 {
+		fmt.Fprintf(w, "  SETTINGS ACK: %s\n", formatDuration(trace.SettingsAck))
+	}
+	if trace.PingRTT > 0 {
+		fmt.Fprintf(w, "  PING RTT: %s\n", formatDuration(trace.PingRTT))
+	}
+	fmt.Fprintf(w, "  DATA: %d frame(s), %s", trace.DataFrames, formatBytes(trace.DataBytes))
+	if trace.DataFrames > 0 {
+		fmt.Fprintf(w, ", largest %s", formatBytes(int64(trace.MaxDataFrame)))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  WINDOW_UPDATE: %d sent, %d received\n", trace.WindowUpdatesSent, trace.WindowUpdatesReceived)
+
+	if trace.Reset != "" {
+		fmt.Fprintf(w, "  %s Stream reset: RST_STREAM %s\n", color.YellowString("⚠"), trace.Reset)
+	}
+	if g := trace.GoAway; g != nil {
+		fmt.Fprintf(w, "  %s Server sent GOAWAY %s (last stream %d)", color.YellowString("⚠"), g.ErrorCode, g.LastStreamID)
+		if g.Debug != "" {
+			fmt.Fprintf(w, ": %s", g.Debug)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(trace.Stalls) > 0 {
+		var flowControl, server time.Duration
+		for _, stall := range trace.Stalls {
+			if stall.Cause == "flow-control" {
+				flowControl += time.Duration(stall.Duration)
+			} else {
+				server += time.Duration(stall.Duration)
+			}
+		}
+		var causes []string
+		if flowControl > 0 {
+			causes = append(causes, formatDuration(client.Duration(flowControl))+" flow control")
+		}
+		if server > 0 {
+			causes = append(causes, formatDuration(client.Duration(server))+" server")
+		}
+		fmt.Fprintf(w, "  %s %d stall(s) between DATA frames: %s\n",
+			color.YellowString("⚠"), len(trace.Stalls), strings.Join(causes, ", "))
+		for i, stall := range trace.Stalls {
+			fmt.Fprintf(w, "    #%d: %s at %s, window %s (%s)\n",
+				i+1,
+				formatDuration(stall.Duration),
+				formatDuration(stall.Start),
+				formatBytes(stall.Window),
+				stall.Cause)
+		}
+	}
+
+	if verbose && len(trace.Frames) > 0 {
+		fmt.Fprintln(w)
+		t := table.NewWriter()
+		t.SetOutputMirror(w)
+		t.AppendHeader(table.Row{"At", "Dir", "Frame", "Stream", "Flags", "Length", "Detail"})
+		for _, f := range trace.Frames {
+			dir := "recv"
+			if f.Sent {
+				dir = "sent"
+			}
+			t.AppendRow(table.Row{formatDuration(f.At), dir, f.Type, f.StreamID, f.Flags, f.Length, f.Detail})
+		}
+		t.SetStyle(table.StyleLight)
+		t.Render()
+	}
+}
+
+// formatH2Settings lists SETTINGS parameters as name=value
+func formatH2Settings(settings []client.H2Setting) string {
+	parts := make([]string, len(settings))
+	for i, s := range settings {
+		parts[i] = fmt.Sprintf("%s=%d", s.Name, s.Value)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,100 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/erfi/gocurl/internal/metrics"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// PlanCaseResult is the outcome of one test case of a plan
+type PlanCaseResult struct {
+	Name       string         `json:"name"`
+	Method     string         `json:"method"`
+	URL        string         `json:"url"`
+	Passed     bool           `json:"passed"`
+	Stats      *metrics.Stats `json:"stats,omitempty"`
+	Violations []string       `json:"violations,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// PlanReport is the consolidated result of "gocurl run"
+type PlanReport struct {
+	Name     string           `json:"name,omitempty"`
+	Parallel bool             `json:"parallel"`
+	Duration metrics.Duration `json:"duration"`
+	Passed   bool             `json:"passed"`
+	Cases    []PlanCaseResult `json:"cases"`
+}
+
+// WritePlanJSON writes a plan report as JSON
+func WritePlanJSON(w io.Writer, report PlanReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"plan": report,
+	})
+}
+
+// WritePlanTable writes one row per case followed by the threshold violations
+func WritePlanTable(w io.Writer, report PlanReport) {
+	title := "=== Test Plan ==="
+	if report.Name != "" {
+		title = fmt.Sprintf("=== Test Plan: %s ===", report.Name)
+	}
+	fmt.Fprintf(w, "%s\n", color.CyanString(title))
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.AppendHeader(table.Row{"Case", "Request", "OK/Total", "Req/s", "P50", "P95", "P99", "Max", "Result"})
+	passed := 0
+	for _, r := range report.Cases {
+		result := color.RedString("FAIL")
+		if r.Passed {
+			result = color.GreenString("PASS")
+			passed++
+		}
+
+		request := r.Method + " " + r.URL
+		if r.Stats == nil || r.Stats.SuccessfulRequests == 0 {
+			t.AppendRow(table.Row{r.Name, request, failedCount(r.Stats), "-", "-", "-", "-", "-", result})
+			continue
+		}
+		s := r.Stats
+		t.AppendRow(table.Row{
+			r.Name,
+			request,
+			fmt.Sprintf("%d/%d", s.SuccessfulRequests, s.TotalRequests),
+			fmt.Sprintf("%.2f", s.RequestsPerSecond),
+			formatDuration(s.P50),
+			formatDuration(s.P95),
+			formatDuration(s.P99),
+			formatDuration(s.MaxLatency),
+			result,
+		})
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+
+	// List failures below the table so long messages don't widen it
+	for _, r := range report.Cases {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s %s: %s\n", color.YellowString("⚠"), r.Name, r.Error)
+		}
+		for _, v := range r.Violations {
+			fmt.Fprintf(w, "%s %s: %s\n", color.YellowString("⚠"), r.Name, v)
+		}
+	}
+
+	summary := fmt.Sprintf("%d/%d cases passed in %s", passed, len(report.Cases),
+		formatTimeDuration(time.Duration(report.Duration)))
+	if report.Passed {
+		fmt.Fprintf(w, "%s %s\n", color.GreenString("✓"), summary)
+	} else {
+		fmt.Fprintf(w, "%s %s\n", color.RedString("✗"), summary)
+	}
+}
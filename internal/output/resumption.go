@@ -0,0 +1,70 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// WriteResumptionJSON writes TLS session resumption results as JSON
+func WriteResumptionJSON(w io.Writer, result *client.ResumptionResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// WriteResumptionTable writes full versus resumed handshake timings
+func WriteResumptionTable(w io.Writer, result *client.ResumptionResult, verbose bool) {
+	fmt.Fprintf(w, "%s\n", color.CyanString("=== TLS Session Resumption ==="))
+	fmt.Fprintf(w, "TLS Version: %s\n", result.TLSVersion)
+
+	rateColor := color.GreenString
+	if result.SuccessRate < 1 {
+		rateColor = color.YellowString
+	}
+	if result.Resumptions == 0 {
+		rateColor = color.RedString
+	}
+	fmt.Fprintf(w, "Resumed: %s\n", rateColor("%d/%d (%.1f%%)", result.Resumptions, result.Attempts, result.SuccessRate*100))
+	fmt.Fprintf(w, "0-RTT early data accepted: %d/%d\n\n", result.EarlyDataAccepted, result.Attempts)
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetTitle("Handshake Timing")
+	t.AppendHeader(table.Row{"Handshake", "Duration"})
+	t.AppendRow(table.Row{"Full", formatDuration(result.FullHandshake)})
+	if result.Resumptions > 0 {
+		t.AppendRow(table.Row{"Resumed (mean)", formatDuration(result.MeanResumedHandshake)})
+		t.AppendRow(table.Row{"Resumed (min)", formatDuration(result.MinResumedHandshake)})
+		t.AppendRow(table.Row{"Resumed (max)", formatDuration(result.MaxResumedHandshake)})
+		if result.FullHandshake > 0 {
+			saved := 1 - result.MeanResumedHandshake.Seconds()/result.FullHandshake.Seconds()
+			t.AppendSeparator()
+			t.AppendRow(table.Row{"Saved by resumption", fmt.Sprintf("%.1f%%", saved*100)})
+		}
+	}
+	t.SetStyle(table.StyleLight)
+	t.Render()
+
+	if verbose {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%s\n", color.CyanString("Attempts:"))
+		for i, timing := range result.Resumed {
+			status := color.GreenString("resumed")
+			switch {
+			case timing.Error != "":
+				status = color.RedString("error: %s", timing.Error)
+			case !timing.TLSResumed:
+				status = color.YellowString("full handshake")
+			case timing.TLSEarlyData:
+				status = color.GreenString("resumed, 0-RTT")
+			}
+			fmt.Fprintf(w, "  #%-3d %8s  %s\n", i+1, formatTimeDuration(time.Duration(timing.TLSHandshake)), status)
+		}
+	}
+}
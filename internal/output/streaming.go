@@ -3,6 +3,7 @@ package output
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"time"
 
@@ -118,6 +119,10 @@ func WriteStreamingMetrics(w io.Writer, metrics *client.StreamMetrics, verbose b
 		fmt.Fprintln(w)
 	}
 
+	if metrics.SSE != nil {
+		writeSSEMetrics(w, metrics.SSE, verbose)
+	}
+
 	// Performance metrics
 	fmt.Fprintf(w, "%s\n", color.CyanString("Performance Metrics:"))
 	fmt.Fprintf(w, "  Protocol: %s\n", metrics.Protocol)
@@ -149,6 +154,64 @@ func WriteStreamingMetrics(w io.Writer, metrics *client.StreamMetrics, verbose b
 	}
 }
 
+// writeSSEMetrics outputs event counts, cadence and coalescing for an event stream
+func writeSSEMetrics(w io.Writer, sse *client.SSEMetrics, verbose bool) {
+	fmt.Fprintf(w, "%s\n", color.CyanString("Server-Sent Events:"))
+	if sse.Events == 0 {
+		fmt.Fprintf(w, "  %s No events received\n", color.YellowString("⚠"))
+		fmt.Fprintln(w)
+		return
+	}
+
+	types := make([]string, 0, len(sse.EventTypes))
+	for name := range sse.EventTypes {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	counts := make([]string, len(types))
+	for i, name := range types {
+		counts[i] = fmt.Sprintf("%s %d", name, sse.EventTypes[name])
+	}
+	fmt.Fprintf(w, "  Events: %d (%s)\n", sse.Events, strings.Join(counts, ", "))
+	if sse.Comments > 0 {
+		fmt.Fprintf(w, "  Comments: %d\n", sse.Comments)
+	}
+	if sse.LastEventID != "" {
+		fmt.Fprintf(w, "  Last event ID: %s\n", sse.LastEventID)
+	}
+	fmt.Fprintf(w, "  Time to first event: %s\n", formatDuration(sse.TimeToFirstEvent))
+	if sse.Events > 1 {
+		fmt.Fprintf(w, "  Inter-event gap: mean %s, p50 %s, p90 %s, p99 %s, max %s\n",
+			formatDuration(sse.MeanGap), formatDuration(sse.P50Gap), formatDuration(sse.P90Gap),
+			formatDuration(sse.P99Gap), formatDuration(sse.MaxGap))
+	}
+
+	switch {
+	case sse.BufferingDetected:
+		fmt.Fprintf(w, "  Status: %s (%d of %d events arrived with others in %d read(s))\n",
+			color.RedString("✗ Events coalesced"), sse.CoalescedEvents, sse.Events, sse.CoalescedReads)
+	case sse.CoalescedEvents > 0:
+		fmt.Fprintf(w, "  Status: %s (%d of %d events arrived with others)\n",
+			color.GreenString("✓ Progressive delivery"), sse.CoalescedEvents, sse.Events)
+	default:
+		fmt.Fprintf(w, "  Status: %s\n", color.GreenString("✓ Progressive delivery"))
+	}
+
+	if verbose && len(sse.EventTimings) <= 20 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "  %s\n", color.CyanString("Event Details:"))
+		for _, e := range sse.EventTimings {
+			id := ""
+			if e.ID != "" {
+				id = " id=" + e.ID
+			}
+			fmt.Fprintf(w, "    #%-3d %-12s %6s at %7s (read #%d)%s\n",
+				e.Sequence, e.Type, formatBytes(int64(e.Size)), formatDuration(e.Elapsed), e.Read, id)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
 // drawChunkTimeline creates a visual timeline of data chunks
 func drawChunkTimeline(w io.Writer, metrics *client.StreamMetrics) {
 	if len(metrics.ChunkTimings) == 0 {
@@ -34,19 +34,42 @@ func (f *TableFormatter) Format(timing *client.TimingBreakdown) (string, error)
 // Write writes a single timing result as a table to the writer
 func (f *TableFormatter) Write(w io.Writer, timing *client.TimingBreakdown) error {
 	// Status line
-	statusColor := getStatusColor(timing.StatusCode)
-	fmt.Fprintf(w, "%s %s\n", statusColor("✓ Status:"), statusColor(fmt.Sprintf("%d %s", timing.StatusCode, getStatusText(timing.StatusCode))))
+	if timing.GRPC != nil {
+		// The HTTP status of a gRPC call is 200 whatever the outcome
+		statusColor := color.GreenString
+		if timing.GRPC.Code != client.GRPCCodeOK {
+			statusColor = color.RedString
+		}
+		fmt.Fprintf(w, "%s %s\n", statusColor("✓ Status:"), statusColor(fmt.Sprintf("%d %s (gRPC)", timing.GRPC.Code, timing.GRPC.Status)))
+	} else {
+		statusColor := getStatusColor(timing.StatusCode)
+		fmt.Fprintf(w, "%s %s\n", statusColor("✓ Status:"), statusColor(fmt.Sprintf("%d %s", timing.StatusCode, getStatusText(timing.StatusCode))))
+	}
 	fmt.Fprintf(w, "%s %s\n", color.GreenString("✓ Time:"), formatTimeDuration(time.Duration(timing.Total)))
+	// A redirect that was not followed (--no-follow); followed chains show
+	// their targets in the redirect waterfall
+	if timing.Location != "" && len(timing.Redirects) == 0 {
+		fmt.Fprintf(w, "%s %s\n", color.GreenString("✓ Location:"), timing.Location)
+	}
 
 	if timing.ConnectionReused {
 		fmt.Fprintf(w, "%s %s\n", color.GreenString("✓ Connection:"), "Reused")
 	}
+	if timing.Proxy != "" {
+		fmt.Fprintf(w, "%s %s\n", color.GreenString("✓ Proxy:"), timing.Proxy)
+	}
 
 	fmt.Fprintln(w)
 
 	// Waterfall timeline visualization (like Chrome DevTools)
 	drawWaterfall(w, timing)
 
+	// Each hop of a redirect chain gets its own bar
+	if len(timing.Redirects) > 0 {
+		fmt.Fprintln(w)
+		drawRedirectWaterfall(w, timing)
+	}
+
 	fmt.Fprintln(w)
 
 	// Timing breakdown table (objective metrics only)
@@ -55,7 +78,8 @@ func (f *TableFormatter) Write(w io.Writer, timing *client.TimingBreakdown) erro
 	t.SetTitle("Timing Breakdown")
 	t.AppendHeader(table.Row{"Phase", "Duration", "% of Total"})
 
-	total := timing.Total.Seconds()
+	// With redirects the phases are the last hop's, so they are shares of it
+	total := lastHopTotal(timing).Seconds()
 
 	if timing.DNSLookup > 0 {
 		pct := (timing.DNSLookup.Seconds() / total) * 100
@@ -67,14 +91,36 @@ func (f *TableFormatter) Write(w io.Writer, timing *client.TimingBreakdown) erro
 	}
 
 	if timing.TCPConnection > 0 {
+		label := "TCP Connection"
+		if timing.Proxy != "" {
+			label = "TCP Connection (proxy)"
+		}
 		pct := (timing.TCPConnection.Seconds() / total) * 100
 		t.AppendRow(table.Row{
-			"TCP Connection",
+			label,
 			formatTimeDuration(time.Duration(timing.TCPConnection)),
 			fmt.Sprintf("%.1f%%", pct),
 		})
 	}
 
+	if timing.ProxyTLSHandshake > 0 {
+		pct := (timing.ProxyTLSHandshake.Seconds() / total) * 100
+		t.AppendRow(table.Row{
+			"Proxy TLS Handshake",
+			formatTimeDuration(time.Duration(timing.ProxyTLSHandshake)),
+			fmt.Sprintf("%.1f%%", pct),
+		})
+	}
+
+	if timing.ProxyTunnel > 0 {
+		pct := (timing.ProxyTunnel.Seconds() / total) * 100
+		t.AppendRow(table.Row{
+			"Proxy Tunnel",
+			formatTimeDuration(time.Duration(timing.ProxyTunnel)),
+			fmt.Sprintf("%.1f%%", pct),
+		})
+	}
+
 	if timing.TLSHandshake > 0 {
 		pct := (timing.TLSHandshake.Seconds() / total) * 100
 		t.AppendRow(table.Row{
@@ -84,6 +130,15 @@ func (f *TableFormatter) Write(w io.Writer, timing *client.TimingBreakdown) erro
 		})
 	}
 
+	if timing.AuthChallenge > 0 {
+		pct := (timing.AuthChallenge.Seconds() / total) * 100
+		t.AppendRow(table.Row{
+			"Auth Challenge",
+			formatTimeDuration(time.Duration(timing.AuthChallenge)),
+			fmt.Sprintf("%.1f%%", pct),
+		})
+	}
+
 	if timing.ServerProcessing > 0 {
 		pct := (timing.ServerProcessing.Seconds() / total) * 100
 		t.AppendRow(table.Row{
@@ -103,7 +158,12 @@ func (f *TableFormatter) Write(w io.Writer, timing *client.TimingBreakdown) erro
 	}
 
 	t.AppendSeparator()
-	t.AppendRow(table.Row{"Total", formatTimeDuration(time.Duration(timing.Total)), "100%"})
+	if len(timing.Redirects) > 0 {
+		t.AppendRow(table.Row{"Last Hop", formatTimeDuration(time.Duration(lastHopTotal(timing))), "100%"})
+		t.AppendRow(table.Row{fmt.Sprintf("Total (%d redirects)", len(timing.Redirects)), formatTimeDuration(time.Duration(timing.Total)), ""})
+	} else {
+		t.AppendRow(table.Row{"Total", formatTimeDuration(time.Duration(timing.Total)), "100%"})
+	}
 
 	t.SetStyle(table.StyleLight)
 	t.Render()
@@ -112,8 +172,8 @@ func (f *TableFormatter) Write(w io.Writer, timing *client.TimingBreakdown) erro
 	if len(timing.ResponseHeaders) > 0 {
 		fmt.Fprintln(w)
 		fmt.Fprintf(w, "%s\n", color.CyanString("Response Headers:"))
-		for key, value := range timing.ResponseHeaders {
-			fmt.Fprintf(w, "  %s: %s\n", key, value)
+		for _, h := range timing.ResponseHeaders {
+			fmt.Fprintf(w, "  %s: %s\n", h.Name, h.Value)
 		}
 	}
 
@@ -133,6 +193,20 @@ func (f *TableFormatter) Write(w io.Writer, timing *client.TimingBreakdown) erro
 				fmt.Fprintf(w, "Content Length: %s\n", formatBytes(timing.ContentLength))
 			}
 		}
+		if timing.ContentEncoding != "" {
+			fmt.Fprintf(w, "Content Encoding: %s (%s on the wire → %s decoded, %.2fx, decompression %s)\n",
+				timing.ContentEncoding,
+				formatBytes(timing.WireSize),
+				formatBytes(timing.DecodedSize),
+				timing.CompressionRatio,
+				formatTimeDuration(time.Duration(timing.Decompression)))
+		}
+
+		if timing.RemoteAddr != "" || len(timing.DNSAddresses) > 0 || len(timing.ConnectAttempts) > 0 {
+			writeDNSDetails(w, timing)
+		}
+
+		writeCookies(w, timing)
 
 		// TLS information
 		if timing.TLSVersion != "" {
@@ -143,6 +217,22 @@ func (f *TableFormatter) Write(w io.Writer, timing *client.TimingBreakdown) erro
 			if timing.TLSServerName != "" {
 				fmt.Fprintf(w, "  SNI: %s\n", timing.TLSServerName)
 			}
+			if timing.TLSALPN != "" {
+				fmt.Fprintf(w, "  ALPN: %s\n", timing.TLSALPN)
+			}
+			if timing.TLSResumed {
+				fmt.Fprintf(w, "  Session: %s\n", color.GreenString("resumed"))
+			}
+			if timing.TLSEarlyData {
+				fmt.Fprintf(w, "  Early data: %s\n", color.GreenString("accepted (0-RTT)"))
+			}
+			if timing.TLSClientCertSent {
+				fmt.Fprintf(w, "  Client certificate: %s\n", color.GreenString("sent"))
+			}
+		}
+
+		if len(timing.TLSCertificates) > 0 {
+			writeCertificateChain(w, timing)
 		}
 
 		// Connection info
@@ -158,6 +248,113 @@ func (f *TableFormatter) Write(w io.Writer, timing *client.TimingBreakdown) erro
 	return nil
 }
 
+// writeDNSDetails prints the resolved addresses, CNAME chain and the address connected to
+func writeDNSDetails(w io.Writer, timing *client.TimingBreakdown) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s\n", color.CyanString("DNS & Connection:"))
+	if len(timing.DNSAddresses) > 0 {
+		resolved := strings.Join(timing.DNSAddresses, ", ")
+		if timing.DNSCoalesced {
+			resolved += " (shared with a concurrent lookup)"
+		}
+		fmt.Fprintf(w, "  Resolved: %s\n", resolved)
+	}
+	if len(timing.DNSCNAMEChain) > 0 {
+		fmt.Fprintf(w, "  CNAME chain: %s\n", strings.Join(timing.DNSCNAMEChain, " → "))
+	}
+	for _, record := range timing.DNSRecords {
+		fmt.Fprintf(w, "  %-6s %s → %s (TTL %ds)\n", record.Type, record.Name, record.Value, record.TTL)
+	}
+	if timing.RemoteAddr != "" {
+		fmt.Fprintf(w, "  Connected to: %s\n", timing.RemoteAddr)
+	}
+
+	// A single successful attempt is already covered by "Connected to"
+	if len(timing.ConnectAttempts) > 1 || (len(timing.ConnectAttempts) == 1 && timing.ConnectAttempts[0].Error != "") {
+		fmt.Fprintf(w, "  Connect attempts:\n")
+		for _, a := range timing.ConnectAttempts {
+			result := color.GreenString("connected in %s", formatDuration(a.Duration))
+			if a.Error != "" {
+				result = color.RedString("failed after %s: %s", formatDuration(a.Duration), a.Error)
+			}
+			fmt.Fprintf(w, "    +%-8s %-4s %s %s\n", formatDuration(a.Started), a.Family, a.Address, result)
+		}
+	}
+}
+
+// writeCookies prints the cookies sent and set on each hop of the request
+func writeCookies(w io.Writer, timing *client.TimingBreakdown) {
+	hops := append(append([]client.TimingBreakdown{}, timing.Redirects...), *timing)
+
+	printed := false
+	for i, hop := range hops {
+		if len(hop.CookiesSent) == 0 && len(hop.CookiesSet) == 0 {
+			continue
+		}
+		if !printed {
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "%s\n", color.CyanString("Cookies:"))
+			printed = true
+		}
+
+		indent := "  "
+		if len(hops) > 1 {
+			fmt.Fprintf(w, "  Hop %d: %s\n", i+1, hop.URL)
+			indent = "    "
+		}
+		for _, c := range hop.CookiesSent {
+			fmt.Fprintf(w, "%s%s %s\n", indent, color.CyanString("→ sent"), c)
+		}
+		for _, c := range hop.CookiesSet {
+			fmt.Fprintf(w, "%s%s %s\n", indent, color.GreenString("← set "), c)
+		}
+	}
+}
+
+// writeCertificateChain prints the peer certificate chain with expiry details
+func writeCertificateChain(w io.Writer, timing *client.TimingBreakdown) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s\n", color.CyanString("Certificate Chain:"))
+	for i, cert := range timing.TLSCertificates {
+		fmt.Fprintf(w, "  #%d %s\n", i, cert.Subject)
+		fmt.Fprintf(w, "     Issuer: %s\n", cert.Issuer)
+		if len(cert.DNSNames) > 0 || len(cert.IPAddresses) > 0 {
+			sans := append(append([]string{}, cert.DNSNames...), cert.IPAddresses...)
+			fmt.Fprintf(w, "     SANs: %s\n", strings.Join(sans, ", "))
+		}
+		fmt.Fprintf(w, "     Valid: %s to %s (%s)\n",
+			cert.NotBefore.Format("2006-01-02"),
+			cert.NotAfter.Format("2006-01-02"),
+			formatDaysUntilExpiry(cert.DaysUntilExpiry))
+		if cert.KeySize > 0 {
+			fmt.Fprintf(w, "     Key: %s %d bits, Signature: %s\n", cert.KeyType, cert.KeySize, cert.SignatureAlgorithm)
+		} else {
+			fmt.Fprintf(w, "     Key: %s, Signature: %s\n", cert.KeyType, cert.SignatureAlgorithm)
+		}
+	}
+
+	if timing.TLSOCSPStapled {
+		fmt.Fprintf(w, "  OCSP stapling: %s\n", color.GreenString("present"))
+	} else {
+		fmt.Fprintf(w, "  OCSP stapling: not present\n")
+	}
+	fmt.Fprintf(w, "  SCTs: %d\n", timing.TLSSCTCount)
+}
+
+// formatDaysUntilExpiry colors the remaining validity of a certificate
+func formatDaysUntilExpiry(days int) string {
+	switch {
+	case days < 0:
+		return color.RedString("expired %d days ago", -days)
+	case days < 14:
+		return color.RedString("%d days left", days)
+	case days < 30:
+		return color.YellowString("%d days left", days)
+	default:
+		return color.GreenString("%d days left", days)
+	}
+}
+
 // FormatMultiple formats multiple timing results as statistics
 func (f *TableFormatter) FormatMultiple(stats *metrics.Stats) (string, error) {
 	var buf strings.Builder
@@ -210,9 +407,58 @@ func (f *TableFormatter) WriteMultiple(w io.Writer, stats *metrics.Stats) error
 		st.Render()
 	}
 
+	if len(stats.GRPCStatus) > 0 {
+		fmt.Fprintln(w)
+		gt := table.NewWriter()
+		gt.SetOutputMirror(w)
+		gt.SetTitle("gRPC Status Distribution")
+		gt.AppendHeader(table.Row{"Status", "Count", "Percentage"})
+		for _, status := range sortedKeys(stats.GRPCStatus) {
+			count := stats.GRPCStatus[status]
+			pct := (float64(count) / float64(stats.TotalRequests)) * 100
+			gt.AppendRow(table.Row{status, count, fmt.Sprintf("%.1f%%", pct)})
+		}
+		gt.SetStyle(table.StyleLight)
+		gt.Render()
+	}
+
+	// Per-backend latency, only interesting with more than one remote IP
+	if len(stats.RemoteIPs) > 1 {
+		fmt.Fprintln(w)
+		writeRemoteIPTable(w, stats.RemoteIPs)
+	}
+
+	if stats.Tokens != nil {
+		fmt.Fprintln(w)
+		writeTokenStats(w, stats.Tokens)
+	}
+
 	return nil
 }
 
+// writeRemoteIPTable renders the latency distribution for each remote IP
+func writeRemoteIPTable(w io.Writer, remoteIPs []metrics.RemoteIPStats) {
+	rt := table.NewWriter()
+	rt.SetOutputMirror(w)
+	rt.SetTitle("Latency by Remote IP")
+	rt.AppendHeader(table.Row{"Remote IP", "Requests", "Failed", "Min", "Mean", "P50", "P90", "P99", "Max"})
+	for _, r := range remoteIPs {
+		rt.AppendRow(table.Row{
+			r.IP,
+			r.Requests,
+			r.Failed,
+			formatDuration(r.MinLatency),
+			formatDuration(r.MeanLatency),
+			formatDuration(r.P50),
+			formatDuration(r.P90),
+			formatDuration(r.P99),
+			formatDuration(r.MaxLatency),
+		})
+	}
+	rt.SetStyle(table.StyleLight)
+	rt.Render()
+}
+
 // Helper functions
 
 func getStatusColor(code int) func(string, ...interface{}) string {
@@ -466,72 +712,36 @@ func assessTimeTotal(d time.Duration) string {
 	}
 }
 
+// Phase colors shared by the waterfall views
+var (
+	dnsColor     = color.New(color.FgMagenta)
+	tcpColor     = color.New(color.FgYellow)
+	proxyColor   = color.New(color.FgHiYellow)
+	tlsColor     = color.New(color.FgCyan)
+	authColor    = color.New(color.FgRed)
+	serverColor  = color.New(color.FgGreen)
+	contentColor = color.New(color.FgBlue)
+)
+
 // drawWaterfall creates a horizontal timeline visualization of request phases
 // Similar to Chrome DevTools Network tab waterfall view
 func drawWaterfall(w io.Writer, timing *client.TimingBreakdown) {
 	fmt.Fprintln(w, "Request Timeline:")
 
-	totalMs := float64(timing.Total.Milliseconds())
+	totalMs := durationMs(timing.Total)
 	if totalMs == 0 {
 		return
 	}
 
-	// Calculate width for each phase (max 60 chars wide)
-	maxWidth := 60
-
-	// Define colors for each phase (using 256-color ANSI)
-	dnsColor := color.New(color.FgMagenta)
-	tcpColor := color.New(color.FgYellow)
-	tlsColor := color.New(color.FgCyan)
-	serverColor := color.New(color.FgGreen)
-	contentColor := color.New(color.FgBlue)
-
-	// Calculate bar widths
-	dnsWidth := int((float64(timing.DNSLookup.Milliseconds()) / totalMs) * float64(maxWidth))
-	tcpWidth := int((float64(timing.TCPConnection.Milliseconds()) / totalMs) * float64(maxWidth))
-	tlsWidth := int((float64(timing.TLSHandshake.Milliseconds()) / totalMs) * float64(maxWidth))
-	serverWidth := int((float64(timing.ServerProcessing.Milliseconds()) / totalMs) * float64(maxWidth))
-	contentWidth := int((float64(timing.ContentTransfer.Milliseconds()) / totalMs) * float64(maxWidth))
-
-	// Ensure at least 1 char width for non-zero values
-	if timing.DNSLookup > 0 && dnsWidth == 0 {
-		dnsWidth = 1
-	}
-	if timing.TCPConnection > 0 && tcpWidth == 0 {
-		tcpWidth = 1
-	}
-	if timing.TLSHandshake > 0 && tlsWidth == 0 {
-		tlsWidth = 1
-	}
-	if timing.ServerProcessing > 0 && serverWidth == 0 {
-		serverWidth = 1
-	}
-	if timing.ContentTransfer > 0 && contentWidth == 0 {
-		contentWidth = 1
-	}
-
-	// Draw the waterfall bar
+	// Draw the waterfall bar (max 60 chars wide)
 	fmt.Fprint(w, "  ")
-	if dnsWidth > 0 {
-		dnsColor.Fprint(w, strings.Repeat("█", dnsWidth))
-	}
-	if tcpWidth > 0 {
-		tcpColor.Fprint(w, strings.Repeat("█", tcpWidth))
-	}
-	if tlsWidth > 0 {
-		tlsColor.Fprint(w, strings.Repeat("█", tlsWidth))
-	}
-	if serverWidth > 0 {
-		serverColor.Fprint(w, strings.Repeat("█", serverWidth))
-	}
-	if contentWidth > 0 {
-		contentColor.Fprint(w, strings.Repeat("█", contentWidth))
-	}
+	drawPhaseBar(w, timing, totalMs, 60)
 	fmt.Fprintf(w, " %s\n", formatTimeDuration(time.Duration(timing.Total)))
 
 	// Draw legend
 	fmt.Fprintln(w)
 	fmt.Fprint(w, "  ")
+	proxy := timing.ProxyTLSHandshake + timing.ProxyTunnel
 	if timing.DNSLookup > 0 {
 		dnsColor.Fprint(w, "■")
 		fmt.Fprintf(w, " DNS (%s)  ", formatTimeDuration(time.Duration(timing.DNSLookup)))
@@ -540,10 +750,18 @@ func drawWaterfall(w io.Writer, timing *client.TimingBreakdown) {
 		tcpColor.Fprint(w, "■")
 		fmt.Fprintf(w, " TCP (%s)  ", formatTimeDuration(time.Duration(timing.TCPConnection)))
 	}
+	if proxy > 0 {
+		proxyColor.Fprint(w, "■")
+		fmt.Fprintf(w, " Proxy (%s)  ", formatTimeDuration(time.Duration(proxy)))
+	}
 	if timing.TLSHandshake > 0 {
 		tlsColor.Fprint(w, "■")
 		fmt.Fprintf(w, " TLS (%s)  ", formatTimeDuration(time.Duration(timing.TLSHandshake)))
 	}
+	if timing.AuthChallenge > 0 {
+		authColor.Fprint(w, "■")
+		fmt.Fprintf(w, " Auth (%s)  ", formatTimeDuration(time.Duration(timing.AuthChallenge)))
+	}
 	if timing.ServerProcessing > 0 {
 		serverColor.Fprint(w, "■")
 		fmt.Fprintf(w, " Server (%s)  ", formatTimeDuration(time.Duration(timing.ServerProcessing)))
@@ -554,3 +772,83 @@ func drawWaterfall(w io.Writer, timing *client.TimingBreakdown) {
 	}
 	fmt.Fprintln(w)
 }
+
+// drawPhaseBar draws the colored phase segments of one request, scaled so
+// that scaleMs fills maxWidth characters
+func drawPhaseBar(w io.Writer, timing *client.TimingBreakdown, scaleMs float64, maxWidth int) {
+	phases := []struct {
+		duration client.Duration
+		color    *color.Color
+	}{
+		{timing.DNSLookup, dnsColor},
+		{timing.TCPConnection, tcpColor},
+		{timing.ProxyTLSHandshake + timing.ProxyTunnel, proxyColor},
+		{timing.TLSHandshake, tlsColor},
+		{timing.AuthChallenge, authColor},
+		{timing.ServerProcessing, serverColor},
+		{timing.ContentTransfer, contentColor},
+	}
+
+	for _, phase := range phases {
+		width := int((durationMs(phase.duration) / scaleMs) * float64(maxWidth))
+		// Ensure at least 1 char width for non-zero values
+		if phase.duration > 0 && width == 0 {
+			width = 1
+		}
+		if width > 0 {
+			phase.color.Fprint(w, strings.Repeat("█", width))
+		}
+	}
+}
+
+// drawRedirectWaterfall stacks one bar per hop on a shared time axis, each
+// starting where the previous hop ended
+func drawRedirectWaterfall(w io.Writer, timing *client.TimingBreakdown) {
+	fmt.Fprintf(w, "%s\n", color.CyanString("Redirect Chain:"))
+
+	totalMs := durationMs(timing.Total)
+	if totalMs == 0 {
+		totalMs = 1
+	}
+	const maxWidth = 50
+
+	final := *timing
+	final.Total = lastHopTotal(timing)
+
+	hops := append(append([]client.TimingBreakdown{}, timing.Redirects...), final)
+	var offset client.Duration
+	for i, hop := range hops {
+		statusColor := getStatusColor(hop.StatusCode)
+		target := hop.URL
+		if i == len(hops)-1 && target == "" {
+			target = "(final)"
+		}
+		fmt.Fprintf(w, "  #%d %s %s\n", i+1, statusColor("%d", hop.StatusCode), target)
+
+		pad := int((durationMs(offset) / totalMs) * maxWidth)
+		fmt.Fprintf(w, "     %s", strings.Repeat(" ", pad))
+		drawPhaseBar(w, &hop, totalMs, maxWidth)
+		fmt.Fprintf(w, " %s\n", formatTimeDuration(time.Duration(hop.Total)))
+
+		if hop.Location != "" {
+			fmt.Fprintf(w, "     → %s\n", hop.Location)
+		}
+		offset += hop.Total
+	}
+}
+
+// lastHopTotal is the duration of the final hop: what remains of Total after
+// the redirects
+func lastHopTotal(timing *client.TimingBreakdown) client.Duration {
+	total := timing.Total
+	for _, hop := range timing.Redirects {
+		total -= hop.Total
+	}
+	return max(total, 0)
+}
+
+// durationMs is d in fractional milliseconds, so that sub-millisecond
+// phases still get their share of a bar
+func durationMs(d client.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,109 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/erfi/gocurl/internal/metrics"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// WriteTokenMetrics outputs the token streaming analysis of a single request
+func WriteTokenMetrics(w io.Writer, m *client.TokenMetrics) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s\n", color.CyanString("Token Streaming (%s):", m.Format))
+	if m.Model != "" {
+		fmt.Fprintf(w, "  Model: %s\n", m.Model)
+	}
+	if m.Error != "" {
+		fmt.Fprintf(w, "  %s Stream error: %s\n", color.RedString("✗"), m.Error)
+	}
+	if m.Deltas == 0 {
+		fmt.Fprintf(w, "  %s No output deltas received\n", color.YellowString("⚠"))
+		if m.Malformed > 0 {
+			fmt.Fprintf(w, "  %d message(s) were not valid JSON; check --stream-format\n", m.Malformed)
+		}
+		return
+	}
+
+	fmt.Fprintf(w, "  Output: %s\n", tokenCount(m))
+	if m.InputTokens > 0 {
+		fmt.Fprintf(w, "  Input tokens: %d\n", m.InputTokens)
+	}
+	if m.StopReason != "" {
+		fmt.Fprintf(w, "  Stop reason: %s\n", m.StopReason)
+	}
+	fmt.Fprintf(w, "  Time to first token: %s\n", formatDuration(m.TimeToFirstToken))
+	if m.Deltas > 1 {
+		fmt.Fprintf(w, "  Generation rate: %.1f tokens/s (%.1f deltas/s)\n", m.TokensPerSecond, m.DeltasPerSecond)
+		fmt.Fprintf(w, "  Inter-token latency: mean %s, p50 %s, p90 %s, p99 %s, max %s\n",
+			formatDuration(m.InterTokenMean), formatDuration(m.InterTokenP50), formatDuration(m.InterTokenP90),
+			formatDuration(m.InterTokenP99), formatDuration(m.InterTokenMax))
+	}
+	if m.Malformed > 0 {
+		fmt.Fprintf(w, "  %s %d message(s) were not valid JSON\n", color.YellowString("⚠"), m.Malformed)
+	}
+}
+
+// tokenCount says where the output token count came from
+func tokenCount(m *client.TokenMetrics) string {
+	if m.UsageReported {
+		return fmt.Sprintf("%d tokens in %d deltas", m.OutputTokens, m.Deltas)
+	}
+	return fmt.Sprintf("%d deltas (no usage reported, deltas counted as tokens)", m.Deltas)
+}
+
+// writeTokenStats renders the token streaming aggregate of a load test
+func writeTokenStats(w io.Writer, ts *metrics.TokenStats) {
+	tt := table.NewWriter()
+	tt.SetOutputMirror(w)
+	tt.SetTitle("Token Streaming")
+	tt.AppendHeader(table.Row{"Metric", "Min/Mean", "P50", "P90", "P99", "Max"})
+	tt.AppendRow(table.Row{
+		"Time to first token",
+		fmt.Sprintf("%s / %s", formatDuration(ts.MinTTFT), formatDuration(ts.MeanTTFT)),
+		formatDuration(ts.TTFTP50),
+		formatDuration(ts.TTFTP90),
+		formatDuration(ts.TTFTP99),
+		formatDuration(ts.MaxTTFT),
+	})
+	tt.AppendRow(table.Row{
+		"Inter-token latency",
+		fmt.Sprintf("- / %s", formatDuration(ts.InterTokenMean)),
+		formatDuration(ts.InterTokenP50),
+		formatDuration(ts.InterTokenP90),
+		formatDuration(ts.InterTokenP99),
+		formatDuration(ts.InterTokenMax),
+	})
+	tt.SetStyle(table.StyleLight)
+	tt.Render()
+
+	fmt.Fprintf(w, "Output tokens: %d", ts.OutputTokens)
+	if ts.InputTokens > 0 {
+		fmt.Fprintf(w, " (input: %d)", ts.InputTokens)
+	}
+	if ts.UsageReported < ts.Requests {
+		fmt.Fprintf(w, ", %d of %d requests without usage counted by delta", ts.Requests-ts.UsageReported, ts.Requests)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Throughput: %.1f tokens/s overall, %.1f tokens/s per request\n", ts.TokensPerSecond, ts.MeanTokensPerSecond)
+
+	if len(ts.StopReasons) > 0 {
+		reasons := make([]string, 0, len(ts.StopReasons))
+		for reason := range ts.StopReasons {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for i, reason := range reasons {
+			reasons[i] = fmt.Sprintf("%s %d", reason, ts.StopReasons[reason])
+		}
+		fmt.Fprintf(w, "Stop reasons: %s\n", strings.Join(reasons, ", "))
+	}
+	if ts.StreamErrors > 0 {
+		fmt.Fprintf(w, "%s %d stream(s) reported an error\n", color.YellowString("⚠"), ts.StreamErrors)
+	}
+}
@@ -0,0 +1,64 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/erfi/gocurl/internal/client"
+	"github.com/fatih/color"
+)
+
+// WriteWebSocketMetrics outputs the message phase of a WebSocket session; the
+// upgrade is covered by the regular timing table
+func WriteWebSocketMetrics(w io.Writer, m *client.WebSocketMetrics, verbose bool) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%s\n", color.CyanString("WebSocket:"))
+	if m.Subprotocol != "" {
+		fmt.Fprintf(w, "  Subprotocol: %s\n", m.Subprotocol)
+	}
+	if m.Extensions != "" {
+		fmt.Fprintf(w, "  Extensions: %s\n", m.Extensions)
+	}
+	fmt.Fprintf(w, "  Sent: %d message(s), %s\n", m.MessagesSent, formatBytes(m.BytesSent))
+	fmt.Fprintf(w, "  Received: %d message(s), %s\n", m.MessagesReceived, formatBytes(m.BytesReceived))
+
+	if m.RoundTrips > 0 {
+		fmt.Fprintf(w, "  Round trips: %d (min %s, mean %s, p50 %s, p90 %s, p99 %s, max %s)\n",
+			m.RoundTrips, formatDuration(m.MinRTT), formatDuration(m.MeanRTT), formatDuration(m.P50RTT),
+			formatDuration(m.P90RTT), formatDuration(m.P99RTT), formatDuration(m.MaxRTT))
+		if m.MessagesPerSecond > 0 {
+			fmt.Fprintf(w, "  Echo throughput: %.1f messages/s\n", m.MessagesPerSecond)
+		}
+		if verbose {
+			for i, rtt := range m.RTTs {
+				fmt.Fprintf(w, "    #%d %s\n", i+1, formatDuration(rtt))
+			}
+		}
+	}
+
+	switch {
+	case m.ClosedByServer:
+		fmt.Fprintf(w, "  %s Closed by the server: %s\n", color.YellowString("⚠"), closeStatus(m))
+	case m.CloseCode != 0:
+		fmt.Fprintf(w, "  Close handshake: %s (%s)\n", formatDuration(m.CloseHandshake), closeStatus(m))
+	default:
+		fmt.Fprintf(w, "  %s No close frame from the server\n", color.YellowString("⚠"))
+	}
+	fmt.Fprintf(w, "  Session: %s\n", formatDuration(m.Session))
+
+	if m.Pushed != nil {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%s\n", color.CyanString("Server-Pushed Messages:"))
+		fmt.Fprintf(w, "  %d message(s), first after %s, last after %s\n",
+			m.Pushed.TotalChunks, formatDuration(m.Pushed.FirstChunkTime), formatDuration(m.Pushed.LastChunkTime))
+		WriteStreamingMetrics(w, m.Pushed, verbose)
+	}
+}
+
+// closeStatus formats a close code with its reason
+func closeStatus(m *client.WebSocketMetrics) string {
+	if m.CloseReason != "" {
+		return fmt.Sprintf("%d %s", m.CloseCode, m.CloseReason)
+	}
+	return fmt.Sprintf("%d", m.CloseCode)
+}
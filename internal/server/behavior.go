@@ -0,0 +1,207 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Behavior is the response a request asks for through its query parameters
+type Behavior struct {
+	Status      int           // status: response status code
+	Latency     time.Duration // latency: delay before the response headers
+	Jitter      time.Duration // jitter: random extra delay in [0, jitter)
+	Size        int64         // size: body size, e.g. 512, 10KB, 1MB
+	Chunks      int           // chunks: number of flushed writes the body is split into
+	Interval    time.Duration // interval: delay between chunks
+	Buffer      bool          // buffer: produce the chunks at the cadence but send them all at the end
+	Stall       time.Duration // stall: pause in the middle of the stream
+	StallAfter  int           // stall_after: chunk after which the stall happens (default: half way)
+	Reset       string        // reset: abort the connection at "headers" or mid-"body"
+	ContentType string        // type: Content-Type of the response
+	Format      string        // format: frame each chunk as an event or token delta
+}
+
+// Reset points
+const (
+	ResetHeaders = "headers"
+	ResetBody    = "body"
+)
+
+// Body formats
+const (
+	FormatSSE       = "sse"       // Server-Sent Events, one event per chunk
+	FormatOpenAI    = "openai"    // OpenAI chat completion stream, one delta per chunk
+	FormatAnthropic = "anthropic" // Anthropic Messages stream, one text delta per chunk
+	FormatNDJSON    = "ndjson"    // Ollama-style JSON lines, one delta per chunk
+)
+
+// contentTypes is the default Content-Type of each format
+var contentTypes = map[string]string{
+	FormatSSE:       "text/event-stream",
+	FormatOpenAI:    "text/event-stream",
+	FormatAnthropic: "text/event-stream",
+	FormatNDJSON:    "application/x-ndjson",
+}
+
+// defaultChunkSize is the chunk size when chunks is given without size
+const defaultChunkSize = 128
+
+// ParseBehavior reads a Behavior from query parameters
+func ParseBehavior(query url.Values) (Behavior, error) {
+	b := Behavior{Status: 200, ContentType: "text/plain; charset=utf-8"}
+
+	for key, values := range query {
+		value := values[len(values)-1]
+		var err error
+		switch key {
+		case "status":
+			b.Status, err = strconv.Atoi(value)
+			if err == nil && (b.Status < 100 || b.Status > 999) {
+				err = fmt.Errorf("out of range")
+			}
+		case "latency":
+			b.Latency, err = parseDelay(value)
+		case "jitter":
+			b.Jitter, err = parseDelay(value)
+		case "size":
+			b.Size, err = ParseSize(value)
+		case "chunks":
+			b.Chunks, err = strconv.Atoi(value)
+			if err == nil && b.Chunks < 0 {
+				err = fmt.Errorf("must be 0 or more")
+			}
+		case "interval":
+			b.Interval, err = parseDelay(value)
+		case "buffer":
+			b.Buffer, err = strconv.ParseBool(value)
+		case "stall":
+			b.Stall, err = parseDelay(value)
+		case "stall_after":
+			b.StallAfter, err = strconv.Atoi(value)
+			if err == nil && b.StallAfter < 1 {
+				err = fmt.Errorf("must be 1 or more")
+			}
+		case "reset":
+			b.Reset = value
+			if value != ResetHeaders && value != ResetBody {
+				err = fmt.Errorf("must be %s or %s", ResetHeaders, ResetBody)
+			}
+		case "type":
+			b.ContentType = value
+		case "format":
+			b.Format = value
+			if _, ok := contentTypes[value]; !ok {
+				err = fmt.Errorf("must be %s, %s, %s or %s", FormatSSE, FormatOpenAI, FormatAnthropic, FormatNDJSON)
+			}
+		default:
+			return b, fmt.Errorf("unknown parameter '%s'", key)
+		}
+		if err != nil {
+			return b, fmt.Errorf("invalid %s '%s': %v", key, value, err)
+		}
+	}
+
+	if b.Format != "" && query.Get("type") == "" {
+		b.ContentType = contentTypes[b.Format]
+	}
+	if b.Format != "" && b.Chunks == 0 {
+		// A framed body is at least one event
+		b.Chunks = 1
+	}
+	if b.Chunks > 0 && b.Size == 0 {
+		b.Size = int64(b.Chunks) * defaultChunkSize
+	}
+	if b.Chunks == 0 && (b.Interval > 0 || b.Stall > 0) {
+		return b, fmt.Errorf("interval and stall need chunks")
+	}
+	if b.Stall > 0 && b.StallAfter == 0 {
+		b.StallAfter = (b.Chunks + 1) / 2
+	}
+	if b.StallAfter >= b.Chunks && b.Stall > 0 {
+		return b, fmt.Errorf("stall_after must be less than chunks")
+	}
+	return b, nil
+}
+
+// parseDelay parses a non-negative duration
+func parseDelay(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("expected a duration such as 100ms")
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return d, nil
+}
+
+// ParseSize parses a byte count with an optional binary unit: 512, 4K, 10KB,
+// 1MiB, 2G
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffixes []string
+		size     int64
+	}{
+		{[]string{"GIB", "GB", "G"}, 1 << 30},
+		{[]string{"MIB", "MB", "M"}, 1 << 20},
+		{[]string{"KIB", "KB", "K"}, 1 << 10},
+		{[]string{"B"}, 1},
+	} {
+		matched := false
+		for _, suffix := range unit.suffixes {
+			if strings.HasSuffix(s, suffix) {
+				s = strings.TrimSpace(strings.TrimSuffix(s, suffix))
+				multiplier = unit.size
+				matched = true
+				break
+			}
+		}
+		if matched {
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("expected a size such as 512, 10KB or 1MB")
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// delay returns the latency plus a random share of the jitter
+func (b Behavior) delay() time.Duration {
+	if b.Jitter > 0 {
+		return b.Latency + time.Duration(rand.Int63n(int64(b.Jitter)))
+	}
+	return b.Latency
+}
+
+// chunkSizes splits the body into the requested number of writes
+func (b Behavior) chunkSizes() []int64 {
+	if b.Chunks <= 1 {
+		return []int64{b.Size}
+	}
+	sizes := make([]int64, b.Chunks)
+	for i := range sizes {
+		sizes[i] = b.Size / int64(b.Chunks)
+		// Spread the remainder over the first chunks
+		if int64(i) < b.Size%int64(b.Chunks) {
+			sizes[i]++
+		}
+	}
+	return sizes
+}
+
+// streamDuration is how long producing the chunks takes
+func (b Behavior) streamDuration() time.Duration {
+	if b.Chunks <= 1 {
+		return b.Stall
+	}
+	return time.Duration(b.Chunks-1)*b.Interval + b.Stall
+}
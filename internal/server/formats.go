@@ -0,0 +1,77 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// mockModel is the model name the token stream formats report
+const mockModel = "gocurl-mock"
+
+// mockPromptTokens is the prompt size the token stream formats report
+const mockPromptTokens = 12
+
+// writePrologue writes what a format sends before the first delta
+func writePrologue(w io.Writer, b Behavior) error {
+	var err error
+	if b.Format == FormatAnthropic {
+		_, err = fmt.Fprintf(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":"+
+			"{\"id\":\"msg_gocurl\",\"type\":\"message\",\"role\":\"assistant\",\"model\":%q,\"content\":[],"+
+			"\"stop_reason\":null,\"usage\":{\"input_tokens\":%d,\"output_tokens\":1}}}\n\n"+
+			"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,"+
+			"\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n", mockModel, mockPromptTokens)
+	}
+	return err
+}
+
+// writeChunk writes one chunk of the body, framed as the format asks. Each
+// chunk of a token stream format is one delta and counts as one token.
+func writeChunk(w io.Writer, b Behavior, i int, size int64) error {
+	var err error
+	switch b.Format {
+	case "":
+		return writeFill(w, size)
+	case FormatSSE:
+		_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", i, text(size))
+	case FormatOpenAI:
+		_, err = fmt.Fprintf(w, "data: {\"id\":\"chatcmpl-gocurl\",\"object\":\"chat.completion.chunk\",\"model\":%q,"+
+			"\"choices\":[{\"index\":0,\"delta\":{\"content\":%q},\"finish_reason\":null}]}\n\n", mockModel, text(size))
+	case FormatAnthropic:
+		_, err = fmt.Fprintf(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,"+
+			"\"delta\":{\"type\":\"text_delta\",\"text\":%q}}\n\n", text(size))
+	case FormatNDJSON:
+		_, err = fmt.Fprintf(w, "{\"model\":%q,\"response\":%q,\"done\":false}\n", mockModel, text(size))
+	}
+	return err
+}
+
+// writeEpilogue writes the stop reason and usage a format sends after the
+// last delta
+func writeEpilogue(w io.Writer, b Behavior, tokens int) error {
+	var err error
+	switch b.Format {
+	case FormatOpenAI:
+		_, err = fmt.Fprintf(w, "data: {\"id\":\"chatcmpl-gocurl\",\"object\":\"chat.completion.chunk\",\"model\":%q,"+
+			"\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}],"+
+			"\"usage\":{\"prompt_tokens\":%d,\"completion_tokens\":%d,\"total_tokens\":%d}}\n\ndata: [DONE]\n\n",
+			mockModel, mockPromptTokens, tokens, mockPromptTokens+tokens)
+	case FormatAnthropic:
+		_, err = fmt.Fprintf(w, "event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n"+
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\","+
+			"\"stop_sequence\":null},\"usage\":{\"output_tokens\":%d}}\n\n"+
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n", tokens)
+	case FormatNDJSON:
+		_, err = fmt.Fprintf(w, "{\"model\":%q,\"response\":\"\",\"done\":true,\"done_reason\":\"stop\","+
+			"\"prompt_eval_count\":%d,\"eval_count\":%d}\n", mockModel, mockPromptTokens, tokens)
+	}
+	return err
+}
+
+// text returns n bytes of the fill pattern without its newline, for event
+// data and JSON strings
+func text(n int64) string {
+	var sb strings.Builder
+	writePattern(&sb, fill[:len(fill)-1], n)
+	return sb.String()
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const usage = `gocurl calibration server
+
+Every path accepts these query parameters:
+
+  status=503          response status code (default 200)
+  latency=200ms       delay before the response headers
+  jitter=50ms         random extra delay in [0, jitter)
+  size=10KB           body size (512, 4K, 10KB, 1MiB, ...)
+  type=text/html      Content-Type of the response
+  chunks=20           split the body into flushed writes
+  interval=100ms      delay between chunks
+  buffer=true         produce the chunks at the cadence, send them at the end
+  stall=2s            pause in the middle of the stream
+  stall_after=5       chunk after which the stall happens (default: half way)
+  reset=headers|body  abort the connection before the headers or mid-body
+  format=sse          send each chunk as a Server-Sent Event
+  format=openai       send each chunk as an OpenAI chat completion delta
+  format=anthropic    send each chunk as an Anthropic Messages text delta
+  format=ndjson       send each chunk as an Ollama-style JSON line
+
+Examples:
+
+  /?latency=100ms&jitter=20ms
+  /?chunks=50&interval=20ms
+  /?chunks=10&interval=100ms&buffer=true
+  /?chunks=10&interval=50ms&stall=1s
+  /?format=sse&chunks=20&interval=100ms
+  /?format=openai&chunks=50&interval=20ms&latency=300ms
+  /?status=503&size=1KB
+  /?reset=body&size=1MB
+`
+
+// NewHandler returns the handler that shapes each response from the request's
+// query parameters. A request without parameters to / gets the usage text.
+// Requests are logged to log when it is not nil.
+func NewHandler(log io.Writer) http.Handler {
+	return &handler{log: log}
+}
+
+type handler struct {
+	log io.Writer
+	mu  sync.Mutex
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if r.URL.Path == "/" && r.URL.RawQuery == "" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, usage)
+		h.logf(r, http.StatusOK, start)
+		return
+	}
+
+	b, err := ParseBehavior(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.logf(r, http.StatusBadRequest, start)
+		return
+	}
+
+	// Drain the request body so uploads are timed the same way a real
+	// server would read them
+	io.Copy(io.Discard, r.Body)
+
+	status := b.Status
+	if b.Reset != "" {
+		status = 0
+	}
+	// Deferred so resets that abort the handler are logged too
+	defer h.logf(r, status, start)
+
+	time.Sleep(b.delay())
+
+	if b.Reset == ResetHeaders {
+		reset(w)
+		return
+	}
+	h.respond(w, b)
+}
+
+// respond writes the status and body the behavior asks for
+func (h *handler) respond(w http.ResponseWriter, b Behavior) {
+	header := w.Header()
+	header.Set("Content-Type", b.ContentType)
+	header.Set("X-Gocurl-Server", "calibration")
+
+	sizes := b.chunkSizes()
+	streaming := b.Chunks > 1 && !b.Buffer
+	if streaming {
+		// Ask proxies in front of the server not to buffer the stream
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no")
+	} else if b.Reset == "" && b.Format == "" {
+		header.Set("Content-Length", strconv.FormatInt(b.Size, 10))
+	}
+
+	if b.Buffer {
+		// Produce the chunks at the requested cadence, as a buffering
+		// proxy would receive them, and release them all at once
+		time.Sleep(b.streamDuration())
+	}
+
+	w.WriteHeader(b.Status)
+	if b.Size == 0 {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	if err := writePrologue(w, b); err != nil {
+		return
+	}
+	var written int64
+	for i, size := range sizes {
+		if streaming && i > 0 {
+			time.Sleep(b.Interval)
+			if b.Stall > 0 && i == b.StallAfter {
+				time.Sleep(b.Stall)
+			}
+		}
+
+		if b.Reset == ResetBody && written+size >= b.Size/2 {
+			// Send half the body, then drop the connection
+			writeFill(w, b.Size/2-written)
+			if flusher != nil {
+				flusher.Flush()
+			}
+			reset(w)
+			return
+		}
+
+		if err := writeChunk(w, b, i, size); err != nil {
+			return
+		}
+		written += size
+		if i == len(sizes)-1 {
+			writeEpilogue(w, b, len(sizes))
+		}
+		if streaming && flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// fill is the repeating pattern bodies are made of
+var fill = []byte("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ\n")
+
+// writeFill writes n bytes of the fill pattern
+func writeFill(w io.Writer, n int64) error {
+	return writePattern(w, fill, n)
+}
+
+// writePattern writes n bytes of a repeating pattern
+func writePattern(w io.Writer, pattern []byte, n int64) error {
+	for n > 0 {
+		chunk := pattern
+		if n < int64(len(chunk)) {
+			chunk = chunk[:n]
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+		n -= int64(len(chunk))
+	}
+	return nil
+}
+
+// reset aborts the connection. On HTTP/1 the TCP connection is closed with
+// SO_LINGER 0 so the client sees a reset rather than a clean close; on HTTP/2
+// the stream is reset.
+func reset(w http.ResponseWriter) {
+	if hijacker, ok := w.(http.Hijacker); ok {
+		conn, _, err := hijacker.Hijack()
+		if err == nil {
+			if netConn, ok := conn.(interface{ NetConn() net.Conn }); ok {
+				conn = netConn.NetConn()
+			}
+			if tcp, ok := conn.(*net.TCPConn); ok {
+				tcp.SetLinger(0)
+			}
+			conn.Close()
+			return
+		}
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// logf writes one line per request, status 0 meaning the connection was reset
+func (h *handler) logf(r *http.Request, status int, start time.Time) {
+	if h.log == nil {
+		return
+	}
+	result := strconv.Itoa(status)
+	if status == 0 {
+		result = "reset"
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(h.log, "%s %s %s %s %s %s\n", start.Format("15:04:05.000"), r.Proto, r.Method,
+		r.URL.RequestURI(), result, time.Since(start).Round(time.Millisecond))
+}
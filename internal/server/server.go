@@ -0,0 +1,172 @@
+// Package server implements the calibration server behind `gocurl serve`: a
+// local HTTP/1.1, h2c and h2 server whose latency, size, streaming cadence,
+// buffering, stalls, status codes and resets are set per request, so the
+// client's timing and streaming analysis can be checked against known
+// behaviour.
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Options configures the listeners
+type Options struct {
+	Addr     string        // plain listener, serving HTTP/1.1 and h2c; empty to disable
+	TLSAddr  string        // TLS listener, serving HTTP/1.1 and h2; empty to disable
+	CertFile string        // TLS certificate; a self-signed one is generated when empty
+	KeyFile  string        // TLS private key
+	TLSDelay time.Duration // delay before answering each TLS ClientHello
+	Log      io.Writer     // request log; nil to disable
+}
+
+// Server is a running calibration server
+type Server struct {
+	opts    Options
+	servers []*http.Server
+	addrs   []string
+	errs    chan error
+}
+
+// New creates a server with the given options
+func New(opts Options) (*Server, error) {
+	if opts.Addr == "" && opts.TLSAddr == "" {
+		return nil, fmt.Errorf("no listen address")
+	}
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, fmt.Errorf("--cert and --key must be used together")
+	}
+	return &Server{opts: opts, errs: make(chan error, 2)}, nil
+}
+
+// Start binds the listeners and serves in the background
+func (s *Server) Start() error {
+	handler := NewHandler(s.opts.Log)
+
+	if s.opts.Addr != "" {
+		ln, err := net.Listen("tcp", s.opts.Addr)
+		if err != nil {
+			return err
+		}
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+		s.serve(&http.Server{Handler: handler, Protocols: protocols}, ln, "http://")
+	}
+
+	if s.opts.TLSAddr != "" {
+		config, err := s.tlsConfig()
+		if err != nil {
+			s.Shutdown(context.Background())
+			return err
+		}
+		ln, err := net.Listen("tcp", s.opts.TLSAddr)
+		if err != nil {
+			s.Shutdown(context.Background())
+			return err
+		}
+		s.serve(&http.Server{Handler: handler, TLSConfig: config}, tls.NewListener(ln, config), "https://")
+	}
+	return nil
+}
+
+func (s *Server) serve(srv *http.Server, ln net.Listener, scheme string) {
+	s.servers = append(s.servers, srv)
+	s.addrs = append(s.addrs, scheme+ln.Addr().String())
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.errs <- err
+		}
+	}()
+}
+
+// Addrs returns the base URL of each listener
+func (s *Server) Addrs() []string {
+	return s.addrs
+}
+
+// Err receives an error if a listener stops unexpectedly
+func (s *Server) Err() <-chan error {
+	return s.errs
+}
+
+// Shutdown stops the listeners, waiting for in-flight requests until ctx is
+// done
+func (s *Server) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, srv := range s.servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tlsConfig loads or generates the certificate and applies the handshake delay
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	if s.opts.CertFile != "" {
+		cert, err = tls.LoadX509KeyPair(s.opts.CertFile, s.opts.KeyFile)
+	} else {
+		cert, err = selfSignedCertificate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if delay := s.opts.TLSDelay; delay > 0 {
+		// Stall the handshake after the ClientHello to simulate a slow
+		// TLS terminator
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			time.Sleep(delay)
+			return nil, nil
+		}
+	}
+	return config, nil
+}
+
+// selfSignedCertificate generates a short-lived certificate for localhost
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "gocurl calibration server"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBehavior(t *testing.T) {
+	b, err := ParseBehavior(url.Values{"chunks": {"4"}, "interval": {"10ms"}, "stall": {"1s"}})
+	if err != nil {
+		t.Fatalf("ParseBehavior failed: %v", err)
+	}
+	if b.Status != 200 || b.Size != 4*defaultChunkSize || b.StallAfter != 2 {
+		t.Errorf("Unexpected defaults: %+v", b)
+	}
+	if got := b.streamDuration(); got != 1030*time.Millisecond {
+		t.Errorf("Expected a 1.03s stream, got %v", got)
+	}
+
+	b, _ = ParseBehavior(url.Values{"size": {"10"}, "chunks": {"3"}})
+	if sizes := b.chunkSizes(); len(sizes) != 3 || sizes[0] != 4 || sizes[1] != 3 || sizes[2] != 3 {
+		t.Errorf("Expected chunks of 4, 3 and 3 bytes, got %v", sizes)
+	}
+
+	for _, invalid := range []string{
+		"status=abc", "status=42", "latency=fast", "jitter=-1s", "size=lots", "chunks=-1",
+		"buffer=maybe", "reset=later", "format=xml", "nope=1", "interval=10ms", "chunks=4&stall=1s&stall_after=4",
+	} {
+		query, _ := url.ParseQuery(invalid)
+		if _, err := ParseBehavior(query); err == nil {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":    512,
+		"4k":     4096,
+		"10KB":   10 << 10,
+		"1MiB":   1 << 20,
+		"1.5 MB": 3 << 19,
+		"2G":     2 << 30,
+		"100B":   100,
+	}
+	for input, expected := range tests {
+		got, err := ParseSize(input)
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+		} else if got != expected {
+			t.Errorf("%s: expected %d, got %d", input, expected, got)
+		}
+	}
+	if _, err := ParseSize("-1KB"); err == nil {
+		t.Error("Expected an error for a negative size")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(nil))
+	defer srv.Close()
+
+	get := func(query string) (*http.Response, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/" + query)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", query, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, body := get("")
+	if !strings.Contains(body, "stall_after") {
+		t.Errorf("Expected usage text, got %q", body)
+	}
+
+	resp, body = get("?status=503&size=1KB&type=application/json")
+	if resp.StatusCode != 503 || len(body) != 1024 || resp.ContentLength != 1024 ||
+		resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected response: %d, %d bytes, %s", resp.StatusCode, len(body), resp.Header.Get("Content-Type"))
+	}
+
+	start := time.Now()
+	resp, body = get("?latency=50ms&chunks=5&interval=20ms&size=500")
+	if elapsed := time.Since(start); elapsed < 130*time.Millisecond {
+		t.Errorf("Expected at least 130ms, took %v", elapsed)
+	}
+	if len(body) != 500 || resp.ContentLength != -1 || resp.Header.Get("X-Accel-Buffering") != "no" {
+		t.Errorf("Expected a 500-byte chunked stream, got %d bytes, length %d", len(body), resp.ContentLength)
+	}
+
+	resp, _ = get("?chunks=3&interval=10ms&buffer=true")
+	if resp.ContentLength != 3*defaultChunkSize {
+		t.Errorf("Expected a buffered body with a length, got %d", resp.ContentLength)
+	}
+
+	resp, body = get("?latency=soon")
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, "latency") {
+		t.Errorf("Expected a 400 naming the parameter, got %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestHandlerReset(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(nil))
+	defer srv.Close()
+
+	if resp, err := http.Get(srv.URL + "/?reset=headers"); err == nil {
+		resp.Body.Close()
+		t.Error("Expected the request to fail before the headers")
+	}
+
+	resp, err := http.Get(srv.URL + "/?reset=body&size=64KB")
+	if err != nil {
+		t.Fatalf("Expected headers before the reset, got %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err == nil {
+		t.Error("Expected the body read to fail")
+	}
+	if len(body) >= 64<<10 {
+		t.Errorf("Expected a partial body, got %d bytes", len(body))
+	}
+}
+
+func TestServerProtocols(t *testing.T) {
+	s, err := New(Options{Addr: "127.0.0.1:0", TLSAddr: "127.0.0.1:0", TLSDelay: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+
+	addrs := s.Addrs()
+	if len(addrs) != 2 || !strings.HasPrefix(addrs[0], "http://") || !strings.HasPrefix(addrs[1], "https://") {
+		t.Fatalf("Unexpected addresses: %v", addrs)
+	}
+
+	h2c := new(http.Protocols)
+	h2c.SetUnencryptedHTTP2(true)
+	h2 := new(http.Protocols)
+	h2.SetHTTP2(true)
+
+	tests := []struct {
+		name      string
+		url       string
+		protocols *http.Protocols
+		proto     string
+	}{
+		{"http/1.1", addrs[0], nil, "HTTP/1.1"},
+		{"h2c", addrs[0], h2c, "HTTP/2.0"},
+		{"h2", addrs[1], h2, "HTTP/2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				Protocols:       tt.protocols,
+			}
+			defer transport.CloseIdleConnections()
+
+			start := time.Now()
+			resp, err := (&http.Client{Transport: transport}).Get(tt.url + "/?size=10")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.Proto != tt.proto {
+				t.Errorf("Expected %s, got %s", tt.proto, resp.Proto)
+			}
+			if tt.name == "h2" && time.Since(start) < 50*time.Millisecond {
+				t.Error("Expected the TLS handshake to be delayed")
+			}
+		})
+	}
+}